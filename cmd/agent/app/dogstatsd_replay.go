@@ -13,6 +13,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/DataDog/datadog-agent/cmd/agent/common"
 	"github.com/DataDog/datadog-agent/pkg/api/security"
@@ -32,6 +33,12 @@ var (
 	dsdVerboseReplay    bool
 	dsdMmapReplay       bool
 	dsdReplayIterations int
+	dsdReplaySpeed      float64
+	dsdReplayStart      time.Duration
+	dsdReplayEnd        time.Duration
+	dsdReplayPrefixes   []string
+	dsdReplayPids       []int32
+	dsdReplayDecode     string
 )
 
 const (
@@ -44,6 +51,12 @@ func init() {
 	dogstatsdReplayCmd.Flags().BoolVarP(&dsdVerboseReplay, "verbose", "v", false, "Verbose replay.")
 	dogstatsdReplayCmd.Flags().BoolVarP(&dsdMmapReplay, "mmap", "m", true, "Mmap file for replay. Set to false to load the entire file into memory instead")
 	dogstatsdReplayCmd.Flags().IntVarP(&dsdReplayIterations, "loops", "l", defaultIterations, "Number of iterationsi to replay.")
+	dogstatsdReplayCmd.Flags().Float64VarP(&dsdReplaySpeed, "speed", "s", 1, "Replay speed multiplier, e.g. 2 to replay twice as fast as recorded. Set to 0 to replay as fast as possible.")
+	dogstatsdReplayCmd.Flags().DurationVar(&dsdReplayStart, "start", 0, "Offset from the first packet of the capture at which the replay starts, e.g. 30s.")
+	dogstatsdReplayCmd.Flags().DurationVar(&dsdReplayEnd, "end", 0, "Offset from the first packet of the capture at which the replay ends, e.g. 1m. Set to 0 to replay until the end of the capture.")
+	dogstatsdReplayCmd.Flags().StringSliceVar(&dsdReplayPrefixes, "prefix", nil, "Only replay the metrics whose name starts with one of these prefixes.")
+	dogstatsdReplayCmd.Flags().Int32SliceVar(&dsdReplayPids, "pid", nil, "Only replay the packets sent by these PIDs.")
+	dogstatsdReplayCmd.Flags().StringVarP(&dsdReplayDecode, "decode", "d", "", "Print the packets of the capture as \"text\" or \"json\" instead of replaying them.")
 }
 
 var dogstatsdReplayCmd = &cobra.Command{
@@ -67,10 +80,47 @@ var dogstatsdReplayCmd = &cobra.Command{
 			return err
 		}
 
+		if dsdReplaySpeed < 0 {
+			return fmt.Errorf("invalid replay speed %v, must be positive", dsdReplaySpeed)
+		}
+		if dsdReplayEnd > 0 && dsdReplayEnd < dsdReplayStart {
+			return fmt.Errorf("the end of the replay must be after its start")
+		}
+		if dsdReplayDecode != "" {
+			return dogstatsdDecodeCapture()
+		}
 		return dogstatsdReplay()
 	},
 }
 
+func dogstatsdReplayOptions() replay.ReadOptions {
+	opts := replay.ReadOptions{
+		Speed: dsdReplaySpeed,
+		Start: dsdReplayStart,
+		End:   dsdReplayEnd,
+	}
+	if len(dsdReplayPrefixes) > 0 || len(dsdReplayPids) > 0 {
+		opts.Filter = &replay.PacketFilter{
+			MetricPrefixes: dsdReplayPrefixes,
+			Pids:           dsdReplayPids,
+		}
+	}
+	return opts
+}
+
+// dogstatsdDecodeCapture prints the packets of the capture without sending them to the agent.
+func dogstatsdDecodeCapture() error {
+	reader, err := replay.NewTrafficCaptureReader(dsdReplayFilePath, 0, dsdMmapReplay)
+	if reader != nil {
+		defer reader.Close()
+	}
+	if err != nil {
+		fmt.Printf("could not open: %s\n", dsdReplayFilePath)
+		return err
+	}
+	return reader.Decode(os.Stdout, dsdReplayDecode, dogstatsdReplayOptions())
+}
+
 func dogstatsdReplay() error {
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -176,7 +226,7 @@ func dogstatsdReplay() error {
 
 		// enable reading at natural rate
 		ready := make(chan struct{})
-		go reader.Read(ready)
+		go reader.ReadWithOptions(ready, dogstatsdReplayOptions())
 
 		// wait for go routine to start processing...
 		<-ready
@@ -10,9 +10,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 
 	"github.com/DataDog/datadog-agent/cmd/agent/common"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/api/util"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/dogstatsd"
@@ -107,6 +109,7 @@ func requestDogstatsdStats() error {
 			fmt.Printf("Could not format the statistics, the data must be inconsistent. You may want to try the JSON output. Contact the support if you continue having issues.\n")
 			return nil
 		}
+		s += "\n" + requestDogstatsdContextLimits(c, ipcAddress)
 	}
 
 	if dsdStatsFilePath == "" {
@@ -130,3 +133,18 @@ func requestDogstatsdStats() error {
 
 	return nil
 }
+
+// requestDogstatsdContextLimits returns the formatted list of the metric names and origins over the
+// dogstatsd context limits during the last flush.
+func requestDogstatsdContextLimits(c *http.Client, ipcAddress string) string {
+	urlstr := fmt.Sprintf("https://%v:%v/agent/dogstatsd-context-limits", ipcAddress, config.Datadog.GetInt("cmd_port"))
+	r, err := util.DoGet(c, urlstr, util.LeaveConnectionOpen)
+	if err != nil {
+		return fmt.Sprintf("Could not get the context limits: %v\n", err)
+	}
+	s, err := aggregator.FormatContextLimiterOffenders(r)
+	if err != nil {
+		return fmt.Sprintf("Could not format the context limits: %v\n", err)
+	}
+	return "Context limits (last flush):\n\n" + s
+}
@@ -0,0 +1,255 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/common"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	logsconfig "github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/ruletester"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	grokPattern         string
+	grokCustomPatterns  []string
+	grokTimestampField  string
+	grokTimestampLayout string
+	grokStatusField     string
+	grokSampleFilePath  string
+)
+
+// maxSampleLineSize is the maximum size of a sample line, it matches the size limit of the decoders.
+const maxSampleLineSize = 256 * 1000
+
+func init() {
+	AgentCmd.AddCommand(logsCmd)
+	logsCmd.AddCommand(logsGrokCmd)
+	logsGrokCmd.Flags().StringVarP(&grokPattern, "pattern", "p", "", "Grok pattern to test, e.g. '%{COMMONAPACHELOG}'")
+	logsGrokCmd.Flags().StringArrayVarP(&grokCustomPatterns, "custom-pattern", "c", nil, "Custom pattern referenced by the grok pattern, as NAME=REGEX, can be repeated")
+	logsGrokCmd.Flags().StringVar(&grokTimestampField, "timestamp-field", "", "Field holding the timestamp of the message")
+	logsGrokCmd.Flags().StringVar(&grokTimestampLayout, "timestamp-layout", "", "Go time layout of the timestamp field")
+	logsGrokCmd.Flags().StringVar(&grokStatusField, "status-field", "", "Field holding the status of the message")
+	logsGrokCmd.Flags().StringVarP(&grokSampleFilePath, "file", "f", "", "File holding the sample lines, read from stdin when not set")
+	logsGrokCmd.MarkFlagRequired("pattern") //nolint:errcheck
+	logsCmd.AddCommand(logsTestRulesCmd)
+}
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Troubleshoot the logs collection",
+	Long:  ``,
+}
+
+var logsGrokCmd = &cobra.Command{
+	Use:   "grok",
+	Short: "Test a grok_parser processing rule against sample lines",
+	Long: `Run a grok_parser processing rule against each line of a sample file, or of stdin,
+and print the JSON payload of the matching lines along with their status and timestamp.
+It runs offline and does not require a running agent.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		if flagNoColor {
+			color.NoColor = true
+		}
+
+		err := config.SetupLogger(loggerName, config.GetEnvDefault("DD_LOG_LEVEL", "off"), "", "", false, true, false)
+		if err != nil {
+			fmt.Printf("Cannot setup logger, exiting: %v\n", err)
+			return err
+		}
+
+		return testGrokRule()
+	},
+}
+
+var logsTestRulesCmd = &cobra.Command{
+	Use:   "test-rules <config file> <sample file>",
+	Short: "Dry-run the processing rules of a logs configuration against a sample file",
+	Long: `Decode a sample file with each source of a logs integration configuration, applying its
+multi-line rules or the automatic multi-line detection, then apply the global processing rules
+and the processing rules of the source on each message. Each message is printed along with the
+sample lines it is made of, the outcome of each rule and the content that would be sent.
+The configuration file is a YAML integration configuration with a "logs" section, or a JSON list
+of logs configurations. It runs offline and does not require a running agent.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		if flagNoColor {
+			color.NoColor = true
+		}
+
+		// the agent configuration holds the global processing rules and the multi-line settings
+		err := common.SetupConfigWithoutSecrets(confFilePath, "")
+		if err != nil {
+			return fmt.Errorf("unable to set up global agent configuration: %v", err)
+		}
+
+		err = config.SetupLogger(loggerName, config.GetEnvDefault("DD_LOG_LEVEL", "off"), "", "", false, true, false)
+		if err != nil {
+			fmt.Printf("Cannot setup logger, exiting: %v\n", err)
+			return err
+		}
+
+		return testRules(args[0], args[1])
+	},
+}
+
+func testRules(configFilePath, sampleFilePath string) error {
+	data, err := ioutil.ReadFile(configFilePath)
+	if err != nil {
+		return err
+	}
+	var sourceConfigs []*logsconfig.LogsConfig
+	if filepath.Ext(configFilePath) == ".json" {
+		sourceConfigs, err = logsconfig.ParseJSON(data)
+	} else {
+		sourceConfigs, err = logsconfig.ParseYAML(data)
+	}
+	if err != nil {
+		return err
+	}
+	if len(sourceConfigs) == 0 {
+		return fmt.Errorf("no logs configuration found in %s", configFilePath)
+	}
+	globalRules, err := logsconfig.GlobalProcessingRules()
+	if err != nil {
+		return fmt.Errorf("invalid global processing rules: %v", err)
+	}
+	sample, err := ioutil.ReadFile(sampleFilePath)
+	if err != nil {
+		return err
+	}
+
+	for i, sourceConfig := range sourceConfigs {
+		fmt.Println(color.CyanString("=== Source %d: type %s, service %s, source %s", i+1, sourceConfig.Type, sourceConfig.Service, sourceConfig.Source))
+		result, err := ruletester.RunSource(sourceConfig, globalRules, sample)
+		if err != nil {
+			fmt.Printf("%s %v\n\n", color.RedString("Invalid configuration:"), err)
+			continue
+		}
+		if result.DetectedMultiLinePattern != "" {
+			fmt.Printf("Detected multi-line pattern: %s\n", result.DetectedMultiLinePattern)
+		}
+		for j, msg := range result.Messages {
+			printTestedMessage(j+1, msg)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+func printTestedMessage(index int, msg ruletester.Result) {
+	lines := fmt.Sprintf("line %d", msg.FirstLine)
+	if msg.LastLine != msg.FirstLine {
+		lines = fmt.Sprintf("lines %d-%d, multi-line", msg.FirstLine, msg.LastLine)
+	}
+	fmt.Printf("%s\n", color.YellowString("Message %d (%s):", index, lines))
+	fmt.Printf("  original: %s\n", msg.Original)
+	for _, rule := range msg.Rules {
+		outcome := "no match"
+		if rule.Matched {
+			outcome = color.GreenString("matched")
+		}
+		fmt.Printf("  rule %s (%s): %s\n", rule.Name, rule.Type, outcome)
+		for _, masked := range rule.Masked {
+			fmt.Printf("    masked: %s\n", masked)
+		}
+	}
+	if msg.DroppedBy != "" {
+		fmt.Printf("  %s\n", color.RedString("dropped by rule %s", msg.DroppedBy))
+		return
+	}
+	timestamp := "unset"
+	if !msg.Timestamp.IsZero() {
+		timestamp = msg.Timestamp.Format(time.RFC3339Nano)
+	}
+	fmt.Printf("  sent: %s\n", msg.Content)
+	fmt.Printf("  status: %s, timestamp: %s\n", msg.Status, timestamp)
+}
+
+func testGrokRule() error {
+	customPatterns := make(map[string]string)
+	for _, p := range grokCustomPatterns {
+		name, definition := splitCustomPattern(p)
+		if name == "" {
+			return fmt.Errorf("invalid custom pattern %s, expected NAME=REGEX", p)
+		}
+		customPatterns[name] = definition
+	}
+	rule := &logsconfig.ProcessingRule{
+		Type:            logsconfig.GrokParser,
+		Name:            "grok",
+		Pattern:         grokPattern,
+		CustomPatterns:  customPatterns,
+		TimestampField:  grokTimestampField,
+		TimestampLayout: grokTimestampLayout,
+		StatusField:     grokStatusField,
+	}
+
+	lines, err := readSampleLines(grokSampleFilePath)
+	if err != nil {
+		return err
+	}
+	results, err := ruletester.Run([]*logsconfig.ProcessingRule{rule}, lines)
+	if err != nil {
+		return err
+	}
+
+	for i, result := range results {
+		if !result.Rules[0].Matched {
+			fmt.Printf("%s %s\n", color.YellowString("line %d:", i+1), color.RedString("no match"))
+			continue
+		}
+		fmt.Printf("%s %s\n", color.YellowString("line %d:", i+1), result.Content)
+		timestamp := "unset"
+		if !result.Timestamp.IsZero() {
+			timestamp = result.Timestamp.Format(time.RFC3339Nano)
+		}
+		fmt.Printf("  status: %s, timestamp: %s\n", result.Status, timestamp)
+	}
+	return nil
+}
+
+// splitCustomPattern splits a NAME=REGEX custom pattern, name is empty if it is invalid.
+func splitCustomPattern(p string) (string, string) {
+	parts := strings.SplitN(p, "=", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
+
+// readSampleLines reads the lines of path, or of stdin when path is empty.
+func readSampleLines(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSampleLineSize)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
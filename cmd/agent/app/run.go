@@ -33,11 +33,13 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/config"
 	remoteconfig "github.com/DataDog/datadog-agent/pkg/config/remote/service"
 	"github.com/DataDog/datadog-agent/pkg/dogstatsd"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/remotewrite"
 	"github.com/DataDog/datadog-agent/pkg/forwarder"
 	"github.com/DataDog/datadog-agent/pkg/logs"
 	"github.com/DataDog/datadog-agent/pkg/metadata"
 	"github.com/DataDog/datadog-agent/pkg/metadata/host"
 	"github.com/DataDog/datadog-agent/pkg/metadata/inventories"
+	"github.com/DataDog/datadog-agent/pkg/metrics/openmetrics"
 	"github.com/DataDog/datadog-agent/pkg/otlp"
 	"github.com/DataDog/datadog-agent/pkg/pidfile"
 	"github.com/DataDog/datadog-agent/pkg/snmp/traps"
@@ -358,6 +360,19 @@ func StartAgent() error {
 	forwarderOpts.EnabledFeatures = forwarder.SetFeature(forwarderOpts.EnabledFeatures, forwarder.CoreFeatures)
 	opts := aggregator.DefaultDemultiplexerOptions(forwarderOpts)
 	opts.UseContainerLifecycleForwarder = config.Datadog.GetBool("container_lifecycle.enabled")
+
+	// start the local OpenMetrics scrape endpoint, exposing the flushes of the demultiplexer
+	if config.Datadog.GetBool("openmetrics_export.enabled") {
+		exporter := openmetrics.NewExporter()
+		common.OpenMetricsExport, err = openmetrics.NewServer(exporter)
+		if err != nil {
+			log.Errorf("Could not start the OpenMetrics scrape endpoint: %s", err)
+		} else {
+			opts.OpenMetricsExporter = exporter
+			common.OpenMetricsExport.Start()
+		}
+	}
+
 	demux = aggregator.InitAndStartAgentDemultiplexer(opts, hostname)
 	demux.AddAgentStartupTelemetry(version.AgentVersion)
 
@@ -372,6 +387,17 @@ func StartAgent() error {
 		}
 	}
 
+	// start the Prometheus remote-write receiver
+	if config.Datadog.GetBool("prometheus_remote_write.enabled") {
+		var err error
+		common.PrometheusRemoteWrite, err = remotewrite.NewReceiver(demux)
+		if err != nil {
+			log.Errorf("Could not start the Prometheus remote-write receiver: %s", err)
+		} else {
+			common.PrometheusRemoteWrite.Start()
+		}
+	}
+
 	// Setup stats telemetry handler
 	if sender, err := demux.GetDefaultSender(); err == nil {
 		telemetry.RegisterStatsSender(sender)
@@ -410,7 +436,7 @@ func StartAgent() error {
 		if config.Datadog.GetBool("log_enabled") {
 			log.Warn(`"log_enabled" is deprecated, use "logs_enabled" instead`)
 		}
-		if _, err := logs.Start(func() *autodiscovery.AutoConfig { return common.AC }); err != nil {
+		if _, err := logs.Start(func() *autodiscovery.AutoConfig { return common.AC }, demux); err != nil {
 			log.Error("Could not start logs-agent: ", err)
 		}
 	} else {
@@ -472,6 +498,12 @@ func StopAgent() {
 	if common.DSD != nil {
 		common.DSD.Stop()
 	}
+	if common.PrometheusRemoteWrite != nil {
+		common.PrometheusRemoteWrite.Stop()
+	}
+	if common.OpenMetricsExport != nil {
+		common.OpenMetricsExport.Stop()
+	}
 	if common.OTLP != nil {
 		common.OTLP.Stop()
 	}
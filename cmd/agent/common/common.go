@@ -21,7 +21,9 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/config/settings"
 	settingshttp "github.com/DataDog/datadog-agent/pkg/config/settings/http"
 	"github.com/DataDog/datadog-agent/pkg/dogstatsd"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/remotewrite"
 	"github.com/DataDog/datadog-agent/pkg/metadata"
+	"github.com/DataDog/datadog-agent/pkg/metrics/openmetrics"
 	"github.com/DataDog/datadog-agent/pkg/util/executable"
 	"github.com/DataDog/datadog-agent/pkg/version"
 )
@@ -36,6 +38,12 @@ var (
 	// DSD is the global dogstatsd instance
 	DSD *dogstatsd.Server
 
+	// PrometheusRemoteWrite is the global Prometheus remote-write receiver instance
+	PrometheusRemoteWrite *remotewrite.Receiver
+
+	// OpenMetricsExport is the global server of the local OpenMetrics scrape endpoint
+	OpenMetricsExport *openmetrics.Server
+
 	// ExpvarServer is the global expvar server
 	ExpvarServer *http.Server
 
@@ -617,7 +617,7 @@ func newRuntimeReporter(stopper startstop.Stopper, sourceName, sourceType string
 	stopper.Add(auditor)
 
 	// setup the pipeline provider that provides pairs of processor and sender
-	pipelineProvider := pipeline.NewProvider(config.NumberOfPipelines, auditor, &diagnostic.NoopMessageReceiver{}, nil, endpoints, context)
+	pipelineProvider := pipeline.NewProvider(config.NumberOfPipelines, auditor, &diagnostic.NoopMessageReceiver{}, nil, endpoints, context, nil, nil)
 	pipelineProvider.Start()
 	stopper.Add(pipelineProvider)
 
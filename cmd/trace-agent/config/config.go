@@ -13,6 +13,7 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -26,6 +27,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
 	"github.com/DataDog/datadog-agent/pkg/trace/config/features"
+	"github.com/DataDog/datadog-agent/pkg/trace/filters"
 	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
 	"github.com/DataDog/datadog-agent/pkg/util/fargate"
 	"github.com/DataDog/datadog-agent/pkg/util/grpc"
@@ -209,6 +211,31 @@ func applyDatadogConfig(c *config.AgentConfig) error {
 		c.MaxRemoteTPS = coreconfig.Datadog.GetFloat64("apm_config.max_remote_traces_per_second")
 	}
 
+	if k := "apm_config.stats_dimensions"; coreconfig.Datadog.IsSet(k) {
+		c.StatsDimensions = coreconfig.Datadog.GetStringSlice(k)
+	}
+	if k := "apm_config.stats_dimensions_max_cardinality"; coreconfig.Datadog.IsSet(k) {
+		c.StatsDimensionsMaxCardinality = coreconfig.Datadog.GetInt(k)
+	}
+
+	if k := "apm_config.tail_sampling.enabled"; coreconfig.Datadog.IsSet(k) {
+		c.TailSampling.Enabled = coreconfig.Datadog.GetBool(k)
+	}
+	if k := "apm_config.tail_sampling.decision_wait"; coreconfig.Datadog.IsSet(k) {
+		c.TailSampling.DecisionWait = time.Duration(coreconfig.Datadog.GetFloat64(k) * float64(time.Second))
+	}
+	if k := "apm_config.tail_sampling.max_buffered_spans"; coreconfig.Datadog.IsSet(k) {
+		c.TailSampling.MaxBufferedSpans = coreconfig.Datadog.GetInt(k)
+	}
+	if k := "apm_config.tail_sampling.policies"; coreconfig.Datadog.IsSet(k) {
+		var policies []config.TailSamplingPolicy
+		if err := coreconfig.Datadog.UnmarshalKey(k, &policies); err != nil {
+			log.Errorf("Bad format for %q, error: %v", k, err)
+		} else {
+			c.TailSampling.Policies = policies
+		}
+	}
+
 	if k := "apm_config.ignore_resources"; coreconfig.Datadog.IsSet(k) {
 		c.Ignore["resource"] = coreconfig.Datadog.GetStringSlice(k)
 	}
@@ -227,6 +254,17 @@ func applyDatadogConfig(c *config.AgentConfig) error {
 			c.ReplaceTags = rt
 		}
 	}
+	if k := "apm_config.span_rules"; coreconfig.Datadog.IsSet(k) {
+		var rules []*config.SpanRule
+		if err := coreconfig.Datadog.UnmarshalKey(k, &rules); err != nil {
+			log.Errorf("Bad format for %q it should be of the form '[{\"name\": \"rule_name\",\"match\":[\"service == web\"],\"action\":\"drop_span\"}]', error: %v", k, err)
+		} else {
+			if _, err := filters.NewSpanRules(rules); err != nil {
+				osutil.Exitf("span_rules: %s", err)
+			}
+			c.SpanRules = rules
+		}
+	}
 
 	if coreconfig.Datadog.IsSet("bind_host") || coreconfig.Datadog.IsSet("apm_config.apm_non_local_traffic") {
 		if coreconfig.Datadog.IsSet("bind_host") {
@@ -306,6 +344,17 @@ func applyDatadogConfig(c *config.AgentConfig) error {
 		}
 	}
 
+	if k := "apm_config.disk_buffer_max_size_in_bytes"; coreconfig.Datadog.IsSet(k) {
+		c.DiskBuffer.MaxSizeInBytes = coreconfig.Datadog.GetInt64(k)
+	}
+	c.DiskBuffer.Path = filepath.Join(coreconfig.Datadog.GetString("run_path"), "apm_disk_buffer")
+	if k := "apm_config.disk_buffer_path"; coreconfig.Datadog.IsSet(k) {
+		c.DiskBuffer.Path = coreconfig.Datadog.GetString(k)
+	}
+	if k := "apm_config.disk_buffer_max_age"; coreconfig.Datadog.IsSet(k) {
+		c.DiskBuffer.MaxAge = time.Duration(coreconfig.Datadog.GetFloat64(k) * float64(time.Hour))
+	}
+
 	// undocumented
 	if coreconfig.Datadog.IsSet("apm_config.max_cpu_percent") {
 		c.MaxCPU = coreconfig.Datadog.GetFloat64("apm_config.max_cpu_percent") / 100
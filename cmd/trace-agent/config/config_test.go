@@ -391,6 +391,9 @@ func TestNoAPMConfig(t *testing.T) {
 	assert.Equal("0.0.0.0", c.ReceiverHost)
 	assert.Equal(28125, c.StatsdPort)
 	assert.Equal("DEBUG", c.LogLevel)
+	assert.Zero(c.DiskBuffer.MaxSizeInBytes)
+	assert.Equal(filepath.Join(coreconfig.Datadog.GetString("run_path"), "apm_disk_buffer"), c.DiskBuffer.Path)
+	assert.Equal(24*time.Hour, c.DiskBuffer.MaxAge)
 }
 
 func TestFullYamlConfig(t *testing.T) {
@@ -480,6 +483,11 @@ func TestFullYamlConfig(t *testing.T) {
 	assert.True(o.RemoveStackTraces)
 	assert.True(o.Redis.Enabled)
 	assert.True(o.Memcached.Enabled)
+	assert.True(o.GraphQL.Enabled)
+	assert.True(o.DynamoDB.Enabled)
+	assert.EqualValues([]string{":status"}, o.DynamoDB.KeepValues)
+	assert.True(o.Messaging.Enabled)
+	assert.EqualValues([]string{"kafka.message.key", "kafka.header.*"}, o.Messaging.Keys)
 	assert.True(o.CreditCards.Enabled)
 	assert.True(o.CreditCards.Luhn)
 }
@@ -832,6 +840,21 @@ func TestLoadEnv(t *testing.T) {
 		assert.Contains(cfg.ReplaceTags, rule2)
 	})
 
+	env = "DD_APM_SPAN_RULES"
+	t.Run(env, func(t *testing.T) {
+		defer cleanConfig()()
+		assert := assert.New(t)
+		err := os.Setenv(env, `[{"name":"health","match":["resource == GET /health"],"action":"drop_span"},{"action":"hash_tag","tag":"user.email"}]`)
+		assert.NoError(err)
+		defer os.Unsetenv(env)
+		cfg, err := LoadConfigFile("./testdata/full.yaml")
+		assert.NoError(err)
+		assert.Equal([]*config.SpanRule{
+			{Name: "health", Match: []string{"resource == GET /health"}, Action: "drop_span"},
+			{Action: "hash_tag", Tag: "user.email"},
+		}, cfg.SpanRules)
+	})
+
 	env = "DD_APM_FILTER_TAGS_REQUIRE"
 	t.Run(env, func(t *testing.T) {
 		defer cleanConfig()()
@@ -946,6 +969,68 @@ func TestLoadEnv(t *testing.T) {
 		assert.Equal(337.41, cfg.MaxRemoteTPS)
 	})
 
+	env = "DD_APM_STATS_DIMENSIONS"
+	t.Run(env, func(t *testing.T) {
+		defer cleanConfig()()
+		assert := assert.New(t)
+		err := os.Setenv(env, "customer_tier http.route")
+		assert.NoError(err)
+		defer os.Unsetenv(env)
+		err = os.Setenv("DD_APM_STATS_DIMENSIONS_MAX_CARDINALITY", "20")
+		assert.NoError(err)
+		defer os.Unsetenv("DD_APM_STATS_DIMENSIONS_MAX_CARDINALITY")
+		cfg, err := LoadConfigFile("./testdata/full.yaml")
+		assert.NoError(err)
+		assert.Equal([]string{"customer_tier", "http.route"}, cfg.StatsDimensions)
+		assert.Equal(20, cfg.StatsDimensionsMaxCardinality)
+	})
+
+	env = "DD_APM_TAIL_SAMPLING_POLICIES"
+	t.Run(env, func(t *testing.T) {
+		defer cleanConfig()()
+		assert := assert.New(t)
+		for k, v := range map[string]string{
+			"DD_APM_TAIL_SAMPLING_ENABLED":       "true",
+			"DD_APM_TAIL_SAMPLING_DECISION_WAIT": "2.5",
+			env:                                  `[{"name":"slow","type":"latency","threshold_ms":300},{"type":"service","services":["payments"]}]`,
+		} {
+			err := os.Setenv(k, v)
+			assert.NoError(err)
+			defer os.Unsetenv(k)
+		}
+		cfg, err := LoadConfigFile("./testdata/full.yaml")
+		assert.NoError(err)
+		assert.True(cfg.TailSampling.Enabled)
+		assert.Equal(2500*time.Millisecond, cfg.TailSampling.DecisionWait)
+		assert.Equal(100000, cfg.TailSampling.MaxBufferedSpans)
+		assert.Equal([]config.TailSamplingPolicy{
+			{Name: "slow", Type: "latency", ThresholdMs: 300},
+			{Type: "service", Services: []string{"payments"}},
+		}, cfg.TailSampling.Policies)
+	})
+
+	env = "DD_APM_DISK_BUFFER_MAX_SIZE_IN_BYTES"
+	t.Run(env, func(t *testing.T) {
+		defer cleanConfig()()
+		assert := assert.New(t)
+		for k, v := range map[string]string{
+			"DD_APM_DISK_BUFFER_PATH":    "/tmp/apm_buffer",
+			"DD_APM_DISK_BUFFER_MAX_AGE": "2",
+			env:                          "1000000",
+		} {
+			err := os.Setenv(k, v)
+			assert.NoError(err)
+			defer os.Unsetenv(k)
+		}
+		cfg, err := LoadConfigFile("./testdata/full.yaml")
+		assert.NoError(err)
+		assert.Equal(&config.DiskBufferConfig{
+			MaxSizeInBytes: 1000000,
+			Path:           "/tmp/apm_buffer",
+			MaxAge:         2 * time.Hour,
+		}, cfg.DiskBuffer)
+	})
+
 	env = "DD_APM_ADDITIONAL_ENDPOINTS"
 	t.Run(env, func(t *testing.T) {
 		defer cleanConfig()()
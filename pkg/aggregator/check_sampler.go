@@ -25,6 +25,8 @@ type CheckSampler struct {
 	metrics         metrics.CheckMetrics
 	sketchMap       sketchMap
 	lastBucketValue map[ckey.ContextKey]int64
+	// sketchRules sets the config of the sketches of the histogram buckets by metric name
+	sketchRules sketchRules
 }
 
 // newCheckSampler returns a newly initialized CheckSampler
@@ -108,7 +110,7 @@ func (cs *CheckSampler) addBucket(bucket *metrics.HistogramBucket) {
 
 	// "if the quantile falls into the highest bucket, the upper bound of the 2nd highest bucket is returned"
 	if math.IsInf(bucket.UpperBound, 1) {
-		cs.sketchMap.insertInterp(int64(bucket.Timestamp), contextKey, bucket.LowerBound, bucket.LowerBound, uint(bucket.Value))
+		cs.sketchMap.insertInterp(int64(bucket.Timestamp), contextKey, bucket.LowerBound, bucket.LowerBound, uint(bucket.Value), cs.sketchRules.config(bucket.Name))
 		return
 	}
 
@@ -116,7 +118,7 @@ func (cs *CheckSampler) addBucket(bucket *metrics.HistogramBucket) {
 		"Interpolating %d values over the [%f-%f] bucket",
 		bucket.Value, bucket.LowerBound, bucket.UpperBound,
 	)
-	cs.sketchMap.insertInterp(int64(bucket.Timestamp), contextKey, bucket.LowerBound, bucket.UpperBound, uint(bucket.Value))
+	cs.sketchMap.insertInterp(int64(bucket.Timestamp), contextKey, bucket.LowerBound, bucket.UpperBound, uint(bucket.Value), cs.sketchRules.config(bucket.Name))
 }
 
 func (cs *CheckSampler) commitSeries(timestamp float64) {
@@ -156,7 +158,9 @@ func (cs *CheckSampler) commitSketches(timestamp float64) {
 		pointsByCtx[ck] = append(pointsByCtx[ck], p)
 	})
 	for ck, points := range pointsByCtx {
-		cs.sketches = append(cs.sketches, cs.newSketchSeries(ck, points))
+		ss := cs.newSketchSeries(ck, points)
+		cs.sketchRules.observe(ss)
+		cs.sketches = append(cs.sketches, ss)
 	}
 }
 
@@ -0,0 +1,213 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// overflowTag replaces the metric tags of the contexts folded by the context limiter.
+	overflowTag = "overflow:true"
+
+	contextLimitByMetric = "metric"
+	contextLimitByOrigin = "origin"
+)
+
+var (
+	tlmContextLimiterOverflow = telemetry.NewCounter("aggregator", "dogstatsd_contexts_over_limit",
+		[]string{"limit", "action"}, "Count of dogstatsd samples of contexts over the limits, by limit and by action taken")
+
+	// contextLimiterOffenders holds the offenders of the last flush of each time sampler
+	contextLimiterOffenders = struct {
+		sync.Mutex
+		bySampler map[TimeSamplerID][]ContextLimiterOffender
+	}{bySampler: make(map[TimeSamplerID][]ContextLimiterOffender)}
+)
+
+// ContextLimiterOffender is a metric name or an origin whose contexts exceeded the context limits.
+type ContextLimiterOffender struct {
+	// Limit is either "metric" or "origin"
+	Limit string `json:"limit"`
+	// Name is the metric name or the origin
+	Name string `json:"name"`
+	// Samples is the number of samples of the contexts over the limit
+	Samples uint64 `json:"samples"`
+}
+
+// contextLimiter bounds the number of contexts a TimeSampler accepts per flush, per metric name
+// and per origin. The samples of the other contexts are either dropped or folded into an
+// overflow context.
+type contextLimiter struct {
+	metricLimit int
+	originLimit int
+	fold        bool
+
+	// accepted holds the contexts accepted since the last flush
+	accepted map[ckey.ContextKey]struct{}
+	byMetric map[string]int
+	byOrigin map[string]int
+	// overflows counts the samples over the limits since the last flush
+	overflows map[ContextLimiterOffender]uint64
+}
+
+// newContextLimiter returns a context limiter, or nil if no limit is set.
+func newContextLimiter(metricLimit, originLimit int, fold bool) *contextLimiter {
+	if metricLimit <= 0 && originLimit <= 0 {
+		return nil
+	}
+	l := &contextLimiter{
+		metricLimit: metricLimit,
+		originLimit: originLimit,
+		fold:        fold,
+	}
+	l.reset()
+	return l
+}
+
+// newContextLimiterFromConfig returns the context limiter of one of samplerCount time samplers.
+// The contexts are sharded between the time samplers so each of them gets a share of the limits.
+func newContextLimiterFromConfig(samplerCount int) *contextLimiter {
+	fold := true
+	switch action := config.Datadog.GetString("dogstatsd_context_limit_overflow"); action {
+	case "fold":
+	case "drop":
+		fold = false
+	default:
+		log.Warnf("Invalid dogstatsd_context_limit_overflow value %q, the contexts over the limits will be folded", action)
+	}
+	return newContextLimiter(
+		shareOf(config.Datadog.GetInt("dogstatsd_context_limit_per_metric"), samplerCount),
+		shareOf(config.Datadog.GetInt("dogstatsd_context_limit_per_origin"), samplerCount),
+		fold)
+}
+
+// shareOf returns the share of a limit of one of count samplers, rounded up.
+func shareOf(limit int, count int) int {
+	if limit <= 0 || count <= 1 {
+		return limit
+	}
+	return (limit + count - 1) / count
+}
+
+// accept returns true if the context can be tracked, that is if it has already been accepted
+// since the last flush or if its metric name and its origin have not reached their limits.
+// The samples without origin are only limited by metric name.
+func (l *contextLimiter) accept(key ckey.ContextKey, name string, origin string) bool {
+	if _, found := l.accepted[key]; found {
+		return true
+	}
+	if l.metricLimit > 0 && l.byMetric[name] >= l.metricLimit {
+		l.reject(contextLimitByMetric, name)
+		return false
+	}
+	if origin != "" && l.originLimit > 0 && l.byOrigin[origin] >= l.originLimit {
+		l.reject(contextLimitByOrigin, origin)
+		return false
+	}
+	l.accepted[key] = struct{}{}
+	if l.metricLimit > 0 {
+		l.byMetric[name]++
+	}
+	if origin != "" && l.originLimit > 0 {
+		l.byOrigin[origin]++
+	}
+	return true
+}
+
+func (l *contextLimiter) reject(limit string, name string) {
+	l.overflows[ContextLimiterOffender{Limit: limit, Name: name}]++
+	if l.fold {
+		tlmContextLimiterOverflow.Inc(limit, "fold")
+	} else {
+		tlmContextLimiterOverflow.Inc(limit, "drop")
+	}
+}
+
+// flush publishes the offenders since the last flush and resets the limits.
+func (l *contextLimiter) flush(id TimeSamplerID) {
+	offenders := make([]ContextLimiterOffender, 0, len(l.overflows))
+	for offender, samples := range l.overflows {
+		offender.Samples = samples
+		offenders = append(offenders, offender)
+	}
+	contextLimiterOffenders.Lock()
+	contextLimiterOffenders.bySampler[id] = offenders
+	contextLimiterOffenders.Unlock()
+
+	l.reset()
+}
+
+func (l *contextLimiter) reset() {
+	// the maps are reallocated to release the memory of a spike of contexts
+	l.accepted = make(map[ckey.ContextKey]struct{})
+	l.byMetric = make(map[string]int)
+	l.byOrigin = make(map[string]int)
+	l.overflows = make(map[ContextLimiterOffender]uint64)
+}
+
+// GetContextLimiterOffenders returns the top metric names and origins whose contexts exceeded
+// the context limits during the last flush, the largest first.
+func GetContextLimiterOffenders(top int) []ContextLimiterOffender {
+	merged := make(map[ContextLimiterOffender]uint64)
+	contextLimiterOffenders.Lock()
+	for _, offenders := range contextLimiterOffenders.bySampler {
+		for _, offender := range offenders {
+			merged[ContextLimiterOffender{Limit: offender.Limit, Name: offender.Name}] += offender.Samples
+		}
+	}
+	contextLimiterOffenders.Unlock()
+
+	offenders := make([]ContextLimiterOffender, 0, len(merged))
+	for offender, samples := range merged {
+		offender.Samples = samples
+		offenders = append(offenders, offender)
+	}
+	sort.Slice(offenders, func(i, j int) bool {
+		if offenders[i].Samples != offenders[j].Samples {
+			return offenders[i].Samples > offenders[j].Samples
+		}
+		if offenders[i].Limit != offenders[j].Limit {
+			return offenders[i].Limit < offenders[j].Limit
+		}
+		return offenders[i].Name < offenders[j].Name
+	})
+	if top > 0 && len(offenders) > top {
+		offenders = offenders[:top]
+	}
+	return offenders
+}
+
+// FormatContextLimiterOffenders returns a printable version of the JSON encoded offenders
+// returned by GetContextLimiterOffenders.
+func FormatContextLimiterOffenders(data []byte) (string, error) {
+	var offenders []ContextLimiterOffender
+	if err := json.Unmarshal(data, &offenders); err != nil {
+		return "", err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	header := fmt.Sprintf("%-10s | %-60s | %-10s\n", "Limit", "Metric or origin", "Samples")
+	buf.WriteString(header)
+	buf.WriteString(strings.Repeat("-", len(header)) + "\n")
+	for _, offender := range offenders {
+		buf.WriteString(fmt.Sprintf("%-10s | %-60s | %-10d\n", offender.Limit, offender.Name, offender.Samples))
+	}
+	if len(offenders) == 0 {
+		buf.WriteString("No context over the limits during the last flush.")
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,145 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package aggregator
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func TestNewContextLimiterDisabled(t *testing.T) {
+	assert.Nil(t, newContextLimiter(0, 0, true))
+	assert.NotNil(t, newContextLimiter(1, 0, true))
+	assert.NotNil(t, newContextLimiter(0, 1, true))
+}
+
+func TestShareOf(t *testing.T) {
+	assert.Equal(t, 0, shareOf(0, 4))
+	assert.Equal(t, 10, shareOf(10, 1))
+	assert.Equal(t, 3, shareOf(10, 4))
+	assert.Equal(t, 1, shareOf(1, 4))
+}
+
+func TestContextLimiterAccept(t *testing.T) {
+	l := newContextLimiter(2, 3, false)
+
+	// per metric limit
+	assert.True(t, l.accept(ckey.ContextKey(1), "foo", ""))
+	assert.True(t, l.accept(ckey.ContextKey(2), "foo", ""))
+	assert.False(t, l.accept(ckey.ContextKey(3), "foo", ""))
+	// known contexts are still accepted
+	assert.True(t, l.accept(ckey.ContextKey(1), "foo", ""))
+	assert.True(t, l.accept(ckey.ContextKey(4), "bar", ""))
+
+	// per origin limit
+	assert.True(t, l.accept(ckey.ContextKey(5), "a", "container_id://abc"))
+	assert.True(t, l.accept(ckey.ContextKey(6), "b", "container_id://abc"))
+	assert.True(t, l.accept(ckey.ContextKey(7), "c", "container_id://abc"))
+	assert.False(t, l.accept(ckey.ContextKey(8), "d", "container_id://abc"))
+	assert.False(t, l.accept(ckey.ContextKey(9), "e", "container_id://abc"))
+	assert.True(t, l.accept(ckey.ContextKey(10), "d", "container_id://def"))
+
+	assert.Equal(t, map[ContextLimiterOffender]uint64{
+		{Limit: contextLimitByMetric, Name: "foo"}:                1,
+		{Limit: contextLimitByOrigin, Name: "container_id://abc"}: 2,
+	}, l.overflows)
+
+	// the limits are reset at flush
+	l.flush(TimeSamplerID(0))
+	assert.True(t, l.accept(ckey.ContextKey(3), "foo", ""))
+	assert.True(t, l.accept(ckey.ContextKey(8), "d", "container_id://abc"))
+	assert.Empty(t, l.overflows)
+}
+
+func TestGetContextLimiterOffenders(t *testing.T) {
+	defer func() {
+		contextLimiterOffenders.Lock()
+		contextLimiterOffenders.bySampler = make(map[TimeSamplerID][]ContextLimiterOffender)
+		contextLimiterOffenders.Unlock()
+	}()
+
+	l1 := newContextLimiter(1, 0, false)
+	l2 := newContextLimiter(1, 0, false)
+	for i := 0; i < 4; i++ {
+		l1.accept(ckey.ContextKey(i), "foo", "")
+		l2.accept(ckey.ContextKey(i), "foo", "")
+		l2.accept(ckey.ContextKey(i+10), "bar", "")
+	}
+	l2.accept(ckey.ContextKey(20), "baz", "")
+	l2.accept(ckey.ContextKey(21), "baz", "")
+	l1.flush(TimeSamplerID(0))
+	l2.flush(TimeSamplerID(1))
+
+	assert.Equal(t, []ContextLimiterOffender{
+		{Limit: contextLimitByMetric, Name: "foo", Samples: 6},
+		{Limit: contextLimitByMetric, Name: "bar", Samples: 3},
+	}, GetContextLimiterOffenders(2))
+	assert.Len(t, GetContextLimiterOffenders(0), 3)
+
+	data, err := json.Marshal(GetContextLimiterOffenders(0))
+	require.NoError(t, err)
+	formatted, err := FormatContextLimiterOffenders(data)
+	require.NoError(t, err)
+	assert.Contains(t, formatted, "foo")
+	assert.Contains(t, formatted, "baz")
+
+	// the offenders are replaced at every flush
+	l1.flush(TimeSamplerID(0))
+	l2.flush(TimeSamplerID(1))
+	assert.Empty(t, GetContextLimiterOffenders(0))
+}
+
+func testLimitedTimeSampler(t *testing.T, fold bool) metrics.Series {
+	sampler := testTimeSampler()
+	sampler.contextLimiter = newContextLimiter(2, 0, fold)
+
+	for i := 0; i < 4; i++ {
+		sampler.sample(&metrics.MetricSample{
+			Name:       "my.metric",
+			Value:      1,
+			Mtype:      metrics.CountType,
+			Tags:       []string{fmt.Sprintf("request:%d", i)},
+			SampleRate: 1,
+		}, 12346.0)
+	}
+	series, _ := flushSerie(sampler, 12360.0)
+	return series
+}
+
+func TestTimeSamplerContextLimitDrop(t *testing.T) {
+	series := testLimitedTimeSampler(t, false)
+
+	require.Len(t, series, 2)
+	for _, serie := range series {
+		assert.NotEqual(t, overflowTag, serie.Tags.Join(","))
+	}
+}
+
+func TestTimeSamplerContextLimitFold(t *testing.T) {
+	series := testLimitedTimeSampler(t, true)
+
+	require.Len(t, series, 3)
+	var overflow *metrics.Serie
+	for _, serie := range series {
+		if serie.Tags.Join(",") == overflowTag {
+			overflow = serie
+		}
+	}
+	require.NotNil(t, overflow)
+	assert.Equal(t, "my.metric", overflow.Name)
+	require.Len(t, overflow.Points, 1)
+	assert.Equal(t, float64(2), overflow.Points[0].Value)
+}
@@ -21,6 +21,10 @@ type Context struct {
 	mtype      metrics.MetricType
 	taggerTags *tags.Entry
 	metricTags *tags.Entry
+
+	// histogramRule is the histogram rule of the context, resolved on its first histogram sample
+	histogramRule         *histogramRule
+	histogramRuleResolved bool
 }
 
 // Tags returns tags for the context.
@@ -61,9 +65,28 @@ func newContextResolver(cache *tags.Store) *contextResolver {
 
 // trackContext returns the contextKey associated with the context of the metricSample and tracks that context
 func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSampleContext) ckey.ContextKey {
+	contextKey, _ := cr.trackLimitedContext(metricSampleContext, nil, "")
+	return contextKey
+}
+
+// trackLimitedContext is trackContext bounded by a context limiter, which can be nil. The contexts over
+// the limits are either folded into an overflow context, with the same name, host and origin tags, whose
+// contextKey is returned, or not tracked, in which case false is returned.
+func (cr *contextResolver) trackLimitedContext(metricSampleContext metrics.MetricSampleContext, limiter *contextLimiter, origin string) (ckey.ContextKey, bool) {
 	metricSampleContext.GetTags(cr.taggerBuffer, cr.metricBuffer)                  // tags here are not sorted and can contain duplicates
 	contextKey, taggerKey, metricKey := cr.generateContextKey(metricSampleContext) // the generator will remove duplicates (and doesn't mind the order)
 
+	if limiter != nil && !limiter.accept(contextKey, metricSampleContext.GetName(), origin) {
+		if !limiter.fold {
+			cr.taggerBuffer.Reset()
+			cr.metricBuffer.Reset()
+			return contextKey, false
+		}
+		cr.metricBuffer.Reset()
+		cr.metricBuffer.Append(overflowTag)
+		contextKey, taggerKey, metricKey = cr.generateContextKey(metricSampleContext)
+	}
+
 	if _, ok := cr.contextsByKey[contextKey]; !ok {
 		mtype := metricSampleContext.GetMetricType()
 		cr.contextsByKey[contextKey] = &Context{
@@ -79,7 +102,7 @@ func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSample
 	cr.taggerBuffer.Reset()
 	cr.metricBuffer.Reset()
 
-	return contextKey
+	return contextKey, true
 }
 
 func (cr *contextResolver) get(key ckey.ContextKey) (*Context, bool) {
@@ -140,6 +163,15 @@ func (cr *timestampContextResolver) trackContext(metricSampleContext metrics.Met
 	return contextKey
 }
 
+// trackLimitedContext is trackContext bounded by a context limiter, see contextResolver.trackLimitedContext
+func (cr *timestampContextResolver) trackLimitedContext(metricSampleContext metrics.MetricSampleContext, currentTimestamp float64, limiter *contextLimiter, origin string) (ckey.ContextKey, bool) {
+	contextKey, ok := cr.resolver.trackLimitedContext(metricSampleContext, limiter, origin)
+	if ok {
+		cr.lastSeenByKey[contextKey] = currentTimestamp
+	}
+	return contextKey, ok
+}
+
 func (cr *timestampContextResolver) length() int {
 	return cr.resolver.length()
 }
@@ -13,6 +13,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/forwarder"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/metrics/openmetrics"
 
 	agentruntime "github.com/DataDog/datadog-agent/pkg/runtime"
 	"github.com/DataDog/datadog-agent/pkg/serializer"
@@ -86,6 +87,7 @@ type DemultiplexerOptions struct {
 	UseOrchestratorForwarder       bool
 	UseContainerLifecycleForwarder bool
 	FlushInterval                  time.Duration
+	OpenMetricsExporter            *openmetrics.Exporter // when set, receives the series and sketches of every flush
 
 	DontStartForwarders bool // unit tests don't need the forwarders to be instanciated
 }
@@ -133,11 +135,15 @@ func startSendingIterableSeries(
 	serializer serializer.MetricSerializer,
 	flushAndSerializeInParallel FlushAndSerializeInParallel,
 	logPayloads bool,
+	exporter *openmetrics.Exporter,
 	start time.Time) (*metrics.IterableSeries, chan struct{}) {
 	seriesSink := metrics.NewIterableSeries(func(se *metrics.Serie) {
 		if logPayloads {
 			log.Debugf("Flushing serie: %s", se)
 		}
+		if exporter != nil {
+			exporter.AddSerie(se)
+		}
 		tagsetTlm.updateHugeSerieTelemetry(se)
 	}, flushAndSerializeInParallel.BufferSize, flushAndSerializeInParallel.ChannelSize)
 	done := make(chan struct{})
@@ -142,11 +142,15 @@ func initAgentDemultiplexer(options DemultiplexerOptions, hostname string) *Agen
 	log.Debug("the Demultiplexer will use", statsdPipelinesCount, "pipelines")
 
 	statsdWorkers := make([]*timeSamplerWorker, statsdPipelinesCount)
+	histogramRules := newHistogramRulesFromConfig()
 
 	for i := 0; i < statsdPipelinesCount; i++ {
 		// the sampler
 		tagsStore := tags.NewStore(config.Datadog.GetBool("aggregator_use_tags_store"), fmt.Sprintf("timesampler #%d", i))
 		statsdSampler := NewTimeSampler(TimeSamplerID(i), bucketSize, tagsStore)
+		statsdSampler.contextLimiter = newContextLimiterFromConfig(statsdPipelinesCount)
+		statsdSampler.sketchRules = agg.sketchRules
+		statsdSampler.histogramRules = histogramRules
 
 		// its worker (process loop + flush/serialization mechanism)
 
@@ -400,6 +404,7 @@ func (d *AgentDemultiplexer) flushToSerializer(start time.Time, waitForSerialize
 		d.sharedSerializer,
 		d.aggregator.flushAndSerializeInParallel,
 		logPayloads,
+		d.options.OpenMetricsExporter,
 		start)
 
 	// flush DogStatsD pipelines (statsd/time samplers)
@@ -459,6 +464,14 @@ func (d *AgentDemultiplexer) flushToSerializer(start time.Time, waitForSerialize
 		}
 	}
 
+	// expose the series and sketches of this flush locally
+	// ------------------------------------------------------
+
+	if d.options.OpenMetricsExporter != nil {
+		d.options.OpenMetricsExporter.AddSketches(sketches)
+		d.options.OpenMetricsExporter.Commit()
+	}
+
 	// send these to the serializer
 	// ----------------------------
 
@@ -44,6 +44,9 @@ func InitAndStartServerlessDemultiplexer(domainResolvers map[string]resolver.Dom
 	tagsStore := tags.NewStore(config.Datadog.GetBool("aggregator_use_tags_store"), "timesampler")
 
 	statsdSampler := NewTimeSampler(TimeSamplerID(0), bucketSize, tagsStore)
+	statsdSampler.contextLimiter = newContextLimiterFromConfig(1)
+	statsdSampler.sketchRules = newSketchRulesFromConfig()
+	statsdSampler.histogramRules = newHistogramRulesFromConfig()
 	flushAndSerializeInParallel := NewFlushAndSerializeInParallel(config.Datadog)
 	statsdWorker := newTimeSamplerWorker(statsdSampler, DefaultFlushInterval, bufferSize, metricSamplePool, flushAndSerializeInParallel, tagsStore)
 
@@ -108,6 +111,7 @@ func (d *ServerlessDemultiplexer) ForceFlushToSerializer(start time.Time, waitFo
 		d.serializer,
 		d.flushAndSerializeInParallel,
 		logPayloads,
+		nil,
 		start)
 
 	flushedSketches := make([]metrics.SketchSeriesList, 0)
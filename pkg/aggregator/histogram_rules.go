@@ -0,0 +1,113 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	histogramMatchTypeWildcard = "wildcard"
+	histogramMatchTypeRegex    = "regex"
+)
+
+var allowedHistogramWildcardPattern = regexp.MustCompile(`^[a-zA-Z0-9\-_*.]+$`)
+
+// histogramRule sets the aggregation of the DogStatsD histograms whose names match a pattern:
+// either the aggregates and percentiles computed, or their conversion to distributions.
+type histogramRule struct {
+	regex          *regexp.Regexp
+	config         *metrics.HistogramConfig
+	toDistribution bool
+}
+
+// histogramRules resolves the aggregation of the DogStatsD histograms by metric name, the first
+// rule whose pattern matches the name applies. The other histograms use the `histogram_aggregates`
+// and `histogram_percentiles` settings.
+type histogramRules []histogramRule
+
+// newHistogramRules validates the rules and compiles their pattern.
+func newHistogramRules(rules []config.HistogramRule) (histogramRules, error) {
+	hr := make(histogramRules, 0, len(rules))
+	for i, rule := range rules {
+		if rule.Match == "" {
+			return nil, fmt.Errorf("rule num %d: match is required", i)
+		}
+		regex, err := compileHistogramMatch(rule.Match, rule.MatchType)
+		if err != nil {
+			return nil, fmt.Errorf("rule num %d: %v", i, err)
+		}
+		if rule.ToDistribution {
+			if rule.Aggregates != nil || rule.Percentiles != nil {
+				return nil, fmt.Errorf("rule num %d: aggregates and percentiles can't be set with to_distribution", i)
+			}
+			hr = append(hr, histogramRule{regex: regex, toDistribution: true})
+			continue
+		}
+		if rule.Aggregates == nil && rule.Percentiles == nil {
+			return nil, fmt.Errorf("rule num %d: aggregates, percentiles or to_distribution is required", i)
+		}
+		hc, err := metrics.NewHistogramConfig(rule.Aggregates, rule.Percentiles)
+		if err != nil {
+			return nil, fmt.Errorf("rule num %d: %v", i, err)
+		}
+		hr = append(hr, histogramRule{regex: regex, config: hc})
+	}
+	return hr, nil
+}
+
+// compileHistogramMatch compiles a pattern, where the `*` of the wildcard patterns match any
+// characters but dots, like in the DogStatsD mapper profiles.
+func compileHistogramMatch(match string, matchType string) (*regexp.Regexp, error) {
+	switch matchType {
+	case "", histogramMatchTypeWildcard:
+		if !allowedHistogramWildcardPattern.MatchString(match) {
+			return nil, fmt.Errorf("invalid wildcard match pattern `%s`, it does not match allowed match regex `%s`", match, allowedHistogramWildcardPattern)
+		}
+		match = strings.Replace(match, ".", "\\.", -1)
+		match = strings.Replace(match, "*", "[^.]*", -1)
+	case histogramMatchTypeRegex:
+	default:
+		return nil, fmt.Errorf("invalid match type, must be `%s` or `%s`", histogramMatchTypeWildcard, histogramMatchTypeRegex)
+	}
+	regex, err := regexp.Compile("^" + match + "$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid match `%s`: %v", match, err)
+	}
+	return regex, nil
+}
+
+// newHistogramRulesFromConfig returns the rules set in histogram_rules, or no rule when they are
+// invalid.
+func newHistogramRulesFromConfig() histogramRules {
+	var rules []config.HistogramRule
+	if err := config.Datadog.UnmarshalKey("histogram_rules", &rules); err != nil {
+		log.Errorf("Could not parse histogram_rules, using histogram_aggregates and histogram_percentiles for all the histograms: %v", err)
+		return nil
+	}
+	hr, err := newHistogramRules(rules)
+	if err != nil {
+		log.Errorf("Invalid histogram_rules, using histogram_aggregates and histogram_percentiles for all the histograms: %v", err)
+		return nil
+	}
+	return hr
+}
+
+// match returns the rule matching a metric name, nil if there is none.
+func (hr histogramRules) match(name string) *histogramRule {
+	for i := range hr {
+		if hr[i].regex.MatchString(name) {
+			return &hr[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package aggregator
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func TestNewHistogramRules(t *testing.T) {
+	rules, err := newHistogramRules([]config.HistogramRule{
+		{Match: "app.*.latency", Percentiles: []string{"0.5", "0.99"}},
+		{Match: "^slo\\..*", MatchType: "regex", ToDistribution: true},
+		{Match: "app.*", Aggregates: []string{"max"}},
+	})
+	require.NoError(t, err)
+
+	assert.Nil(t, rules.match("other.metric"))
+	// `*` does not match dots
+	assert.Nil(t, rules.match("app.a.b.latency"))
+	// the first matching rule applies
+	assert.Equal(t, []string{"max"}, rules.match("app.requests").config.Aggregates())
+	rule := rules.match("app.api.latency")
+	require.NotNil(t, rule)
+	assert.Equal(t, []int{50, 99}, rule.config.Percentiles())
+	assert.True(t, rules.match("slo.checkout.latency").toDistribution)
+
+	for _, invalid := range [][]config.HistogramRule{
+		{{Aggregates: []string{"max"}}},
+		{{Match: "app.$", Aggregates: []string{"max"}}},
+		{{Match: "app.(", MatchType: "regex", Aggregates: []string{"max"}}},
+		{{Match: "app.*", MatchType: "prefix", Aggregates: []string{"max"}}},
+		{{Match: "app.*"}},
+		{{Match: "app.*", Aggregates: []string{"p99"}}},
+		{{Match: "app.*", Percentiles: []string{"1.5"}}},
+		{{Match: "app.*", Aggregates: []string{"max"}, ToDistribution: true}},
+	} {
+		_, err := newHistogramRules(invalid)
+		assert.Error(t, err, "%+v", invalid)
+	}
+}
+
+func TestNewHistogramRulesFromConfig(t *testing.T) {
+	defer config.Datadog.Set("histogram_rules", nil)
+
+	config.Datadog.Set("histogram_rules", []map[string]interface{}{{"match": "app.*", "aggregates": []string{"max"}}})
+	require.Len(t, newHistogramRulesFromConfig(), 1)
+
+	// invalid rules are ignored
+	config.Datadog.Set("histogram_rules", []map[string]interface{}{{"match": "app.*"}})
+	assert.Empty(t, newHistogramRulesFromConfig())
+}
+
+func TestTimeSamplerHistogramRules(t *testing.T) {
+	rules, err := newHistogramRules([]config.HistogramRule{
+		{Match: "app.latency", Aggregates: []string{"max"}, Percentiles: []string{"0.99"}},
+		{Match: "app.slo", ToDistribution: true},
+	})
+	require.NoError(t, err)
+	sampler := testTimeSampler()
+	sampler.histogramRules = rules
+
+	for v := 1; v <= 100; v++ {
+		for _, name := range []string{"app.latency", "app.slo", "app.other"} {
+			sampler.sample(&metrics.MetricSample{Name: name, Value: float64(v), Mtype: metrics.HistogramType, SampleRate: 1}, 10001)
+		}
+	}
+	series, sketches := flushSerie(sampler, 10020.0)
+
+	names := make([]string, 0, len(series))
+	for _, serie := range series {
+		names = append(names, serie.Name)
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{
+		"app.latency.99percentile",
+		"app.latency.max",
+		"app.other.95percentile",
+		"app.other.avg",
+		"app.other.count",
+		"app.other.max",
+		"app.other.median",
+	}, names)
+
+	require.Len(t, sketches, 1)
+	assert.Equal(t, "app.slo", sketches[0].Name)
+	assert.Equal(t, int64(100), sketches[0].Points[0].Sketch.Basic.Cnt)
+}
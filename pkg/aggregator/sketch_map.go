@@ -26,16 +26,17 @@ func (m sketchMap) Len() int {
 
 // insert v into a sketch for the given (ts, contextKey)
 // NOTE: ts is truncated to bucketSize
-func (m sketchMap) insert(ts int64, ck ckey.ContextKey, v float64, sampleRate float64) bool {
+// The sketch is created with the config c when it doesn't exist, nil for the default config.
+func (m sketchMap) insert(ts int64, ck ckey.ContextKey, v float64, sampleRate float64, c *quantile.Config) bool {
 	if math.IsInf(v, 0) || math.IsNaN(v) {
 		return false
 	}
 
-	m.getOrCreate(ts, ck).Insert(v, sampleRate)
+	m.getOrCreate(ts, ck, c).Insert(v, sampleRate)
 	return true
 }
 
-func (m sketchMap) insertInterp(ts int64, ck ckey.ContextKey, lower float64, upper float64, count uint) bool {
+func (m sketchMap) insertInterp(ts int64, ck ckey.ContextKey, lower float64, upper float64, count uint, c *quantile.Config) bool {
 	if math.IsInf(lower, 0) || math.IsNaN(lower) {
 		return false
 	}
@@ -44,11 +45,11 @@ func (m sketchMap) insertInterp(ts int64, ck ckey.ContextKey, lower float64, upp
 		return false
 	}
 
-	m.getOrCreate(ts, ck).InsertInterpolate(lower, upper, count)
+	m.getOrCreate(ts, ck, c).InsertInterpolate(lower, upper, count)
 	return true
 }
 
-func (m sketchMap) getOrCreate(ts int64, ck ckey.ContextKey) *quantile.Agent {
+func (m sketchMap) getOrCreate(ts int64, ck ckey.ContextKey, c *quantile.Config) *quantile.Agent {
 	// level 1: ts -> ctx
 	byCtx, ok := m[ts]
 	if !ok {
@@ -59,7 +60,7 @@ func (m sketchMap) getOrCreate(ts int64, ck ckey.ContextKey) *quantile.Agent {
 	// level 2: ctx -> sketch
 	s, ok := byCtx[ck]
 	if !ok {
-		s = &quantile.Agent{}
+		s = &quantile.Agent{Config: c}
 		m[ts][ck] = s
 	}
 
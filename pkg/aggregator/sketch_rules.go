@@ -0,0 +1,109 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/quantile"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// defaultSketchRule is the telemetry tag of the sketches which match no rule
+const defaultSketchRule = "default"
+
+var (
+	tlmSketchesFlushed = telemetry.NewCounter("aggregator", "sketches_flushed",
+		[]string{"rule"}, "Count of sketch points flushed, by sketch rule prefix")
+	tlmSketchBinsFlushed = telemetry.NewCounter("aggregator", "sketch_bins_flushed",
+		[]string{"rule"}, "Count of sketch bins flushed, by sketch rule prefix")
+)
+
+// sketchRule sets the config of the sketches of the distributions whose names start with a prefix.
+type sketchRule struct {
+	prefix string
+	config *quantile.Config
+}
+
+// sketchRules resolves the config of the sketches of the distributions by metric name, the first
+// rule whose prefix matches the name applies. The sketches of the other distributions use the
+// default config.
+type sketchRules []sketchRule
+
+// newSketchRules validates the rules and builds their sketch config. A relative accuracy finer than
+// the default one is not supported, the default one is used instead.
+func newSketchRules(rules []config.SketchRule) (sketchRules, error) {
+	sr := make(sketchRules, 0, len(rules))
+	for i, rule := range rules {
+		if rule.Prefix == "" {
+			return nil, fmt.Errorf("rule num %d: prefix is required", i)
+		}
+		relativeAccuracy := rule.RelativeAccuracy
+		// the backend decodes the sketch keys with the default accuracy, so it is the finest one
+		if finest := quantile.Default().RelativeAccuracy(); relativeAccuracy > 0 && relativeAccuracy < finest {
+			log.Warnf("distribution_sketch_rules: rule num %d (%s): relative_accuracy %g is finer than the finest supported one, using %g instead. Only a coarser accuracy than the default one can be set", i, rule.Prefix, relativeAccuracy, finest)
+			relativeAccuracy = finest
+		}
+		c, err := quantile.NewAgentConfig(relativeAccuracy, rule.BinLimit)
+		if err != nil {
+			return nil, fmt.Errorf("rule num %d (%s): %v", i, rule.Prefix, err)
+		}
+		sr = append(sr, sketchRule{prefix: rule.Prefix, config: c})
+	}
+	return sr, nil
+}
+
+// newSketchRulesFromConfig returns the rules set in distribution_sketch_rules, or no rule when
+// they are invalid.
+func newSketchRulesFromConfig() sketchRules {
+	var rules []config.SketchRule
+	if err := config.Datadog.UnmarshalKey("distribution_sketch_rules", &rules); err != nil {
+		log.Errorf("Could not parse distribution_sketch_rules, using the default sketch config for all the distributions: %v", err)
+		return nil
+	}
+	sr, err := newSketchRules(rules)
+	if err != nil {
+		log.Errorf("Invalid distribution_sketch_rules, using the default sketch config for all the distributions: %v", err)
+		return nil
+	}
+	return sr
+}
+
+// match returns the rule matching a metric name, nil if there is none.
+func (sr sketchRules) match(name string) *sketchRule {
+	for i := range sr {
+		if strings.HasPrefix(name, sr[i].prefix) {
+			return &sr[i]
+		}
+	}
+	return nil
+}
+
+// config returns the sketch config of a metric name, nil for the default config.
+func (sr sketchRules) config(name string) *quantile.Config {
+	if rule := sr.match(name); rule != nil {
+		return rule.config
+	}
+	return nil
+}
+
+// observe counts the points and bins of a flushed sketch series in the telemetry, by rule.
+func (sr sketchRules) observe(ss metrics.SketchSeries) {
+	tag := defaultSketchRule
+	if rule := sr.match(ss.Name); rule != nil {
+		tag = rule.prefix
+	}
+	bins := 0
+	for _, p := range ss.Points {
+		bins += p.Sketch.BinCount()
+	}
+	tlmSketchesFlushed.Add(float64(len(ss.Points)), tag)
+	tlmSketchBinsFlushed.Add(float64(bins), tag)
+}
@@ -0,0 +1,84 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func TestNewSketchRules(t *testing.T) {
+	rules, err := newSketchRules([]config.SketchRule{
+		{Prefix: "slo.", BinLimit: 8192},
+		{Prefix: "bulk.", RelativeAccuracy: 0.05, BinLimit: 256},
+		{Prefix: "bulk.other.", RelativeAccuracy: 0.1},
+	})
+	require.NoError(t, err)
+
+	assert.Nil(t, rules.config("other.metric"))
+	assert.Equal(t, 8192, rules.config("slo.latency").BinLimit())
+	// the first matching rule applies
+	assert.Equal(t, 256, rules.config("bulk.other.metric").BinLimit())
+	assert.InDelta(t, 0.05, rules.config("bulk.other.metric").RelativeAccuracy(), 0.01)
+
+	_, err = newSketchRules([]config.SketchRule{{RelativeAccuracy: 0.05}})
+	assert.EqualError(t, err, "rule num 0: prefix is required")
+	_, err = newSketchRules([]config.SketchRule{{Prefix: "a."}, {Prefix: "b.", RelativeAccuracy: 1.5}})
+	assert.Error(t, err)
+	_, err = newSketchRules([]config.SketchRule{{Prefix: "a.", BinLimit: -1}})
+	assert.Error(t, err)
+
+	// a finer accuracy than the default one falls back to the default one
+	rules, err = newSketchRules([]config.SketchRule{{Prefix: "slo.", RelativeAccuracy: 0.001}})
+	require.NoError(t, err)
+	assert.Equal(t, 1.0/128, rules.config("slo.latency").RelativeAccuracy())
+}
+
+func TestNewSketchRulesFromConfig(t *testing.T) {
+	defer config.Datadog.Set("distribution_sketch_rules", nil)
+
+	config.Datadog.Set("distribution_sketch_rules", []map[string]interface{}{{"prefix": "bulk.", "relative_accuracy": 0.05, "bin_limit": 256}})
+	rules := newSketchRulesFromConfig()
+	require.Len(t, rules, 1)
+	assert.Equal(t, "bulk.", rules[0].prefix)
+	assert.Equal(t, 256, rules[0].config.BinLimit())
+
+	// invalid rules are ignored
+	config.Datadog.Set("distribution_sketch_rules", []map[string]interface{}{{"prefix": "bulk.", "relative_accuracy": 2}})
+	assert.Empty(t, newSketchRulesFromConfig())
+}
+
+func TestTimeSamplerSketchRules(t *testing.T) {
+	rules, err := newSketchRules([]config.SketchRule{{Prefix: "bulk.", RelativeAccuracy: 0.1}})
+	require.NoError(t, err)
+	sampler := testTimeSampler()
+	sampler.sketchRules = rules
+
+	for v := 1; v <= 1000; v++ {
+		for _, name := range []string{"bulk.metric", "slo.metric"} {
+			sampler.sample(&metrics.MetricSample{Name: name, Value: float64(v), Mtype: metrics.DistributionType, SampleRate: 1}, 10001)
+		}
+	}
+	_, flushed := flushSerie(sampler, 10020.0)
+	require.Len(t, flushed, 2)
+
+	bins := map[string]int{}
+	for _, ss := range flushed {
+		require.Len(t, ss.Points, 1)
+		bins[ss.Name] = ss.Points[0].Sketch.BinCount()
+		assert.Equal(t, int64(1000), ss.Points[0].Sketch.Basic.Cnt)
+	}
+	// the coarser sketch has several times fewer bins
+	assert.Less(t, bins["bulk.metric"]*5, bins["slo.metric"])
+}
@@ -31,6 +31,12 @@ type TimeSampler struct {
 	counterLastSampledByContext map[ckey.ContextKey]float64
 	lastCutOffTime              int64
 	sketchMap                   sketchMap
+	// contextLimiter bounds the contexts tracked per flush, nil when there is no limit
+	contextLimiter *contextLimiter
+	// sketchRules sets the config of the sketches of the distributions by metric name
+	sketchRules sketchRules
+	// histogramRules sets the aggregation of the histograms by metric name
+	histogramRules histogramRules
 
 	// id is a number to differentiate multiple time samplers
 	// since we start running more than one with the demultiplexer introduction
@@ -72,12 +78,31 @@ func (s *TimeSampler) sample(metricSample *metrics.MetricSample, timestamp float
 	}
 
 	// Keep track of the context
-	contextKey := s.contextResolver.trackContext(metricSample, timestamp)
+	origin := metricSample.OriginFromUDS
+	if origin == "" {
+		origin = metricSample.OriginFromClient
+	}
+	contextKey, ok := s.contextResolver.trackLimitedContext(metricSample, timestamp, s.contextLimiter, origin)
+	if !ok {
+		return
+	}
 	bucketStart := s.calculateBucketStart(timestamp)
 
-	switch metricSample.Mtype {
+	mtype := metricSample.Mtype
+	var histogramConfig *metrics.HistogramConfig
+	if mtype == metrics.HistogramType && len(s.histogramRules) > 0 {
+		if rule := s.histogramRule(contextKey); rule != nil {
+			if rule.toDistribution {
+				mtype = metrics.DistributionType
+			} else {
+				histogramConfig = rule.config
+			}
+		}
+	}
+
+	switch mtype {
 	case metrics.DistributionType:
-		s.sketchMap.insert(bucketStart, contextKey, metricSample.Value, metricSample.SampleRate)
+		s.sketchMap.insert(bucketStart, contextKey, metricSample.Value, metricSample.SampleRate, s.sketchRules.config(metricSample.Name))
 	default:
 		// If it's a new bucket, initialize it
 		bucketMetrics, ok := s.metricsByTimestamp[bucketStart]
@@ -91,11 +116,25 @@ func (s *TimeSampler) sample(metricSample *metrics.MetricSample, timestamp float
 		}
 
 		// Add sample to bucket
-		if err := bucketMetrics.AddSample(contextKey, metricSample, timestamp, s.interval, nil); err != nil {
+		if err := bucketMetrics.AddSampleWithHistogramConfig(contextKey, metricSample, timestamp, s.interval, nil, histogramConfig); err != nil {
 			log.Debugf("TimeSampler #%d Ignoring sample '%s' on host '%s' and tags '%s': %s", s.id, metricSample.Name, metricSample.Host, metricSample.Tags, err)
 		}
 	}
 }
+
+// histogramRule returns the histogram rule of a context, resolved once per context.
+func (s *TimeSampler) histogramRule(contextKey ckey.ContextKey) *histogramRule {
+	context, ok := s.contextResolver.get(contextKey)
+	if !ok {
+		return nil
+	}
+	if !context.histogramRuleResolved {
+		context.histogramRule = s.histogramRules.match(context.Name)
+		context.histogramRuleResolved = true
+	}
+	return context.histogramRule
+}
+
 func (s *TimeSampler) newSketchSeries(ck ckey.ContextKey, points []metrics.SketchPoint) metrics.SketchSeries {
 	ctx, _ := s.contextResolver.get(ck)
 	ss := metrics.SketchSeries{
@@ -200,7 +239,9 @@ func (s *TimeSampler) flushSketches(cutoffTime int64) metrics.SketchSeriesList {
 		pointsByCtx[ck] = append(pointsByCtx[ck], p)
 	})
 	for ck, points := range pointsByCtx {
-		sketches = append(sketches, s.newSketchSeries(ck, points))
+		ss := s.newSketchSeries(ck, points)
+		s.sketchRules.observe(ss)
+		sketches = append(sketches, ss)
 	}
 
 	return sketches
@@ -217,6 +258,10 @@ func (s *TimeSampler) flush(timestamp float64, series metrics.SerieSink) metrics
 	s.contextResolver.expireContexts(timestamp - config.Datadog.GetFloat64("dogstatsd_context_expiry_seconds"))
 	s.lastCutOffTime = cutoffTime
 
+	if s.contextLimiter != nil {
+		s.contextLimiter.flush(s.id)
+	}
+
 	totalContexts := s.contextResolver.length()
 	aggregatorDogstatsdContexts.Set(int64(totalContexts))
 	tlmDogstatsdContexts.Set(float64(totalContexts))
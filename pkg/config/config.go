@@ -170,6 +170,7 @@ type MappingProfile struct {
 	Name     string          `mapstructure:"name" json:"name"`
 	Prefix   string          `mapstructure:"prefix" json:"prefix"`
 	Mappings []MetricMapping `mapstructure:"mappings" json:"mappings"`
+	TagRules []TagRule       `mapstructure:"tag_rules" json:"tag_rules"`
 }
 
 // MetricMapping represent one mapping rule
@@ -180,6 +181,39 @@ type MetricMapping struct {
 	Tags      map[string]string `mapstructure:"tags" json:"tags"`
 }
 
+// TagRule represent one rule dropping, rewriting or renaming the tags of the metrics of a mapping profile
+type TagRule struct {
+	Match     string            `mapstructure:"match" json:"match"`
+	MatchType string            `mapstructure:"match_type" json:"match_type"`
+	MatchTags map[string]string `mapstructure:"match_tags" json:"match_tags"`
+	Drop      []string          `mapstructure:"drop" json:"drop"`
+	Rewrite   []TagRewrite      `mapstructure:"rewrite" json:"rewrite"`
+	Rename    map[string]string `mapstructure:"rename" json:"rename"`
+}
+
+// TagRewrite represent the rewriting of the values of a tag
+type TagRewrite struct {
+	Tag   string `mapstructure:"tag" json:"tag"`
+	Match string `mapstructure:"match" json:"match"`
+	Value string `mapstructure:"value" json:"value"`
+}
+
+// SketchRule represent the accuracy of the sketches of the distributions whose names start with a prefix
+type SketchRule struct {
+	Prefix           string  `mapstructure:"prefix" json:"prefix"`
+	RelativeAccuracy float64 `mapstructure:"relative_accuracy" json:"relative_accuracy"`
+	BinLimit         int     `mapstructure:"bin_limit" json:"bin_limit"`
+}
+
+// HistogramRule represent the aggregation of the histograms whose names match a pattern
+type HistogramRule struct {
+	Match          string   `mapstructure:"match" json:"match"`
+	MatchType      string   `mapstructure:"match_type" json:"match_type"`
+	Aggregates     []string `mapstructure:"aggregates" json:"aggregates"`
+	Percentiles    []string `mapstructure:"percentiles" json:"percentiles"`
+	ToDistribution bool     `mapstructure:"to_distribution" json:"to_distribution"`
+}
+
 // Endpoint represent a datadog endpoint
 type Endpoint struct {
 	Site   string `mapstructure:"site" json:"site"`
@@ -420,12 +454,28 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("proc_root", "/proc")
 	config.BindEnvAndSetDefault("histogram_aggregates", []string{"max", "median", "avg", "count"})
 	config.BindEnvAndSetDefault("histogram_percentiles", []string{"0.95"})
+	config.BindEnvAndSetDefault("histogram_rules", []HistogramRule{})
+	config.SetEnvKeyTransformer("histogram_rules", func(in string) interface{} {
+		var rules []HistogramRule
+		if err := json.Unmarshal([]byte(in), &rules); err != nil {
+			log.Errorf(`"histogram_rules" can not be parsed: %v`, err)
+		}
+		return rules
+	})
 	config.BindEnvAndSetDefault("aggregator_stop_timeout", 2)
 	config.BindEnvAndSetDefault("aggregator_buffer_size", 100)
 	config.BindEnvAndSetDefault("aggregator_use_tags_store", true)
 	config.BindEnvAndSetDefault("basic_telemetry_add_container_tags", false) // configure adding the agent container tags to the basic agent telemetry metrics (e.g. `datadog.agent.running`)
 	config.BindEnvAndSetDefault("aggregator_flush_metrics_and_serialize_in_parallel_chan_size", 200)
 	config.BindEnvAndSetDefault("aggregator_flush_metrics_and_serialize_in_parallel_buffer_size", 4000)
+	config.BindEnv("distribution_sketch_rules")
+	config.SetEnvKeyTransformer("distribution_sketch_rules", func(in string) interface{} {
+		var rules []SketchRule
+		if err := json.Unmarshal([]byte(in), &rules); err != nil {
+			log.Errorf(`"distribution_sketch_rules" can not be parsed: %v`, err)
+		}
+		return rules
+	})
 
 	// Serializer
 	config.BindEnvAndSetDefault("enable_stream_payload_serialization", true)
@@ -482,6 +532,9 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("use_dogstatsd", true)
 	config.BindEnvAndSetDefault("dogstatsd_port", 8125)    // Notice: 0 means UDP port closed
 	config.BindEnvAndSetDefault("dogstatsd_pipe_name", "") // experimental and not officially supported for now.
+	config.BindEnvAndSetDefault("dogstatsd_tcp_port", 0)   // Notice: 0 means TCP port closed
+	config.BindEnvAndSetDefault("dogstatsd_tcp_tls_cert_file", "")
+	config.BindEnvAndSetDefault("dogstatsd_tcp_tls_key_file", "")
 	// Experimental and not officially supported for now.
 	// Options are: udp, uds, named_pipe
 	config.BindEnvAndSetDefault("dogstatsd_eol_required", []string{})
@@ -510,7 +563,11 @@ func InitConfig(config Config) {
 	// is 10s), otherwise we won't be able to sample unseen counter as
 	// contexts will be deleted (see 'dogstatsd_expiry_seconds').
 	config.BindEnvAndSetDefault("dogstatsd_context_expiry_seconds", 300)
-	config.BindEnvAndSetDefault("dogstatsd_origin_detection", false) // Only supported for socket traffic
+	// Limits of the dogstatsd contexts tracked per flush, per metric name and per origin (0 to disable)
+	config.BindEnvAndSetDefault("dogstatsd_context_limit_per_metric", 0)
+	config.BindEnvAndSetDefault("dogstatsd_context_limit_per_origin", 0)
+	config.BindEnvAndSetDefault("dogstatsd_context_limit_overflow", "fold") // "fold" or "drop"
+	config.BindEnvAndSetDefault("dogstatsd_origin_detection", false)        // Only supported for socket traffic
 	config.BindEnvAndSetDefault("dogstatsd_origin_detection_client", false)
 	config.BindEnvAndSetDefault("dogstatsd_so_rcvbuf", 0)
 	config.BindEnvAndSetDefault("dogstatsd_metrics_stats_enable", false)
@@ -536,6 +593,26 @@ func InitConfig(config Config) {
 		return mappings
 	})
 
+	// Prometheus remote-write receiver
+	config.BindEnvAndSetDefault("prometheus_remote_write.enabled", false)
+	config.BindEnvAndSetDefault("prometheus_remote_write.port", 9201)
+	config.BindEnvAndSetDefault("prometheus_remote_write.non_local_traffic", false)
+	config.BindEnvAndSetDefault("prometheus_remote_write.namespace", "")
+	config.BindEnvAndSetDefault("prometheus_remote_write.max_request_size", 10*1024*1024)
+	config.BindEnv("prometheus_remote_write.mapper_profiles")
+	config.SetEnvKeyTransformer("prometheus_remote_write.mapper_profiles", func(in string) interface{} {
+		var mappings []MappingProfile
+		if err := json.Unmarshal([]byte(in), &mappings); err != nil {
+			log.Errorf(`"prometheus_remote_write.mapper_profiles" can not be parsed: %v`, err)
+		}
+		return mappings
+	})
+
+	// Local OpenMetrics export of the flushed metrics
+	config.BindEnvAndSetDefault("openmetrics_export.enabled", false)
+	config.BindEnvAndSetDefault("openmetrics_export.port", 9202)
+	config.BindEnvAndSetDefault("openmetrics_export.non_local_traffic", false)
+
 	config.BindEnvAndSetDefault("statsd_forward_host", "")
 	config.BindEnvAndSetDefault("statsd_forward_port", 0)
 	config.BindEnvAndSetDefault("statsd_metric_namespace", "")
@@ -833,6 +910,25 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("logs_config.use_podman_logs", false)
 
 	config.BindEnvAndSetDefault("logs_config.auditor_ttl", DefaultAuditorTTL) // in hours
+	// When set to a positive size, payloads that can't be sent because all the reliable endpoints
+	// are unavailable are stored on disk, up to this size, and sent once the endpoints recover.
+	config.BindEnvAndSetDefault("logs_config.disk_buffer_max_size_in_bytes", 0)
+	// Defaults to the logs_disk_buffer directory in logs_config.run_path.
+	config.BindEnvAndSetDefault("logs_config.disk_buffer_path", "")
+	config.BindEnvAndSetDefault("logs_config.disk_buffer_max_age", 24) // in hours
+	// When brokers are set, the logs are also sent to Kafka, one record per log.
+	config.BindEnvAndSetDefault("logs_config.kafka.brokers", []string{})
+	config.BindEnvAndSetDefault("logs_config.kafka.topic", "datadog-logs")
+	config.BindEnvAndSetDefault("logs_config.kafka.topic_by", "")
+	config.BindEnvAndSetDefault("logs_config.kafka.key_by", "host")
+	config.BindEnvAndSetDefault("logs_config.kafka.required_acks", 1)
+	config.BindEnvAndSetDefault("logs_config.kafka.timeout", 10)   // in seconds
+	config.BindEnvAndSetDefault("logs_config.kafka.batch_wait", 1) // in seconds
+	config.BindEnvAndSetDefault("logs_config.kafka.batch_max_records", 1000)
+	config.BindEnvAndSetDefault("logs_config.kafka.batch_max_bytes", 1000000)
+	config.BindEnvAndSetDefault("logs_config.kafka.client_id", "datadog-agent")
+	config.BindEnvAndSetDefault("logs_config.kafka.use_ssl", false)
+	config.BindEnvAndSetDefault("logs_config.kafka.is_reliable", false)
 	// Timeout in milliseonds used when performing agreggation operations,
 	// including multi-line log processing rules and chunked line reaggregation.
 	// It may be useful to increase it when logs writing is slowed down, that
@@ -1370,6 +1466,8 @@ func bindEnvAndSetLogsConfigKeys(config Config, prefix string) {
 	config.BindEnv(prefix + "additional_endpoints")
 	config.BindEnvAndSetDefault(prefix+"use_compression", true)
 	config.BindEnvAndSetDefault(prefix+"compression_level", 6) // Default level for the gzip/deflate algorithm
+	config.BindEnvAndSetDefault(prefix+"compression_kind", "gzip")
+	config.BindEnvAndSetDefault(prefix+"zstd_compression_level", 1) // Default level for the zstd algorithm
 	config.BindEnvAndSetDefault(prefix+"batch_wait", DefaultBatchWait)
 	config.BindEnvAndSetDefault(prefix+"connection_reset_interval", 0) // in seconds, 0 means disabled
 	config.BindEnvAndSetDefault(prefix+"logs_no_ssl", false)
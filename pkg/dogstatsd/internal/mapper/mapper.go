@@ -34,6 +34,7 @@ type MappingProfile struct {
 	Name     string
 	Prefix   string
 	Mappings []*MetricMapping
+	TagRules []*TagRule
 }
 
 // MetricMapping represent one mapping rule
@@ -43,6 +44,22 @@ type MetricMapping struct {
 	regex *regexp.Regexp
 }
 
+// TagRule represent one rule dropping, rewriting and renaming the tags of the metrics
+type TagRule struct {
+	// regex matches the metric names the rule applies to, nil to apply it to every metric of the profile
+	regex     *regexp.Regexp
+	matchTags map[string]*regexp.Regexp
+	drop      map[string]struct{}
+	rewrites  []tagRewrite
+	rename    map[string]string
+}
+
+type tagRewrite struct {
+	key   string
+	regex *regexp.Regexp
+	value string
+}
+
 // MapResult represent the outcome of the mapping
 type MapResult struct {
 	Name    string
@@ -81,6 +98,13 @@ func NewMetricMapper(configProfiles []config.MappingProfile, cacheSize int) (*Me
 			}
 			profile.Mappings = append(profile.Mappings, &MetricMapping{name: currentMapping.Name, tags: currentMapping.Tags, regex: regex})
 		}
+		for i, currentRule := range configProfile.TagRules {
+			rule, err := buildTagRule(currentRule)
+			if err != nil {
+				return nil, fmt.Errorf("profile: %s, tag rule num %d: %v", profile.Name, i, err)
+			}
+			profile.TagRules = append(profile.TagRules, rule)
+		}
 		profiles = append(profiles, profile)
 	}
 	cache, err := newMapperCache(cacheSize)
@@ -108,6 +132,52 @@ func buildRegex(matchRe string, matchType string) (*regexp.Regexp, error) {
 	return regex, nil
 }
 
+func buildTagRule(configRule config.TagRule) (*TagRule, error) {
+	if len(configRule.Drop) == 0 && len(configRule.Rewrite) == 0 && len(configRule.Rename) == 0 {
+		return nil, fmt.Errorf("drop, rewrite or rename is required")
+	}
+	rule := &TagRule{
+		matchTags: make(map[string]*regexp.Regexp, len(configRule.MatchTags)),
+		drop:      make(map[string]struct{}, len(configRule.Drop)),
+		rename:    configRule.Rename,
+	}
+	if configRule.Match != "" {
+		matchType := configRule.MatchType
+		if matchType == "" {
+			matchType = matchTypeWildcard
+		}
+		if matchType != matchTypeWildcard && matchType != matchTypeRegex {
+			return nil, fmt.Errorf("invalid match type, must be `wildcard` or `regex`")
+		}
+		regex, err := buildRegex(configRule.Match, matchType)
+		if err != nil {
+			return nil, err
+		}
+		rule.regex = regex
+	}
+	for key, valueMatch := range configRule.MatchTags {
+		regex, err := buildRegex(valueMatch, matchTypeRegex)
+		if err != nil {
+			return nil, err
+		}
+		rule.matchTags[key] = regex
+	}
+	for _, key := range configRule.Drop {
+		rule.drop[key] = struct{}{}
+	}
+	for i, rewrite := range configRule.Rewrite {
+		if rewrite.Tag == "" || rewrite.Match == "" {
+			return nil, fmt.Errorf("rewrite num %d: tag and match are required", i)
+		}
+		regex, err := buildRegex(rewrite.Match, matchTypeRegex)
+		if err != nil {
+			return nil, err
+		}
+		rule.rewrites = append(rule.rewrites, tagRewrite{key: rewrite.Tag, regex: regex, value: rewrite.Value})
+	}
+	return rule, nil
+}
+
 // Map returns a MapResult
 func (m *MetricMapper) Map(metricName string) *MapResult {
 	for _, profile := range m.Profiles {
@@ -150,3 +220,88 @@ func (m *MetricMapper) Map(metricName string) *MapResult {
 	}
 	return nil
 }
+
+// MapTags applies the tag rules of the profile matching metricName to tags, in their order. tags is
+// modified in place and the mapped tags are returned.
+func (m *MetricMapper) MapTags(metricName string, tags []string) []string {
+	for _, profile := range m.Profiles {
+		if !strings.HasPrefix(metricName, profile.Prefix) && profile.Prefix != "*" {
+			continue
+		}
+		for _, rule := range profile.TagRules {
+			if rule.matches(metricName, tags) {
+				tags = rule.apply(tags)
+			}
+		}
+		return tags
+	}
+	return tags
+}
+
+// matches returns true if the rule applies to the metric, that is if its name matches and it has
+// all the tags to match.
+func (r *TagRule) matches(metricName string, tags []string) bool {
+	if r.regex != nil && !r.regex.MatchString(metricName) {
+		return false
+	}
+	for key, valueRegex := range r.matchTags {
+		found := false
+		for _, tag := range tags {
+			tagKey, value, _ := splitTag(tag)
+			if tagKey == key && valueRegex.MatchString(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// apply drops the tags, then rewrites the values and renames the keys of the tags left.
+func (r *TagRule) apply(tags []string) []string {
+	mapped := tags[:0]
+	for _, tag := range tags {
+		key, value, hasValue := splitTag(tag)
+		if _, found := r.drop[key]; found {
+			continue
+		}
+		changed := false
+		if hasValue {
+			for _, rewrite := range r.rewrites {
+				if rewrite.key != key {
+					continue
+				}
+				if matches := rewrite.regex.FindStringSubmatchIndex(value); matches != nil {
+					value = string(rewrite.regex.ExpandString([]byte{}, rewrite.value, value, matches))
+					changed = true
+					break
+				}
+			}
+		}
+		if newKey, found := r.rename[key]; found {
+			key = newKey
+			changed = true
+		}
+		if changed {
+			if hasValue {
+				tag = key + ":" + value
+			} else {
+				tag = key
+			}
+		}
+		mapped = append(mapped, tag)
+	}
+	return mapped
+}
+
+// splitTag returns the key and the value of a tag, the key of a tag without value is the whole tag.
+func splitTag(tag string) (string, string, bool) {
+	i := strings.IndexByte(tag, ':')
+	if i < 0 {
+		return tag, "", false
+	}
+	return tag[:i], tag[i+1:], true
+}
@@ -349,6 +349,186 @@ dogstatsd_mapper_profiles:
 	}
 }
 
+func TestTagRules(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		config       string
+		metricName   string
+		tags         []string
+		expectedTags []string
+	}{
+		{
+			name: "Drop tags",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    tag_rules:
+      - drop: ["user_id", "request_id"]
+`,
+			metricName:   "test.requests",
+			tags:         []string{"user_id:42", "env:prod", "request_id:abc", "user_id"},
+			expectedTags: []string{"env:prod"},
+		},
+		{
+			name: "Rewrite tag values",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    tag_rules:
+      - rewrite:
+          - tag: path
+            match: '/users/[0-9]+(/.*)?'
+            value: '/users/:id$1'
+          - tag: path
+            match: '/.*'
+            value: 'other'
+`,
+			metricName:   "test.requests",
+			tags:         []string{"path:/users/42/orders", "path:/users/42", "path:/health", "env:prod", "path"},
+			expectedTags: []string{"path:/users/:id/orders", "path:/users/:id", "path:other", "env:prod", "path"},
+		},
+		{
+			name: "Rename tag keys",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    tag_rules:
+      - rename:
+          svc: service
+          flag: enabled
+`,
+			metricName:   "test.requests",
+			tags:         []string{"svc:web", "env:prod", "flag"},
+			expectedTags: []string{"service:web", "env:prod", "enabled"},
+		},
+		{
+			name: "Rewrite before rename",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    tag_rules:
+      - rewrite:
+          - tag: route
+            match: '/items/.*'
+            value: '/items/:id'
+        rename:
+          route: path
+`,
+			metricName:   "test.requests",
+			tags:         []string{"route:/items/12"},
+			expectedTags: []string{"path:/items/:id"},
+		},
+		{
+			name: "Match metric name",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    tag_rules:
+      - match: 'test.http.*'
+        drop: ["user_id"]
+      - match: 'test\.db\..*'
+        match_type: regex
+        drop: ["query"]
+`,
+			metricName:   "test.db.latency",
+			tags:         []string{"user_id:42", "query:select"},
+			expectedTags: []string{"user_id:42"},
+		},
+		{
+			name: "Match tags",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    tag_rules:
+      - match_tags:
+          env: 'prod|staging'
+          team: '.*'
+        drop: ["user_id"]
+`,
+			metricName:   "test.requests",
+			tags:         []string{"user_id:42", "env:dev", "team:core"},
+			expectedTags: []string{"user_id:42", "env:dev", "team:core"},
+		},
+		{
+			name: "Match tags OK",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    tag_rules:
+      - match_tags:
+          env: 'prod|staging'
+          team: '.*'
+        drop: ["user_id"]
+`,
+			metricName:   "test.requests",
+			tags:         []string{"user_id:42", "env:staging", "team:core"},
+			expectedTags: []string{"env:staging", "team:core"},
+		},
+		{
+			name: "Rules applied in order",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    tag_rules:
+      - rename:
+          usr: user_id
+      - drop: ["user_id"]
+`,
+			metricName:   "test.requests",
+			tags:         []string{"usr:42", "env:prod"},
+			expectedTags: []string{"env:prod"},
+		},
+		{
+			name: "Only the first matching profile applies",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.job.*"
+        name: "test.job"
+  - name: all
+    prefix: '*'
+    tag_rules:
+      - drop: ["user_id"]
+`,
+			metricName:   "test.requests",
+			tags:         []string{"user_id:42"},
+			expectedTags: []string{"user_id:42"},
+		},
+		{
+			name: "Profile prefix not matched",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    tag_rules:
+      - drop: ["user_id"]
+`,
+			metricName:   "other.requests",
+			tags:         []string{"user_id:42"},
+			expectedTags: []string{"user_id:42"},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			mapper, err := getMapper(scenario.config)
+			require.NoError(t, err)
+
+			assert.Equal(t, scenario.expectedTags, mapper.MapTags(scenario.metricName, scenario.tags))
+		})
+	}
+}
+
 func TestMappingErrors(t *testing.T) {
 	scenarios := []struct {
 		name          string
@@ -484,6 +664,56 @@ dogstatsd_mapper_profiles:
 			},
 			expectedError: "missing profile name",
 		},
+		{
+			name: "Tag rule without action",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    tag_rules:
+      - match: "test.job.*"
+`,
+			expectedError: "drop, rewrite or rename is required",
+		},
+		{
+			name: "Tag rule invalid match type",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    tag_rules:
+      - match: "test.job.*"
+        match_type: invalid
+        drop: ["user_id"]
+`,
+			expectedError: "invalid match type",
+		},
+		{
+			name: "Tag rule rewrite without match",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    tag_rules:
+      - rewrite:
+          - tag: path
+            value: "/"
+`,
+			expectedError: "tag and match are required",
+		},
+		{
+			name: "Tag rule invalid regex",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    tag_rules:
+      - match_tags:
+          path: "(["
+        drop: ["user_id"]
+`,
+			expectedError: "cannot compile regex",
+		},
 		{
 			name: "Missing profile prefix",
 			config: `
@@ -2,8 +2,6 @@
 // under the Apache License Version 2.0.
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2016-present Datadog, Inc.
-//go:build windows
-// +build windows
 
 package listeners
 
@@ -15,9 +13,9 @@ import (
 )
 
 type listenerTelemetry struct {
-	packetReadingErrors expvar.Int
-	packets             expvar.Int
-	bytes               expvar.Int
+	packetReadingErrors *expvar.Int
+	packets             *expvar.Int
+	bytes               *expvar.Int
 	expvars             *expvar.Map
 	tlmPackets          telemetry.Counter
 	tlmPacketsBytes     telemetry.Counter
@@ -25,17 +23,17 @@ type listenerTelemetry struct {
 
 func newListenerTelemetry(metricName string, name string) *listenerTelemetry {
 	expvars := expvar.NewMap("dogstatsd-" + metricName)
-	packetReadingErrors := expvar.Int{}
-	packets := expvar.Int{}
-	bytes := expvar.Int{}
+	packetReadingErrors := &expvar.Int{}
+	packets := &expvar.Int{}
+	bytes := &expvar.Int{}
 
 	tlmPackets := telemetry.NewCounter("dogstatsd", metricName+"_packets",
 		[]string{"state"}, fmt.Sprintf("Dogstatsd %s packets count", name))
 	tlmPacketsBytes := telemetry.NewCounter("dogstatsd", metricName+"_packets_bytes",
 		nil, fmt.Sprintf("Dogstatsd %s packets bytes count", name))
-	expvars.Set("PacketReadingErrors", &packetReadingErrors)
-	expvars.Set("Packets", &packets)
-	expvars.Set("Bytes", &bytes)
+	expvars.Set("PacketReadingErrors", packetReadingErrors)
+	expvars.Set("Packets", packets)
+	expvars.Set("Bytes", bytes)
 
 	return &listenerTelemetry{
 		expvars:             expvars,
@@ -44,7 +44,7 @@ func NewNamedPipeListener(pipeName string, packetOut chan packets.Packets,
 	return newNamedPipeListener(
 		pipeName,
 		bufferSize,
-		packets.NewPacketManagerFromConfig(packetOut, sharedPacketPoolManager),
+		packets.NewPacketManagerFromConfig(packetOut, sharedPacketPoolManager, packets.NamedPipe),
 		capture)
 }
 
@@ -0,0 +1,229 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/packets"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/replay"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var (
+	tcpTelemetry = newListenerTelemetry("tcp", "TCP")
+
+	tlmTCPConnections = telemetry.NewGauge("dogstatsd", "tcp_connections",
+		nil, "Dogstatsd TCP active connections")
+	tlmTCPConnectionsTotal = telemetry.NewCounter("dogstatsd", "tcp_connections_total",
+		[]string{"state"}, "Dogstatsd TCP connections count")
+	tlmTCPDroppedMessages = telemetry.NewCounter("dogstatsd", "tcp_dropped_messages",
+		[]string{"reason"}, "Dogstatsd TCP messages dropped because they are not newline terminated or too long")
+)
+
+// TCPListener implements the StatsdListener interface for TCP protocol.
+// It listens to a given TCP address, optionally with TLS, and sends back packets
+// ready to be processed. The messages must be newline terminated.
+// Origin detection is not implemented for TCP.
+type TCPListener struct {
+	listener       net.Listener
+	packetManager  *packets.PacketManager
+	trafficCapture *replay.TrafficCapture // Currently ignored
+
+	connsMu         sync.Mutex
+	conns           map[net.Conn]struct{}
+	stopped         bool
+	connsWg         sync.WaitGroup
+	activeConnCount int32
+}
+
+// NewTCPListener returns an idle TCP Statsd listener
+func NewTCPListener(packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager, capture *replay.TrafficCapture) (*TCPListener, error) {
+	var url string
+	if config.Datadog.GetBool("dogstatsd_non_local_traffic") == true {
+		// Listen to all network interfaces
+		url = fmt.Sprintf(":%d", config.Datadog.GetInt("dogstatsd_tcp_port"))
+	} else {
+		url = net.JoinHostPort(config.GetBindHost(), config.Datadog.GetString("dogstatsd_tcp_port"))
+	}
+
+	var tlsConfig *tls.Config
+	certFile := config.Datadog.GetString("dogstatsd_tcp_tls_cert_file")
+	keyFile := config.Datadog.GetString("dogstatsd_tcp_tls_key_file")
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("dogstatsd-tcp: can't load the TLS certificate: %s", err)
+		}
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
+	return newTCPListener(
+		url,
+		tlsConfig,
+		packets.NewPacketManagerFromConfig(packetOut, sharedPacketPoolManager, packets.TCP),
+		capture)
+}
+
+func newTCPListener(
+	url string,
+	tlsConfig *tls.Config,
+	packetManager *packets.PacketManager,
+	capture *replay.TrafficCapture) (*TCPListener, error) {
+
+	listener, err := net.Listen("tcp", url)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %s", err)
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	l := &TCPListener{
+		listener:       listener,
+		packetManager:  packetManager,
+		trafficCapture: capture,
+		conns:          make(map[net.Conn]struct{}),
+	}
+	log.Debugf("dogstatsd-tcp: %s successfully initialized", listener.Addr())
+	return l, nil
+}
+
+// Listen runs the intake loop. Should be called in its own goroutine
+func (l *TCPListener) Listen() {
+	log.Infof("dogstatsd-tcp: starting to listen on %s", l.listener.Addr())
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			// listener has been closed
+			if errors.Is(err, net.ErrClosed) {
+				log.Debug("dogstatsd-tcp: stop listening")
+				return
+			}
+			log.Errorf("dogstatsd-tcp: error accepting connection: %v", err)
+			tlmTCPConnectionsTotal.Inc("error")
+			continue
+		}
+		tlmTCPConnectionsTotal.Inc("ok")
+
+		l.connsMu.Lock()
+		if l.stopped {
+			l.connsMu.Unlock()
+			conn.Close()
+			return
+		}
+		l.conns[conn] = struct{}{}
+		l.connsWg.Add(1)
+		l.connsMu.Unlock()
+		atomic.AddInt32(&l.activeConnCount, 1)
+		tlmTCPConnections.Inc()
+
+		go l.listenConnection(conn, l.packetManager.CreateBuffer())
+	}
+}
+
+func (l *TCPListener) listenConnection(conn net.Conn, buffer []byte) {
+	defer func() {
+		conn.Close()
+		l.connsMu.Lock()
+		delete(l.conns, conn)
+		l.connsMu.Unlock()
+		atomic.AddInt32(&l.activeConnCount, -1)
+		tlmTCPConnections.Dec()
+		l.connsWg.Done()
+	}()
+
+	log.Debugf("dogstatsd-tcp: start listening a new client from %s", conn.RemoteAddr())
+	startWriteIndex := 0
+	// skipping is set while discarding the end of a message larger than the buffer
+	skipping := false
+	var t1, t2 time.Time
+	for {
+		bytesRead, err := conn.Read(buffer[startWriteIndex:])
+
+		t1 = time.Now()
+
+		if err != nil {
+			if startWriteIndex > 0 || skipping {
+				tlmTCPDroppedMessages.Inc("unterminated")
+			}
+			if err == io.EOF || errors.Is(err, net.ErrClosed) {
+				log.Debugf("dogstatsd-tcp: client disconnected from %s", conn.RemoteAddr())
+				return
+			}
+			log.Debugf("dogstatsd-tcp: error reading from %s: %v", conn.RemoteAddr(), err)
+			tcpTelemetry.onReadError()
+			return
+		}
+
+		endIndex := startWriteIndex + bytesRead
+		messageStart := 0
+		if skipping {
+			// drop the data up to the end of the message being skipped
+			messageStart = bytes.IndexByte(buffer[:endIndex], '\n') + 1
+			if messageStart == 0 {
+				startWriteIndex = 0
+				continue
+			}
+			skipping = false
+		}
+
+		// When there is no '\n', the message is partial. LastIndexByte returns -1 and messageSize is 0.
+		// If there is a '\n', at least one message is completed and '\n' is part of this message.
+		messageEnd := bytes.LastIndexByte(buffer[messageStart:endIndex], '\n') + 1 + messageStart
+		if messageEnd > messageStart {
+			tcpTelemetry.onReadSuccess(messageEnd - messageStart)
+
+			// PacketAssembler merges multiple packets together and sends them when its buffer is full
+			l.packetManager.PacketAssembler.AddMessage(buffer[messageStart:messageEnd])
+		} else {
+			messageEnd = messageStart
+		}
+
+		startWriteIndex = endIndex - messageEnd
+		if startWriteIndex >= len(buffer) {
+			// the message is bigger than the buffer, drop it up to its end
+			tlmTCPDroppedMessages.Inc("too_long")
+			startWriteIndex = 0
+			skipping = true
+		} else {
+			copy(buffer, buffer[messageEnd:endIndex])
+		}
+
+		t2 = time.Now()
+		tlmListener.Observe(float64(t2.Sub(t1).Nanoseconds()), "tcp")
+	}
+}
+
+// Stop closes the TCP listener and its connections and stops listening
+func (l *TCPListener) Stop() {
+	l.listener.Close()
+
+	l.connsMu.Lock()
+	l.stopped = true
+	for conn := range l.conns {
+		conn.Close()
+	}
+	l.connsMu.Unlock()
+	l.connsWg.Wait()
+
+	l.packetManager.Close()
+}
+
+// getActiveConnectionsCount returns the number of active connections.
+func (l *TCPListener) getActiveConnectionsCount() int32 {
+	return atomic.LoadInt32(&l.activeConnCount)
+}
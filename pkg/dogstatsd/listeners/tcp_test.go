@@ -0,0 +1,228 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/packets"
+)
+
+const tcpBufferSize = 16
+
+type tcpListenerTest struct {
+	*TCPListener
+	packetOut chan packets.Packets
+}
+
+func newTCPListenerTest(t *testing.T, tlsConfig *tls.Config) tcpListenerTest {
+	pool := packets.NewPool(1024)
+	poolManager := packets.NewPoolManager(pool)
+	packetOut := make(chan packets.Packets, 1024)
+	packetManager := packets.NewPacketManager(tcpBufferSize, 1024, 10*time.Millisecond, packetOut, poolManager, packets.TCP)
+
+	listener, err := newTCPListener("127.0.0.1:0", tlsConfig, packetManager, nil)
+	require.NoError(t, err)
+
+	go listener.Listen()
+	return tcpListenerTest{
+		TCPListener: listener,
+		packetOut:   packetOut,
+	}
+}
+
+func (l tcpListenerTest) dial(t *testing.T) net.Conn {
+	conn, err := net.Dial("tcp", l.listener.Addr().String())
+	require.NoError(t, err)
+	return conn
+}
+
+// readMessages returns the messages received by the listener until count messages are read.
+func (l tcpListenerTest) readMessages(t *testing.T, count int) []string {
+	var messages []string
+	timeout := time.After(5 * time.Second)
+	for len(messages) < count {
+		select {
+		case received := <-l.packetOut:
+			for _, packet := range received {
+				assert.Equal(t, packets.TCP, packet.Source)
+				messages = append(messages, strings.FieldsFunc(string(packet.Contents), func(c rune) bool { return c == '\n' })...)
+			}
+		case <-timeout:
+			require.Fail(t, "timeout waiting for messages", "received %v", messages)
+		}
+	}
+	return messages
+}
+
+func TestTCPListen(t *testing.T) {
+	listener := newTCPListenerTest(t, nil)
+	defer listener.Stop()
+	client := listener.dial(t)
+	defer client.Close()
+
+	_, err := client.Write([]byte("foo:1|c\nbar:2|c\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"foo:1|c", "bar:2|c"}, listener.readMessages(t, 2))
+}
+
+func TestTCPPartialMessages(t *testing.T) {
+	listener := newTCPListenerTest(t, nil)
+	defer listener.Stop()
+	client := listener.dial(t)
+	defer client.Close()
+
+	for _, part := range []string{"foo:", "1|c\nba", "r:2|c", "\n"} {
+		_, err := client.Write([]byte(part))
+		require.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, []string{"foo:1|c", "bar:2|c"}, listener.readMessages(t, 2))
+}
+
+func TestTCPMessageTooLong(t *testing.T) {
+	listener := newTCPListenerTest(t, nil)
+	defer listener.Stop()
+	client := listener.dial(t)
+	defer client.Close()
+
+	long := strings.Repeat("x", 3*tcpBufferSize)
+	for _, part := range []string{"foo:1|c\n", long[:tcpBufferSize], long[tcpBufferSize:], "|c\nbar:2|c\n"} {
+		_, err := client.Write([]byte(part))
+		require.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, []string{"foo:1|c", "bar:2|c"}, listener.readMessages(t, 2))
+}
+
+func TestTCPSeveralClients(t *testing.T) {
+	listener := newTCPListenerTest(t, nil)
+	defer listener.Stop()
+
+	for i := 0; i < 3; i++ {
+		client := listener.dial(t)
+		_, err := client.Write([]byte("client:" + strconv.Itoa(i) + "|c\n"))
+		require.NoError(t, err)
+		client.Close()
+	}
+	assert.ElementsMatch(t, []string{"client:0|c", "client:1|c", "client:2|c"}, listener.readMessages(t, 3))
+}
+
+func TestTCPStop(t *testing.T) {
+	listener := newTCPListenerTest(t, nil)
+	client := listener.dial(t)
+	defer client.Close()
+
+	_, err := client.Write([]byte("foo:1|c\n"))
+	require.NoError(t, err)
+	listener.readMessages(t, 1)
+	assert.Equal(t, int32(1), listener.getActiveConnectionsCount())
+
+	listener.Stop()
+	assert.Equal(t, int32(0), listener.getActiveConnectionsCount())
+
+	// the connection is closed by the listener
+	require.NoError(t, client.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = client.Read(make([]byte, 1))
+	assert.Error(t, err)
+}
+
+func TestTCPListenerWithTLS(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t)
+	port, err := getAvailableTCPPort()
+	require.NoError(t, err)
+
+	mockConfig := config.Mock()
+	mockConfig.Set("dogstatsd_tcp_port", port)
+	mockConfig.Set("dogstatsd_tcp_tls_cert_file", certFile)
+	mockConfig.Set("dogstatsd_tcp_tls_key_file", keyFile)
+	defer mockConfig.Set("dogstatsd_tcp_port", 0)
+	defer mockConfig.Set("dogstatsd_tcp_tls_cert_file", "")
+	defer mockConfig.Set("dogstatsd_tcp_tls_key_file", "")
+
+	packetOut := make(chan packets.Packets, 16)
+	s, err := NewTCPListener(packetOut, packets.NewPoolManager(packets.NewPool(1024)), nil)
+	require.NoError(t, err)
+	listener := tcpListenerTest{TCPListener: s, packetOut: packetOut}
+	go listener.Listen()
+	defer listener.Stop()
+
+	client, err := tls.Dial("tcp", s.listener.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+	require.NoError(t, err)
+	defer client.Close()
+	_, err = client.Write([]byte("foo:1|c\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"foo:1|c"}, listener.readMessages(t, 1))
+
+	// plain text clients are rejected
+	plain := listener.dial(t)
+	defer plain.Close()
+	_, err = plain.Write([]byte("bar:1|c\n"))
+	require.NoError(t, err)
+	require.NoError(t, plain.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = plain.Read(make([]byte, 1))
+	assert.Error(t, err)
+}
+
+func TestNewTCPListenerWithInvalidCertificate(t *testing.T) {
+	mockConfig := config.Mock()
+	mockConfig.Set("dogstatsd_tcp_tls_cert_file", filepath.Join(t.TempDir(), "missing.pem"))
+	defer mockConfig.Set("dogstatsd_tcp_tls_cert_file", "")
+
+	_, err := NewTCPListener(nil, packets.NewPoolManager(packets.NewPool(1024)), nil)
+	assert.Error(t, err)
+}
+
+func getAvailableTCPPort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// writeTestCertificate writes a self-signed certificate and its key to PEM files.
+func writeTestCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile
+}
@@ -2,8 +2,6 @@
 // under the Apache License Version 2.0.
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2016-present Datadog, Inc.
-//go:build windows
-// +build windows
 
 package packets
 
@@ -21,12 +19,12 @@ type PacketManager struct {
 }
 
 // NewPacketManagerFromConfig creates a PacketManager from the relevant config settings.
-func NewPacketManagerFromConfig(packetOut chan Packets, sharedPacketPoolManager *PoolManager) *PacketManager {
+func NewPacketManagerFromConfig(packetOut chan Packets, sharedPacketPoolManager *PoolManager, packetSourceType SourceType) *PacketManager {
 	bufferSize := config.Datadog.GetInt("dogstatsd_buffer_size")
 	packetsBufferSize := config.Datadog.GetInt("dogstatsd_packet_buffer_size")
 	flushTimeout := config.Datadog.GetDuration("dogstatsd_packet_buffer_flush_timeout")
 
-	return NewPacketManager(bufferSize, packetsBufferSize, flushTimeout, packetOut, sharedPacketPoolManager)
+	return NewPacketManager(bufferSize, packetsBufferSize, flushTimeout, packetOut, sharedPacketPoolManager, packetSourceType)
 }
 
 // NewPacketManager instantiates a PacketManager
@@ -35,14 +33,15 @@ func NewPacketManager(
 	packetsBufferSize int,
 	flushTimeout time.Duration,
 	packetOut chan Packets,
-	sharedPacketPoolManager *PoolManager) *PacketManager {
+	sharedPacketPoolManager *PoolManager,
+	packetSourceType SourceType) *PacketManager {
 
 	packetsBuffer := NewBuffer(uint(packetsBufferSize), flushTimeout, packetOut)
 
 	return &PacketManager{
 		bufferSize:      bufferSize,
 		PacketsBuffer:   packetsBuffer,
-		PacketAssembler: NewAssembler(flushTimeout, packetsBuffer, sharedPacketPoolManager, NamedPipe),
+		PacketAssembler: NewAssembler(flushTimeout, packetsBuffer, sharedPacketPoolManager, packetSourceType),
 	}
 }
 
@@ -0,0 +1,356 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package remotewrite
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/internal/mapper"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+const (
+	nameLabel   = "__name__"
+	bucketLabel = "le"
+
+	// stateExpiry is how long the last value of a counter or of a histogram bucket is kept
+	// without receiving new samples
+	stateExpiry = 10 * time.Minute
+	// metadataExpiry is how long the type of a metric family is kept without receiving its metadata
+	// or its series, a few flush intervals and longer than the 1 minute interval at which Prometheus
+	// sends the metadata by default
+	metadataExpiry = 12 * flushInterval
+)
+
+// seriesKind is how the samples of a series are converted.
+type seriesKind int
+
+const (
+	kindGauge seriesKind = iota
+	kindCounter
+	kindBucket
+	kindSkip
+)
+
+// typeSuffixes are the suffixes added to the name of a metric family by the type of the metric.
+var typeSuffixes = []string{"_total", "_bucket", "_sum", "_count", "_created"}
+
+// counterState is the last sample of a counter or of a histogram bucket.
+type counterState struct {
+	value     float64
+	timestamp int64
+	lastSeen  time.Time
+}
+
+// familyMetadata is the type of a metric family, as last declared in the metadata of the requests.
+type familyMetadata struct {
+	mtype    metricType
+	lastSeen time.Time
+}
+
+// pendingHistogram holds the observations of the buckets of a histogram since the last flush.
+type pendingHistogram struct {
+	name   string
+	tags   []string
+	origin string
+	// buckets are the counts of observations by bucket upper bound, cumulative like the Prometheus buckets
+	buckets map[float64]float64
+}
+
+// converter converts the series of the remote-write requests into metric samples. The gauges are sent as
+// is, the counters as counts of their increase, and the histograms as distributions. The types of the
+// metrics are read from the metadata sent by Prometheus, falling back to the naming conventions.
+type converter struct {
+	mapper    *mapper.MetricMapper
+	namespace string
+	hostname  string
+
+	mu         sync.Mutex
+	metadata   map[string]*familyMetadata
+	lastValues map[string]*counterState
+	histograms map[string]*pendingHistogram
+}
+
+func newConverter(metricMapper *mapper.MetricMapper, namespace string, hostname string) *converter {
+	return &converter{
+		mapper:     metricMapper,
+		namespace:  namespace,
+		hostname:   hostname,
+		metadata:   make(map[string]*familyMetadata),
+		lastValues: make(map[string]*counterState),
+		histograms: make(map[string]*pendingHistogram),
+	}
+}
+
+// convert sends the gauges and the counters of a request to emit, the histograms are
+// sent at the next flush. origin is the tagger entity of the client, if known.
+func (c *converter) convert(req writeRequest, origin string, now time.Time, emit func(metrics.MetricSample)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, md := range req.metadata {
+		if md.family != "" {
+			c.metadata[md.family] = &familyMetadata{mtype: md.mtype, lastSeen: now}
+		}
+	}
+
+	for _, ts := range req.timeseries {
+		if len(ts.samples) == 0 {
+			continue
+		}
+		name, bucket, hasBucket := seriesName(ts.labels)
+		if name == "" {
+			continue
+		}
+		latest := ts.samples[0]
+		for _, s := range ts.samples[1:] {
+			if s.timestamp >= latest.timestamp {
+				latest = s
+			}
+		}
+		if math.IsNaN(latest.value) {
+			// stale marker or missing observation
+			continue
+		}
+
+		switch c.kindOf(name, hasBucket, now) {
+		case kindGauge:
+			emit(c.sample(name, ts.labels, false, origin, metrics.GaugeType, latest.value, now))
+		case kindCounter:
+			if delta, ok := c.delta(seriesKey(ts.labels), latest, now); ok {
+				emit(c.sample(name, ts.labels, false, origin, metrics.CountType, delta, now))
+			}
+		case kindBucket:
+			upperBound, err := strconv.ParseFloat(bucket, 64)
+			if err != nil {
+				continue
+			}
+			if delta, ok := c.delta(seriesKey(ts.labels), latest, now); ok {
+				c.addBucket(strings.TrimSuffix(name, "_bucket"), ts.labels, origin, upperBound, delta)
+			}
+		}
+	}
+}
+
+// kindOf returns how to convert the samples of a series. The counters are identified by the `_total`
+// suffix and the histogram buckets by the `_bucket` suffix and the `le` label when there is no metadata.
+// The metadata of the family is marked as seen at now.
+func (c *converter) kindOf(name string, hasBucket bool, now time.Time) seriesKind {
+	suffix := ""
+	for _, s := range typeSuffixes {
+		if strings.HasSuffix(name, s) {
+			suffix = s
+			break
+		}
+	}
+	md, found := c.metadata[name]
+	if !found && suffix != "" {
+		md, found = c.metadata[strings.TrimSuffix(name, suffix)]
+	}
+	if !found {
+		switch {
+		case suffix == "_total":
+			return kindCounter
+		case suffix == "_bucket" && hasBucket:
+			return kindBucket
+		}
+		return kindGauge
+	}
+	md.lastSeen = now
+
+	mtype := md.mtype
+	switch mtype {
+	case metricTypeCounter:
+		if suffix == "_created" {
+			return kindSkip
+		}
+		return kindCounter
+	case metricTypeHistogram, metricTypeSummary:
+		switch suffix {
+		case "_bucket":
+			if hasBucket && mtype == metricTypeHistogram {
+				return kindBucket
+			}
+		case "_sum", "_count":
+			return kindCounter
+		case "_created":
+			return kindSkip
+		}
+	}
+	return kindGauge
+}
+
+// delta returns the increase of a counter since its previous sample, a decrease being a reset of
+// the counter. The first sample of a counter and the samples older than the last one are ignored.
+func (c *converter) delta(key string, s sample, now time.Time) (float64, bool) {
+	last, found := c.lastValues[key]
+	if !found {
+		c.lastValues[key] = &counterState{value: s.value, timestamp: s.timestamp, lastSeen: now}
+		return 0, false
+	}
+	if s.timestamp <= last.timestamp {
+		return 0, false
+	}
+	delta := s.value - last.value
+	if delta < 0 {
+		delta = s.value
+	}
+	last.value = s.value
+	last.timestamp = s.timestamp
+	last.lastSeen = now
+	return delta, true
+}
+
+func (c *converter) addBucket(name string, labels []label, origin string, upperBound float64, delta float64) {
+	key := histogramKey(labels)
+	h, found := c.histograms[key]
+	if !found {
+		mapped := c.sample(name, labels, true, origin, metrics.DistributionType, 0, time.Time{})
+		h = &pendingHistogram{
+			name:    mapped.Name,
+			tags:    mapped.Tags,
+			origin:  origin,
+			buckets: make(map[float64]float64),
+		}
+		c.histograms[key] = h
+	}
+	h.buckets[upperBound] += delta
+}
+
+// flush sends the observations of the histograms since the last flush to emit, as distribution samples,
+// and forgets the counters, buckets and metric family types not seen for a while.
+func (c *converter) flush(now time.Time, emit func(metrics.MetricSample)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, h := range c.histograms {
+		bounds := make([]float64, 0, len(h.buckets))
+		for bound := range h.buckets {
+			bounds = append(bounds, bound)
+		}
+		sort.Float64s(bounds)
+
+		previousCount, lowerBound := 0.0, 0.0
+		for i, upperBound := range bounds {
+			if i == 0 && upperBound <= 0 {
+				lowerBound = upperBound
+			}
+			count := math.Round(h.buckets[upperBound] - previousCount)
+			previousCount = h.buckets[upperBound]
+			if count >= 1 {
+				// the observations are counted at the middle of their bucket, and at the lower bound
+				// of the last one which is not bounded
+				value := lowerBound + (upperBound-lowerBound)/2
+				if math.IsInf(upperBound, 1) {
+					value = lowerBound
+				}
+				emit(metrics.MetricSample{
+					Name:             h.name,
+					Value:            value,
+					Mtype:            metrics.DistributionType,
+					Tags:             h.tags,
+					Host:             c.hostname,
+					SampleRate:       1 / count,
+					Timestamp:        float64(now.Unix()),
+					OriginFromClient: h.origin,
+				})
+			}
+			lowerBound = upperBound
+		}
+	}
+	c.histograms = make(map[string]*pendingHistogram)
+
+	for key, state := range c.lastValues {
+		if now.Sub(state.lastSeen) > stateExpiry {
+			delete(c.lastValues, key)
+		}
+	}
+	for family, md := range c.metadata {
+		if now.Sub(md.lastSeen) > metadataExpiry {
+			delete(c.metadata, family)
+		}
+	}
+}
+
+// sample returns the metric sample of a series, with the labels as tags and the name and the tags mapped.
+func (c *converter) sample(name string, labels []label, isBucket bool, origin string, mtype metrics.MetricType, value float64, now time.Time) metrics.MetricSample {
+	tags := make([]string, 0, len(labels))
+	for _, l := range labels {
+		if l.name == nameLabel || l.value == "" || (isBucket && l.name == bucketLabel) {
+			continue
+		}
+		tags = append(tags, l.name+":"+l.value)
+	}
+	if c.mapper != nil {
+		tags = c.mapper.MapTags(name, tags)
+		if mapResult := c.mapper.Map(name); mapResult != nil {
+			name = mapResult.Name
+			tags = append(tags, mapResult.Tags...)
+		}
+	}
+	if c.namespace != "" {
+		name = c.namespace + "." + name
+	}
+	return metrics.MetricSample{
+		Name:             name,
+		Value:            value,
+		Mtype:            mtype,
+		Tags:             tags,
+		Host:             c.hostname,
+		SampleRate:       1,
+		Timestamp:        float64(now.Unix()),
+		OriginFromClient: origin,
+	}
+}
+
+// seriesName returns the metric name of a series and the value of its `le` label.
+func seriesName(labels []label) (name string, bucket string, hasBucket bool) {
+	for _, l := range labels {
+		switch l.name {
+		case nameLabel:
+			name = l.value
+		case bucketLabel:
+			bucket, hasBucket = l.value, true
+		}
+	}
+	return name, bucket, hasBucket
+}
+
+// seriesKey returns the identifier of a series, the labels of the series sent by Prometheus being sorted.
+func seriesKey(labels []label) string {
+	var b strings.Builder
+	for _, l := range labels {
+		b.WriteString(l.name)
+		b.WriteByte('=')
+		b.WriteString(l.value)
+		b.WriteByte(0xff)
+	}
+	return b.String()
+}
+
+// histogramKey returns the identifier of the histogram of a bucket series.
+func histogramKey(labels []label) string {
+	var b strings.Builder
+	for _, l := range labels {
+		if l.name == bucketLabel {
+			continue
+		}
+		value := l.value
+		if l.name == nameLabel {
+			value = strings.TrimSuffix(value, "_bucket")
+		}
+		b.WriteString(l.name)
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte(0xff)
+	}
+	return b.String()
+}
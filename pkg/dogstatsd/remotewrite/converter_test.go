@@ -0,0 +1,207 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package remotewrite
+
+import (
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/internal/mapper"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func series(name string, value float64, timestamp int64, labels ...string) timeSeries {
+	ts := timeSeries{
+		labels:  []label{{name: nameLabel, value: name}},
+		samples: []sample{{value: value, timestamp: timestamp}},
+	}
+	for i := 0; i+1 < len(labels); i += 2 {
+		ts.labels = append(ts.labels, label{name: labels[i], value: labels[i+1]})
+	}
+	return ts
+}
+
+func convert(c *converter, req writeRequest) []metrics.MetricSample {
+	var samples []metrics.MetricSample
+	c.convert(req, "", time.Now(), func(s metrics.MetricSample) { samples = append(samples, s) })
+	return samples
+}
+
+func TestConvertGauge(t *testing.T) {
+	c := newConverter(nil, "", "myhost")
+	ts := series("temperature", 21.5, 1000, "room", "kitchen", "empty", "")
+	ts.samples = append(ts.samples, sample{value: 22, timestamp: 2000}, sample{value: 20, timestamp: 1500})
+
+	samples := convert(c, writeRequest{timeseries: []timeSeries{ts}})
+	require.Len(t, samples, 1)
+	assert.Equal(t, "temperature", samples[0].Name)
+	assert.Equal(t, metrics.GaugeType, samples[0].Mtype)
+	assert.Equal(t, 22.0, samples[0].Value)
+	assert.Equal(t, []string{"room:kitchen"}, samples[0].Tags)
+	assert.Equal(t, "myhost", samples[0].Host)
+
+	// stale markers are ignored
+	assert.Empty(t, convert(c, writeRequest{timeseries: []timeSeries{series("temperature", math.NaN(), 3000)}}))
+}
+
+func TestConvertCounter(t *testing.T) {
+	c := newConverter(nil, "", "")
+
+	// the first sample is the reference
+	assert.Empty(t, convert(c, writeRequest{timeseries: []timeSeries{series("requests_total", 10, 1000)}}))
+
+	samples := convert(c, writeRequest{timeseries: []timeSeries{series("requests_total", 15, 2000)}})
+	require.Len(t, samples, 1)
+	assert.Equal(t, metrics.CountType, samples[0].Mtype)
+	assert.Equal(t, 5.0, samples[0].Value)
+
+	// older samples are ignored
+	assert.Empty(t, convert(c, writeRequest{timeseries: []timeSeries{series("requests_total", 12, 1500)}}))
+
+	// reset
+	samples = convert(c, writeRequest{timeseries: []timeSeries{series("requests_total", 3, 3000)}})
+	require.Len(t, samples, 1)
+	assert.Equal(t, 3.0, samples[0].Value)
+}
+
+func TestConvertTypesFromMetadata(t *testing.T) {
+	c := newConverter(nil, "", "")
+	req := writeRequest{
+		metadata: []metricMetadata{
+			{mtype: metricTypeCounter, family: "jobs"},
+			{mtype: metricTypeGauge, family: "queue_total"},
+			{mtype: metricTypeSummary, family: "latency"},
+		},
+		timeseries: []timeSeries{
+			series("jobs", 1, 1000),
+			series("queue_total", 1, 1000),
+			series("latency", 1, 1000, "quantile", "0.99"),
+			series("latency_count", 1, 1000),
+			series("latency_created", 1, 1000),
+		},
+	}
+	samples := convert(c, req)
+	require.Len(t, samples, 2)
+	assert.Equal(t, "queue_total", samples[0].Name)
+	assert.Equal(t, metrics.GaugeType, samples[0].Mtype)
+	assert.Equal(t, "latency", samples[1].Name)
+	assert.Equal(t, metrics.GaugeType, samples[1].Mtype)
+
+	for i := range req.timeseries {
+		req.timeseries[i].samples[0] = sample{value: 3, timestamp: 2000}
+	}
+	var types []string
+	for _, s := range convert(c, req) {
+		types = append(types, s.Name+":"+s.Mtype.String())
+	}
+	assert.Equal(t, []string{"jobs:Count", "queue_total:Gauge", "latency:Gauge", "latency_count:Count"}, types)
+}
+
+func TestConvertHistogram(t *testing.T) {
+	c := newConverter(nil, "", "")
+	buckets := func(timestamp int64, counts ...float64) writeRequest {
+		var req writeRequest
+		for i, le := range []string{"0.1", "1", "+Inf"} {
+			req.timeseries = append(req.timeseries, series("duration_seconds_bucket", counts[i], timestamp, "le", le, "path", "/"))
+		}
+		return req
+	}
+	var samples []metrics.MetricSample
+	emit := func(s metrics.MetricSample) { samples = append(samples, s) }
+
+	assert.Empty(t, convert(c, buckets(1000, 1, 2, 2)))
+	assert.Empty(t, convert(c, buckets(2000, 3, 7, 8)))
+	c.flush(time.Now(), emit)
+
+	require.Len(t, samples, 3)
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Value < samples[j].Value })
+	for _, s := range samples {
+		assert.Equal(t, "duration_seconds", s.Name)
+		assert.Equal(t, metrics.DistributionType, s.Mtype)
+		assert.Equal(t, []string{"path:/"}, s.Tags)
+	}
+	// 2 observations in [0, 0.1]
+	assert.Equal(t, 0.05, samples[0].Value)
+	assert.Equal(t, 0.5, samples[0].SampleRate)
+	// 3 observations in [0.1, 1]
+	assert.Equal(t, 0.55, samples[1].Value)
+	assert.Equal(t, 1.0/3, samples[1].SampleRate)
+	// 1 observation above 1
+	assert.Equal(t, 1.0, samples[2].Value)
+	assert.Equal(t, 1.0, samples[2].SampleRate)
+
+	// the observations are sent once
+	samples = nil
+	c.flush(time.Now(), emit)
+	assert.Empty(t, samples)
+}
+
+func TestConvertStateExpiry(t *testing.T) {
+	c := newConverter(nil, "", "")
+	convert(c, writeRequest{timeseries: []timeSeries{series("requests_total", 10, 1000)}})
+	require.Len(t, c.lastValues, 1)
+
+	c.flush(time.Now(), func(metrics.MetricSample) {})
+	assert.Len(t, c.lastValues, 1)
+	c.flush(time.Now().Add(stateExpiry+time.Minute), func(metrics.MetricSample) {})
+	assert.Empty(t, c.lastValues)
+}
+
+func TestConvertMetadataExpiry(t *testing.T) {
+	c := newConverter(nil, "", "")
+	now := time.Now()
+	c.convert(writeRequest{metadata: []metricMetadata{
+		{mtype: metricTypeCounter, family: "jobs"},
+		{mtype: metricTypeGauge, family: "queue_total"},
+	}}, "", now, func(metrics.MetricSample) {})
+	require.Len(t, c.metadata, 2)
+
+	// the families whose series are received are kept
+	now = now.Add(metadataExpiry / 2)
+	c.convert(writeRequest{timeseries: []timeSeries{series("jobs", 1, 1000)}}, "", now, func(metrics.MetricSample) {})
+	c.flush(now, func(metrics.MetricSample) {})
+	assert.Len(t, c.metadata, 2)
+
+	now = now.Add(metadataExpiry/2 + time.Second)
+	c.flush(now, func(metrics.MetricSample) {})
+	assert.Len(t, c.metadata, 1)
+	assert.Contains(t, c.metadata, "jobs")
+
+	c.flush(now.Add(metadataExpiry), func(metrics.MetricSample) {})
+	assert.Empty(t, c.metadata)
+}
+
+func TestConvertMapping(t *testing.T) {
+	metricMapper, err := mapper.NewMetricMapper([]config.MappingProfile{{
+		Name:   "http",
+		Prefix: "http_",
+		Mappings: []config.MetricMapping{{
+			Match:     `http_(\w+)_requests`,
+			MatchType: "regex",
+			Name:      "http.requests",
+			Tags:      map[string]string{"method": "$1"},
+		}},
+		TagRules: []config.TagRule{{Drop: []string{"instance"}}},
+	}}, 100)
+	require.NoError(t, err)
+	c := newConverter(metricMapper, "prom", "")
+
+	samples := convert(c, writeRequest{timeseries: []timeSeries{
+		series("http_get_requests", 1, 1000, "instance", "localhost:8080", "job", "web"),
+		series("other", 1, 1000, "instance", "localhost:8080"),
+	}})
+	require.Len(t, samples, 2)
+	assert.Equal(t, "prom.http.requests", samples[0].Name)
+	assert.Equal(t, []string{"job:web", "method:get"}, samples[0].Tags)
+	assert.Equal(t, "prom.other", samples[1].Name)
+	assert.Equal(t, []string{"instance:localhost:8080"}, samples[1].Tags)
+}
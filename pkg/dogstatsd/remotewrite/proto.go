@@ -0,0 +1,172 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package remotewrite
+
+import (
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The messages of the Prometheus remote-write protocol, limited to the fields used by the receiver,
+// see https://github.com/prometheus/prometheus/blob/main/prompb/remote.proto and types.proto.
+
+// metricType is the type of a metric family, as declared in the metadata of the requests.
+type metricType int32
+
+const (
+	metricTypeUnknown        metricType = 0
+	metricTypeCounter        metricType = 1
+	metricTypeGauge          metricType = 2
+	metricTypeHistogram      metricType = 3
+	metricTypeGaugeHistogram metricType = 4
+	metricTypeSummary        metricType = 5
+	metricTypeInfo           metricType = 6
+	metricTypeStateset       metricType = 7
+)
+
+type writeRequest struct {
+	timeseries []timeSeries
+	metadata   []metricMetadata
+}
+
+type timeSeries struct {
+	labels  []label
+	samples []sample
+}
+
+type label struct {
+	name  string
+	value string
+}
+
+type sample struct {
+	value float64
+	// timestamp is in milliseconds
+	timestamp int64
+}
+
+type metricMetadata struct {
+	mtype  metricType
+	family string
+}
+
+// decodeWriteRequest decodes a serialized WriteRequest message, the native histograms
+// and the exemplars are ignored.
+func decodeWriteRequest(buf []byte) (writeRequest, error) {
+	var req writeRequest
+	err := forEachField(buf, func(num protowire.Number, typ protowire.Type, _ uint64, b []byte) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			ts, err := decodeTimeSeries(b)
+			if err != nil {
+				return err
+			}
+			req.timeseries = append(req.timeseries, ts)
+		case num == 3 && typ == protowire.BytesType:
+			md, err := decodeMetricMetadata(b)
+			if err != nil {
+				return err
+			}
+			req.metadata = append(req.metadata, md)
+		}
+		return nil
+	})
+	return req, err
+}
+
+func decodeTimeSeries(buf []byte) (timeSeries, error) {
+	var ts timeSeries
+	err := forEachField(buf, func(num protowire.Number, typ protowire.Type, _ uint64, b []byte) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			var l label
+			err := forEachField(b, func(num protowire.Number, typ protowire.Type, _ uint64, b []byte) error {
+				switch {
+				case num == 1 && typ == protowire.BytesType:
+					l.name = string(b)
+				case num == 2 && typ == protowire.BytesType:
+					l.value = string(b)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			ts.labels = append(ts.labels, l)
+		case num == 2 && typ == protowire.BytesType:
+			var s sample
+			err := forEachField(b, func(num protowire.Number, typ protowire.Type, v uint64, _ []byte) error {
+				switch {
+				case num == 1 && typ == protowire.Fixed64Type:
+					s.value = math.Float64frombits(v)
+				case num == 2 && typ == protowire.VarintType:
+					s.timestamp = int64(v)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			ts.samples = append(ts.samples, s)
+		}
+		return nil
+	})
+	return ts, err
+}
+
+func decodeMetricMetadata(buf []byte) (metricMetadata, error) {
+	var md metricMetadata
+	err := forEachField(buf, func(num protowire.Number, typ protowire.Type, v uint64, b []byte) error {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			md.mtype = metricType(v)
+		case num == 2 && typ == protowire.BytesType:
+			md.family = string(b)
+		}
+		return nil
+	})
+	return md, err
+}
+
+// forEachField calls fn with each field of a serialized message, the value of the varint and
+// fixed fields being in v and the one of the length-delimited fields in b.
+func forEachField(buf []byte, fn func(num protowire.Number, typ protowire.Type, v uint64, b []byte) error) error {
+	for len(buf) > 0 {
+		num, typ, n := protowire.ConsumeTag(buf)
+		if n < 0 {
+			return fmt.Errorf("invalid field tag: %v", protowire.ParseError(n))
+		}
+		buf = buf[n:]
+
+		var v uint64
+		var b []byte
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(buf)
+		case protowire.Fixed64Type:
+			v, n = protowire.ConsumeFixed64(buf)
+		case protowire.Fixed32Type:
+			var v32 uint32
+			v32, n = protowire.ConsumeFixed32(buf)
+			v = uint64(v32)
+		case protowire.BytesType:
+			b, n = protowire.ConsumeBytes(buf)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, buf)
+		}
+		if n < 0 {
+			return fmt.Errorf("invalid field %d: %v", num, protowire.ParseError(n))
+		}
+		buf = buf[n:]
+
+		if err := fn(num, typ, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
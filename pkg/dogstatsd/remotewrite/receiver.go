@@ -0,0 +1,247 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package remotewrite implements a receiver of the Prometheus remote-write protocol which sends
+// the samples to the aggregator, like the DogStatsD samples.
+package remotewrite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/internal/mapper"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// WritePath is the path of the remote-write endpoint
+	WritePath = "/api/v1/write"
+
+	// headerContainerID is the header the clients can set to the ID of their container for origin detection
+	headerContainerID = "Datadog-Container-ID"
+
+	// flushInterval is the interval at which the histograms are sent, the bucket size of the time samplers
+	flushInterval = 10 * time.Second
+)
+
+var (
+	tlmRequests = telemetry.NewCounter("prometheus_remote_write", "requests",
+		[]string{"status"}, "Count of Prometheus remote-write requests by response status")
+	tlmSamples = telemetry.NewCounter("prometheus_remote_write", "samples",
+		[]string{"type"}, "Count of metric samples converted from the Prometheus remote-write requests by type")
+)
+
+// Receiver receives the Prometheus remote-write requests and sends their samples to the
+// first time sampler of the demultiplexer.
+type Receiver struct {
+	demux          aggregator.Demultiplexer
+	converter      *converter
+	maxRequestSize int
+	listener       net.Listener
+	server         *http.Server
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewReceiver returns a receiver listening on the address set in the configuration, it is
+// started with Start.
+func NewReceiver(demux aggregator.Demultiplexer) (*Receiver, error) {
+	var addr string
+	port := config.Datadog.GetInt("prometheus_remote_write.port")
+	if config.Datadog.GetBool("prometheus_remote_write.non_local_traffic") {
+		// Listen to all network interfaces
+		addr = fmt.Sprintf(":%d", port)
+	} else {
+		addr = net.JoinHostPort(config.GetBindHost(), fmt.Sprintf("%d", port))
+	}
+
+	var metricMapper *mapper.MetricMapper
+	var profiles []config.MappingProfile
+	if err := config.Datadog.UnmarshalKey("prometheus_remote_write.mapper_profiles", &profiles); err != nil {
+		return nil, fmt.Errorf("could not parse prometheus_remote_write.mapper_profiles: %v", err)
+	}
+	if len(profiles) > 0 {
+		var err error
+		metricMapper, err = mapper.NewMetricMapper(profiles, config.Datadog.GetInt("dogstatsd_mapper_cache_size"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid prometheus_remote_write.mapper_profiles: %v", err)
+		}
+	}
+
+	hostname, err := util.GetHostname(context.TODO())
+	if err != nil {
+		log.Errorf("Prometheus remote-write: unable to determine default hostname: %s", err)
+	}
+
+	return newReceiver(
+		addr,
+		demux,
+		newConverter(metricMapper, config.Datadog.GetString("prometheus_remote_write.namespace"), hostname),
+		config.Datadog.GetInt("prometheus_remote_write.max_request_size"))
+}
+
+func newReceiver(addr string, demux aggregator.Demultiplexer, converter *converter, maxRequestSize int) (*Receiver, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %s", err)
+	}
+	r := &Receiver{
+		demux:          demux,
+		converter:      converter,
+		maxRequestSize: maxRequestSize,
+		listener:       listener,
+		stop:           make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(WritePath, r.handleWrite)
+	r.server = &http.Server{
+		Handler:     mux,
+		ReadTimeout: 30 * time.Second,
+	}
+	return r, nil
+}
+
+// Start starts serving the requests and sending the histograms.
+func (r *Receiver) Start() {
+	log.Infof("Prometheus remote-write: listening on %s%s", r.listener.Addr(), WritePath)
+	r.wg.Add(2)
+	go func() {
+		defer r.wg.Done()
+		if err := r.server.Serve(r.listener); err != nil && err != http.ErrServerClosed {
+			log.Errorf("Prometheus remote-write: error serving the requests: %v", err)
+		}
+	}()
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				r.flush(now)
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the receiver, the pending histograms are sent.
+func (r *Receiver) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.server.Shutdown(ctx); err != nil {
+		log.Warnf("Prometheus remote-write: error stopping the server: %v", err)
+	}
+	close(r.stop)
+	r.wg.Wait()
+	r.flush(time.Now())
+}
+
+// Addr returns the address the receiver listens to.
+func (r *Receiver) Addr() net.Addr {
+	return r.listener.Addr()
+}
+
+func (r *Receiver) handleWrite(w http.ResponseWriter, req *http.Request) {
+	status, err := r.write(req)
+	tlmRequests.Inc(fmt.Sprintf("%d", status))
+	if err != nil {
+		log.Debugf("Prometheus remote-write: invalid request from %s: %v", req.RemoteAddr, err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.WriteHeader(status)
+}
+
+// write handles a request and returns the status of the response.
+func (r *Receiver) write(req *http.Request) (int, error) {
+	if req.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", req.Method)
+	}
+	compressed, err := ioutil.ReadAll(io.LimitReader(req.Body, int64(r.maxRequestSize)+1))
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	if len(compressed) > r.maxRequestSize {
+		return http.StatusRequestEntityTooLarge, errors.New("request too large")
+	}
+	if size, err := snappy.DecodedLen(compressed); err != nil {
+		return http.StatusBadRequest, err
+	} else if size > r.maxRequestSize {
+		return http.StatusRequestEntityTooLarge, errors.New("request too large")
+	}
+	payload, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	writeRequest, err := decodeWriteRequest(payload)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	origin := ""
+	if containerID := req.Header.Get(headerContainerID); containerID != "" {
+		origin = containers.BuildTaggerEntityName(containerID)
+	}
+
+	b := r.newBatcher()
+	r.converter.convert(writeRequest, origin, time.Now(), b.add)
+	b.flush()
+	return http.StatusNoContent, nil
+}
+
+func (r *Receiver) flush(now time.Time) {
+	b := r.newBatcher()
+	r.converter.flush(now, b.add)
+	b.flush()
+}
+
+// batcher sends the samples to the first time sampler in batches from the demultiplexer pool.
+type batcher struct {
+	demux aggregator.Demultiplexer
+	batch metrics.MetricSampleBatch
+	count int
+}
+
+func (r *Receiver) newBatcher() *batcher {
+	return &batcher{demux: r.demux}
+}
+
+func (b *batcher) add(sample metrics.MetricSample) {
+	if b.batch == nil {
+		b.batch = b.demux.GetMetricSamplePool().GetBatch()
+	}
+	b.batch[b.count] = sample
+	b.count++
+	tlmSamples.Inc(sample.Mtype.String())
+	if b.count == len(b.batch) {
+		b.flush()
+	}
+}
+
+func (b *batcher) flush() {
+	if b.count > 0 {
+		b.demux.AddTimeSampleBatch(aggregator.TimeSamplerID(0), b.batch[:b.count])
+	}
+	b.batch = nil
+	b.count = 0
+}
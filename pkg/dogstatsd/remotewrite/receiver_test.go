@@ -0,0 +1,153 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package remotewrite
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// encodeWriteRequest serializes a WriteRequest message.
+func encodeWriteRequest(req writeRequest) []byte {
+	var buf []byte
+	for _, ts := range req.timeseries {
+		var tsBuf []byte
+		for _, l := range ts.labels {
+			var lBuf []byte
+			lBuf = protowire.AppendTag(lBuf, 1, protowire.BytesType)
+			lBuf = protowire.AppendString(lBuf, l.name)
+			lBuf = protowire.AppendTag(lBuf, 2, protowire.BytesType)
+			lBuf = protowire.AppendString(lBuf, l.value)
+			tsBuf = protowire.AppendTag(tsBuf, 1, protowire.BytesType)
+			tsBuf = protowire.AppendBytes(tsBuf, lBuf)
+		}
+		for _, s := range ts.samples {
+			var sBuf []byte
+			sBuf = protowire.AppendTag(sBuf, 1, protowire.Fixed64Type)
+			sBuf = protowire.AppendFixed64(sBuf, math.Float64bits(s.value))
+			sBuf = protowire.AppendTag(sBuf, 2, protowire.VarintType)
+			sBuf = protowire.AppendVarint(sBuf, uint64(s.timestamp))
+			tsBuf = protowire.AppendTag(tsBuf, 2, protowire.BytesType)
+			tsBuf = protowire.AppendBytes(tsBuf, sBuf)
+		}
+		buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, tsBuf)
+	}
+	for _, md := range req.metadata {
+		var mdBuf []byte
+		mdBuf = protowire.AppendTag(mdBuf, 1, protowire.VarintType)
+		mdBuf = protowire.AppendVarint(mdBuf, uint64(md.mtype))
+		mdBuf = protowire.AppendTag(mdBuf, 2, protowire.BytesType)
+		mdBuf = protowire.AppendString(mdBuf, md.family)
+		// help, ignored
+		mdBuf = protowire.AppendTag(mdBuf, 4, protowire.BytesType)
+		mdBuf = protowire.AppendString(mdBuf, "some help")
+		buf = protowire.AppendTag(buf, 3, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, mdBuf)
+	}
+	return buf
+}
+
+func TestDecodeWriteRequest(t *testing.T) {
+	req := writeRequest{
+		timeseries: []timeSeries{
+			series("foo", 1.5, 1000, "a", "b"),
+			series("bar_total", -2, 2000),
+		},
+		metadata: []metricMetadata{{mtype: metricTypeCounter, family: "bar"}},
+	}
+	decoded, err := decodeWriteRequest(encodeWriteRequest(req))
+	require.NoError(t, err)
+	assert.Equal(t, req, decoded)
+
+	_, err = decodeWriteRequest([]byte{0x0a, 0x10, 0x01})
+	assert.Error(t, err)
+}
+
+func newTestReceiver(t *testing.T) (*Receiver, *aggregator.TestAgentDemultiplexer) {
+	demux := aggregator.InitTestAgentDemultiplexer()
+	r, err := newReceiver("127.0.0.1:0", demux, newConverter(nil, "", "myhost"), 1024)
+	require.NoError(t, err)
+	r.Start()
+	return r, demux
+}
+
+func post(t *testing.T, r *Receiver, body []byte, containerID string) *http.Response {
+	req, err := http.NewRequest(http.MethodPost, "http://"+r.Addr().String()+WritePath, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	if containerID != "" {
+		req.Header.Set(headerContainerID, containerID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
+
+func TestReceiverWrite(t *testing.T) {
+	r, demux := newTestReceiver(t)
+	defer demux.Stop(false)
+	defer r.Stop()
+
+	body := snappy.Encode(nil, encodeWriteRequest(writeRequest{timeseries: []timeSeries{series("temperature", 21, 1000, "room", "kitchen")}}))
+	resp := post(t, r, body, "abcdef")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	samples := demux.WaitForSamples(5 * time.Second)
+	require.Len(t, samples, 1)
+	assert.Equal(t, "temperature", samples[0].Name)
+	assert.Equal(t, metrics.GaugeType, samples[0].Mtype)
+	assert.Equal(t, []string{"room:kitchen"}, samples[0].Tags)
+	assert.Equal(t, "container_id://abcdef", samples[0].OriginFromClient)
+}
+
+func TestReceiverInvalidRequests(t *testing.T) {
+	r, demux := newTestReceiver(t)
+	defer demux.Stop(false)
+	defer r.Stop()
+
+	// not snappy encoded
+	assert.Equal(t, http.StatusBadRequest, post(t, r, []byte("not snappy"), "").StatusCode)
+	// not protobuf
+	assert.Equal(t, http.StatusBadRequest, post(t, r, snappy.Encode(nil, []byte{0x0a, 0x10, 0x01}), "").StatusCode)
+	// too large
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post(t, r, snappy.Encode(nil, make([]byte, 2048)), "").StatusCode)
+
+	resp, err := http.Get("http://" + r.Addr().String() + WritePath)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestReceiverStopFlushesHistograms(t *testing.T) {
+	r, demux := newTestReceiver(t)
+	defer demux.Stop(false)
+
+	for i, count := range []float64{1, 4} {
+		req := writeRequest{timeseries: []timeSeries{series("duration_bucket", count, int64(i), "le", "+Inf")}}
+		assert.Equal(t, http.StatusNoContent, post(t, r, snappy.Encode(nil, encodeWriteRequest(req)), "").StatusCode)
+	}
+	r.Stop()
+
+	samples := demux.WaitForSamples(5 * time.Second)
+	require.Len(t, samples, 1)
+	assert.Equal(t, "duration", samples[0].Name)
+	assert.Equal(t, metrics.DistributionType, samples[0].Mtype)
+	assert.Equal(t, 1.0/3, samples[0].SampleRate)
+}
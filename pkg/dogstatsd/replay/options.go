@@ -0,0 +1,172 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo"
+)
+
+const (
+	// DecodeText is the format of the decoded packets for humans
+	DecodeText = "text"
+	// DecodeJSON is the format of the decoded packets as JSON lines
+	DecodeJSON = "json"
+)
+
+// ReadOptions selects the packets of a capture to read, and their pace.
+type ReadOptions struct {
+	// Speed multiplies the pace of the packets, 2 replays twice as fast as recorded, 0 replays as fast as possible
+	Speed float64
+	// Start is the offset, from the first packet of the capture, of the first packet read
+	Start time.Duration
+	// End is the offset, from the first packet of the capture, after which the packets are not read, 0 for no end
+	End time.Duration
+	// Filter selects the packets and their messages, nil to select them all
+	Filter *PacketFilter
+}
+
+// window returns whether a packet at an offset from the first packet is selected, and
+// whether it is after the end of the window.
+func (o ReadOptions) window(offset time.Duration) (selected bool, after bool) {
+	if o.End > 0 && offset > o.End {
+		return false, true
+	}
+	return offset >= o.Start, false
+}
+
+// PacketFilter selects the packets of some processes and their messages of some metrics.
+type PacketFilter struct {
+	// MetricPrefixes are the prefixes of the names of the metrics kept, empty to keep all the messages
+	MetricPrefixes []string
+	// Pids are the PIDs of the processes whose packets are kept, empty to keep the packets of all the processes
+	Pids []int32
+}
+
+// Filter returns the packet with its messages selected by the filter, or nil if none is selected.
+func (f *PacketFilter) Filter(msg *pb.UnixDogstatsdMsg) *pb.UnixDogstatsdMsg {
+	if f == nil {
+		return msg
+	}
+	if len(f.Pids) > 0 {
+		found := false
+		for _, pid := range f.Pids {
+			if msg.Pid == pid {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+	if len(f.MetricPrefixes) == 0 {
+		return msg
+	}
+
+	var payload []byte
+	for _, message := range messages(msg) {
+		for _, prefix := range f.MetricPrefixes {
+			if strings.HasPrefix(message, prefix) {
+				if len(payload) > 0 {
+					payload = append(payload, '\n')
+				}
+				payload = append(payload, message...)
+				break
+			}
+		}
+	}
+	if len(payload) == 0 {
+		return nil
+	}
+	return &pb.UnixDogstatsdMsg{
+		Timestamp:     msg.Timestamp,
+		PayloadSize:   int32(len(payload)),
+		Payload:       payload,
+		Pid:           msg.Pid,
+		AncillarySize: msg.AncillarySize,
+		Ancillary:     msg.Ancillary,
+	}
+}
+
+// messages returns the DogStatsD messages of a packet.
+func messages(msg *pb.UnixDogstatsdMsg) []string {
+	payload := msg.Payload
+	if int(msg.PayloadSize) <= len(payload) {
+		payload = payload[:msg.PayloadSize]
+	}
+	var messages []string
+	for _, message := range bytes.Split(payload, []byte{'\n'}) {
+		if len(message) > 0 {
+			messages = append(messages, string(message))
+		}
+	}
+	return messages
+}
+
+// decodedPacket is the JSON representation of a decoded packet.
+type decodedPacket struct {
+	Timestamp int64    `json:"timestamp"`
+	Offset    string   `json:"offset"`
+	Pid       int32    `json:"pid"`
+	Messages  []string `json:"messages"`
+}
+
+// Decode writes the packets of the capture selected by opts to w, in the DecodeText or DecodeJSON format,
+// without waiting between them. opts.Speed is ignored.
+func (tc *TrafficCaptureReader) Decode(w io.Writer, format string, opts ReadOptions) error {
+	if format != DecodeText && format != DecodeJSON {
+		return fmt.Errorf("unknown decode format %q, must be %q or %q", format, DecodeText, DecodeJSON)
+	}
+	tc.Seek(0)
+	tsResolution := tc.timestampResolution()
+
+	encoder := json.NewEncoder(w)
+	var first int64
+	for i := 0; ; i++ {
+		msg, err := tc.ReadNext()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if i == 0 {
+			first = msg.Timestamp
+		}
+		offset := tsResolution * time.Duration(msg.Timestamp-first)
+		selected, after := opts.window(offset)
+		if after {
+			return nil
+		}
+		if !selected {
+			continue
+		}
+		if msg = opts.Filter.Filter(msg); msg == nil {
+			continue
+		}
+
+		packet := decodedPacket{
+			Timestamp: msg.Timestamp,
+			Offset:    offset.String(),
+			Pid:       msg.Pid,
+			Messages:  messages(msg),
+		}
+		if format == DecodeJSON {
+			err = encoder.Encode(packet)
+		} else {
+			_, err = fmt.Fprintf(w, "[+%s] pid %d, %d messages\n  %s\n", packet.Offset, packet.Pid, len(packet.Messages), strings.Join(packet.Messages, "\n  "))
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
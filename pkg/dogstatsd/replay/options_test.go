@@ -0,0 +1,118 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo"
+)
+
+func readAll(t *testing.T, opts ReadOptions) []*pb.UnixDogstatsdMsg {
+	tc, err := NewTrafficCaptureReader("resources/test/datadog-capture.dog", 32, false)
+	require.NoError(t, err)
+	defer tc.Close()
+
+	ready := make(chan struct{})
+	go tc.ReadWithOptions(ready, opts)
+	<-ready
+
+	var msgs []*pb.UnixDogstatsdMsg
+	for {
+		select {
+		case msg := <-tc.Traffic:
+			msgs = append(msgs, msg)
+		case <-tc.Done:
+			// drain the packets sent before done
+			for len(tc.Traffic) > 0 {
+				msgs = append(msgs, <-tc.Traffic)
+			}
+			return msgs
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "timeout reading the capture")
+		}
+	}
+}
+
+func TestReadWithOptionsWindow(t *testing.T) {
+	// the capture spans 13 seconds, replayed as fast as possible
+	start := time.Now()
+	assert.Len(t, readAll(t, ReadOptions{}), 21)
+	assert.Less(t, int64(time.Since(start)), int64(5*time.Second))
+
+	assert.Len(t, readAll(t, ReadOptions{Start: 10 * time.Second}), 5)
+	assert.Len(t, readAll(t, ReadOptions{Start: time.Second, End: 2 * time.Second}), 3)
+	assert.Len(t, readAll(t, ReadOptions{End: 100 * time.Second}), 21)
+}
+
+func TestReadWithOptionsSpeed(t *testing.T) {
+	// 2 seconds of capture replayed 4 times faster
+	start := time.Now()
+	msgs := readAll(t, ReadOptions{Speed: 4, End: 2 * time.Second})
+	assert.Len(t, msgs, 5)
+	elapsed := time.Since(start)
+	assert.GreaterOrEqual(t, int64(elapsed), int64(400*time.Millisecond))
+	assert.Less(t, int64(elapsed), int64(2*time.Second))
+}
+
+func TestReadWithOptionsFilter(t *testing.T) {
+	msgs := readAll(t, ReadOptions{Filter: &PacketFilter{Pids: []int32{2809, 2876, 1}}})
+	require.Len(t, msgs, 2)
+	assert.Equal(t, int32(2809), msgs[0].Pid)
+	assert.Equal(t, int32(2876), msgs[1].Pid)
+
+	assert.Len(t, readAll(t, ReadOptions{Filter: &PacketFilter{MetricPrefixes: []string{"jaime.uds."}}}), 21)
+	assert.Empty(t, readAll(t, ReadOptions{Filter: &PacketFilter{MetricPrefixes: []string{"other."}}}))
+}
+
+func TestPacketFilter(t *testing.T) {
+	payload := []byte("foo.a:1|c\nbar.b:2|g\n_e{1,1}:a|b\nfoo.c:3|c\nunused")
+	msg := &pb.UnixDogstatsdMsg{Timestamp: 12, Pid: 42, Payload: payload, PayloadSize: int32(len(payload) - len("\nunused"))}
+
+	var nilFilter *PacketFilter
+	assert.Equal(t, msg, nilFilter.Filter(msg))
+	assert.Equal(t, msg, (&PacketFilter{Pids: []int32{42}}).Filter(msg))
+	assert.Nil(t, (&PacketFilter{Pids: []int32{43}}).Filter(msg))
+	assert.Nil(t, (&PacketFilter{MetricPrefixes: []string{"baz."}}).Filter(msg))
+
+	filtered := (&PacketFilter{MetricPrefixes: []string{"foo.", "_e"}, Pids: []int32{42}}).Filter(msg)
+	require.NotNil(t, filtered)
+	assert.Equal(t, "foo.a:1|c\n_e{1,1}:a|b\nfoo.c:3|c", string(filtered.Payload))
+	assert.Equal(t, int32(len(filtered.Payload)), filtered.PayloadSize)
+	assert.Equal(t, int64(12), filtered.Timestamp)
+	assert.Equal(t, int32(42), filtered.Pid)
+	// the original packet is not modified
+	assert.Equal(t, payload, msg.Payload)
+}
+
+func TestDecode(t *testing.T) {
+	tc, err := NewTrafficCaptureReader("resources/test/datadog-capture.dog", 1, false)
+	require.NoError(t, err)
+	defer tc.Close()
+
+	var buf bytes.Buffer
+	require.NoError(t, tc.Decode(&buf, DecodeJSON, ReadOptions{Start: 13 * time.Second}))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	var packet decodedPacket
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &packet))
+	assert.Equal(t, "13s", packet.Offset)
+	assert.Equal(t, int32(2873), packet.Pid)
+	assert.Equal(t, []string{"jaime.uds.test:8|g|#shell:test"}, packet.Messages)
+
+	buf.Reset()
+	require.NoError(t, tc.Decode(&buf, DecodeText, ReadOptions{Filter: &PacketFilter{Pids: []int32{2809}}}))
+	assert.Equal(t, "[+0s] pid 2809, 1 messages\n  jaime.uds.test:8|g|#shell:test\n", buf.String())
+
+	assert.Error(t, tc.Decode(&buf, "yaml", ReadOptions{}))
+}
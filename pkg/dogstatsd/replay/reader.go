@@ -81,6 +81,12 @@ func NewTrafficCaptureReader(path string, depth int, mmap bool) (*TrafficCapture
 
 // Read reads the contents of the traffic capture and writes each packet to a channel
 func (tc *TrafficCaptureReader) Read(ready chan struct{}) {
+	tc.ReadWithOptions(ready, ReadOptions{Speed: 1})
+}
+
+// ReadWithOptions reads the packets of the traffic capture selected by opts and writes each of them
+// to a channel, at the recorded pace multiplied by opts.Speed.
+func (tc *TrafficCaptureReader) ReadWithOptions(ready chan struct{}, opts ReadOptions) {
 	tc.Lock()
 	tc.Done = make(chan struct{})
 	tc.fuse = make(chan struct{})
@@ -90,15 +96,10 @@ func (tc *TrafficCaptureReader) Read(ready chan struct{}) {
 
 	// skip header
 	tc.offset = uint32(len(datadogHeader))
-
-	var tsResolution time.Duration
-	if tc.Version < minNanoVersion {
-		tsResolution = time.Second
-	} else {
-		tsResolution = time.Nanosecond
-	}
 	tc.Unlock()
 
+	tsResolution := tc.timestampResolution()
+	first := int64(0)
 	last := int64(0)
 
 	// we are all ready to go - let the caller know
@@ -107,7 +108,7 @@ func (tc *TrafficCaptureReader) Read(ready chan struct{}) {
 	// The state must be read out of band, it makes zero sense in the context
 	// of the replaying process, it must be pushed to the agent. We just read
 	// and submit the packets here.
-	for {
+	for i := 0; ; i++ {
 		msg, err := tc.ReadNext()
 		if err != nil && err == io.EOF {
 			log.Debugf("Done reading capture file...")
@@ -117,9 +118,24 @@ func (tc *TrafficCaptureReader) Read(ready chan struct{}) {
 			break
 		}
 
-		if last != 0 {
+		if i == 0 {
+			first = msg.Timestamp
+		}
+		selected, after := opts.window(tsResolution * time.Duration(msg.Timestamp-first))
+		if after {
+			log.Debugf("Done reading capture file window...")
+			break
+		}
+		if !selected {
+			continue
+		}
+		if msg = opts.Filter.Filter(msg); msg == nil {
+			continue
+		}
+
+		if last != 0 && opts.Speed > 0 {
 			if msg.Timestamp > last {
-				util.Wait(tsResolution * time.Duration(msg.Timestamp-last))
+				util.Wait(time.Duration(float64(tsResolution*time.Duration(msg.Timestamp-last)) / opts.Speed))
 			}
 		}
 
@@ -135,6 +151,17 @@ func (tc *TrafficCaptureReader) Read(ready chan struct{}) {
 	}
 }
 
+// timestampResolution returns the unit of the timestamps of the packets.
+func (tc *TrafficCaptureReader) timestampResolution() time.Duration {
+	tc.Lock()
+	defer tc.Unlock()
+
+	if tc.Version < minNanoVersion {
+		return time.Second
+	}
+	return time.Nanosecond
+}
+
 // Close cleans up any resources used by the TrafficCaptureReader, should not normally
 // be called directly.
 func (tc *TrafficCaptureReader) Close() error {
@@ -306,6 +306,45 @@ func TestUDPReceive(t *testing.T) {
 	}
 }
 
+func TestTCPReceive(t *testing.T) {
+	port, err := getAvailableUDPPort()
+	require.NoError(t, err)
+	config.Datadog.SetDefault("dogstatsd_port", port)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	tcpPort := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	config.Datadog.SetDefault("dogstatsd_tcp_port", tcpPort)
+	defer config.Datadog.SetDefault("dogstatsd_tcp_port", 0)
+
+	demux := aggregator.InitTestAgentDemultiplexerWithFlushInterval(10 * time.Millisecond)
+	defer demux.Stop(false)
+	s, err := NewServer(demux, false)
+	require.NoError(t, err, "cannot start DSD")
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", tcpPort))
+	require.NoError(t, err, "cannot connect to DSD TCP listener")
+	defer conn.Close()
+
+	// the messages are framed by newlines, the unterminated one is not processed yet
+	conn.Write([]byte("daemon:666|g|#sometag1:somevalue1\ndaemon2:1|c\ndaemon3:"))
+	samples := demux.WaitForSamples(time.Second * 2)
+	require.Equal(t, 2, len(samples))
+	assert.Equal(t, "daemon", samples[0].Name)
+	assert.EqualValues(t, 666.0, samples[0].Value)
+	assert.Equal(t, metrics.GaugeType, samples[0].Mtype)
+	assert.ElementsMatch(t, []string{"sometag1:somevalue1"}, samples[0].Tags)
+	assert.Equal(t, "daemon2", samples[1].Name)
+	demux.Reset()
+
+	conn.Write([]byte("2|c\n"))
+	samples = demux.WaitForSamples(time.Second * 2)
+	require.Equal(t, 1, len(samples))
+	assert.Equal(t, "daemon3", samples[0].Name)
+	assert.EqualValues(t, 2.0, samples[0].Value)
+}
+
 func TestUDPForward(t *testing.T) {
 	fport, err := getAvailableUDPPort()
 	require.NoError(t, err)
@@ -765,6 +804,34 @@ dogstatsd_mapper_profiles:
 			},
 			expectedCacheSize: 1000,
 		},
+		{
+			name: "Tag rules",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.job.duration.*"
+        name: "test.job.duration"
+        tags:
+          job_type: "$1"
+    tag_rules:
+      - drop: ["user_id"]
+        rewrite:
+          - tag: path
+            match: '/users/[0-9]+'
+            value: '/users/:id'
+`,
+			packets: []string{
+				"test.job.duration.my_job_type:666|g|#user_id:42,path:/users/42",
+				"test.other:666|g|#user_id:42",
+			},
+			expectedSamples: []MetricSample{
+				{Name: "test.job.duration", Tags: []string{"path:/users/:id", "job_type:my_job_type"}, Mtype: metrics.GaugeType, Value: 666.0},
+				{Name: "test.other", Tags: []string{}, Mtype: metrics.GaugeType, Value: 666.0},
+			},
+			expectedCacheSize: 1000,
+		},
 		{
 			name: "Cache size",
 			config: `
@@ -7,6 +7,7 @@ package logs
 
 import (
 	"context"
+	"path/filepath"
 	"time"
 
 	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
@@ -26,6 +27,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/util/containersorpods"
 	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
 	"github.com/DataDog/datadog-agent/pkg/logs/schedulers"
+	"github.com/DataDog/datadog-agent/pkg/logs/sender"
 	"github.com/DataDog/datadog-agent/pkg/logs/service"
 	"github.com/DataDog/datadog-agent/pkg/status/health"
 	"github.com/DataDog/datadog-agent/pkg/util"
@@ -52,7 +54,8 @@ type Agent struct {
 }
 
 // NewAgent returns a new Logs Agent
-func NewAgent(sources *config.LogSources, services *service.Services, processingRules []*config.ProcessingRule, endpoints *config.Endpoints) *Agent {
+// metricSender receives the metrics generated from logs, it can be nil.
+func NewAgent(sources *config.LogSources, services *service.Services, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, metricSender pipeline.MetricSender) *Agent {
 	health := health.RegisterLiveness("logs-agent")
 
 	// setup the auditor
@@ -64,7 +67,7 @@ func NewAgent(sources *config.LogSources, services *service.Services, processing
 	diagnosticMessageReceiver := diagnostic.NewBufferedMessageReceiver()
 
 	// setup the pipeline provider that provides pairs of processor and sender
-	pipelineProvider := pipeline.NewProvider(config.NumberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsCtx)
+	pipelineProvider := pipeline.NewProvider(config.NumberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsCtx, buildDiskBuffer(), metricSender)
 
 	cop := containersorpods.NewChooser()
 
@@ -105,6 +108,27 @@ func NewAgent(sources *config.LogSources, services *service.Services, processing
 	}
 }
 
+// buildDiskBuffer returns the disk buffer of the logs pipelines,
+// returns nil if it is disabled or can not be set up.
+func buildDiskBuffer() *sender.DiskBuffer {
+	maxSize := coreConfig.Datadog.GetInt64("logs_config.disk_buffer_max_size_in_bytes")
+	if maxSize <= 0 {
+		return nil
+	}
+	path := coreConfig.Datadog.GetString("logs_config.disk_buffer_path")
+	if path == "" {
+		path = filepath.Join(coreConfig.Datadog.GetString("logs_config.run_path"), "logs_disk_buffer")
+	}
+	maxAge := time.Duration(coreConfig.Datadog.GetInt("logs_config.disk_buffer_max_age")) * time.Hour
+	diskBuffer, err := sender.NewDiskBuffer(path, maxSize, maxAge)
+	if err != nil {
+		log.Errorf("Could not set up the logs disk buffer in %s, payloads will not be buffered on disk: %v", path, err)
+		return nil
+	}
+	log.Infof("Logs payloads will be buffered on disk in %s when the endpoints are unavailable", path)
+	return diskBuffer
+}
+
 // NewServerless returns a Logs Agent instance to run in a serverless environment.
 // The Serverless Logs Agent has only one input being the channel to receive the logs to process.
 // It is using a NullAuditor because we've nothing to do after having sent the logs to the intake.
@@ -0,0 +1,378 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package kafka
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// produceError is the error of a partition rejecting records.
+type produceError struct {
+	topic     string
+	partition int32
+	code      int16
+}
+
+func (e *produceError) Error() string {
+	return fmt.Sprintf("kafka broker rejected the records of partition %d of topic %s with error code %d", e.partition, e.topic, e.code)
+}
+
+// brokerConn is a connection to a broker.
+type brokerConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// cluster produces records to the partition leaders of a Kafka cluster, it is not safe for concurrent use.
+type cluster struct {
+	config        *config.KafkaConfig
+	correlationID int32
+	conns         map[int32]*brokerConn
+	// brokers holds the addresses of the brokers by node id
+	brokers map[int32]string
+	// leaders holds the leader node of the partitions of each known topic
+	leaders map[string][]int32
+	// roundRobin holds the next partition of each topic for records without key
+	roundRobin map[string]int
+	stale      bool
+}
+
+func newCluster(config *config.KafkaConfig) *cluster {
+	return &cluster{
+		config:     config,
+		conns:      make(map[int32]*brokerConn),
+		brokers:    make(map[int32]string),
+		leaders:    make(map[string][]int32),
+		roundRobin: make(map[string]int),
+	}
+}
+
+// produce sends records to the leaders of their partitions. It returns the records which could not
+// be produced because of a transient error, to be retried, along with the first error encountered.
+// The records rejected with a permanent error are dropped and reported by onDropped.
+func (c *cluster) produce(ctx context.Context, records []record, onDropped func(record, error)) ([]record, error) {
+	if err := c.ensureMetadata(ctx, records); err != nil {
+		return records, err
+	}
+
+	// group the records by leader, then by topic and partition
+	requests := make(map[int32]map[string]map[int32][]record)
+	var failed []record
+	var firstErr error
+	for _, r := range records {
+		leaders := c.leaders[r.topic]
+		if len(leaders) == 0 {
+			failed = append(failed, r)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("no partition available for kafka topic %s", r.topic)
+			}
+			continue
+		}
+		partition := c.partition(r, len(leaders))
+		leader := leaders[partition]
+		if requests[leader] == nil {
+			requests[leader] = make(map[string]map[int32][]record)
+		}
+		if requests[leader][r.topic] == nil {
+			requests[leader][r.topic] = make(map[int32][]record)
+		}
+		requests[leader][r.topic][partition] = append(requests[leader][r.topic][partition], r)
+	}
+
+	for leader, topics := range requests {
+		err := c.produceTo(ctx, leader, topics, func(topic string, partition int32, code int16) {
+			err := &produceError{topic: topic, partition: partition, code: code}
+			if isRetriable(code) {
+				c.stale = true
+				failed = append(failed, topics[topic][partition]...)
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			for _, r := range topics[topic][partition] {
+				onDropped(r, err)
+			}
+		})
+		if err != nil {
+			// the whole request failed, none of its records were produced
+			c.closeConn(leader)
+			c.stale = true
+			for _, partitions := range topics {
+				for _, recs := range partitions {
+					failed = append(failed, recs...)
+				}
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return failed, firstErr
+}
+
+// partition returns the partition of a record, records with a key are partitioned by key
+// and the others are spread in a round-robin fashion.
+func (c *cluster) partition(r record, numPartitions int) int32 {
+	if r.key != nil {
+		return partitionForKey(r.key, numPartitions)
+	}
+	next := c.roundRobin[r.topic] % numPartitions
+	c.roundRobin[r.topic] = next + 1
+	return int32(next)
+}
+
+// produceTo sends a produce request to a leader, calling onError for each partition answering with an error.
+func (c *cluster) produceTo(ctx context.Context, leader int32, topics map[string]map[int32][]record, onError func(string, int32, int16)) error {
+	var e encoder
+	e.nullableString("") // transactional id
+	e.int16(int16(c.config.RequiredAcks))
+	e.int32(int32(c.config.Timeout / time.Millisecond))
+	e.int32(int32(len(topics)))
+	for topic, partitions := range topics {
+		e.string(topic)
+		e.int32(int32(len(partitions)))
+		for partition, records := range partitions {
+			e.int32(partition)
+			e.bytes(encodeRecordBatch(records))
+		}
+	}
+
+	conn, err := c.conn(ctx, leader)
+	if err != nil {
+		return err
+	}
+	// the brokers do not answer when no acknowledgement is required
+	response, err := c.roundTrip(conn, apiKeyProduce, produceVersion, e.buf, c.config.RequiredAcks != 0)
+	if err != nil || response == nil {
+		return err
+	}
+
+	d := decoder{buf: response}
+	for i, n := 0, d.arrayLen(); i < n; i++ {
+		topic := d.string()
+		for j, m := 0, d.arrayLen(); j < m; j++ {
+			partition := d.int32()
+			code := d.int16()
+			d.int64() // base offset
+			d.int64() // log append time
+			if d.err == nil && code != errNone {
+				onError(topic, partition, code)
+			}
+		}
+	}
+	return d.err
+}
+
+// ensureMetadata fetches the metadata of the cluster if it is stale or if some topics of records are unknown.
+func (c *cluster) ensureMetadata(ctx context.Context, records []record) error {
+	topics := make(map[string]bool)
+	missing := c.stale
+	for _, r := range records {
+		topics[r.topic] = true
+		if _, exists := c.leaders[r.topic]; !exists {
+			missing = true
+		}
+	}
+	if !missing {
+		return nil
+	}
+	for topic := range c.leaders {
+		topics[topic] = true
+	}
+	names := make([]string, 0, len(topics))
+	for topic := range topics {
+		names = append(names, topic)
+	}
+	return c.refreshMetadata(ctx, names)
+}
+
+// refreshMetadata fetches the brokers of the cluster and the partition leaders of topics,
+// from the bootstrap brokers first and from the known brokers otherwise.
+func (c *cluster) refreshMetadata(ctx context.Context, topics []string) error {
+	var e encoder
+	e.int32(int32(len(topics)))
+	for _, topic := range topics {
+		e.string(topic)
+	}
+	e.bool(true) // allow auto topic creation
+
+	addresses := append([]string{}, c.config.Brokers...)
+	for _, address := range c.brokers {
+		addresses = append(addresses, address)
+	}
+	var err error
+	for _, address := range addresses {
+		var conn *brokerConn
+		conn, err = c.dial(ctx, address)
+		if err != nil {
+			continue
+		}
+		var response []byte
+		response, err = c.roundTrip(conn, apiKeyMetadata, metadataVersion, e.buf, true)
+		conn.conn.Close()
+		if err == nil {
+			err = c.updateMetadata(response)
+		}
+		if err == nil {
+			c.stale = false
+			return nil
+		}
+		log.Debugf("Could not fetch the kafka metadata from %s: %v", address, err)
+	}
+	return fmt.Errorf("could not fetch the kafka metadata: %v", err)
+}
+
+// updateMetadata updates the brokers and the partition leaders from a metadata response.
+func (c *cluster) updateMetadata(response []byte) error {
+	d := decoder{buf: response}
+	d.int32() // throttle time
+	brokers := make(map[int32]string)
+	for i, n := 0, d.arrayLen(); i < n; i++ {
+		node := d.int32()
+		host := d.string()
+		port := d.int32()
+		d.string() // rack
+		brokers[node] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	d.string() // cluster id
+	d.int32()  // controller id
+	leaders := make(map[string][]int32)
+	for i, n := 0, d.arrayLen(); i < n; i++ {
+		code := d.int16()
+		topic := d.string()
+		d.bool() // is internal
+		partitions := make([]int32, d.arrayLen())
+		available := code == errNone
+		for range partitions {
+			partitionCode := d.int16()
+			index := d.int32()
+			leader := d.int32()
+			for k, m := 0, d.arrayLen(); k < m; k++ {
+				d.int32() // replica
+			}
+			for k, m := 0, d.arrayLen(); k < m; k++ {
+				d.int32() // in-sync replica
+			}
+			if d.err != nil {
+				break
+			}
+			// the topic is retried later while one of its partitions has no leader
+			if index < 0 || int(index) >= len(partitions) || leader < 0 || partitionCode != errNone {
+				available = false
+				continue
+			}
+			partitions[index] = leader
+		}
+		if available {
+			leaders[topic] = partitions
+		}
+	}
+	if d.err != nil {
+		return d.err
+	}
+
+	for node, conn := range c.conns {
+		if brokers[node] != c.brokers[node] {
+			conn.conn.Close()
+			delete(c.conns, node)
+		}
+	}
+	c.brokers = brokers
+	c.leaders = leaders
+	return nil
+}
+
+// conn returns the connection to a broker, opening it if needed.
+func (c *cluster) conn(ctx context.Context, node int32) (*brokerConn, error) {
+	if conn, exists := c.conns[node]; exists {
+		return conn, nil
+	}
+	address, exists := c.brokers[node]
+	if !exists {
+		c.stale = true
+		return nil, fmt.Errorf("unknown kafka broker %d", node)
+	}
+	conn, err := c.dial(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	c.conns[node] = conn
+	return conn, nil
+}
+
+func (c *cluster) dial(ctx context.Context, address string) (*brokerConn, error) {
+	dialer := &net.Dialer{Timeout: c.config.Timeout}
+	var conn net.Conn
+	var err error
+	if c.config.UseSSL {
+		host, _, _ := net.SplitHostPort(address)
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &brokerConn{conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+func (c *cluster) closeConn(node int32) {
+	if conn, exists := c.conns[node]; exists {
+		conn.conn.Close()
+		delete(c.conns, node)
+	}
+}
+
+// close closes the connections to the brokers.
+func (c *cluster) close() {
+	for node := range c.conns {
+		c.closeConn(node)
+	}
+}
+
+// roundTrip sends a request and returns the body of its response, or nil when expectResponse is false.
+func (c *cluster) roundTrip(conn *brokerConn, apiKey int16, apiVersion int16, body []byte, expectResponse bool) ([]byte, error) {
+	c.correlationID++
+	correlationID := c.correlationID
+	if err := conn.conn.SetDeadline(time.Now().Add(c.config.Timeout)); err != nil {
+		return nil, err
+	}
+	if _, err := conn.conn.Write(encodeRequest(apiKey, apiVersion, correlationID, c.config.ClientID, body)); err != nil {
+		return nil, err
+	}
+	if !expectResponse {
+		return nil, nil
+	}
+	var header [8]byte
+	if _, err := io.ReadFull(conn.reader, header[:]); err != nil {
+		return nil, err
+	}
+	size := int32(binary.BigEndian.Uint32(header[:4]))
+	if size < 4 {
+		return nil, errShortBuffer
+	}
+	if received := int32(binary.BigEndian.Uint32(header[4:])); received != correlationID {
+		return nil, fmt.Errorf("unexpected kafka correlation id %d, expected %d", received, correlationID)
+	}
+	response := make([]byte, size-4)
+	if _, err := io.ReadFull(conn.reader, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
@@ -0,0 +1,231 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package kafka sends the logs to a Kafka cluster, one record per log, with a minimal
+// implementation of the Kafka protocol.
+package kafka
+
+import (
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/backoff"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var (
+	tlmRecordsSent    = telemetry.NewCounter("logs_kafka", "records_sent", []string{}, "Number of records produced to Kafka")
+	tlmBytesSent      = telemetry.NewCounter("logs_kafka", "bytes_sent", []string{}, "Size of the values of the records produced to Kafka")
+	tlmRecordsDropped = telemetry.NewCounter("logs_kafka", "records_dropped", []string{}, "Number of records that could not be produced to Kafka")
+	tlmErrors         = telemetry.NewCounter("logs_kafka", "errors", []string{}, "Number of failed attempts to produce records to Kafka")
+)
+
+// invalidTopicChars matches the characters not allowed in the name of a topic.
+var invalidTopicChars = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+// Destination sends the logs of the payloads to Kafka, the records are batched
+// across payloads and the payloads are committed once their records are produced.
+type Destination struct {
+	config              *config.KafkaConfig
+	cluster             *cluster
+	destinationsContext *client.DestinationsContext
+	shouldRetry         bool
+	backoff             backoff.Policy
+	nbErrors            int
+	retryLock           sync.Mutex
+	lastRetryError      error
+
+	// the payloads and the records of the batch being built
+	payloads []*message.Payload
+	records  []record
+	size     int
+}
+
+// NewDestination returns a new Kafka destination, it retries the records forever when
+// kafkaConfig.IsReliable is set and drops them after a failed retry otherwise.
+func NewDestination(kafkaConfig *config.KafkaConfig, destinationsContext *client.DestinationsContext) *Destination {
+	return &Destination{
+		config:              kafkaConfig,
+		cluster:             newCluster(kafkaConfig),
+		destinationsContext: destinationsContext,
+		shouldRetry:         kafkaConfig.IsReliable,
+		backoff:             backoff.NewPolicy(2, 1, 30, 2, false),
+	}
+}
+
+// Start reads the payloads of input and produces their records in batches, the payloads are sent
+// to output once produced.
+func (d *Destination) Start(input chan *message.Payload, output chan *message.Payload, isRetrying chan bool) (stopChan <-chan struct{}) {
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(d.config.BatchWait)
+		defer ticker.Stop()
+	loop:
+		for {
+			select {
+			case payload, isOpen := <-input:
+				if !isOpen {
+					break loop
+				}
+				d.add(payload)
+				if len(d.records) >= d.config.BatchMaxRecords || d.size >= d.config.BatchMaxBytes {
+					d.flush(output, isRetrying)
+				}
+			case <-ticker.C:
+				d.flush(output, isRetrying)
+			}
+		}
+		d.flush(output, isRetrying)
+		d.cluster.close()
+		d.updateRetryState(nil, isRetrying)
+		stop <- struct{}{}
+	}()
+	return stop
+}
+
+// add adds the records of the messages of a payload to the batch. The value of a record is
+// the JSON encoding of its message, whatever the encoding of the other destinations.
+func (d *Destination) add(payload *message.Payload) {
+	d.payloads = append(d.payloads, payload)
+	for _, msg := range payload.Messages {
+		r := record{
+			topic:     d.topic(msg),
+			key:       d.key(msg),
+			value:     msg.JSONContent,
+			timestamp: msg.Timestamp,
+		}
+		if r.value == nil {
+			r.value = msg.Content
+		}
+		if r.timestamp.IsZero() {
+			r.timestamp = time.Now()
+		}
+		d.records = append(d.records, r)
+		d.size += len(r.value)
+	}
+}
+
+// flush produces the records of the batch, in chunks bounded by the batch limits, and commits its payloads.
+func (d *Destination) flush(output chan *message.Payload, isRetrying chan bool) {
+	for start := 0; start < len(d.records); {
+		end, size := start, 0
+		for end < len(d.records) && end-start < d.config.BatchMaxRecords && (end == start || size+len(d.records[end].value) <= d.config.BatchMaxBytes) {
+			size += len(d.records[end].value)
+			end++
+		}
+		d.sendAndRetry(d.records[start:end], isRetrying)
+		start = end
+	}
+	for _, payload := range d.payloads {
+		output <- payload
+	}
+	d.payloads = nil
+	d.records = nil
+	d.size = 0
+}
+
+// sendAndRetry produces records, retrying the ones failing with a transient error
+// once when the destination is unreliable, and until they succeed otherwise.
+func (d *Destination) sendAndRetry(records []record, isRetrying chan bool) {
+	onDropped := func(r record, err error) {
+		log.Warnf("Dropping a log sent to kafka topic %s: %v", r.topic, err)
+		tlmRecordsDropped.Inc()
+	}
+	for attempt := 0; ; attempt++ {
+		ctx := d.destinationsContext.Context()
+		dropped := 0
+		failed, err := d.cluster.produce(ctx, records, func(r record, err error) {
+			dropped++
+			onDropped(r, err)
+		})
+		tlmRecordsSent.Add(float64(len(records) - len(failed) - dropped))
+		tlmBytesSent.Add(float64(valuesSize(records) - valuesSize(failed)))
+		if err == nil {
+			d.nbErrors = d.backoff.DecError(d.nbErrors)
+			d.updateRetryState(nil, isRetrying)
+			return
+		}
+		tlmErrors.Inc()
+		log.Debugf("Could not produce %d records to kafka: %v", len(failed), err)
+
+		if ctx.Err() != nil || (!d.shouldRetry && attempt > 0) {
+			for _, r := range failed {
+				onDropped(r, err)
+			}
+			return
+		}
+		if d.shouldRetry {
+			d.updateRetryState(err, isRetrying)
+			d.nbErrors = d.backoff.IncError(d.nbErrors)
+		}
+		select {
+		case <-time.After(d.backoff.GetBackoffDuration(d.nbErrors)):
+		case <-ctx.Done():
+		}
+		records = failed
+	}
+}
+
+// valuesSize returns the size of the values of records.
+func valuesSize(records []record) int {
+	size := 0
+	for _, r := range records {
+		size += len(r.value)
+	}
+	return size
+}
+
+// topic returns the topic of the record of a message.
+func (d *Destination) topic(msg *message.Message) string {
+	var suffix string
+	if msg.Origin == nil {
+		return d.config.Topic
+	}
+	switch d.config.TopicBy {
+	case config.KafkaTopicBySource:
+		suffix = msg.Origin.Source()
+	case config.KafkaTopicByService:
+		suffix = msg.Origin.Service()
+	}
+	if suffix == "" {
+		return d.config.Topic
+	}
+	return d.config.Topic + "-" + invalidTopicChars.ReplaceAllString(suffix, "_")
+}
+
+// key returns the key of the record of a message, the container key falls back to the
+// host for the logs which do not come from a container.
+func (d *Destination) key(msg *message.Message) []byte {
+	switch d.config.KeyBy {
+	case config.KafkaKeyByNone:
+		return nil
+	case config.KafkaKeyByContainer:
+		if msg.Origin != nil && msg.Origin.Identifier != "" {
+			return []byte(msg.Origin.Identifier)
+		}
+	}
+	return []byte(msg.GetHostname())
+}
+
+func (d *Destination) updateRetryState(err error, isRetrying chan bool) {
+	d.retryLock.Lock()
+	defer d.retryLock.Unlock()
+
+	if err != nil {
+		if isRetrying != nil && d.lastRetryError == nil {
+			isRetrying <- true
+		}
+	} else {
+		if isRetrying != nil && d.lastRetryError != nil {
+			isRetrying <- false
+		}
+	}
+	d.lastRetryError = err
+}
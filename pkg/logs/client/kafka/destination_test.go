@@ -0,0 +1,241 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package kafka
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/processor"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/util/backoff"
+)
+
+func newTestConfig(broker *TestBroker) *config.KafkaConfig {
+	return &config.KafkaConfig{
+		Brokers:         []string{broker.Addr()},
+		Topic:           "logs",
+		KeyBy:           config.KafkaKeyByHost,
+		RequiredAcks:    1,
+		Timeout:         5 * time.Second,
+		BatchWait:       10 * time.Millisecond,
+		BatchMaxRecords: 100,
+		BatchMaxBytes:   100000,
+		ClientID:        "test",
+	}
+}
+
+func newTestMessage(content string, logsConfig *config.LogsConfig, hostname, identifier string) *message.Message {
+	msg := message.NewMessageWithSource([]byte(content), message.StatusInfo, config.NewLogSource("test", logsConfig), 0)
+	msg.Hostname = hostname
+	msg.Origin.Identifier = identifier
+	return msg
+}
+
+func startTestDestination(kafkaConfig *config.KafkaConfig) (*client.DestinationsContext, chan *message.Payload, chan *message.Payload, <-chan struct{}) {
+	destinationsContext := client.NewDestinationsContext()
+	destinationsContext.Start()
+	input := make(chan *message.Payload)
+	output := make(chan *message.Payload, 10)
+	destination := NewDestination(kafkaConfig, destinationsContext)
+	destination.backoff = backoff.NewPolicy(2, 0.01, 0.1, 2, false)
+	stop := destination.Start(input, output, nil)
+	return destinationsContext, input, output, stop
+}
+
+func TestDestinationProducesRecords(t *testing.T) {
+	broker, err := NewTestBroker(3)
+	require.NoError(t, err)
+	defer broker.Close()
+
+	destinationsContext, input, output, stop := startTestDestination(newTestConfig(broker))
+	defer destinationsContext.Stop()
+
+	input <- &message.Payload{Messages: []*message.Message{
+		newTestMessage("a", &config.LogsConfig{}, "host-1", ""),
+		newTestMessage("b", &config.LogsConfig{}, "host-1", ""),
+	}}
+	input <- &message.Payload{Messages: []*message.Message{
+		newTestMessage("c", &config.LogsConfig{}, "host-2", ""),
+	}}
+	<-output
+	<-output
+	close(input)
+	<-stop
+
+	records := broker.Records("logs")
+	require.Len(t, records, 3)
+	values := make(map[string]TestRecord)
+	for _, r := range records {
+		values[string(r.Value)] = r
+	}
+	assert.Equal(t, []byte("host-1"), values["a"].Key)
+	assert.Equal(t, []byte("host-2"), values["c"].Key)
+	// the records with the same key go to the same partition
+	assert.Equal(t, values["a"].Partition, values["b"].Partition)
+	assert.Equal(t, partitionForKey([]byte("host-1"), 3), values["a"].Partition)
+}
+
+func TestDestinationProducesJSONRecords(t *testing.T) {
+	broker, err := NewTestBroker(1)
+	require.NoError(t, err)
+	defer broker.Close()
+
+	destinationsContext, input, output, stop := startTestDestination(newTestConfig(broker))
+	defer destinationsContext.Stop()
+
+	// the messages sent to Datadog over TCP are encoded in raw
+	msg := newTestMessage("a", &config.LogsConfig{Service: "web", Source: "nginx"}, "host", "")
+	content, err := processor.WithJSONContent(processor.RawEncoder).Encode(msg, []byte("redacted"))
+	require.NoError(t, err)
+	msg.Content = content
+	require.False(t, json.Valid(msg.Content))
+
+	input <- &message.Payload{Messages: []*message.Message{msg}}
+	<-output
+	close(input)
+	<-stop
+
+	records := broker.Records("logs")
+	require.Len(t, records, 1)
+	var value map[string]interface{}
+	require.NoError(t, json.Unmarshal(records[0].Value, &value))
+	assert.Equal(t, "redacted", value["message"])
+	assert.Equal(t, "web", value["service"])
+	assert.Equal(t, "nginx", value["ddsource"])
+	assert.Equal(t, "host", value["hostname"])
+	assert.Equal(t, message.StatusInfo, value["status"])
+}
+
+func TestDestinationTopicAndKey(t *testing.T) {
+	broker, err := NewTestBroker(1)
+	require.NoError(t, err)
+	defer broker.Close()
+
+	kafkaConfig := newTestConfig(broker)
+	kafkaConfig.TopicBy = config.KafkaTopicBySource
+	kafkaConfig.KeyBy = config.KafkaKeyByContainer
+	destinationsContext, input, output, stop := startTestDestination(kafkaConfig)
+	defer destinationsContext.Stop()
+
+	input <- &message.Payload{Messages: []*message.Message{
+		newTestMessage("a", &config.LogsConfig{Source: "nginx"}, "host", "container-1"),
+		newTestMessage("b", &config.LogsConfig{Source: "my app"}, "host", ""),
+		newTestMessage("c", &config.LogsConfig{}, "host", "container-2"),
+	}}
+	<-output
+	close(input)
+	<-stop
+
+	nginx := broker.Records("logs-nginx")
+	require.Len(t, nginx, 1)
+	assert.Equal(t, []byte("container-1"), nginx[0].Key)
+
+	app := broker.Records("logs-my_app")
+	require.Len(t, app, 1)
+	assert.Equal(t, []byte("host"), app[0].Key)
+
+	logs := broker.Records("logs")
+	require.Len(t, logs, 1)
+	assert.Equal(t, []byte("container-2"), logs[0].Key)
+}
+
+func TestDestinationBatchesRecords(t *testing.T) {
+	broker, err := NewTestBroker(1)
+	require.NoError(t, err)
+	defer broker.Close()
+
+	kafkaConfig := newTestConfig(broker)
+	kafkaConfig.KeyBy = config.KafkaKeyByNone
+	kafkaConfig.BatchWait = time.Hour
+	kafkaConfig.BatchMaxRecords = 2
+	destinationsContext, input, output, stop := startTestDestination(kafkaConfig)
+	defer destinationsContext.Stop()
+
+	input <- &message.Payload{Messages: []*message.Message{newTestMessage("a", &config.LogsConfig{}, "host", "")}}
+	select {
+	case <-output:
+		assert.Fail(t, "the payload should be held until the batch is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Empty(t, broker.Records("logs"))
+
+	input <- &message.Payload{Messages: []*message.Message{newTestMessage("b", &config.LogsConfig{}, "host", "")}}
+	<-output
+	<-output
+	records := broker.Records("logs")
+	require.Len(t, records, 2)
+	assert.Nil(t, records[0].Key)
+
+	close(input)
+	<-stop
+}
+
+func TestDestinationRetriesTransientErrors(t *testing.T) {
+	broker, err := NewTestBroker(1)
+	require.NoError(t, err)
+	defer broker.Close()
+	broker.FailProduce(errNotLeaderForPartition, 3)
+
+	kafkaConfig := newTestConfig(broker)
+	kafkaConfig.IsReliable = true
+	destinationsContext, input, output, stop := startTestDestination(kafkaConfig)
+	defer destinationsContext.Stop()
+
+	input <- &message.Payload{Messages: []*message.Message{newTestMessage("a", &config.LogsConfig{}, "host", "")}}
+	<-output
+	close(input)
+	<-stop
+
+	assert.Len(t, broker.Records("logs"), 1)
+}
+
+func TestDestinationDropsPermanentErrors(t *testing.T) {
+	broker, err := NewTestBroker(1)
+	require.NoError(t, err)
+	defer broker.Close()
+	// message too large
+	broker.FailProduce(10, 1)
+
+	kafkaConfig := newTestConfig(broker)
+	kafkaConfig.IsReliable = true
+	destinationsContext, input, output, stop := startTestDestination(kafkaConfig)
+	defer destinationsContext.Stop()
+
+	input <- &message.Payload{Messages: []*message.Message{newTestMessage("a", &config.LogsConfig{}, "host", "")}}
+	<-output
+	input <- &message.Payload{Messages: []*message.Message{newTestMessage("b", &config.LogsConfig{}, "host", "")}}
+	<-output
+	close(input)
+	<-stop
+
+	records := broker.Records("logs")
+	require.Len(t, records, 1)
+	assert.Equal(t, []byte("b"), records[0].Value)
+}
+
+func TestDestinationWithoutAcks(t *testing.T) {
+	broker, err := NewTestBroker(1)
+	require.NoError(t, err)
+	defer broker.Close()
+
+	kafkaConfig := newTestConfig(broker)
+	kafkaConfig.RequiredAcks = 0
+	destinationsContext, input, output, stop := startTestDestination(kafkaConfig)
+	defer destinationsContext.Stop()
+
+	input <- &message.Payload{Messages: []*message.Message{newTestMessage("a", &config.LogsConfig{}, "host", "")}}
+	<-output
+	assert.Eventually(t, func() bool { return len(broker.Records("logs")) == 1 }, 5*time.Second, 10*time.Millisecond)
+	close(input)
+	<-stop
+}
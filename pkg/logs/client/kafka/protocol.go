@@ -0,0 +1,367 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package kafka
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"time"
+)
+
+// The subset of the Kafka protocol used to produce records. These versions are the oldest
+// ones still supported by the current brokers, they do not use the flexible encoding.
+const (
+	apiKeyProduce   int16 = 0
+	apiKeyMetadata  int16 = 3
+	produceVersion  int16 = 3
+	metadataVersion int16 = 4
+)
+
+// Error codes returned by the brokers that are worth refreshing the metadata and retrying for,
+// the other errors are permanent.
+const (
+	errNone                    int16 = 0
+	errUnknownTopicOrPartition int16 = 3
+	errLeaderNotAvailable      int16 = 5
+	errNotLeaderForPartition   int16 = 6
+	errRequestTimedOut         int16 = 7
+	errNetworkException        int16 = 13
+	errNotEnoughReplicas       int16 = 19
+	errNotEnoughReplicasAfter  int16 = 20
+)
+
+var errShortBuffer = errors.New("kafka message too short")
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// isRetriable returns true if an error code is transient.
+func isRetriable(code int16) bool {
+	switch code {
+	case errUnknownTopicOrPartition, errLeaderNotAvailable, errNotLeaderForPartition, errRequestTimedOut,
+		errNetworkException, errNotEnoughReplicas, errNotEnoughReplicasAfter:
+		return true
+	}
+	return false
+}
+
+// record is a log sent to Kafka.
+type record struct {
+	topic     string
+	key       []byte
+	value     []byte
+	timestamp time.Time
+}
+
+// encoder appends the Kafka primitive types to a buffer, in big endian.
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) int8(v int8) {
+	e.buf = append(e.buf, byte(v))
+}
+
+func (e *encoder) int16(v int16) {
+	e.buf = append(e.buf, byte(v>>8), byte(v))
+}
+
+func (e *encoder) int32(v int32) {
+	e.buf = append(e.buf, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func (e *encoder) int64(v int64) {
+	e.int32(int32(v >> 32))
+	e.int32(int32(v))
+}
+
+func (e *encoder) bool(v bool) {
+	if v {
+		e.int8(1)
+	} else {
+		e.int8(0)
+	}
+}
+
+func (e *encoder) string(v string) {
+	e.int16(int16(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+// nullableString encodes an empty string as null.
+func (e *encoder) nullableString(v string) {
+	if v == "" {
+		e.int16(-1)
+		return
+	}
+	e.string(v)
+}
+
+func (e *encoder) bytes(v []byte) {
+	e.int32(int32(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+// varint encodes a zigzag variable-length integer, as in protocol buffers.
+func (e *encoder) varint(v int64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(buf[:], v)
+	e.buf = append(e.buf, buf[:n]...)
+}
+
+// varintBytes encodes a varint length followed by v, nil is encoded as a -1 length.
+func (e *encoder) varintBytes(v []byte) {
+	if v == nil {
+		e.varint(-1)
+		return
+	}
+	e.varint(int64(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+// decoder reads the Kafka primitive types from a buffer, the first error is kept
+// and the following reads return zero values.
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.buf) < n {
+		d.err = errShortBuffer
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) int8() int8 {
+	if b := d.next(1); b != nil {
+		return int8(b[0])
+	}
+	return 0
+}
+
+func (d *decoder) int16() int16 {
+	if b := d.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *decoder) int32() int32 {
+	if b := d.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (d *decoder) int64() int64 {
+	if b := d.next(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (d *decoder) bool() bool {
+	return d.int8() != 0
+}
+
+func (d *decoder) string() string {
+	n := d.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(d.next(int(n)))
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.int32()
+	if n < 0 {
+		return nil
+	}
+	return d.next(int(n))
+}
+
+func (d *decoder) arrayLen() int {
+	n := d.int32()
+	if n < 0 {
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.err = errShortBuffer
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) varintBytes() []byte {
+	n := d.varint()
+	if n < 0 {
+		return nil
+	}
+	return d.next(int(n))
+}
+
+// encodeRequest frames a request with its size and its header.
+func encodeRequest(apiKey int16, apiVersion int16, correlationID int32, clientID string, body []byte) []byte {
+	e := encoder{buf: make([]byte, 4, 4+10+len(clientID)+len(body))}
+	e.int16(apiKey)
+	e.int16(apiVersion)
+	e.int32(correlationID)
+	e.nullableString(clientID)
+	e.buf = append(e.buf, body...)
+	binary.BigEndian.PutUint32(e.buf, uint32(len(e.buf)-4))
+	return e.buf
+}
+
+// encodeRecordBatch encodes records into an uncompressed record batch, the message format
+// used since Kafka 0.11.
+func encodeRecordBatch(records []record) []byte {
+	firstTimestamp := records[0].timestamp.UnixNano() / int64(time.Millisecond)
+	maxTimestamp := firstTimestamp
+
+	var recs encoder
+	var rec encoder
+	for i, r := range records {
+		timestamp := r.timestamp.UnixNano() / int64(time.Millisecond)
+		if timestamp > maxTimestamp {
+			maxTimestamp = timestamp
+		}
+		rec.buf = rec.buf[:0]
+		rec.int8(0) // attributes
+		rec.varint(timestamp - firstTimestamp)
+		rec.varint(int64(i)) // offset delta
+		rec.varintBytes(r.key)
+		rec.varintBytes(r.value)
+		rec.varint(0) // headers
+		recs.varint(int64(len(rec.buf)))
+		recs.buf = append(recs.buf, rec.buf...)
+	}
+
+	// the fields covered by the CRC
+	var crced encoder
+	crced.int16(0) // attributes: no compression, no transaction
+	crced.int32(int32(len(records) - 1))
+	crced.int64(firstTimestamp)
+	crced.int64(maxTimestamp)
+	crced.int64(-1) // producer id
+	crced.int16(-1) // producer epoch
+	crced.int32(-1) // base sequence
+	crced.int32(int32(len(records)))
+	crced.buf = append(crced.buf, recs.buf...)
+
+	var batch encoder
+	batch.int64(0) // base offset, assigned by the broker
+	batch.int32(int32(4 + 1 + 4 + len(crced.buf)))
+	batch.int32(-1) // partition leader epoch
+	batch.int8(2)   // magic
+	batch.int32(int32(crc32.Checksum(crced.buf, castagnoli)))
+	batch.buf = append(batch.buf, crced.buf...)
+	return batch.buf
+}
+
+// decodeRecordBatches decodes the uncompressed record batches of a produce request.
+func decodeRecordBatches(b []byte) ([]record, error) {
+	var records []record
+	d := decoder{buf: b}
+	for len(d.buf) > 0 && d.err == nil {
+		d.int64() // base offset
+		batch := decoder{buf: d.next(int(d.int32()))}
+		batch.int32() // partition leader epoch
+		if magic := batch.int8(); magic != 2 {
+			return nil, fmt.Errorf("unsupported record batch version %d", magic)
+		}
+		crc := uint32(batch.int32())
+		if batch.err == nil && crc32.Checksum(batch.buf, castagnoli) != crc {
+			return nil, fmt.Errorf("invalid record batch checksum")
+		}
+		if attributes := batch.int16(); attributes&0x7 != 0 {
+			return nil, fmt.Errorf("compressed record batches are not supported")
+		}
+		batch.int32() // last offset delta
+		firstTimestamp := batch.int64()
+		batch.int64() // max timestamp
+		batch.int64() // producer id
+		batch.int16() // producer epoch
+		batch.int32() // base sequence
+		count := batch.int32()
+		for i := int32(0); i < count && batch.err == nil; i++ {
+			rec := decoder{buf: batch.next(int(batch.varint()))}
+			rec.int8() // attributes
+			timestampDelta := rec.varint()
+			rec.varint() // offset delta
+			r := record{
+				key:       rec.varintBytes(),
+				value:     rec.varintBytes(),
+				timestamp: time.Unix(0, (firstTimestamp+timestampDelta)*int64(time.Millisecond)),
+			}
+			if rec.err != nil {
+				return nil, rec.err
+			}
+			records = append(records, r)
+		}
+		if batch.err != nil {
+			return nil, batch.err
+		}
+	}
+	return records, d.err
+}
+
+// murmur2 is the hash used by the default partitioner of the Java client, so that the records
+// are partitioned by key the same way.
+func murmur2(data []byte) int32 {
+	const (
+		seed uint32 = 0x9747b28c
+		m    uint32 = 0x5bd1e995
+		r           = 24
+	)
+	length := len(data)
+	h := seed ^ uint32(length)
+	for i := 0; i+4 <= length; i += 4 {
+		k := binary.LittleEndian.Uint32(data[i:])
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+	tail := data[length&^3:]
+	switch len(tail) {
+	case 3:
+		h ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(tail[0])
+		h *= m
+	}
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return int32(h)
+}
+
+// partitionForKey returns the partition of a key among numPartitions partitions.
+func partitionForKey(key []byte, numPartitions int) int32 {
+	return (murmur2(key) & 0x7fffffff) % int32(numPartitions)
+}
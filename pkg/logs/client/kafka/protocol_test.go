@@ -0,0 +1,57 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordBatchRoundTrip(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+	records := []record{
+		{key: []byte("host"), value: []byte("first"), timestamp: now},
+		{key: nil, value: []byte("second"), timestamp: now.Add(time.Second)},
+		{key: []byte{}, value: []byte{}, timestamp: now.Add(-time.Second)},
+	}
+
+	batch := encodeRecordBatch(records)
+	decoded, err := decodeRecordBatches(append(batch, batch...))
+	require.NoError(t, err)
+	require.Len(t, decoded, 6)
+	for i, r := range decoded {
+		expected := records[i%3]
+		assert.Equal(t, expected.key, r.key)
+		assert.Equal(t, expected.value, r.value)
+		assert.True(t, expected.timestamp.Equal(r.timestamp))
+	}
+
+	// corrupted batches are rejected
+	batch[len(batch)-1]++
+	_, err = decodeRecordBatches(batch)
+	assert.Error(t, err)
+}
+
+func TestMurmur2(t *testing.T) {
+	// the test vectors of org.apache.kafka.common.utils.Utils.murmur2
+	assert.Equal(t, int32(-973932308), murmur2([]byte("21")))
+	assert.Equal(t, int32(-790332482), murmur2([]byte("foobar")))
+	assert.Equal(t, int32(-985981536), murmur2([]byte("a-little-bit-long-string")))
+	assert.Equal(t, int32(-1486304829), murmur2([]byte("a-little-bit-longer-string")))
+	assert.Equal(t, int32(-58897971), murmur2([]byte("lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8")))
+	assert.Equal(t, int32(479470107), murmur2([]byte{'a', 'b', 'c'}))
+}
+
+func TestPartitionForKey(t *testing.T) {
+	for _, key := range []string{"21", "foobar", "host-1", "container-1"} {
+		partition := partitionForKey([]byte(key), 7)
+		assert.True(t, partition >= 0 && partition < 7)
+		assert.Equal(t, partition, partitionForKey([]byte(key), 7))
+	}
+}
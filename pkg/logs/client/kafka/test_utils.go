@@ -0,0 +1,225 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package kafka
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// TestBroker is an in-process stand-in for a Kafka cluster made of a single broker,
+// it answers the metadata and produce requests and stores the records produced.
+type TestBroker struct {
+	listener   net.Listener
+	partitions int32
+
+	mu        sync.Mutex
+	records   map[string][]TestRecord
+	errorCode int16
+	failures  int
+	conns     []net.Conn
+	wg        sync.WaitGroup
+}
+
+// TestRecord is a record stored by a TestBroker.
+type TestRecord struct {
+	Partition int32
+	Key       []byte
+	Value     []byte
+}
+
+// NewTestBroker starts a test broker leading all the partitions of all the topics.
+func NewTestBroker(partitions int32) (*TestBroker, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	b := &TestBroker{
+		listener:   listener,
+		partitions: partitions,
+		records:    make(map[string][]TestRecord),
+	}
+	b.wg.Add(1)
+	go b.accept()
+	return b, nil
+}
+
+// Addr returns the host:port address of the broker.
+func (b *TestBroker) Addr() string {
+	return b.listener.Addr().String()
+}
+
+// Records returns the records produced to a topic.
+func (b *TestBroker) Records(topic string) []TestRecord {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]TestRecord{}, b.records[topic]...)
+}
+
+// FailProduce makes the broker reject the next count produce requests with an error code.
+func (b *TestBroker) FailProduce(code int16, count int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.errorCode = code
+	b.failures = count
+}
+
+// Close stops the broker and closes its connections.
+func (b *TestBroker) Close() {
+	b.listener.Close()
+	b.mu.Lock()
+	for _, conn := range b.conns {
+		conn.Close()
+	}
+	b.mu.Unlock()
+	b.wg.Wait()
+}
+
+func (b *TestBroker) accept() {
+	defer b.wg.Done()
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		b.mu.Lock()
+		b.conns = append(b.conns, conn)
+		b.mu.Unlock()
+		b.wg.Add(1)
+		go b.serve(conn)
+	}
+}
+
+func (b *TestBroker) serve(conn net.Conn) {
+	defer b.wg.Done()
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(reader, size[:]); err != nil {
+			return
+		}
+		request := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(reader, request); err != nil {
+			return
+		}
+		d := decoder{buf: request}
+		apiKey := d.int16()
+		d.int16() // api version
+		correlationID := d.int32()
+		d.string() // client id
+		if d.err != nil {
+			return
+		}
+
+		var body []byte
+		switch apiKey {
+		case apiKeyMetadata:
+			body = b.metadata(&d)
+		case apiKeyProduce:
+			body = b.produce(&d)
+		default:
+			return
+		}
+		if body == nil {
+			continue
+		}
+		e := encoder{buf: make([]byte, 4)}
+		e.int32(correlationID)
+		e.buf = append(e.buf, body...)
+		binary.BigEndian.PutUint32(e.buf, uint32(len(e.buf)-4))
+		if _, err := conn.Write(e.buf); err != nil {
+			return
+		}
+	}
+}
+
+// metadata answers a metadata request with the broker as the leader of every partition.
+func (b *TestBroker) metadata(d *decoder) []byte {
+	var topics []string
+	for i, n := 0, d.arrayLen(); i < n; i++ {
+		topics = append(topics, d.string())
+	}
+	host, portStr, _ := net.SplitHostPort(b.Addr())
+	port, _ := strconv.Atoi(portStr)
+
+	var e encoder
+	e.int32(0) // throttle time
+	e.int32(1)
+	e.int32(0) // node id
+	e.string(host)
+	e.int32(int32(port))
+	e.nullableString("") // rack
+	e.nullableString("") // cluster id
+	e.int32(0)           // controller id
+	e.int32(int32(len(topics)))
+	for _, topic := range topics {
+		e.int16(errNone)
+		e.string(topic)
+		e.bool(false) // is internal
+		e.int32(b.partitions)
+		for p := int32(0); p < b.partitions; p++ {
+			e.int16(errNone)
+			e.int32(p)
+			e.int32(0) // leader
+			e.int32(1)
+			e.int32(0) // replicas
+			e.int32(1)
+			e.int32(0) // in-sync replicas
+		}
+	}
+	return e.buf
+}
+
+// produce stores the records of a produce request, it returns nil when no acknowledgement is required.
+func (b *TestBroker) produce(d *decoder) []byte {
+	d.string() // transactional id
+	acks := d.int16()
+	d.int32() // timeout
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	code := errNone
+	if b.failures > 0 {
+		code = b.errorCode
+		b.failures--
+	}
+
+	var e encoder
+	topicCount := d.arrayLen()
+	e.int32(int32(topicCount))
+	for i := 0; i < topicCount; i++ {
+		topic := d.string()
+		e.string(topic)
+		partitionCount := d.arrayLen()
+		e.int32(int32(partitionCount))
+		for j := 0; j < partitionCount; j++ {
+			partition := d.int32()
+			records, err := decodeRecordBatches(d.bytes())
+			if err != nil || d.err != nil {
+				return nil
+			}
+			if code == errNone {
+				for _, r := range records {
+					b.records[topic] = append(b.records[topic], TestRecord{Partition: partition, Key: r.key, Value: r.value})
+				}
+			}
+			e.int32(partition)
+			e.int16(code)
+			e.int64(0)  // base offset
+			e.int64(-1) // log append time
+		}
+	}
+	e.int32(0) // throttle time
+	if acks == 0 {
+		return nil
+	}
+	return e.buf
+}
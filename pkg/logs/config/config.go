@@ -203,7 +203,7 @@ func BuildHTTPEndpointsWithConfig(logsConfig *LogsConfigKeys, endpointPrefix str
 	main := Endpoint{
 		APIKey:                  logsConfig.getLogsAPIKey(),
 		UseCompression:          logsConfig.useCompression(),
-		CompressionLevel:        logsConfig.compressionLevel(),
+		CompressionKind:         logsConfig.compressionKind(),
 		ConnectionResetInterval: logsConfig.connectionResetInterval(),
 		BackoffBase:             logsConfig.senderBackoffBase(),
 		BackoffMax:              logsConfig.senderBackoffMax(),
@@ -212,6 +212,7 @@ func BuildHTTPEndpointsWithConfig(logsConfig *LogsConfigKeys, endpointPrefix str
 		RecoveryReset:           logsConfig.senderRecoveryReset(),
 		IsReliable:              true,
 	}
+	main.CompressionLevel = logsConfig.compressionLevelFor(main.CompressionKind)
 
 	if logsConfig.useV2API() && intakeTrackType != "" {
 		main.Version = EPIntakeVersion2
@@ -270,6 +271,11 @@ func BuildHTTPEndpointsWithConfig(logsConfig *LogsConfigKeys, endpointPrefix str
 		additionals[i].UseSSL = main.UseSSL
 		additionals[i].APIKey = coreConfig.SanitizeAPIKey(additionals[i].APIKey)
 		additionals[i].UseCompression = main.UseCompression
+		// the payloads are compressed once for all the endpoints, with the compression of the main one
+		if additionals[i].CompressionKind != "" && !strings.EqualFold(additionals[i].CompressionKind, main.CompressionKind) {
+			log.Warnf("%s.compression_kind is not supported, the additional endpoints use %s", logsConfig.getConfigKey("additional_endpoints"), main.CompressionKind)
+		}
+		additionals[i].CompressionKind = main.CompressionKind
 		additionals[i].CompressionLevel = main.CompressionLevel
 		additionals[i].BackoffBase = main.BackoffBase
 		additionals[i].BackoffMax = main.BackoffMax
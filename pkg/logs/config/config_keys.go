@@ -7,6 +7,7 @@ package config
 
 import (
 	"encoding/json"
+	"strings"
 	"time"
 
 	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
@@ -110,6 +111,37 @@ func (l *LogsConfigKeys) compressionLevel() int {
 	return l.getConfig().GetInt(l.getConfigKey("compression_level"))
 }
 
+// compressionKind returns the compression algorithm, gzip if it is not supported.
+func (l *LogsConfigKeys) compressionKind() string {
+	return parseCompressionKind(l.getConfigKey("compression_kind"), l.getConfig().GetString(l.getConfigKey("compression_kind")))
+}
+
+// compressionLevelFor returns the configured compression level of the given algorithm.
+func (l *LogsConfigKeys) compressionLevelFor(kind string) int {
+	if kind == ZstdCompressionKind {
+		return l.zstdCompressionLevel()
+	}
+	return l.compressionLevel()
+}
+
+func (l *LogsConfigKeys) zstdCompressionLevel() int {
+	return l.getConfig().GetInt(l.getConfigKey("zstd_compression_level"))
+}
+
+// parseCompressionKind returns the compression algorithm set to kind in key, gzip if it is not supported.
+func parseCompressionKind(key string, kind string) string {
+	kind = strings.ToLower(kind)
+	switch kind {
+	case "", GzipCompressionKind:
+		return GzipCompressionKind
+	case ZstdCompressionKind:
+		return kind
+	default:
+		log.Warnf("Invalid %s: %q, falling back to %s", key, kind, GzipCompressionKind)
+		return GzipCompressionKind
+	}
+}
+
 func (l *LogsConfigKeys) useCompression() bool {
 	return l.getConfig().GetBool(l.getConfigKey("use_compression"))
 }
@@ -114,6 +114,34 @@ func (suite *ConfigTestSuite) TestGlobalProcessingRulesShouldReturnRulesWithVali
 	suite.NotNil(rule.Regex)
 }
 
+func (suite *ConfigTestSuite) TestGlobalProcessingRulesShouldReturnJSONRules() {
+	suite.config.Set("logs_config.processing_rules", []map[string]interface{}{
+		{
+			"type": "json_drop_keys",
+			"name": "drop_debug_fields",
+			"keys": []string{"debug", "http.headers"},
+		},
+		{
+			"type":   "json_remap_key",
+			"name":   "remap_level",
+			"keys":   []string{"level"},
+			"target": "status",
+		},
+	})
+
+	rules, err := GlobalProcessingRules()
+	suite.Nil(err)
+	suite.Equal(2, len(rules))
+
+	suite.Equal(JSONDropKeys, rules[0].Type)
+	suite.Equal([]string{"debug", "http.headers"}, rules[0].Keys)
+	suite.Nil(rules[0].Regex)
+
+	suite.Equal(JSONRemapKey, rules[1].Type)
+	suite.Equal([]string{"level"}, rules[1].Keys)
+	suite.Equal(RemapStatus, rules[1].Target)
+}
+
 func (suite *ConfigTestSuite) TestTaggerWarmupDuration() {
 	// assert TaggerWarmupDuration is disabled by default
 	taggerWarmupDuration := TaggerWarmupDuration()
@@ -154,6 +182,7 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsEnvVar() {
 		Port:             443,
 		UseSSL:           true,
 		UseCompression:   true,
+		CompressionKind:  "gzip",
 		CompressionLevel: 6,
 		BackoffFactor:    3,
 		BackoffBase:      1.0,
@@ -169,6 +198,7 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsEnvVar() {
 		Port:             1234,
 		UseSSL:           true,
 		UseCompression:   true,
+		CompressionKind:  "gzip",
 		CompressionLevel: 6,
 		BackoffFactor:    3,
 		BackoffBase:      1.0,
@@ -183,6 +213,7 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsEnvVar() {
 		Port:             1234,
 		UseSSL:           true,
 		UseCompression:   true,
+		CompressionKind:  "gzip",
 		CompressionLevel: 6,
 		BackoffFactor:    3,
 		BackoffBase:      1.0,
@@ -266,6 +297,7 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsInConfig() {
 		Port:             443,
 		UseSSL:           true,
 		UseCompression:   true,
+		CompressionKind:  "gzip",
 		CompressionLevel: 6,
 		BackoffFactor:    coreConfig.DefaultLogsSenderBackoffFactor,
 		BackoffBase:      coreConfig.DefaultLogsSenderBackoffBase,
@@ -280,6 +312,7 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsInConfig() {
 		Port:             1234,
 		UseSSL:           true,
 		UseCompression:   true,
+		CompressionKind:  "gzip",
 		CompressionLevel: 6,
 		BackoffFactor:    coreConfig.DefaultLogsSenderBackoffFactor,
 		BackoffBase:      coreConfig.DefaultLogsSenderBackoffBase,
@@ -293,6 +326,7 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsInConfig() {
 		Port:             1234,
 		UseSSL:           true,
 		UseCompression:   true,
+		CompressionKind:  "gzip",
 		CompressionLevel: 6,
 		BackoffFactor:    coreConfig.DefaultLogsSenderBackoffFactor,
 		BackoffBase:      coreConfig.DefaultLogsSenderBackoffBase,
@@ -340,6 +374,7 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsInConfig2() {
 		Port:             443,
 		UseSSL:           true,
 		UseCompression:   true,
+		CompressionKind:  "gzip",
 		CompressionLevel: 6,
 		BackoffFactor:    coreConfig.DefaultLogsSenderBackoffFactor,
 		BackoffBase:      coreConfig.DefaultLogsSenderBackoffBase,
@@ -357,6 +392,7 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsInConfig2() {
 		Port:             1234,
 		UseSSL:           true,
 		UseCompression:   true,
+		CompressionKind:  "gzip",
 		CompressionLevel: 6,
 		BackoffFactor:    coreConfig.DefaultLogsSenderBackoffFactor,
 		BackoffBase:      coreConfig.DefaultLogsSenderBackoffBase,
@@ -370,6 +406,7 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsInConfig2() {
 		Port:             1234,
 		UseSSL:           true,
 		UseCompression:   true,
+		CompressionKind:  "gzip",
 		CompressionLevel: 6,
 		BackoffFactor:    coreConfig.DefaultLogsSenderBackoffFactor,
 		BackoffBase:      coreConfig.DefaultLogsSenderBackoffBase,
@@ -389,6 +426,65 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsInConfig2() {
 	suite.Equal(expectedEndpoints, endpoints)
 }
 
+func (suite *ConfigTestSuite) TestHTTPEndpointsWithZstdCompression() {
+	suite.config.Set("api_key", "123")
+	suite.config.Set("logs_config.use_compression", true)
+	suite.config.Set("logs_config.compression_level", 6)
+	suite.config.Set("logs_config.compression_kind", "ZSTD")
+	suite.config.Set("logs_config.zstd_compression_level", 3)
+	suite.config.Set("logs_config.additional_endpoints", []map[string]interface{}{
+		{"api_key": "456", "host": "additional.endpoint", "port": 1234},
+	})
+
+	endpoints, err := BuildHTTPEndpoints("test-track", "test-proto", "test-source")
+
+	suite.Nil(err)
+	suite.Equal(ZstdCompressionKind, endpoints.Main.CompressionKind)
+	suite.Equal(3, endpoints.Main.CompressionLevel)
+	suite.Equal(ZstdCompressionKind, endpoints.Endpoints[1].CompressionKind)
+	suite.Equal(3, endpoints.Endpoints[1].CompressionLevel)
+	suite.Contains(endpoints.GetStatus()[0], "Sending zstd compressed logs in HTTPS")
+}
+
+func (suite *ConfigTestSuite) TestHTTPEndpointsWithCompressionPerEndpoint() {
+	suite.config.Set("api_key", "123")
+	suite.config.Set("logs_config.use_compression", true)
+	suite.config.Set("logs_config.compression_level", 6)
+	suite.config.Set("logs_config.zstd_compression_level", 3)
+	suite.config.Set("logs_config.additional_endpoints", []map[string]interface{}{
+		{"api_key": "456", "host": "zstd.endpoint", "port": 1234, "compression_kind": "zstd"},
+		{"api_key": "789", "host": "zstd-level.endpoint", "port": 1234, "compression_kind": "zstd", "compression_level": 9},
+		{"api_key": "abc", "host": "default.endpoint", "port": 1234, "compression_level": 9},
+	})
+
+	endpoints, err := BuildHTTPEndpoints("test-track", "test-proto", "test-source")
+
+	suite.Nil(err)
+	suite.Equal(GzipCompressionKind, endpoints.Main.CompressionKind)
+	suite.Equal(6, endpoints.Main.CompressionLevel)
+	// the payloads are compressed once, with the compression of the main endpoint
+	for _, endpoint := range endpoints.Endpoints[1:] {
+		suite.Equal(GzipCompressionKind, endpoint.CompressionKind)
+		suite.Equal(6, endpoint.CompressionLevel)
+	}
+	for _, status := range endpoints.GetStatus() {
+		suite.Contains(status, "Sending gzip compressed logs in HTTPS")
+	}
+}
+
+func (suite *ConfigTestSuite) TestHTTPEndpointsWithInvalidCompressionKind() {
+	suite.config.Set("api_key", "123")
+	suite.config.Set("logs_config.use_compression", true)
+	suite.config.Set("logs_config.compression_level", 6)
+	suite.config.Set("logs_config.compression_kind", "lz4")
+
+	endpoints, err := BuildHTTPEndpoints("test-track", "test-proto", "test-source")
+
+	suite.Nil(err)
+	suite.Equal(GzipCompressionKind, endpoints.Main.CompressionKind)
+	suite.Equal(6, endpoints.Main.CompressionLevel)
+}
+
 func (suite *ConfigTestSuite) TestMultipleTCPEndpointsInConf() {
 	suite.config.Set("api_key", "123")
 	suite.config.Set("logs_config.logs_dd_url", "agent-http-intake.logs.datadoghq.com:443")
@@ -445,6 +541,7 @@ func (suite *ConfigTestSuite) TestEndpointsSetLogsDDUrl() {
 		Port:             443,
 		UseSSL:           true,
 		UseCompression:   true,
+		CompressionKind:  "gzip",
 		CompressionLevel: 6,
 		BackoffFactor:    coreConfig.DefaultLogsSenderBackoffFactor,
 		BackoffBase:      coreConfig.DefaultLogsSenderBackoffBase,
@@ -491,6 +588,7 @@ func (suite *ConfigTestSuite) TestEndpointsSetDDSite() {
 		Port:             0,
 		UseSSL:           true,
 		UseCompression:   true,
+		CompressionKind:  "gzip",
 		CompressionLevel: 6,
 		BackoffFactor:    coreConfig.DefaultLogsSenderBackoffFactor,
 		BackoffBase:      coreConfig.DefaultLogsSenderBackoffBase,
@@ -527,6 +625,7 @@ func (suite *ConfigTestSuite) TestBuildServerlessEndpoints() {
 		Port:             0,
 		UseSSL:           true,
 		UseCompression:   true,
+		CompressionKind:  "gzip",
 		CompressionLevel: 6,
 		BackoffFactor:    coreConfig.DefaultLogsSenderBackoffFactor,
 		BackoffBase:      coreConfig.DefaultLogsSenderBackoffBase,
@@ -562,6 +661,7 @@ func getTestEndpoint(host string, port int, ssl bool) Endpoint {
 		Port:             port,
 		UseSSL:           ssl,
 		UseCompression:   true,
+		CompressionKind:  "gzip",
 		CompressionLevel: 6,
 		BackoffFactor:    coreConfig.DefaultLogsSenderBackoffFactor,
 		BackoffBase:      coreConfig.DefaultLogsSenderBackoffBase,
@@ -24,6 +24,13 @@ type IntakeProtocol string
 // IntakeOrigin indicates the log source to use for an endpoint intake.
 type IntakeOrigin string
 
+const (
+	// GzipCompressionKind compresses the payloads with gzip
+	GzipCompressionKind = "gzip"
+	// ZstdCompressionKind compresses the payloads with zstd
+	ZstdCompressionKind = "zstd"
+)
+
 const (
 	_ EPIntakeVersion = iota
 	// EPIntakeVersion1 is version 1 of the envets platform intake API
@@ -38,8 +45,9 @@ type Endpoint struct {
 	Host                    string
 	Port                    int
 	UseSSL                  bool
-	UseCompression          bool `mapstructure:"use_compression" json:"use_compression"`
-	CompressionLevel        int  `mapstructure:"compression_level" json:"compression_level"`
+	UseCompression          bool   `mapstructure:"use_compression" json:"use_compression"`
+	CompressionKind         string `mapstructure:"compression_kind" json:"compression_kind"`
+	CompressionLevel        int    `mapstructure:"compression_level" json:"compression_level"`
 	ProxyAddress            string
 	IsReliable              bool `mapstructure:"is_reliable" json:"is_reliable"`
 	ConnectionResetInterval time.Duration
@@ -61,6 +69,9 @@ func (e *Endpoint) GetStatus(prefix string, useHTTP bool) string {
 	compression := "uncompressed"
 	if e.UseCompression {
 		compression = "compressed"
+		if e.CompressionKind != "" {
+			compression = e.CompressionKind + " " + compression
+		}
 	}
 
 	host := e.Host
@@ -103,6 +114,8 @@ type Endpoints struct {
 	BatchMaxConcurrentSend int
 	BatchMaxSize           int
 	BatchMaxContentSize    int
+	// Kafka is the optional Kafka destination the logs are also sent to.
+	Kafka *KafkaConfig
 }
 
 // GetStatus returns the endpoints status, one line per endpoint
@@ -114,6 +127,9 @@ func (e *Endpoints) GetStatus() []string {
 	for _, endpoint := range e.GetUnReliableEndpoints() {
 		result = append(result, endpoint.GetStatus("Unreliable: ", e.UseHTTP))
 	}
+	if e.Kafka != nil {
+		result = append(result, e.Kafka.GetStatus())
+	}
 	return result
 }
 
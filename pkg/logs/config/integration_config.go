@@ -30,6 +30,9 @@ const (
 	UTF16LE string = "utf-16-le"
 	// SHIFTJIS for Shift JIS (Japanese) encoding
 	SHIFTJIS string = "shift-jis"
+
+	// SyslogFormat for syslog messages (RFC 5424 or RFC 3164) received by network sources
+	SyslogFormat string = "syslog"
 )
 
 // LogsConfig represents a log source config, which can be for instance
@@ -39,8 +42,14 @@ type LogsConfig struct {
 
 	Port        int    // Network
 	IdleTimeout string `mapstructure:"idle_timeout" json:"idle_timeout"` // Network
+	Format      string `mapstructure:"format" json:"format"`             // Network
 	Path        string // File, Journald
 
+	TLSCert              string `mapstructure:"tls_cert" json:"tls_cert"`                               // TCP
+	TLSKey               string `mapstructure:"tls_key" json:"tls_key"`                                 // TCP
+	TLSCA                string `mapstructure:"tls_ca" json:"tls_ca"`                                   // TCP
+	TLSRequireClientCert bool   `mapstructure:"tls_require_client_cert" json:"tls_require_client_cert"` // TCP
+
 	Encoding     string   `mapstructure:"encoding" json:"encoding"`             // File
 	ExcludePaths []string `mapstructure:"exclude_paths" json:"exclude_paths"`   // File
 	TailingMode  string   `mapstructure:"start_position" json:"start_position"` // File
@@ -77,6 +86,7 @@ type LogsConfig struct {
 	SourceCategory  string
 	Tags            []string
 	ProcessingRules []*ProcessingRule `mapstructure:"log_processing_rules" json:"log_processing_rules"`
+	Sampling        *SamplingConfig   `mapstructure:"sampling" json:"sampling"`
 
 	AutoMultiLine               *bool   `mapstructure:"auto_multi_line_detection" json:"auto_multi_line_detection"`
 	AutoMultiLineSampleSize     int     `mapstructure:"auto_multi_line_sample_size" json:"auto_multi_line_sample_size"`
@@ -144,6 +154,17 @@ func (c *LogsConfig) Validate() error {
 		return fmt.Errorf("tcp source must have a port")
 	case c.Type == UDPType && c.Port == 0:
 		return fmt.Errorf("udp source must have a port")
+	case (c.Type == TCPType || c.Type == UDPType) && c.Format != "" && c.Format != SyslogFormat:
+		return fmt.Errorf("invalid format '%v' for %v source", c.Format, c.Type)
+	case c.Type == TCPType:
+		if err := c.validateTLS(); err != nil {
+			return err
+		}
+	}
+	if c.Sampling != nil {
+		if err := c.Sampling.Validate(); err != nil {
+			return err
+		}
 	}
 	err := ValidateProcessingRules(c.ProcessingRules)
 	if err != nil {
@@ -163,6 +184,24 @@ func (c *LogsConfig) validateTailingMode() error {
 	return nil
 }
 
+func (c *LogsConfig) validateTLS() error {
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return fmt.Errorf("tls_cert and tls_key must be set together for tcp source on port %d", c.Port)
+	}
+	if c.TLSCert == "" && (c.TLSCA != "" || c.TLSRequireClientCert) {
+		return fmt.Errorf("tls_ca and tls_require_client_cert require tls_cert and tls_key for tcp source on port %d", c.Port)
+	}
+	if c.TLSRequireClientCert && c.TLSCA == "" {
+		return fmt.Errorf("tls_require_client_cert requires tls_ca for tcp source on port %d", c.Port)
+	}
+	return nil
+}
+
+// TLSEnabled returns true if the source expects TLS connections.
+func (c *LogsConfig) TLSEnabled() bool {
+	return c.TLSCert != ""
+}
+
 // AutoMultiLineEnabled determines whether auto multi line detection is enabled for this config,
 // considering both the agent-wide logs_config.auto_multi_line_detection and any config for this
 // particular log source.
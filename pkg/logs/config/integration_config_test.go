@@ -21,7 +21,12 @@ func TestValidateShouldSucceedWithValidConfigs(t *testing.T) {
 		{Type: FileType, Path: "/var/log/foo.log"},
 		{Type: TCPType, Port: 1234},
 		{Type: UDPType, Port: 5678},
+		{Type: TCPType, Port: 1234, Format: SyslogFormat},
+		{Type: UDPType, Port: 5678, Format: SyslogFormat},
+		{Type: TCPType, Port: 1234, TLSCert: "cert.pem", TLSKey: "key.pem"},
+		{Type: TCPType, Port: 1234, TLSCert: "cert.pem", TLSKey: "key.pem", TLSCA: "ca.pem", TLSRequireClientCert: true},
 		{Type: DockerType},
+		{Type: DockerType, Sampling: &SamplingConfig{Rate: 0.1, MaxLinesPerSecond: 100, KeepStatus: "error"}},
 		{Type: JournaldType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: ExcludeAtMatch, Pattern: ".*"}}},
 		{Type: SnmpTrapsType},
 	}
@@ -38,6 +43,12 @@ func TestValidateShouldFailWithInvalidConfigs(t *testing.T) {
 		{Type: FileType},
 		{Type: TCPType},
 		{Type: UDPType},
+		{Type: TCPType, Port: 1234, Format: "gelf"},
+		{Type: TCPType, Port: 1234, TLSCert: "cert.pem"},
+		{Type: TCPType, Port: 1234, TLSCA: "ca.pem"},
+		{Type: TCPType, Port: 1234, TLSCert: "cert.pem", TLSKey: "key.pem", TLSRequireClientCert: true},
+		{Type: DockerType, Sampling: &SamplingConfig{Rate: 2}},
+		{Type: DockerType, Sampling: &SamplingConfig{KeepStatus: "fatal"}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo"}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: "bar"}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: ExcludeAtMatch}}},
@@ -0,0 +1,115 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
+)
+
+// Attributes the Kafka topic of a log can be derived from
+const (
+	KafkaTopicBySource  = "source"
+	KafkaTopicByService = "service"
+)
+
+// Keys of the Kafka records
+const (
+	KafkaKeyByHost      = "host"
+	KafkaKeyByContainer = "container"
+	KafkaKeyByNone      = "none"
+)
+
+// KafkaConfig holds the settings of the Kafka destination.
+type KafkaConfig struct {
+	// Brokers are the host:port addresses used to discover the cluster.
+	Brokers []string
+	// Topic is the topic of the records, suffixed with the source or the service of
+	// the logs depending on TopicBy.
+	Topic   string
+	TopicBy string
+	// KeyBy is the attribute the records are keyed, and thus partitioned, by.
+	KeyBy string
+	// RequiredAcks is the number of acknowledgements the brokers must receive before
+	// answering, 0 for none, 1 for the leader and -1 for all in-sync replicas.
+	RequiredAcks int
+	Timeout      time.Duration
+	// BatchWait, BatchMaxRecords and BatchMaxBytes bound the records produced at once.
+	BatchWait       time.Duration
+	BatchMaxRecords int
+	BatchMaxBytes   int
+	ClientID        string
+	UseSSL          bool
+	// IsReliable makes the Kafka destination block the pipelines while it is unavailable,
+	// like the main endpoint, instead of dropping the logs.
+	IsReliable bool
+}
+
+// BuildKafkaConfig returns the settings of the Kafka destination, or nil if it is disabled.
+func BuildKafkaConfig() (*KafkaConfig, error) {
+	brokers := coreConfig.Datadog.GetStringSlice("logs_config.kafka.brokers")
+	if len(brokers) == 0 {
+		return nil, nil
+	}
+	c := &KafkaConfig{
+		Brokers:         brokers,
+		Topic:           coreConfig.Datadog.GetString("logs_config.kafka.topic"),
+		TopicBy:         coreConfig.Datadog.GetString("logs_config.kafka.topic_by"),
+		KeyBy:           coreConfig.Datadog.GetString("logs_config.kafka.key_by"),
+		RequiredAcks:    coreConfig.Datadog.GetInt("logs_config.kafka.required_acks"),
+		Timeout:         time.Duration(coreConfig.Datadog.GetFloat64("logs_config.kafka.timeout") * float64(time.Second)),
+		BatchWait:       time.Duration(coreConfig.Datadog.GetFloat64("logs_config.kafka.batch_wait") * float64(time.Second)),
+		BatchMaxRecords: coreConfig.Datadog.GetInt("logs_config.kafka.batch_max_records"),
+		BatchMaxBytes:   coreConfig.Datadog.GetInt("logs_config.kafka.batch_max_bytes"),
+		ClientID:        coreConfig.Datadog.GetString("logs_config.kafka.client_id"),
+		UseSSL:          coreConfig.Datadog.GetBool("logs_config.kafka.use_ssl"),
+		IsReliable:      coreConfig.Datadog.GetBool("logs_config.kafka.is_reliable"),
+	}
+	return c, c.Validate()
+}
+
+// Validate returns an error if the settings of the Kafka destination are invalid.
+func (c *KafkaConfig) Validate() error {
+	for _, broker := range c.Brokers {
+		if _, _, err := net.SplitHostPort(broker); err != nil {
+			return fmt.Errorf("invalid kafka broker address %s, expected host:port", broker)
+		}
+	}
+	if c.Topic == "" {
+		return fmt.Errorf("a kafka topic must be set")
+	}
+	switch c.TopicBy {
+	case "", KafkaTopicBySource, KafkaTopicByService:
+		break
+	default:
+		return fmt.Errorf("invalid kafka topic_by %s, expected %s or %s", c.TopicBy, KafkaTopicBySource, KafkaTopicByService)
+	}
+	switch c.KeyBy {
+	case KafkaKeyByHost, KafkaKeyByContainer, KafkaKeyByNone:
+		break
+	default:
+		return fmt.Errorf("invalid kafka key_by %s, expected %s, %s or %s", c.KeyBy, KafkaKeyByHost, KafkaKeyByContainer, KafkaKeyByNone)
+	}
+	if c.RequiredAcks < -1 || c.RequiredAcks > 1 {
+		return fmt.Errorf("invalid kafka required_acks %d, expected -1, 0 or 1", c.RequiredAcks)
+	}
+	if c.Timeout <= 0 || c.BatchWait <= 0 || c.BatchMaxRecords <= 0 || c.BatchMaxBytes <= 0 {
+		return fmt.Errorf("kafka timeout, batch_wait, batch_max_records and batch_max_bytes must be positive")
+	}
+	return nil
+}
+
+// GetStatus returns the status of the Kafka destination.
+func (c *KafkaConfig) GetStatus() string {
+	reliability := "Unreliable"
+	if c.IsReliable {
+		reliability = "Reliable"
+	}
+	return fmt.Sprintf("%s: Sending logs to Kafka topic %s on brokers %v", reliability, c.Topic, c.Brokers)
+}
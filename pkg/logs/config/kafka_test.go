@@ -0,0 +1,74 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func TestBuildKafkaConfig(t *testing.T) {
+	mockConfig := coreConfig.Mock()
+
+	kafkaConfig, err := BuildKafkaConfig()
+	assert.NoError(t, err)
+	assert.Nil(t, kafkaConfig)
+
+	mockConfig.Set("logs_config.kafka.brokers", []string{"kafka-1:9092", "kafka-2:9092"})
+	mockConfig.Set("logs_config.kafka.topic_by", "service")
+	mockConfig.Set("logs_config.kafka.batch_wait", 0.5)
+	defer mockConfig.Set("logs_config.kafka.brokers", []string{})
+	defer mockConfig.Set("logs_config.kafka.topic_by", "")
+	defer mockConfig.Set("logs_config.kafka.batch_wait", 1)
+
+	kafkaConfig, err = BuildKafkaConfig()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kafka-1:9092", "kafka-2:9092"}, kafkaConfig.Brokers)
+	assert.Equal(t, "datadog-logs", kafkaConfig.Topic)
+	assert.Equal(t, KafkaTopicByService, kafkaConfig.TopicBy)
+	assert.Equal(t, KafkaKeyByHost, kafkaConfig.KeyBy)
+	assert.Equal(t, 1, kafkaConfig.RequiredAcks)
+	assert.Equal(t, 10*time.Second, kafkaConfig.Timeout)
+	assert.Equal(t, 500*time.Millisecond, kafkaConfig.BatchWait)
+	assert.False(t, kafkaConfig.IsReliable)
+	assert.Equal(t, "Unreliable: Sending logs to Kafka topic datadog-logs on brokers [kafka-1:9092 kafka-2:9092]", kafkaConfig.GetStatus())
+}
+
+func TestValidateKafkaConfig(t *testing.T) {
+	valid := func() *KafkaConfig {
+		return &KafkaConfig{
+			Brokers:         []string{"localhost:9092"},
+			Topic:           "logs",
+			KeyBy:           KafkaKeyByHost,
+			RequiredAcks:    -1,
+			Timeout:         time.Second,
+			BatchWait:       time.Second,
+			BatchMaxRecords: 1,
+			BatchMaxBytes:   1,
+		}
+	}
+	assert.NoError(t, valid().Validate())
+
+	invalids := []func(c *KafkaConfig){
+		func(c *KafkaConfig) { c.Brokers = []string{"localhost"} },
+		func(c *KafkaConfig) { c.Topic = "" },
+		func(c *KafkaConfig) { c.TopicBy = "host" },
+		func(c *KafkaConfig) { c.KeyBy = "" },
+		func(c *KafkaConfig) { c.RequiredAcks = 2 },
+		func(c *KafkaConfig) { c.Timeout = 0 },
+		func(c *KafkaConfig) { c.BatchMaxRecords = 0 },
+	}
+	for _, invalidate := range invalids {
+		c := valid()
+		invalidate(c)
+		assert.Error(t, c.Validate())
+	}
+}
@@ -8,6 +8,8 @@ package config
 import (
 	"fmt"
 	"regexp"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/grok"
 )
 
 // Processing rule types
@@ -16,6 +18,27 @@ const (
 	IncludeAtMatch = "include_at_match"
 	MaskSequences  = "mask_sequences"
 	MultiLine      = "multi_line"
+	JSONDropKeys   = "json_drop_keys"
+	JSONRenameKey  = "json_rename_key"
+	JSONMaskKeys   = "json_mask_keys"
+	JSONRemapKey   = "json_remap_key"
+	GenerateMetric = "generate_metric"
+	GrokParser     = "grok_parser"
+)
+
+// Attributes a json_remap_key rule can promote a key into
+const (
+	RemapStatus  = "status"
+	RemapService = "service"
+	RemapSource  = "source"
+	RemapTags    = "tags"
+)
+
+// Metric types a generate_metric rule can emit
+const (
+	MetricTypeCount        = "count"
+	MetricTypeGauge        = "gauge"
+	MetricTypeDistribution = "distribution"
 )
 
 // ProcessingRule defines an exclusion or a masking rule to
@@ -25,9 +48,32 @@ type ProcessingRule struct {
 	Name               string
 	ReplacePlaceholder string `mapstructure:"replace_placeholder" json:"replace_placeholder"`
 	Pattern            string
+	// Keys are the JSON keys targeted by json_* rules, nested keys are
+	// addressed with a dot-separated path (e.g. `http.request.headers`)
+	Keys []string
+	// Target is the new key name for json_rename_key rules, or the message
+	// attribute the key is promoted into for json_remap_key rules
+	Target string
+	// MetricName, MetricType and ValueGroup configure generate_metric rules: each
+	// line matching Pattern emits a MetricName metric of MetricType, its value is
+	// read from the ValueGroup named group, or 1 without value group, and the
+	// other named groups are turned into `<group>:<value>` tags
+	MetricName string `mapstructure:"metric_name" json:"metric_name"`
+	MetricType string `mapstructure:"metric_type" json:"metric_type"`
+	ValueGroup string `mapstructure:"value_group" json:"value_group"`
+	// CustomPatterns, TimestampField, TimestampLayout and StatusField configure grok_parser rules:
+	// each line matching the grok Pattern is replaced by a JSON object holding its fields, the
+	// patterns it references are looked up in CustomPatterns before the built-in library.
+	// The timestamp and the status of the message are read from the TimestampField and
+	// StatusField fields when set, TimestampLayout is a Go time layout
+	CustomPatterns  map[string]string `mapstructure:"custom_patterns" json:"custom_patterns"`
+	TimestampField  string            `mapstructure:"timestamp_field" json:"timestamp_field"`
+	TimestampLayout string            `mapstructure:"timestamp_layout" json:"timestamp_layout"`
+	StatusField     string            `mapstructure:"status_field" json:"status_field"`
 	// TODO: should be moved out
 	Regex       *regexp.Regexp
 	Placeholder []byte
+	Grok        *grok.Parser
 }
 
 // ValidateProcessingRules validates the rules and raises an error if one is misconfigured.
@@ -35,6 +81,9 @@ type ProcessingRule struct {
 // - a valid name
 // - a valid type
 // - a valid pattern that compiles
+// JSON rules need keys instead of a pattern, see validateJSONRule.
+// generate_metric rules also need a metric, see validateGenerateMetricRule.
+// grok_parser rules need a grok pattern, see validateGrokRule.
 func ValidateProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
 		if rule.Name == "" {
@@ -42,8 +91,18 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		}
 
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine:
+		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine, GenerateMetric:
 			break
+		case JSONDropKeys, JSONRenameKey, JSONMaskKeys, JSONRemapKey:
+			if err := validateJSONRule(rule); err != nil {
+				return err
+			}
+			continue
+		case GrokParser:
+			if err := validateGrokRule(rule); err != nil {
+				return err
+			}
+			continue
 		case "":
 			return fmt.Errorf("type must be set for processing rule `%s`", rule.Name)
 		default:
@@ -53,10 +112,82 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		if rule.Pattern == "" {
 			return fmt.Errorf("no pattern provided for processing rule: %s", rule.Name)
 		}
-		_, err := regexp.Compile(rule.Pattern)
+		re, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return fmt.Errorf("invalid pattern %s for processing rule: %s", rule.Pattern, rule.Name)
 		}
+		if rule.Type == GenerateMetric {
+			if err := validateGenerateMetricRule(rule, re); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateGenerateMetricRule validates the metric emitted by a generate_metric rule.
+func validateGenerateMetricRule(rule *ProcessingRule, re *regexp.Regexp) error {
+	if rule.MetricName == "" {
+		return fmt.Errorf("no metric name provided for processing rule: %s", rule.Name)
+	}
+	switch rule.MetricType {
+	case "", MetricTypeCount:
+		break
+	case MetricTypeGauge, MetricTypeDistribution:
+		if rule.ValueGroup == "" {
+			return fmt.Errorf("a value group must be provided for %s metrics in processing rule: %s", rule.MetricType, rule.Name)
+		}
+	default:
+		return fmt.Errorf("invalid metric type %s for processing rule: %s", rule.MetricType, rule.Name)
+	}
+	if rule.ValueGroup != "" && re.SubexpIndex(rule.ValueGroup) == -1 {
+		return fmt.Errorf("value group %s is not a named group of the pattern of processing rule: %s", rule.ValueGroup, rule.Name)
+	}
+	return nil
+}
+
+// validateGrokRule validates a rule parsing the content of a message with a grok pattern.
+func validateGrokRule(rule *ProcessingRule) error {
+	if rule.Pattern == "" {
+		return fmt.Errorf("no pattern provided for processing rule: %s", rule.Name)
+	}
+	if _, err := grok.Compile(rule.Pattern, rule.CustomPatterns); err != nil {
+		return fmt.Errorf("invalid grok pattern %s for processing rule: %s: %v", rule.Pattern, rule.Name, err)
+	}
+	if rule.TimestampLayout != "" && rule.TimestampField == "" {
+		return fmt.Errorf("a timestamp field must be provided with the timestamp layout of processing rule: %s", rule.Name)
+	}
+	return nil
+}
+
+// validateJSONRule validates a rule operating on the JSON representation of a message.
+func validateJSONRule(rule *ProcessingRule) error {
+	if len(rule.Keys) == 0 {
+		return fmt.Errorf("no keys provided for processing rule: %s", rule.Name)
+	}
+	for _, key := range rule.Keys {
+		if key == "" {
+			return fmt.Errorf("empty key provided for processing rule: %s", rule.Name)
+		}
+	}
+	switch rule.Type {
+	case JSONRenameKey:
+		if len(rule.Keys) != 1 {
+			return fmt.Errorf("exactly one key must be provided for processing rule: %s", rule.Name)
+		}
+		if rule.Target == "" {
+			return fmt.Errorf("no target provided for processing rule: %s", rule.Name)
+		}
+	case JSONRemapKey:
+		if len(rule.Keys) != 1 {
+			return fmt.Errorf("exactly one key must be provided for processing rule: %s", rule.Name)
+		}
+		switch rule.Target {
+		case RemapStatus, RemapService, RemapSource, RemapTags:
+			break
+		default:
+			return fmt.Errorf("invalid target %s for processing rule: %s", rule.Target, rule.Name)
+		}
 	}
 	return nil
 }
@@ -64,12 +195,26 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 // CompileProcessingRules compiles all processing rule regular expressions.
 func CompileProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
+		switch rule.Type {
+		case JSONDropKeys, JSONRenameKey, JSONRemapKey:
+			continue
+		case JSONMaskKeys:
+			rule.Placeholder = []byte(rule.ReplacePlaceholder)
+			continue
+		case GrokParser:
+			parser, err := grok.Compile(rule.Pattern, rule.CustomPatterns)
+			if err != nil {
+				return err
+			}
+			rule.Grok = parser
+			continue
+		}
 		re, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return err
 		}
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch:
+		case ExcludeAtMatch, IncludeAtMatch, GenerateMetric:
 			rule.Regex = re
 		case MaskSequences:
 			rule.Regex = re
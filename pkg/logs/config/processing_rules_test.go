@@ -34,3 +34,70 @@ func TestCompileShouldFailWithInvalidRules(t *testing.T) {
 		assert.Nil(t, rule.Regex)
 	}
 }
+
+func TestValidateJSONRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "drop", Type: JSONDropKeys, Keys: []string{"password", "http.headers"}},
+		{Name: "mask", Type: JSONMaskKeys, Keys: []string{"token"}, ReplacePlaceholder: "****"},
+		{Name: "rename", Type: JSONRenameKey, Keys: []string{"msg"}, Target: "message"},
+		{Name: "remap", Type: JSONRemapKey, Keys: []string{"level"}, Target: RemapStatus},
+	}
+	assert.Nil(t, ValidateProcessingRules(validRules))
+	assert.Nil(t, CompileProcessingRules(validRules))
+	assert.Equal(t, []byte("****"), validRules[1].Placeholder)
+
+	invalidRules := []*ProcessingRule{
+		{Name: "no_keys", Type: JSONDropKeys},
+		{Name: "empty_key", Type: JSONMaskKeys, Keys: []string{""}},
+		{Name: "rename_no_target", Type: JSONRenameKey, Keys: []string{"msg"}},
+		{Name: "rename_many_keys", Type: JSONRenameKey, Keys: []string{"a", "b"}, Target: "c"},
+		{Name: "remap_bad_target", Type: JSONRemapKey, Keys: []string{"level"}, Target: "hostname"},
+	}
+	for _, rule := range invalidRules {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
+
+func TestValidateGenerateMetricRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "count", Type: GenerateMetric, Pattern: "ERROR", MetricName: "app.errors"},
+		{Name: "count_tags", Type: GenerateMetric, Pattern: "status=(?P<status>\\d+)", MetricName: "app.requests", MetricType: MetricTypeCount},
+		{Name: "gauge", Type: GenerateMetric, Pattern: "queue=(?P<size>\\d+)", MetricName: "app.queue", MetricType: MetricTypeGauge, ValueGroup: "size"},
+		{Name: "distribution", Type: GenerateMetric, Pattern: "took (?P<duration>[\\d.]+)ms", MetricName: "app.duration", MetricType: MetricTypeDistribution, ValueGroup: "duration"},
+	}
+	assert.Nil(t, ValidateProcessingRules(validRules))
+	assert.Nil(t, CompileProcessingRules(validRules))
+	assert.True(t, validRules[0].Regex.MatchString("an ERROR"))
+
+	invalidRules := []*ProcessingRule{
+		{Name: "no_pattern", Type: GenerateMetric, MetricName: "app.errors"},
+		{Name: "no_metric", Type: GenerateMetric, Pattern: "ERROR"},
+		{Name: "bad_type", Type: GenerateMetric, Pattern: "ERROR", MetricName: "app.errors", MetricType: "rate"},
+		{Name: "gauge_no_value", Type: GenerateMetric, Pattern: "queue=(?P<size>\\d+)", MetricName: "app.queue", MetricType: MetricTypeGauge},
+		{Name: "unknown_group", Type: GenerateMetric, Pattern: "queue=(?P<size>\\d+)", MetricName: "app.queue", MetricType: MetricTypeGauge, ValueGroup: "length"},
+	}
+	for _, rule := range invalidRules {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
+
+func TestValidateGrokRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "access", Type: GrokParser, Pattern: "%{COMMONAPACHELOG}", TimestampField: "timestamp", TimestampLayout: "02/Jan/2006:15:04:05 -0700"},
+		{Name: "custom", Type: GrokParser, Pattern: "%{ACTION:action} by %{WORD:user}", CustomPatterns: map[string]string{"ACTION": "(?:login|logout)"}},
+	}
+	assert.Nil(t, ValidateProcessingRules(validRules))
+	assert.Nil(t, CompileProcessingRules(validRules))
+	fields, ok := validRules[1].Grok.Parse([]byte("login by alice"))
+	assert.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"action": "login", "user": "alice"}, fields)
+
+	invalidRules := []*ProcessingRule{
+		{Name: "no_pattern", Type: GrokParser},
+		{Name: "unknown_pattern", Type: GrokParser, Pattern: "%{ACTION:action}"},
+		{Name: "layout_no_field", Type: GrokParser, Pattern: "%{GREEDYDATA:message}", TimestampLayout: "2006-01-02"},
+	}
+	for _, rule := range invalidRules {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
@@ -0,0 +1,170 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+)
+
+// Reasons for which the sampler drops a line
+const (
+	sampledOutReason  = "sampled_out"
+	rateLimitedReason = "rate_limited"
+)
+
+var tlmSamplerDropped = telemetry.NewCounter("logs_sampler", "dropped", []string{"reason"}, "Number of log lines dropped by the sampling of the sources")
+
+// statusLevels holds the statuses of the messages, from the most to the least severe.
+var statusLevels = []string{"emergency", "alert", "critical", "error", "warn", "notice", "info", "debug"}
+
+// SamplingConfig limits the volume of logs sent by a source.
+type SamplingConfig struct {
+	// Rate is the ratio of lines kept, between 0 and 1, 0 disables the ratio sampling.
+	Rate float64 `mapstructure:"rate" json:"rate"`
+	// MaxLinesPerSecond and MaxBytesPerSecond cap the throughput of the source,
+	// allowing bursts of up to one second worth of logs. 0 means no limit.
+	MaxLinesPerSecond float64 `mapstructure:"max_lines_per_second" json:"max_lines_per_second"`
+	MaxBytesPerSecond float64 `mapstructure:"max_bytes_per_second" json:"max_bytes_per_second"`
+	// KeepStatus exempts the lines with this status, or a more severe one, from sampling.
+	KeepStatus string `mapstructure:"keep_status" json:"keep_status"`
+}
+
+// Validate returns an error if the sampling options are invalid.
+func (c *SamplingConfig) Validate() error {
+	if c.Rate < 0 || c.Rate > 1 {
+		return fmt.Errorf("sampling rate must be between 0 and 1, got %v", c.Rate)
+	}
+	if c.MaxLinesPerSecond < 0 || c.MaxBytesPerSecond < 0 {
+		return fmt.Errorf("sampling max_lines_per_second and max_bytes_per_second must be positive")
+	}
+	if c.KeepStatus != "" && statusLevel(c.KeepStatus) == -1 {
+		return fmt.Errorf("invalid sampling keep_status '%v'", c.KeepStatus)
+	}
+	return nil
+}
+
+// Sampler drops the lines of a source exceeding its sampling options,
+// it is safe for concurrent use.
+type Sampler struct {
+	config    SamplingConfig
+	keepLevel int
+	now       func() time.Time
+
+	mu         sync.Mutex
+	rateCredit float64
+	lineTokens tokenBucket
+	byteTokens tokenBucket
+
+	sampledOut  *CountInfo
+	rateLimited *CountInfo
+}
+
+// NewSampler returns a sampler reporting the dropped lines in sampledOut and rateLimited.
+func NewSampler(config SamplingConfig, sampledOut *CountInfo, rateLimited *CountInfo) *Sampler {
+	return newSamplerWithClock(config, sampledOut, rateLimited, time.Now)
+}
+
+func newSamplerWithClock(config SamplingConfig, sampledOut *CountInfo, rateLimited *CountInfo, now func() time.Time) *Sampler {
+	keepLevel := -1
+	if config.KeepStatus != "" {
+		keepLevel = statusLevel(config.KeepStatus)
+	}
+	return &Sampler{
+		config:      config,
+		keepLevel:   keepLevel,
+		now:         now,
+		lineTokens:  newTokenBucket(config.MaxLinesPerSecond, now()),
+		byteTokens:  newTokenBucket(config.MaxBytesPerSecond, now()),
+		sampledOut:  sampledOut,
+		rateLimited: rateLimited,
+	}
+}
+
+// Keep returns true if a line with this status and size in bytes must be kept.
+func (s *Sampler) Keep(status string, size int) bool {
+	if s.keepLevel != -1 {
+		if level := statusLevel(status); level != -1 && level <= s.keepLevel {
+			return true
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.config.Rate > 0 && s.config.Rate < 1 {
+		// keep exactly Rate of the lines, evenly spread
+		s.rateCredit += s.config.Rate
+		if s.rateCredit < 1 {
+			s.sampledOut.Add(1)
+			tlmSamplerDropped.Inc(sampledOutReason)
+			return false
+		}
+		s.rateCredit--
+	}
+
+	now := s.now()
+	if !s.lineTokens.allow(1, now) || !s.byteTokens.allow(float64(size), now) {
+		s.rateLimited.Add(1)
+		tlmSamplerDropped.Inc(rateLimitedReason)
+		return false
+	}
+	s.lineTokens.take(1)
+	s.byteTokens.take(float64(size))
+	return true
+}
+
+// tokenBucket refills at rate tokens per second, up to rate tokens.
+// A zero rate disables the bucket.
+type tokenBucket struct {
+	rate       float64
+	tokens     float64
+	lastRefill time.Time
+}
+
+func newTokenBucket(rate float64, now time.Time) tokenBucket {
+	return tokenBucket{
+		rate:       rate,
+		tokens:     rate,
+		lastRefill: now,
+	}
+}
+
+// allow refills the bucket and returns true if it holds enough tokens,
+// a request larger than the bucket is allowed once the bucket is full.
+func (b *tokenBucket) allow(n float64, now time.Time) bool {
+	if b.rate == 0 {
+		return true
+	}
+	if elapsed := now.Sub(b.lastRefill).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * b.rate
+		if b.tokens > b.rate {
+			b.tokens = b.rate
+		}
+		b.lastRefill = now
+	}
+	return b.tokens >= n || b.tokens == b.rate
+}
+
+func (b *tokenBucket) take(n float64) {
+	if b.rate == 0 {
+		return
+	}
+	b.tokens -= n
+}
+
+// statusLevel returns the severity rank of a status, 0 being the most severe, or -1 if it is unknown.
+func statusLevel(status string) int {
+	for i, s := range statusLevels {
+		if s == status {
+			return i
+		}
+	}
+	return -1
+}
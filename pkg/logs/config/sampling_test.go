@@ -0,0 +1,126 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestSampler(config SamplingConfig) (*Sampler, *fakeClock) {
+	clock := &fakeClock{now: time.Now()}
+	return newSamplerWithClock(config, NewCountInfo("Sampled Out"), NewCountInfo("Rate Limited"), clock.Now), clock
+}
+
+func TestSamplingConfigValidate(t *testing.T) {
+	assert.NoError(t, (&SamplingConfig{}).Validate())
+	assert.NoError(t, (&SamplingConfig{Rate: 0.5, MaxLinesPerSecond: 10, MaxBytesPerSecond: 1000, KeepStatus: "error"}).Validate())
+	assert.Error(t, (&SamplingConfig{Rate: 1.5}).Validate())
+	assert.Error(t, (&SamplingConfig{Rate: -1}).Validate())
+	assert.Error(t, (&SamplingConfig{MaxLinesPerSecond: -1}).Validate())
+	assert.Error(t, (&SamplingConfig{MaxBytesPerSecond: -1}).Validate())
+	assert.Error(t, (&SamplingConfig{KeepStatus: "fatal"}).Validate())
+}
+
+func TestSamplerRate(t *testing.T) {
+	sampler, _ := newTestSampler(SamplingConfig{Rate: 0.25})
+
+	kept := 0
+	for i := 0; i < 100; i++ {
+		if sampler.Keep("info", 10) {
+			kept++
+		}
+	}
+	assert.Equal(t, 25, kept)
+	assert.Equal(t, []string{"75"}, sampler.sampledOut.Info())
+	assert.Equal(t, []string{"0"}, sampler.rateLimited.Info())
+}
+
+func TestSamplerMaxLinesPerSecond(t *testing.T) {
+	sampler, clock := newTestSampler(SamplingConfig{MaxLinesPerSecond: 10})
+
+	for i := 0; i < 10; i++ {
+		assert.True(t, sampler.Keep("info", 10))
+	}
+	assert.False(t, sampler.Keep("info", 10))
+
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		assert.True(t, sampler.Keep("info", 10))
+	}
+	assert.False(t, sampler.Keep("info", 10))
+
+	// the bucket does not hold more than one second worth of lines
+	clock.now = clock.now.Add(time.Hour)
+	for i := 0; i < 10; i++ {
+		assert.True(t, sampler.Keep("info", 10))
+	}
+	assert.False(t, sampler.Keep("info", 10))
+	assert.Equal(t, []string{"3"}, sampler.rateLimited.Info())
+}
+
+func TestSamplerMaxBytesPerSecond(t *testing.T) {
+	sampler, clock := newTestSampler(SamplingConfig{MaxBytesPerSecond: 100})
+
+	assert.True(t, sampler.Keep("info", 60))
+	assert.True(t, sampler.Keep("info", 40))
+	assert.False(t, sampler.Keep("info", 1))
+
+	// a line larger than the limit is kept once the bucket is full
+	clock.now = clock.now.Add(time.Second)
+	assert.True(t, sampler.Keep("info", 250))
+	clock.now = clock.now.Add(time.Second)
+	assert.False(t, sampler.Keep("info", 1))
+	clock.now = clock.now.Add(2 * time.Second)
+	assert.True(t, sampler.Keep("info", 1))
+}
+
+func TestSamplerKeepStatus(t *testing.T) {
+	sampler, _ := newTestSampler(SamplingConfig{Rate: 0.5, MaxLinesPerSecond: 1, KeepStatus: "error"})
+
+	assert.False(t, sampler.Keep("info", 10))
+	assert.True(t, sampler.Keep("info", 10))
+	assert.False(t, sampler.Keep("info", 10))
+	assert.False(t, sampler.Keep("info", 10))
+	for _, status := range []string{"emergency", "alert", "critical", "error"} {
+		assert.True(t, sampler.Keep(status, 10), status)
+	}
+	assert.False(t, sampler.Keep("warn", 10))
+	assert.Equal(t, []string{"3"}, sampler.sampledOut.Info())
+	assert.Equal(t, []string{"1"}, sampler.rateLimited.Info())
+}
+
+func TestLogSourceSampler(t *testing.T) {
+	assert.Nil(t, NewLogSource("", &LogsConfig{}).GetSampler())
+
+	parent := NewLogSource("parent", &LogsConfig{Sampling: &SamplingConfig{MaxLinesPerSecond: 1}})
+	child1 := NewLogSource("child", parent.Config)
+	child1.ParentSource = parent
+	child2 := NewLogSource("child", parent.Config)
+	child2.ParentSource = parent
+
+	sampler := child1.GetSampler()
+	assert.NotNil(t, sampler)
+	assert.Same(t, sampler, child1.GetSampler())
+
+	// each source has its own limit, the dropped lines are reported on the parent
+	assert.True(t, child1.GetSampler().Keep("info", 10))
+	assert.False(t, child1.GetSampler().Keep("info", 10))
+	assert.True(t, child2.GetSampler().Keep("info", 10))
+	assert.False(t, child2.GetSampler().Keep("info", 10))
+	assert.Equal(t, map[string][]string{"Sampled Out": {"0"}, "Rate Limited": {"2"}}, parent.GetInfoStatus())
+	assert.Empty(t, child1.GetInfoStatus())
+}
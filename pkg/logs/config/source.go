@@ -46,6 +46,7 @@ type LogSource struct {
 	LatencyStats     *util.StatsTracker
 	BytesRead        *atomic.Int64
 	hiddenFromStatus bool
+	sampler          *Sampler
 }
 
 // NewLogSource creates a new log source.
@@ -132,6 +133,45 @@ func (s *LogSource) GetInfoStatus() map[string][]string {
 	return info
 }
 
+// GetSampler returns the sampler of the source, or nil if the source is not sampled.
+// The lines dropped by the sampler are reported on the status page of the source,
+// or of its parent.
+func (s *LogSource) GetSampler() *Sampler {
+	if s.Config == nil || s.Config.Sampling == nil {
+		return nil
+	}
+	s.lock.Lock()
+	sampler := s.sampler
+	s.lock.Unlock()
+	if sampler != nil {
+		return sampler
+	}
+
+	sampler = NewSampler(*s.Config.Sampling, s.countInfo("Sampled Out"), s.countInfo("Rate Limited"))
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.sampler == nil {
+		s.sampler = sampler
+	}
+	return s.sampler
+}
+
+// countInfo returns the CountInfo registered with this key on the source displayed on the status page,
+// registering a new one if needed.
+func (s *LogSource) countInfo(key string) *CountInfo {
+	if s.ParentSource != nil {
+		return s.ParentSource.countInfo(key)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if info, ok := s.info[key].(*CountInfo); ok {
+		return info
+	}
+	info := NewCountInfo(key)
+	s.info[key] = info
+	return info
+}
+
 // HideFromStatus hides the source from the status output
 func (s *LogSource) HideFromStatus() {
 	s.lock.Lock()
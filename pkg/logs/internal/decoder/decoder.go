@@ -39,6 +39,9 @@ type Message struct {
 	RawDataLen         int
 	Timestamp          string
 	IngestionTimestamp int64
+	// Hostname and Tags are only set by parsers extracting them from the line
+	Hostname string
+	Tags     []string
 }
 
 // NewMessage returns a new output.
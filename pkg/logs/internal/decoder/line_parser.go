@@ -58,7 +58,10 @@ func (p *SingleLineParser) process(content []byte, rawDataLen int) {
 	if err != nil {
 		log.Debug(err)
 	}
-	p.outputFn(NewMessage(msg.Content, msg.Status, rawDataLen, msg.Timestamp))
+	output := NewMessage(msg.Content, msg.Status, rawDataLen, msg.Timestamp)
+	output.Hostname = msg.Hostname
+	output.Tags = msg.Tags
+	p.outputFn(output)
 }
 
 // MultiLineParser makes sure that chunked lines are properly put together.
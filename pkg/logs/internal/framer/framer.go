@@ -38,6 +38,13 @@ const (
 	// headers are included in the log frame.  The size in those headers is not
 	// consulted.  The result does not include the trailing newlines.
 	DockerStream
+
+	// Syslog messages, either octet-counted or newline-terminated (RFC 6587).
+	Syslog
+
+	// Syslog messages, one per chunk of data given to Process, as sent in
+	// datagrams (RFC 5426).  A trailing newline is not part of the message.
+	SyslogDatagram
 )
 
 // Framer gets chunks of bytes (via Process(..)) and uses an
@@ -95,6 +102,10 @@ func NewFramer(
 		matcher = &oneByteNewLineMatcher{contentLenLimit}
 	case DockerStream:
 		matcher = &dockerStreamMatcher{contentLenLimit}
+	case Syslog:
+		matcher = &syslogMatcher{contentLenLimit: contentLenLimit}
+	case SyslogDatagram:
+		matcher = &datagramMatcher{contentLenLimit}
 	default:
 		panic(fmt.Sprintf("unknown framing %d", framing))
 	}
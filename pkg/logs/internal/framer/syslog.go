@@ -0,0 +1,116 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package framer
+
+import (
+	"bytes"
+)
+
+// maxOctetCountDigits is the maximum number of digits accepted in the
+// MSG-LEN header of an octet-counted frame.
+const maxOctetCountDigits = 9
+
+// syslogMatcher finds syslog frames, using octet counting (RFC 6587, section 3.4.1)
+// when a frame starts with a digit, and newline termination otherwise (RFC 6587,
+// section 3.4.2).  Both framings can be mixed on the same stream.
+type syslogMatcher struct {
+	// contentLenLimit is the maximum content length that will be returned.
+	// Octet-counted messages longer than this value are truncated.
+	contentLenLimit int
+
+	// discard is the number of bytes of a truncated octet-counted message
+	// still to be skipped.
+	discard int
+}
+
+func (sm *syslogMatcher) FindFrame(buf []byte, seen int) ([]byte, int) {
+	if sm.discard > 0 {
+		// skip the remainder of a truncated message, producing an empty frame
+		n := sm.discard
+		if n > len(buf) {
+			n = len(buf)
+		}
+		sm.discard -= n
+		return buf[:0], n
+	}
+
+	msgLen, headerLen, ok := parseOctetCount(buf)
+	if !ok {
+		return sm.findNewLine(buf, seen)
+	}
+	if headerLen == 0 {
+		// the header is not complete yet
+		return nil, 0
+	}
+
+	if headerLen+msgLen > sm.contentLenLimit {
+		// keep the frame within contentLenLimit bytes, so that the framer does not
+		// break it on its own and loses track of the frame boundaries
+		if len(buf) < sm.contentLenLimit {
+			return nil, 0
+		}
+		sm.discard = headerLen + msgLen - sm.contentLenLimit
+		return buf[headerLen:sm.contentLenLimit], sm.contentLenLimit
+	}
+	if len(buf) < headerLen+msgLen {
+		return nil, 0
+	}
+	content := bytes.TrimSuffix(buf[headerLen:headerLen+msgLen], []byte{'\n'})
+	return content, headerLen + msgLen
+}
+
+// findNewLine finds a newline-terminated frame.
+func (sm *syslogMatcher) findNewLine(buf []byte, seen int) ([]byte, int) {
+	nl := bytes.IndexByte(buf[seen:], '\n')
+	if nl == -1 {
+		return nil, 0
+	}
+	eol := nl + seen
+	if eol > sm.contentLenLimit {
+		return buf[:sm.contentLenLimit], sm.contentLenLimit
+	}
+	return buf[:eol], eol + 1
+}
+
+// datagramMatcher frames each chunk of data as one message, syslog messages sent in
+// datagrams are neither octet-counted nor newline-terminated, and may contain newlines
+// (RFC 5426, section 3.1).  It relies on the framer being given one datagram at a time.
+type datagramMatcher struct {
+	// contentLenLimit is the maximum content length that will be returned.
+	// Longer messages are truncated.
+	contentLenLimit int
+}
+
+func (dm *datagramMatcher) FindFrame(buf []byte, seen int) ([]byte, int) {
+	content := bytes.TrimSuffix(buf, []byte{'\n'})
+	if len(content) > dm.contentLenLimit {
+		content = content[:dm.contentLenLimit]
+	}
+	return content, len(buf)
+}
+
+// parseOctetCount parses the `MSG-LEN SP` header of an octet-counted frame.  It returns
+// false if buf does not start with such a header, and a zero headerLen if buf starts with
+// a header which is not complete yet.
+func parseOctetCount(buf []byte) (msgLen int, headerLen int, ok bool) {
+	if len(buf) == 0 || buf[0] < '1' || buf[0] > '9' {
+		return 0, 0, false
+	}
+	for i, b := range buf {
+		switch {
+		case b >= '0' && b <= '9':
+			if i >= maxOctetCountDigits {
+				return 0, 0, false
+			}
+			msgLen = msgLen*10 + int(b-'0')
+		case b == ' ':
+			return msgLen, i + 1, true
+		default:
+			return 0, 0, false
+		}
+	}
+	return 0, 0, true
+}
@@ -0,0 +1,79 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package framer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyslogFraming(t *testing.T) {
+	test := func(input []byte, chunkSize int, limit int, lines []string, rawLens []int) func(*testing.T) {
+		return func(t *testing.T) {
+			gotContent := []string{}
+			gotLens := []int{}
+			outputFn := func(content []byte, rawDataLen int) {
+				gotContent = append(gotContent, string(content))
+				gotLens = append(gotLens, rawDataLen)
+			}
+			framer := NewFramer(outputFn, Syslog, limit)
+			for len(input) > 0 {
+				n := chunkSize
+				if n > len(input) {
+					n = len(input)
+				}
+				framer.Process(input[:n])
+				input = input[n:]
+			}
+			require.Equal(t, lines, gotContent)
+			require.Equal(t, rawLens, gotLens)
+		}
+	}
+
+	octetCounted := []byte("5 <1>ab11 <2>line\nfoo6 <3>x\ny")
+	t.Run("octet counted", test(octetCounted, len(octetCounted), contentLenLimit,
+		[]string{"<1>ab", "<2>line\nfoo", "<3>x\ny"}, []int{7, 14, 8}))
+	t.Run("octet counted one-byte chunks", test(octetCounted, 1, contentLenLimit,
+		[]string{"<1>ab", "<2>line\nfoo", "<3>x\ny"}, []int{7, 14, 8}))
+	t.Run("octet counted trailing newline", test([]byte("6 <1>ab\n6 <2>cd\n"), 3, contentLenLimit,
+		[]string{"<1>ab", "<2>cd"}, []int{8, 8}))
+
+	newline := []byte("<1>ab\n<2>cd\n")
+	t.Run("newline", test(newline, len(newline), contentLenLimit,
+		[]string{"<1>ab", "<2>cd"}, []int{6, 6}))
+
+	mixed := []byte("<1>ab\n5 <2>cd<3>ef\n")
+	t.Run("mixed", test(mixed, 2, contentLenLimit,
+		[]string{"<1>ab", "<2>cd", "<3>ef"}, []int{6, 7, 6}))
+
+	// a message longer than the limit is truncated and its remainder skipped
+	truncated := []byte("12 <1>abcdefghi5 <2>cd")
+	t.Run("truncated", test(truncated, len(truncated), 10,
+		[]string{"<1>abcd", "", "<2>cd"}, []int{10, 5, 7}))
+	t.Run("truncated one-byte chunks", test(truncated, 1, 10,
+		[]string{"<1>abcd", "", "", "", "", "", "<2>cd"}, []int{10, 1, 1, 1, 1, 1, 7}))
+}
+
+func TestSyslogDatagramFraming(t *testing.T) {
+	gotContent := []string{}
+	gotLens := []int{}
+	outputFn := func(content []byte, rawDataLen int) {
+		gotContent = append(gotContent, string(content))
+		gotLens = append(gotLens, rawDataLen)
+	}
+	framer := NewFramer(outputFn, SyslogDatagram, 10)
+	for _, datagram := range []string{
+		"<1>ab\ncd\n",   // newlines are part of the message
+		"12 <2>ef",      // not octet-counted
+		"<3>abcdefghij", // truncated
+		"<4>gh",         // no trailing newline
+	} {
+		framer.Process([]byte(datagram))
+	}
+	require.Equal(t, []string{"<1>ab\ncd", "12 <2>ef", "<3>abcdefg", "<4>gh"}, gotContent)
+	require.Equal(t, []int{9, 8, 13, 5}, gotLens)
+}
@@ -0,0 +1,191 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package grok parses unstructured log lines with grok patterns, regular
+// expressions referencing named patterns with the `%{PATTERN:field:type}` syntax.
+package grok
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Types a field can be converted into, fields are strings by default
+const (
+	IntType   = "int"
+	FloatType = "float"
+)
+
+// maxDepth bounds the nesting of pattern references, it protects against recursive patterns.
+const maxDepth = 32
+
+// referenceRegex matches the `%{PATTERN}`, `%{PATTERN:field}` and `%{PATTERN:field:type}` references.
+var referenceRegex = regexp.MustCompile(`%\{(\w+)(?::([\w.@-]+))?(?::(\w+))?\}`)
+
+// timestampLayouts are the layouts tried when parsing a timestamp without explicit layout,
+// they cover the timestamps of the built-in patterns.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05Z0700",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"02/Jan/2006:15:04:05 -0700",
+	"2006/01/02 15:04:05",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// field is a value extracted by a pattern reference.
+type field struct {
+	name  string
+	group string
+	kind  string
+	index int
+}
+
+// Parser extracts the fields of the lines matching a grok pattern.
+type Parser struct {
+	regex  *regexp.Regexp
+	fields []field
+}
+
+// Compile expands the pattern references of pattern, looking them up in customPatterns
+// first and in the built-in library otherwise, and returns its parser.
+func Compile(pattern string, customPatterns map[string]string) (*Parser, error) {
+	c := compiler{customPatterns: customPatterns}
+	expanded, err := c.expand(pattern, 0)
+	if err != nil {
+		return nil, err
+	}
+	regex, err := regexp.Compile("^" + expanded + "$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid grok pattern %s: %v", pattern, err)
+	}
+	for i := range c.fields {
+		c.fields[i].index = regex.SubexpIndex(c.fields[i].group)
+	}
+	return &Parser{
+		regex:  regex,
+		fields: c.fields,
+	}, nil
+}
+
+// Parse returns the fields extracted from line, or false if line does not match the pattern.
+// Fields with a dot-separated name are nested into objects, fields that did not participate
+// in the match are omitted.
+func (p *Parser) Parse(line []byte) (map[string]interface{}, bool) {
+	match := p.regex.FindSubmatch(line)
+	if match == nil {
+		return nil, false
+	}
+	fields := make(map[string]interface{})
+	for _, f := range p.fields {
+		value := match[f.index]
+		if value == nil {
+			continue
+		}
+		setField(fields, f.name, convert(string(value), f.kind))
+	}
+	return fields, true
+}
+
+// ParseTimestamp parses value with layout, or with the layouts of the built-in timestamps
+// when layout is empty. Timestamps without time zone are assumed to be UTC.
+func ParseTimestamp(value string, layout string) (time.Time, error) {
+	if layout != "" {
+		return time.Parse(layout, value)
+	}
+	for _, layout := range timestampLayouts {
+		if ts, err := time.Parse(layout, value); err == nil {
+			return ts, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown timestamp format: %s", value)
+}
+
+// compiler expands the pattern references into named groups.
+type compiler struct {
+	customPatterns map[string]string
+	fields         []field
+}
+
+// expand replaces the pattern references of pattern by their definition.
+func (c *compiler) expand(pattern string, depth int) (string, error) {
+	if depth > maxDepth {
+		return "", fmt.Errorf("grok patterns nested too deeply, they are probably recursive")
+	}
+	var err error
+	expanded := referenceRegex.ReplaceAllStringFunc(pattern, func(reference string) string {
+		if err != nil {
+			return ""
+		}
+		submatch := referenceRegex.FindStringSubmatch(reference)
+		name, fieldName, kind := submatch[1], submatch[2], submatch[3]
+		definition, exists := c.customPatterns[name]
+		if !exists {
+			definition, exists = builtinPatterns[name]
+		}
+		if !exists {
+			err = fmt.Errorf("unknown grok pattern %s", name)
+			return ""
+		}
+		switch kind {
+		case "", IntType, FloatType:
+			break
+		default:
+			err = fmt.Errorf("invalid type %s for grok field %s", kind, fieldName)
+			return ""
+		}
+		var sub string
+		sub, err = c.expand(definition, depth+1)
+		if err != nil {
+			return ""
+		}
+		if fieldName == "" {
+			return "(?:" + sub + ")"
+		}
+		// field names are not valid group names, the groups are named after their index instead
+		group := "f" + strconv.Itoa(len(c.fields))
+		c.fields = append(c.fields, field{name: fieldName, group: group, kind: kind})
+		return "(?P<" + group + ">" + sub + ")"
+	})
+	return expanded, err
+}
+
+// convert converts value into kind, value is kept as a string if it cannot be converted.
+func convert(value string, kind string) interface{} {
+	switch kind {
+	case IntType:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case FloatType:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return value
+}
+
+// setField sets value at the dot-separated path name of fields, creating the intermediate objects.
+func setField(fields map[string]interface{}, name string, value interface{}) {
+	path := strings.Split(name, ".")
+	parent := fields
+	for _, key := range path[:len(path)-1] {
+		child, ok := parent[key].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			parent[key] = child
+		}
+		parent = child
+	}
+	parent[path[len(path)-1]] = value
+}
@@ -0,0 +1,159 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package grok
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFieldsAndTypes(t *testing.T) {
+	parser, err := Compile(`%{WORD:user.name} took %{NUMBER:duration:float}s for %{INT:count:int} items(?: %{GREEDYDATA:extra})?`, nil)
+	require.NoError(t, err)
+
+	fields, ok := parser.Parse([]byte("alice took 1.5s for 42 items"))
+	assert.True(t, ok)
+	assert.Equal(t, map[string]interface{}{
+		"user":     map[string]interface{}{"name": "alice"},
+		"duration": 1.5,
+		"count":    int64(42),
+	}, fields)
+
+	_, ok = parser.Parse([]byte("alice took 1.5s for many items"))
+	assert.False(t, ok)
+}
+
+func TestParseCustomPatterns(t *testing.T) {
+	parser, err := Compile(`%{ACTION:action} from %{IP:client}`, map[string]string{
+		"ACTION": `(?:login|logout)`,
+		// custom patterns take precedence over the built-in ones
+		"IP": `[0-9.]+`,
+	})
+	require.NoError(t, err)
+
+	fields, ok := parser.Parse([]byte("logout from 10.0.0.1"))
+	assert.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"action": "logout", "client": "10.0.0.1"}, fields)
+
+	_, ok = parser.Parse([]byte("signup from 10.0.0.1"))
+	assert.False(t, ok)
+}
+
+func TestCompileErrors(t *testing.T) {
+	invalidPatterns := []struct {
+		pattern        string
+		customPatterns map[string]string
+	}{
+		{pattern: `%{UNKNOWN:field}`},
+		{pattern: `%{WORD:field:bool}`},
+		{pattern: `%{WORD:field}(`},
+		{pattern: `%{LOOP}`, customPatterns: map[string]string{"LOOP": `a%{LOOP}`}},
+	}
+	for _, p := range invalidPatterns {
+		_, err := Compile(p.pattern, p.customPatterns)
+		assert.Error(t, err, p.pattern)
+	}
+}
+
+func TestBuiltinPatterns(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		line     string
+		expected map[string]interface{}
+	}{
+		{
+			pattern: `%{IP:ipv4} %{IP:ipv6} %{IPORHOST:host}`,
+			line:    "192.168.0.1 fe80::1ff:fe23:4567:890a example.com",
+			expected: map[string]interface{}{
+				"ipv4": "192.168.0.1",
+				"ipv6": "fe80::1ff:fe23:4567:890a",
+				"host": "example.com",
+			},
+		},
+		{
+			pattern: `%{COMBINEDAPACHELOG}`,
+			line:    `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08"`,
+			expected: map[string]interface{}{
+				"clientip":    "127.0.0.1",
+				"ident":       "-",
+				"auth":        "frank",
+				"timestamp":   "10/Oct/2000:13:55:36 -0700",
+				"verb":        "GET",
+				"request":     "/apache_pb.gif",
+				"httpversion": "1.0",
+				"response":    int64(200),
+				"bytes":       int64(2326),
+				"referrer":    `"http://www.example.com/start.html"`,
+				"agent":       `"Mozilla/4.08"`,
+			},
+		},
+		{
+			pattern: `%{NGINXERROR}`,
+			line:    `2021/10/12 08:30:01 [error] 31#31: *7 open() "/usr/share/nginx/html/favicon.ico" failed (2: No such file or directory)`,
+			expected: map[string]interface{}{
+				"timestamp":     "2021/10/12 08:30:01",
+				"level":         "error",
+				"pid":           int64(31),
+				"tid":           int64(31),
+				"connection_id": int64(7),
+				"message":       `open() "/usr/share/nginx/html/favicon.ico" failed (2: No such file or directory)`,
+			},
+		},
+		{
+			pattern: `%{POSTGRESQL}`,
+			line:    `2021-10-12 08:30:01.123 UTC [42] postgres@orders ERROR:  relation "users" does not exist`,
+			expected: map[string]interface{}{
+				"timestamp": "2021-10-12 08:30:01.123",
+				"timezone":  "UTC",
+				"pid":       int64(42),
+				"user":      "postgres",
+				"database":  "orders",
+				"level":     "ERROR",
+				"message":   `relation "users" does not exist`,
+			},
+		},
+		{
+			pattern: `%{TIMESTAMP_ISO8601:timestamp} %{LOGLEVEL:level} %{UUID:request_id}`,
+			line:    "2021-10-12T08:30:01.123Z WARN 123e4567-e89b-12d3-a456-426614174000",
+			expected: map[string]interface{}{
+				"timestamp":  "2021-10-12T08:30:01.123Z",
+				"level":      "WARN",
+				"request_id": "123e4567-e89b-12d3-a456-426614174000",
+			},
+		},
+	}
+	for _, test := range tests {
+		parser, err := Compile(test.pattern, nil)
+		require.NoError(t, err, test.pattern)
+		fields, ok := parser.Parse([]byte(test.line))
+		assert.True(t, ok, test.pattern)
+		assert.Equal(t, test.expected, fields, test.pattern)
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	expected := time.Date(2000, time.October, 10, 20, 55, 36, 0, time.UTC)
+	for _, value := range []string{
+		"10/Oct/2000:13:55:36 -0700",
+		"2000-10-10T20:55:36Z",
+		"2000-10-10 20:55:36",
+		"2000/10/10 20:55:36",
+	} {
+		ts, err := ParseTimestamp(value, "")
+		assert.NoError(t, err, value)
+		assert.True(t, expected.Equal(ts), value)
+	}
+
+	ts, err := ParseTimestamp("10.10.2000 20:55", "02.01.2006 15:04")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2000, time.October, 10, 20, 55, 0, 0, time.UTC), ts)
+
+	_, err = ParseTimestamp("yesterday", "")
+	assert.Error(t, err)
+}
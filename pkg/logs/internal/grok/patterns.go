@@ -0,0 +1,80 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package grok
+
+// builtinPatterns is the library of patterns that can be referenced by name in any grok pattern.
+// They are written in the RE2 syntax of the regexp package, so they do not use lookarounds
+// or atomic groups unlike their usual Oniguruma counterparts.
+var builtinPatterns = map[string]string{
+	// Generic
+	"USERNAME":     `[a-zA-Z0-9._-]+`,
+	"USER":         `%{USERNAME}`,
+	"INT":          `[+-]?[0-9]+`,
+	"POSINT":       `[1-9][0-9]*`,
+	"NONNEGINT":    `[0-9]+`,
+	"NUMBER":       `[+-]?(?:[0-9]+(?:\.[0-9]+)?|\.[0-9]+)`,
+	"BASE16NUM":    `[+-]?(?:0x)?[0-9A-Fa-f]+`,
+	"WORD":         `\b\w+\b`,
+	"NOTSPACE":     `\S+`,
+	"SPACE":        `\s*`,
+	"DATA":         `.*?`,
+	"GREEDYDATA":   `.*`,
+	"QUOTEDSTRING": `(?:"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*')`,
+	"QS":           `%{QUOTEDSTRING}`,
+	"UUID":         `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"LOGLEVEL":     `(?i:trace|debug|info|notice|warn(?:ing)?|err(?:or)?|crit(?:ical)?|fatal|alert|emerg(?:ency)?)`,
+
+	// Networking
+	"IPV4":     `(?:(?:25[0-5]|2[0-4][0-9]|1[0-9]{2}|[1-9]?[0-9])\.){3}(?:25[0-5]|2[0-4][0-9]|1[0-9]{2}|[1-9]?[0-9])`,
+	"IPV6":     `(?:[0-9A-Fa-f]{0,4}:){2,7}(?:%{IPV4}|[0-9A-Fa-f]{1,4})?(?:%[0-9A-Za-z]+)?`,
+	"IP":       `(?:%{IPV6}|%{IPV4})`,
+	"MAC":      `(?:[0-9A-Fa-f]{2}[:-]){5}[0-9A-Fa-f]{2}`,
+	"HOSTNAME": `\b[0-9A-Za-z][0-9A-Za-z-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z-]{0,62})*\.?\b`,
+	"IPORHOST": `(?:%{IP}|%{HOSTNAME})`,
+	"HOSTPORT": `%{IPORHOST}:%{POSINT}`,
+
+	// Paths and URIs
+	"UNIXPATH":     `(?:/[^/\s]*)+`,
+	"URIPROTO":     `[A-Za-z][A-Za-z0-9+\-.]*`,
+	"URIPATH":      `(?:/[A-Za-z0-9$.+!*'(){},~:;=@#%&_\-]*)+`,
+	"URIPARAM":     `\?[A-Za-z0-9$.+!*'|(){},~@#%&/=:;_?\-\[\]<>]*`,
+	"URIPATHPARAM": `%{URIPATH}(?:%{URIPARAM})?`,
+	"URI":          `%{URIPROTO}://(?:%{USER}(?::[^@]*)?@)?%{IPORHOST}(?::%{POSINT})?(?:%{URIPATHPARAM})?`,
+
+	// Dates and times
+	"MONTH":            `\b(?:Jan(?:uary)?|Feb(?:ruary)?|Mar(?:ch)?|Apr(?:il)?|May|June?|July?|Aug(?:ust)?|Sep(?:tember)?|Oct(?:ober)?|Nov(?:ember)?|Dec(?:ember)?)\b`,
+	"MONTHNUM":         `(?:0?[1-9]|1[0-2])`,
+	"MONTHDAY":         `(?:0[1-9]|[12][0-9]|3[01]|[1-9])`,
+	"DAY":              `\b(?:Mon(?:day)?|Tue(?:sday)?|Wed(?:nesday)?|Thu(?:rsday)?|Fri(?:day)?|Sat(?:urday)?|Sun(?:day)?)\b`,
+	"YEAR":             `[0-9]{4}`,
+	"HOUR":             `(?:2[0-3]|[01]?[0-9])`,
+	"MINUTE":           `[0-5][0-9]`,
+	"SECOND":           `(?:[0-5]?[0-9]|60)(?:[.,][0-9]+)?`,
+	"TIME":             `%{HOUR}:%{MINUTE}:%{SECOND}`,
+	"TZ":               `[A-Z]{3,5}`,
+	"ISO8601_TIMEZONE": `(?:Z|[+-]%{HOUR}(?::?%{MINUTE})?)`,
+	"TIMESTAMP_ISO8601": `%{YEAR}-%{MONTHNUM}-%{MONTHDAY}[T ]%{HOUR}:?%{MINUTE}(?::?%{SECOND})?` +
+		`%{ISO8601_TIMEZONE}?`,
+	"HTTPDATE":        `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} %{INT}`,
+	"SYSLOGTIMESTAMP": `%{MONTH} +%{MONTHDAY} %{TIME}`,
+
+	// Apache and nginx access logs
+	"COMMONAPACHELOG": `%{IPORHOST:clientip} %{USER:ident} %{USER:auth} \[%{HTTPDATE:timestamp}\] ` +
+		`"(?:%{WORD:verb} %{NOTSPACE:request}(?: HTTP/%{NUMBER:httpversion})?|%{DATA:rawrequest})" ` +
+		`%{NONNEGINT:response:int} (?:%{NONNEGINT:bytes:int}|-)`,
+	"COMBINEDAPACHELOG": `%{COMMONAPACHELOG} %{QS:referrer} %{QS:agent}`,
+	"NGINXACCESS":       `%{COMBINEDAPACHELOG}(?: "%{DATA:x_forwarded_for}")?`,
+
+	// nginx error logs
+	"NGINXERROR_TIMESTAMP": `%{YEAR}/%{MONTHNUM}/%{MONTHDAY} %{TIME}`,
+	"NGINXERROR": `%{NGINXERROR_TIMESTAMP:timestamp} \[%{LOGLEVEL:level}\] %{NONNEGINT:pid:int}#%{NONNEGINT:tid:int}: ` +
+		`(?:\*%{NONNEGINT:connection_id:int} )?%{GREEDYDATA:message}`,
+
+	// PostgreSQL logs with the default `%m [%p] ` log_line_prefix, optionally followed by `%q%u@%d `
+	"POSTGRESQL_LEVEL": `(?:DEBUG[1-5]?|INFO|NOTICE|WARNING|ERROR|LOG|FATAL|PANIC|STATEMENT|DETAIL|HINT|CONTEXT)`,
+	"POSTGRESQL": `%{TIMESTAMP_ISO8601:timestamp}(?: %{TZ:timezone})? \[%{POSINT:pid:int}\] ` +
+		`(?:%{USERNAME:user}@%{USERNAME:database} )?%{POSTGRESQL_LEVEL:level}: +%{GREEDYDATA:message}`,
+}
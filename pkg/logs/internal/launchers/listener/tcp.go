@@ -6,6 +6,7 @@
 package listener
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
 	"sync"
@@ -19,6 +20,9 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util/startstop"
 )
 
+// tlsHandshakeTimeout is the maximum duration of the TLS handshake of new connections.
+const tlsHandshakeTimeout = 10 * time.Second
+
 // A TCPListener listens and accepts TCP connections and delegates the read operations to a tailer.
 type TCPListener struct {
 	pipelineProvider pipeline.Provider
@@ -26,8 +30,10 @@ type TCPListener struct {
 	idleTimeout      time.Duration
 	frameSize        int
 	listener         net.Listener
+	tlsConfig        *tls.Config
 	tailers          []*tailer.Tailer
 	mu               sync.Mutex
+	stopped          bool
 	stop             chan struct{}
 }
 
@@ -56,6 +62,15 @@ func NewTCPListener(pipelineProvider pipeline.Provider, source *config.LogSource
 // Start starts the listener to accepts new incoming connections.
 func (l *TCPListener) Start() {
 	log.Infof("Starting TCP forwarder on port %d, with read buffer size: %d", l.source.Config.Port, l.frameSize)
+	if l.source.Config.TLSEnabled() {
+		tlsConfig, err := buildTLSConfig(l.source.Config)
+		if err != nil {
+			log.Errorf("Can't start TCP forwarder on port %d: %v", l.source.Config.Port, err)
+			l.source.Status.Error(err)
+			return
+		}
+		l.tlsConfig = tlsConfig
+	}
 	err := l.startListener()
 	if err != nil {
 		log.Errorf("Can't start TCP forwarder on port %d: %v", l.source.Config.Port, err)
@@ -71,6 +86,11 @@ func (l *TCPListener) Stop() {
 	log.Infof("Stopping TCP forwarder on port %d", l.source.Config.Port)
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	l.stopped = true
+	if l.listener == nil {
+		// the listener failed to start
+		return
+	}
 	l.stop <- struct{}{}
 	l.listener.Close()
 	stopper := startstop.NewParallelStopper()
@@ -106,7 +126,12 @@ func (l *TCPListener) run() {
 				l.source.Status.Success()
 				continue
 			default:
-				l.startTailer(conn)
+				if tlsConn, ok := conn.(*tls.Conn); ok {
+					// do not block new connections while the handshake is in progress
+					go l.handshake(tlsConn)
+				} else {
+					l.startTailer(conn, nil)
+				}
 				l.source.Status.Success()
 			}
 		}
@@ -119,10 +144,26 @@ func (l *TCPListener) startListener() error {
 	if err != nil {
 		return err
 	}
+	if l.tlsConfig != nil {
+		listener = tls.NewListener(listener, l.tlsConfig)
+	}
 	l.listener = listener
 	return nil
 }
 
+// handshake completes the TLS handshake of a new connection and starts a tailer
+// tagged with the identity of the client.
+func (l *TCPListener) handshake(conn *tls.Conn) {
+	conn.SetDeadline(time.Now().Add(tlsHandshakeTimeout)) //nolint:errcheck
+	if err := conn.Handshake(); err != nil {
+		log.Warnf("TLS handshake failed on port %d with %s: %v", l.source.Config.Port, conn.RemoteAddr(), err)
+		conn.Close()
+		return
+	}
+	conn.SetDeadline(time.Time{}) //nolint:errcheck
+	l.startTailer(conn, clientTags(conn.ConnectionState()))
+}
+
 // read reads data from connection, returns an error if it failed and stop the tailer.
 func (l *TCPListener) read(tailer *tailer.Tailer) ([]byte, error) {
 	if l.idleTimeout > 0 {
@@ -139,10 +180,14 @@ func (l *TCPListener) read(tailer *tailer.Tailer) ([]byte, error) {
 }
 
 // startTailer creates and starts a new tailer that reads from the connection.
-func (l *TCPListener) startTailer(conn net.Conn) {
+func (l *TCPListener) startTailer(conn net.Conn, tags []string) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	tailer := tailer.NewTailer(l.source, conn, l.pipelineProvider.NextPipelineChan(), l.read)
+	if l.stopped {
+		conn.Close()
+		return
+	}
+	tailer := tailer.NewTailer(l.source, conn, l.pipelineProvider.NextPipelineChan(), l.read, tags)
 	l.tailers = append(l.tailers, tailer)
 	tailer.Start()
 }
@@ -0,0 +1,55 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listener
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+// clientCNTagName is the name of the tag holding the common name of the client certificate.
+const clientCNTagName = "tls_client_cn"
+
+// buildTLSConfig returns the server TLS configuration of a source,
+// returns an error if the certificates can not be loaded.
+func buildTLSConfig(c *config.LogsConfig) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(c.TLSCert, c.TLSKey)
+	if err != nil {
+		return nil, fmt.Errorf("can't load TLS certificate: %v", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if c.TLSCA != "" {
+		pem, err := ioutil.ReadFile(c.TLSCA)
+		if err != nil {
+			return nil, fmt.Errorf("can't read TLS CA: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificate found in TLS CA %s", c.TLSCA)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		if c.TLSRequireClientCert {
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return tlsConfig, nil
+}
+
+// clientTags returns the tags identifying the client of a TLS connection.
+func clientTags(state tls.ConnectionState) []string {
+	if len(state.PeerCertificates) == 0 || state.PeerCertificates[0].Subject.CommonName == "" {
+		return nil
+	}
+	return []string{clientCNTagName + ":" + state.PeerCertificates[0].Subject.CommonName}
+}
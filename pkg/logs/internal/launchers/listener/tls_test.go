@@ -0,0 +1,173 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listener
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline/mock"
+)
+
+type testPKI struct {
+	caPool     *x509.CertPool
+	caPath     string
+	serverCert string
+	serverKey  string
+	clientCert tls.Certificate
+}
+
+// newTestPKI generates a CA, a server certificate and a client certificate signed by this CA.
+func newTestPKI(t *testing.T) *testPKI {
+	dir := t.TempDir()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	newCert := func(serial int64, cn string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: cn},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			DNSNames:     []string{"localhost"},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	}
+
+	pki := &testPKI{
+		caPool:     x509.NewCertPool(),
+		caPath:     filepath.Join(dir, "ca.pem"),
+		serverCert: filepath.Join(dir, "server.pem"),
+		serverKey:  filepath.Join(dir, "server.key"),
+	}
+	pki.caPool.AddCert(caCert)
+	require.NoError(t, ioutil.WriteFile(pki.caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0600))
+
+	serverCert, serverKey := newCert(2, "server", x509.ExtKeyUsageServerAuth)
+	require.NoError(t, ioutil.WriteFile(pki.serverCert, serverCert, 0600))
+	require.NoError(t, ioutil.WriteFile(pki.serverKey, serverKey, 0600))
+
+	clientCert, clientKey := newCert(3, "appliance-1", x509.ExtKeyUsageClientAuth)
+	pki.clientCert, err = tls.X509KeyPair(clientCert, clientKey)
+	require.NoError(t, err)
+	return pki
+}
+
+func TestTCPWithTLSShouldReceiveMessages(t *testing.T) {
+	pki := newTestPKI(t)
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewTCPListener(pp, config.NewLogSource("", &config.LogsConfig{Port: tcpTestPort, TLSCert: pki.serverCert, TLSKey: pki.serverKey}), 9000)
+	listener.Start()
+	defer listener.Stop()
+
+	conn, err := tls.Dial("tcp", fmt.Sprintf("%s", listener.listener.Addr()), &tls.Config{ServerName: "localhost", RootCAs: pki.caPool})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var msg *message.Message
+
+	fmt.Fprintf(conn, "hello world\n")
+	msg = <-msgChan
+	assert.Equal(t, "hello world", string(msg.Content))
+	assert.Empty(t, msg.Origin.Tags())
+}
+
+func TestTCPWithMutualTLSShouldTagMessagesWithClientCN(t *testing.T) {
+	pki := newTestPKI(t)
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewTCPListener(pp, config.NewLogSource("", &config.LogsConfig{
+		Port:                 tcpTestPort,
+		TLSCert:              pki.serverCert,
+		TLSKey:               pki.serverKey,
+		TLSCA:                pki.caPath,
+		TLSRequireClientCert: true,
+	}), 9000)
+	listener.Start()
+	defer listener.Stop()
+
+	conn, err := tls.Dial("tcp", fmt.Sprintf("%s", listener.listener.Addr()), &tls.Config{ServerName: "localhost", RootCAs: pki.caPool, Certificates: []tls.Certificate{pki.clientCert}})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var msg *message.Message
+
+	fmt.Fprintf(conn, "hello world\n")
+	msg = <-msgChan
+	assert.Equal(t, "hello world", string(msg.Content))
+	assert.Equal(t, []string{"tls_client_cn:appliance-1"}, msg.Origin.Tags())
+}
+
+func TestTCPWithMutualTLSShouldRejectClientsWithoutCertificate(t *testing.T) {
+	pki := newTestPKI(t)
+	pp := mock.NewMockProvider()
+	listener := NewTCPListener(pp, config.NewLogSource("", &config.LogsConfig{
+		Port:                 tcpTestPort,
+		TLSCert:              pki.serverCert,
+		TLSKey:               pki.serverKey,
+		TLSCA:                pki.caPath,
+		TLSRequireClientCert: true,
+	}), 9000)
+	listener.Start()
+	defer listener.Stop()
+
+	conn, err := tls.Dial("tcp", fmt.Sprintf("%s", listener.listener.Addr()), &tls.Config{ServerName: "localhost", RootCAs: pki.caPool})
+	if err == nil {
+		// with TLS 1.3 the client learns the handshake failed on its first read
+		defer conn.Close()
+		fmt.Fprintf(conn, "hello world\n")
+		_, err = conn.Read(make([]byte, 1))
+	}
+	assert.Error(t, err)
+}
+
+func TestTCPWithInvalidTLSConfigShouldFailToStart(t *testing.T) {
+	pp := mock.NewMockProvider()
+	source := config.NewLogSource("", &config.LogsConfig{Port: tcpTestPort, TLSCert: "/does/not/exist.pem", TLSKey: "/does/not/exist.key"})
+	listener := NewTCPListener(pp, source, 9000)
+	listener.Start()
+	assert.True(t, source.Status.IsError())
+	listener.Stop()
+}
@@ -37,3 +37,26 @@ func TestUDPShouldReceiveMessage(t *testing.T) {
 
 	listener.Stop()
 }
+
+func TestUDPShouldReceiveOneSyslogMessagePerDatagram(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewUDPListener(pp, config.NewLogSource("", &config.LogsConfig{Port: udpTestPort, Format: config.SyslogFormat}), 9000)
+	listener.Start()
+
+	conn, err := net.Dial("udp", fmt.Sprintf("%s", listener.tailer.Conn.LocalAddr()))
+	assert.Nil(t, err)
+
+	var msg *message.Message
+
+	fmt.Fprintf(conn, "<11>1 2021-11-03T01:02:03Z web-1 app - - - panic: boom\ngoroutine 1 [running]:\nmain.main()")
+	msg = <-msgChan
+	assert.Equal(t, "panic: boom\ngoroutine 1 [running]:\nmain.main()", string(msg.Content))
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+
+	fmt.Fprintf(conn, "<14>1 2021-11-03T01:02:04Z web-1 app - - - done\n")
+	msg = <-msgChan
+	assert.Equal(t, "done", string(msg.Content))
+
+	listener.Stop()
+}
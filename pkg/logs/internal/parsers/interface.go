@@ -28,6 +28,12 @@ type Message struct {
 	// supports partial lines, then this is true only for the message returned
 	// from the last parsed line in a multi-line message.
 	IsPartial bool
+
+	// Hostname is the hostname of the emitter parsed from the message, if any.
+	Hostname string
+
+	// Tags are the tags parsed from the message metadata, if any.
+	Tags []string
 }
 
 // Parser parses messages, given as a raw byte sequence, into content and metadata.
@@ -0,0 +1,294 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package syslog implements a parser for syslog messages following RFC 5424 or RFC 3164.
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+const nilValue = "-"
+
+var (
+	errInvalidPriority = errors.New("cannot parse the syslog priority")
+	errInvalidHeader   = errors.New("cannot parse the syslog header")
+	errInvalidSD       = errors.New("cannot parse the syslog structured data")
+
+	// utf8BOM may prefix the MSG part of RFC 5424 messages
+	utf8BOM = []byte{0xef, 0xbb, 0xbf}
+)
+
+// severities maps syslog severities to message statuses.
+var severities = []string{
+	message.StatusEmergency,
+	message.StatusAlert,
+	message.StatusCritical,
+	message.StatusError,
+	message.StatusWarning,
+	message.StatusNotice,
+	message.StatusInfo,
+	message.StatusDebug,
+}
+
+// facilities holds the names of the syslog facilities, indexed by their code.
+var facilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// New creates a new parser that parses syslog messages, either following
+// RFC 5424 or the BSD syslog format described in RFC 3164.
+//
+// The severity is mapped to the status of the message, the hostname of the emitter
+// is kept as the hostname of the message and the facility, app-name, procid, msgid
+// and structured data are turned into tags.
+//
+// For example: `<34>1 2003-10-11T22:14:15.003Z mymachine.example.com su - ID47 - 'su root' failed`
+func New() parsers.Parser {
+	return &syslogFormat{now: time.Now}
+}
+
+type syslogFormat struct {
+	// now is used to infer the year of RFC 3164 timestamps
+	now func() time.Time
+}
+
+// Parse implements Parser#Parse
+func (p *syslogFormat) Parse(msg []byte) (parsers.Message, error) {
+	facility, severity, rest, err := parsePriority(msg)
+	if err != nil {
+		return parsers.Message{
+			Content: msg,
+			Status:  message.StatusInfo,
+		}, err
+	}
+
+	var parsed parsers.Message
+	if len(rest) > 1 && rest[0] >= '1' && rest[0] <= '9' && rest[1] == ' ' {
+		parsed, err = parseRFC5424(rest[2:])
+	} else {
+		parsed, err = p.parseRFC3164(rest)
+	}
+	if err != nil {
+		return parsers.Message{
+			Content: msg,
+			Status:  severities[severity],
+		}, err
+	}
+	parsed.Status = severities[severity]
+	parsed.Tags = append([]string{"syslog_facility:" + facilityName(facility)}, parsed.Tags...)
+	return parsed, nil
+}
+
+// SupportsPartialLine implements Parser#SupportsPartialLine
+func (p *syslogFormat) SupportsPartialLine() bool {
+	return false
+}
+
+// parsePriority parses the `<PRI>` prefix of a message.
+func parsePriority(msg []byte) (facility int, severity int, rest []byte, err error) {
+	if len(msg) < 3 || msg[0] != '<' {
+		return 0, 0, nil, errInvalidPriority
+	}
+	end := bytes.IndexByte(msg[:min(len(msg), 5)], '>')
+	if end < 2 {
+		return 0, 0, nil, errInvalidPriority
+	}
+	pri, err := strconv.Atoi(string(msg[1:end]))
+	if err != nil || pri < 0 || pri > 191 {
+		return 0, 0, nil, errInvalidPriority
+	}
+	return pri / 8, pri % 8, msg[end+1:], nil
+}
+
+// parseRFC5424 parses `TIMESTAMP SP HOSTNAME SP APP-NAME SP PROCID SP MSGID SP STRUCTURED-DATA [SP MSG]`.
+func parseRFC5424(msg []byte) (parsers.Message, error) {
+	var fields [5]string
+	for i := range fields {
+		end := bytes.IndexByte(msg, ' ')
+		if end <= 0 {
+			return parsers.Message{}, errInvalidHeader
+		}
+		fields[i] = string(msg[:end])
+		msg = msg[end+1:]
+	}
+
+	var parsed parsers.Message
+	if fields[0] != nilValue {
+		ts, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return parsers.Message{}, errInvalidHeader
+		}
+		parsed.Timestamp = ts.UTC().Format(time.RFC3339Nano)
+	}
+	if fields[1] != nilValue {
+		parsed.Hostname = fields[1]
+	}
+	for i, name := range []string{"syslog_appname", "syslog_procid", "syslog_msgid"} {
+		if value := fields[i+2]; value != nilValue {
+			parsed.Tags = append(parsed.Tags, name+":"+value)
+		}
+	}
+
+	tags, rest, err := parseStructuredData(msg)
+	if err != nil {
+		return parsers.Message{}, err
+	}
+	parsed.Tags = append(parsed.Tags, tags...)
+	if len(rest) > 0 && rest[0] == ' ' {
+		rest = rest[1:]
+	}
+	parsed.Content = bytes.TrimPrefix(rest, utf8BOM)
+	return parsed, nil
+}
+
+// parseStructuredData parses the STRUCTURED-DATA part of a RFC 5424 message,
+// each `[SD-ID PARAM-NAME="PARAM-VALUE"]` is turned into a `SD-ID.PARAM-NAME:PARAM-VALUE` tag,
+// the elements without parameters do not add any tag.
+func parseStructuredData(msg []byte) ([]string, []byte, error) {
+	if bytes.HasPrefix(msg, []byte(nilValue)) {
+		return nil, msg[1:], nil
+	}
+	var tags []string
+	elements := 0
+	for len(msg) > 0 && msg[0] == '[' {
+		elements++
+		msg = msg[1:]
+		end := bytes.IndexAny(msg, " ]")
+		if end <= 0 {
+			return nil, nil, errInvalidSD
+		}
+		id := string(msg[:end])
+		msg = msg[end:]
+		for len(msg) > 0 && msg[0] == ' ' {
+			msg = msg[1:]
+			eq := bytes.IndexByte(msg, '=')
+			if eq <= 0 || len(msg) < eq+2 || msg[eq+1] != '"' {
+				return nil, nil, errInvalidSD
+			}
+			name := string(msg[:eq])
+			value, n, ok := parseParamValue(msg[eq+2:])
+			if !ok {
+				return nil, nil, errInvalidSD
+			}
+			tags = append(tags, id+"."+name+":"+value)
+			msg = msg[eq+2+n:]
+		}
+		if len(msg) == 0 || msg[0] != ']' {
+			return nil, nil, errInvalidSD
+		}
+		msg = msg[1:]
+	}
+	if elements == 0 {
+		return nil, nil, errInvalidSD
+	}
+	return tags, msg, nil
+}
+
+// parseParamValue parses an escaped PARAM-VALUE up to its closing quote,
+// returns the unescaped value and the number of bytes consumed.
+func parseParamValue(msg []byte) (string, int, bool) {
+	var value strings.Builder
+	for i := 0; i < len(msg); i++ {
+		switch msg[i] {
+		case '\\':
+			if i+1 < len(msg) && (msg[i+1] == '"' || msg[i+1] == '\\' || msg[i+1] == ']') {
+				i++
+			}
+			value.WriteByte(msg[i])
+		case '"':
+			return value.String(), i + 1, true
+		default:
+			value.WriteByte(msg[i])
+		}
+	}
+	return "", 0, false
+}
+
+// parseRFC3164 parses `TIMESTAMP SP HOSTNAME SP TAG MSG`, where TIMESTAMP is `Mmm dd hh:mm:ss`.
+// As a lot of implementations do not strictly follow this format, the hostname is optional,
+// RFC 3339 timestamps are accepted and the message is kept as is when the header cannot be parsed.
+func (p *syslogFormat) parseRFC3164(msg []byte) (parsers.Message, error) {
+	var parsed parsers.Message
+	if ts, n, ok := p.parseRFC3164Timestamp(msg); ok {
+		parsed.Timestamp = ts.UTC().Format(time.RFC3339Nano)
+		msg = msg[n:]
+	} else {
+		parsed.Content = msg
+		return parsed, nil
+	}
+
+	// the hostname is followed by the tag, which ends with a colon or a `[pid]:`
+	if end := bytes.IndexByte(msg, ' '); end > 0 && !isTag(msg[:end]) {
+		parsed.Hostname = string(msg[:end])
+		msg = msg[end+1:]
+	}
+
+	end := bytes.IndexByte(msg, ' ')
+	if end > 0 && isTag(msg[:end]) {
+		tag := msg[:end-1]
+		if start := bytes.IndexByte(tag, '['); start > 0 && tag[len(tag)-1] == ']' {
+			parsed.Tags = append(parsed.Tags, "syslog_procid:"+string(tag[start+1:len(tag)-1]))
+			tag = tag[:start]
+		}
+		parsed.Tags = append([]string{"syslog_appname:" + string(tag)}, parsed.Tags...)
+		msg = msg[end+1:]
+	}
+	parsed.Content = msg
+	return parsed, nil
+}
+
+// parseRFC3164Timestamp parses the timestamp at the beginning of msg and
+// returns it with the number of bytes consumed, including the trailing space.
+func (p *syslogFormat) parseRFC3164Timestamp(msg []byte) (time.Time, int, bool) {
+	// Mmm dd hh:mm:ss, the day can be padded with a space
+	const stampLen = len(time.Stamp)
+	if len(msg) > stampLen && msg[stampLen] == ' ' {
+		if ts, err := time.ParseInLocation(time.Stamp, string(msg[:stampLen]), time.Local); err == nil {
+			now := p.now()
+			ts = ts.AddDate(now.Year(), 0, 0)
+			// messages sent at the end of the year may be received at the beginning of the next one
+			if ts.After(now.Add(24 * time.Hour)) {
+				ts = ts.AddDate(-1, 0, 0)
+			}
+			return ts, stampLen + 1, true
+		}
+	}
+	end := bytes.IndexByte(msg, ' ')
+	if end > 0 {
+		if ts, err := time.Parse(time.RFC3339Nano, string(msg[:end])); err == nil {
+			return ts, end + 1, true
+		}
+	}
+	return time.Time{}, 0, false
+}
+
+// isTag returns true if token is a RFC 3164 TAG, such as `sshd:` or `sshd[42]:`.
+func isTag(token []byte) bool {
+	return len(token) > 1 && token[len(token)-1] == ':'
+}
+
+func facilityName(facility int) string {
+	if facility < len(facilities) {
+		return facilities[facility]
+	}
+	return strconv.Itoa(facility)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,137 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func newTestParser() *syslogFormat {
+	return &syslogFormat{now: func() time.Time { return time.Date(2021, time.December, 1, 0, 0, 0, 0, time.Local) }}
+}
+
+func TestSyslogParserRFC5424(t *testing.T) {
+	msg, err := New().Parse([]byte(`<34>1 2003-10-11T22:14:15.003Z mymachine.example.com su - ID47 - 'su root' failed for lonvick on /dev/pts/8`))
+	assert.Nil(t, err)
+	assert.Equal(t, message.StatusCritical, msg.Status)
+	assert.Equal(t, "2003-10-11T22:14:15.003Z", msg.Timestamp)
+	assert.Equal(t, "mymachine.example.com", msg.Hostname)
+	assert.Equal(t, []string{"syslog_facility:auth", "syslog_appname:su", "syslog_msgid:ID47"}, msg.Tags)
+	assert.Equal(t, []byte(`'su root' failed for lonvick on /dev/pts/8`), msg.Content)
+}
+
+func TestSyslogParserRFC5424WithStructuredData(t *testing.T) {
+	msg, err := New().Parse([]byte(`<165>1 2003-10-11T22:14:15.003-07:00 host evntslog 1234 ID47 [exampleSDID@32473 iut="3" eventSource="Appl\"ication"][examplePriority@32473 class="high"] ` + "\xef\xbb\xbf" + `An application event`))
+	assert.Nil(t, err)
+	assert.Equal(t, message.StatusNotice, msg.Status)
+	assert.Equal(t, "2003-10-12T05:14:15.003Z", msg.Timestamp)
+	assert.Equal(t, "host", msg.Hostname)
+	assert.Equal(t, []string{
+		"syslog_facility:local4",
+		"syslog_appname:evntslog",
+		"syslog_procid:1234",
+		"syslog_msgid:ID47",
+		"exampleSDID@32473.iut:3",
+		`exampleSDID@32473.eventSource:Appl"ication`,
+		"examplePriority@32473.class:high",
+	}, msg.Tags)
+	assert.Equal(t, []byte("An application event"), msg.Content)
+}
+
+func TestSyslogParserRFC5424WithEmptyStructuredDataElement(t *testing.T) {
+	msg, err := New().Parse([]byte(`<165>1 2003-10-11T22:14:15.003Z host evntslog - ID47 [exampleSDID@32473][examplePriority@32473 class="high"] An application event`))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"syslog_facility:local4",
+		"syslog_appname:evntslog",
+		"syslog_msgid:ID47",
+		"examplePriority@32473.class:high",
+	}, msg.Tags)
+	assert.Equal(t, []byte("An application event"), msg.Content)
+
+	msg, err = New().Parse([]byte(`<165>1 2003-10-11T22:14:15.003Z host evntslog - ID47 [exampleSDID@32473] An application event`))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"syslog_facility:local4", "syslog_appname:evntslog", "syslog_msgid:ID47"}, msg.Tags)
+	assert.Equal(t, []byte("An application event"), msg.Content)
+}
+
+func TestSyslogParserRFC5424WithoutMessage(t *testing.T) {
+	msg, err := New().Parse([]byte(`<14>1 - - - - - -`))
+	assert.Nil(t, err)
+	assert.Equal(t, message.StatusInfo, msg.Status)
+	assert.Equal(t, "", msg.Timestamp)
+	assert.Equal(t, "", msg.Hostname)
+	assert.Equal(t, []string{"syslog_facility:user"}, msg.Tags)
+	assert.Equal(t, 0, len(msg.Content))
+}
+
+func TestSyslogParserRFC3164(t *testing.T) {
+	msg, err := newTestParser().Parse([]byte(`<13>Nov 30 22:14:15 mymachine sshd[4242]: Accepted publickey for root`))
+	assert.Nil(t, err)
+	assert.Equal(t, message.StatusNotice, msg.Status)
+	assert.Equal(t, time.Date(2021, time.November, 30, 22, 14, 15, 0, time.Local).UTC().Format(time.RFC3339Nano), msg.Timestamp)
+	assert.Equal(t, "mymachine", msg.Hostname)
+	assert.Equal(t, []string{"syslog_facility:user", "syslog_appname:sshd", "syslog_procid:4242"}, msg.Tags)
+	assert.Equal(t, []byte("Accepted publickey for root"), msg.Content)
+
+	// messages from the end of the previous year
+	msg, err = newTestParser().Parse([]byte(`<13>Dec 31 23:59:59 mymachine cron: job done`))
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2020, time.December, 31, 23, 59, 59, 0, time.Local).UTC().Format(time.RFC3339Nano), msg.Timestamp)
+	assert.Equal(t, []string{"syslog_facility:user", "syslog_appname:cron"}, msg.Tags)
+	assert.Equal(t, []byte("job done"), msg.Content)
+}
+
+func TestSyslogParserRFC3164WithoutHostname(t *testing.T) {
+	msg, err := newTestParser().Parse([]byte(`<11>Nov  3 01:02:03 app: something failed`))
+	assert.Nil(t, err)
+	assert.Equal(t, message.StatusError, msg.Status)
+	assert.Equal(t, "", msg.Hostname)
+	assert.Equal(t, []string{"syslog_facility:user", "syslog_appname:app"}, msg.Tags)
+	assert.Equal(t, []byte("something failed"), msg.Content)
+}
+
+func TestSyslogParserRFC3164WithRFC3339Timestamp(t *testing.T) {
+	msg, err := newTestParser().Parse([]byte(`<30>2021-11-03T01:02:03.5+01:00 host app[1]: hello`))
+	assert.Nil(t, err)
+	assert.Equal(t, message.StatusInfo, msg.Status)
+	assert.Equal(t, "2021-11-03T00:02:03.5Z", msg.Timestamp)
+	assert.Equal(t, "host", msg.Hostname)
+	assert.Equal(t, []string{"syslog_facility:daemon", "syslog_appname:app", "syslog_procid:1"}, msg.Tags)
+	assert.Equal(t, []byte("hello"), msg.Content)
+}
+
+func TestSyslogParserRFC3164WithoutHeader(t *testing.T) {
+	msg, err := newTestParser().Parse([]byte(`<31>just a message`))
+	assert.Nil(t, err)
+	assert.Equal(t, message.StatusDebug, msg.Status)
+	assert.Equal(t, []string{"syslog_facility:daemon"}, msg.Tags)
+	assert.Equal(t, []byte("just a message"), msg.Content)
+}
+
+func TestSyslogParserShouldFailWithInvalidInput(t *testing.T) {
+	for _, line := range []string{
+		"no priority",
+		"<>1 - - - - - -",
+		"<192>1 - - - - - -",
+		"<abc>hello",
+		"<34>1 not-a-timestamp host app - - - msg",
+		"<34>1 - host app - -",
+		`<34>1 - host app - - [id key="unterminated] msg`,
+		`<34>1 - host app - - [id key=value] msg`,
+		`<34>1 - host app - - [] msg`,
+	} {
+		msg, err := New().Parse([]byte(line))
+		assert.NotNil(t, err, line)
+		assert.Equal(t, []byte(line), msg.Content, line)
+		assert.Nil(t, msg.Tags, line)
+	}
+}
@@ -227,3 +227,25 @@ func TestEncoderToValidUTF8(t *testing.T) {
 	assert.Equal(t, "a���z", toValidUtf8([]byte("a\xed\xa0\x80z")))
 	assert.Equal(t, "a����z", toValidUtf8([]byte("a\xf0\x8f\xbf\xbfz")))
 }
+
+func TestWithJSONContent(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{Service: "Service"})
+
+	msg := newMessage([]byte("message"), source, message.StatusError)
+	raw, err := WithJSONContent(RawEncoder).Encode(msg, []byte("redacted"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(raw), "redacted")
+	assert.False(t, json.Valid(raw))
+
+	log := &jsonPayload{}
+	assert.Nil(t, json.Unmarshal(msg.JSONContent, log))
+	assert.Equal(t, "redacted", log.Message)
+	assert.Equal(t, "Service", log.Service)
+	assert.Equal(t, message.StatusError, log.Status)
+
+	// the JSON encoding is shared when the messages are already encoded in JSON
+	msg = newMessage([]byte("message"), source, message.StatusError)
+	encoded, err := WithJSONContent(JSONEncoder).Encode(msg, []byte("redacted"))
+	assert.Nil(t, err)
+	assert.Equal(t, encoded, msg.JSONContent)
+}
@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/grok"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+)
+
+var (
+	tlmGrokMatched   = telemetry.NewCounter("logs_processor", "grok_matched", []string{"rule"}, "Number of logs parsed by a grok_parser rule")
+	tlmGrokUnmatched = telemetry.NewCounter("logs_processor", "grok_unmatched", []string{"rule"}, "Number of logs not matching the pattern of a grok_parser rule")
+)
+
+// grokStatuses maps the levels extracted by the builtin patterns which are not usual status values,
+// such as the PostgreSQL ones, to message statuses.
+var grokStatuses = map[string]string{
+	"log":       message.StatusInfo,
+	"statement": message.StatusInfo,
+	"detail":    message.StatusInfo,
+	"hint":      message.StatusInfo,
+	"context":   message.StatusInfo,
+	"panic":     message.StatusCritical,
+	"debug1":    message.StatusDebug,
+	"debug2":    message.StatusDebug,
+	"debug3":    message.StatusDebug,
+	"debug4":    message.StatusDebug,
+	"debug5":    message.StatusDebug,
+}
+
+// grokStatus returns the message status of a level extracted by a grok_parser rule.
+func grokStatus(level string) (string, bool) {
+	level = strings.ToLower(level)
+	if status, exists := jsonStatuses[level]; exists {
+		return status, true
+	}
+	status, exists := grokStatuses[level]
+	return status, exists
+}
+
+// parseGrok replaces the decoded fields of j by the fields extracted from content by a grok_parser rule,
+// the original content is kept under the `message` key unless the pattern extracts its own message.
+func parseGrok(rule *config.ProcessingRule, content []byte, msg *message.Message, j *jsonContent) {
+	fields, ok := rule.Grok.Parse(content)
+	if !ok {
+		tlmGrokUnmatched.Inc(rule.Name)
+		return
+	}
+	tlmGrokMatched.Inc(rule.Name)
+	if _, exists := fields["message"]; !exists {
+		fields["message"] = string(content)
+	}
+
+	if rule.StatusField != "" {
+		if parent, name, ok := lookupKey(fields, rule.StatusField); ok {
+			if status, exists := grokStatus(toString(parent[name])); exists {
+				msg.SetStatus(status)
+			}
+		}
+	}
+	if rule.TimestampField != "" {
+		if parent, name, ok := lookupKey(fields, rule.TimestampField); ok {
+			// the timestamp is left unset when it cannot be parsed, the message is then timestamped at encoding
+			if ts, err := grok.ParseTimestamp(toString(parent[name]), rule.TimestampLayout); err == nil {
+				msg.Timestamp = ts.UTC()
+			}
+		}
+	}
+
+	*j = jsonContent{fields: fields, dirty: true}
+}
@@ -47,3 +47,27 @@ func (j *jsonEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte,
 		Tags:      msg.Origin.TagsToString(),
 	})
 }
+
+// WithJSONContent returns an encoder which encodes a message with encoder and also stores
+// its JSON encoding in msg.JSONContent, for the destinations which always send JSON.
+func WithJSONContent(encoder Encoder) Encoder {
+	return &jsonContentEncoder{encoder: encoder}
+}
+
+// jsonContentEncoder wraps an encoder to store the JSON encoding of the messages it encodes.
+type jsonContentEncoder struct {
+	encoder Encoder
+}
+
+// Encode stores the JSON encoding of msg in msg.JSONContent and returns the encoding of the wrapped encoder.
+func (e *jsonContentEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte, error) {
+	content, err := JSONEncoder.Encode(msg, redactedMsg)
+	if err != nil {
+		return nil, err
+	}
+	msg.JSONContent = content
+	if e.encoder == JSONEncoder {
+		return content, nil
+	}
+	return e.encoder.Encode(msg, redactedMsg)
+}
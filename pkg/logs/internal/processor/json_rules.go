@@ -0,0 +1,178 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+var errTrailingData = errors.New("trailing data after JSON object")
+
+// jsonStatuses maps the usual status values found in structured logs to message statuses.
+var jsonStatuses = map[string]string{
+	"emerg":     message.StatusEmergency,
+	"emergency": message.StatusEmergency,
+	"alert":     message.StatusAlert,
+	"crit":      message.StatusCritical,
+	"critical":  message.StatusCritical,
+	"fatal":     message.StatusCritical,
+	"err":       message.StatusError,
+	"error":     message.StatusError,
+	"warn":      message.StatusWarning,
+	"warning":   message.StatusWarning,
+	"notice":    message.StatusNotice,
+	"info":      message.StatusInfo,
+	"debug":     message.StatusDebug,
+	"trace":     message.StatusDebug,
+}
+
+// jsonContent lazily decodes the content of a message as a JSON object,
+// so that consecutive json rules only decode and encode the content once.
+type jsonContent struct {
+	fields  map[string]interface{}
+	dirty   bool
+	invalid bool
+}
+
+// decode decodes content if it is not already, returns false if content is not a JSON object.
+func (j *jsonContent) decode(content []byte) bool {
+	if j.fields != nil {
+		return true
+	}
+	if j.invalid {
+		return false
+	}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	// keep numbers as they are, large integers would lose precision as float64
+	decoder.UseNumber()
+	err := decoder.Decode(&j.fields)
+	if err == nil {
+		// reject content with trailing data after the JSON object
+		if _, tokenErr := decoder.Token(); tokenErr != io.EOF {
+			err = errTrailingData
+		}
+	}
+	if err != nil || j.fields == nil {
+		j.fields = nil
+		j.invalid = true
+		return false
+	}
+	return true
+}
+
+// encode returns the content encoded back from the decoded fields if they were modified,
+// content is returned as is otherwise. The decoded fields are forgotten in both cases, as
+// the returned content is handed to rules which may rewrite it.
+func (j *jsonContent) encode(content []byte) []byte {
+	dirty, fields := j.dirty, j.fields
+	*j = jsonContent{}
+	if !dirty {
+		return content
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	// do not escape characters that were not escaped in the original content
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(fields); err != nil {
+		return content
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// apply applies a json rule on the decoded fields and on msg, and reports whether the rule modified them.
+func (j *jsonContent) apply(rule *config.ProcessingRule, msg *message.Message) bool {
+	changed := false
+	switch rule.Type {
+	case config.JSONDropKeys:
+		for _, key := range rule.Keys {
+			if parent, name, ok := lookupKey(j.fields, key); ok {
+				delete(parent, name)
+				changed = true
+			}
+		}
+	case config.JSONMaskKeys:
+		for _, key := range rule.Keys {
+			if parent, name, ok := lookupKey(j.fields, key); ok {
+				parent[name] = string(rule.Placeholder)
+				changed = true
+			}
+		}
+	case config.JSONRenameKey:
+		if parent, name, ok := lookupKey(j.fields, rule.Keys[0]); ok {
+			value := parent[name]
+			delete(parent, name)
+			parent[rule.Target] = value
+			changed = true
+		}
+	case config.JSONRemapKey:
+		parent, name, ok := lookupKey(j.fields, rule.Keys[0])
+		if !ok {
+			return false
+		}
+		value := toString(parent[name])
+		switch rule.Target {
+		case config.RemapStatus:
+			status, exists := jsonStatuses[strings.ToLower(value)]
+			if !exists {
+				// leave the key in place when it does not hold a known status
+				return false
+			}
+			msg.SetStatus(status)
+		case config.RemapService:
+			msg.Origin.SetService(value)
+		case config.RemapSource:
+			msg.Origin.SetSource(value)
+		case config.RemapTags:
+			msg.Origin.AddTags(name + ":" + value)
+		}
+		delete(parent, name)
+		changed = true
+	}
+	j.dirty = j.dirty || changed
+	return changed
+}
+
+// lookupKey walks the dot-separated path of key in fields and returns the object holding
+// the last element of the path, along with its name.
+func lookupKey(fields map[string]interface{}, key string) (map[string]interface{}, string, bool) {
+	path := strings.Split(key, ".")
+	parent := fields
+	for _, name := range path[:len(path)-1] {
+		child, ok := parent[name].(map[string]interface{})
+		if !ok {
+			return nil, "", false
+		}
+		parent = child
+	}
+	name := path[len(path)-1]
+	if _, exists := parent[name]; !exists {
+		return nil, "", false
+	}
+	return parent, name, true
+}
+
+// toString returns the string representation of a decoded JSON value.
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	case map[string]interface{}, []interface{}:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,79 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+)
+
+var (
+	tlmMetricsGenerated = telemetry.NewCounter("logs_processor", "metrics_generated", []string{"rule"}, "Number of metric samples generated from logs")
+	tlmMetricsErrors    = telemetry.NewCounter("logs_processor", "metrics_errors", []string{"rule"}, "Number of matching logs whose metric value could not be parsed")
+)
+
+// MetricSender submits the metrics generated from logs, it is implemented by aggregator.Demultiplexer.
+type MetricSender interface {
+	AddTimeSample(sample metrics.MetricSample)
+}
+
+var metricTypes = map[string]metrics.MetricType{
+	"":                            metrics.CountType,
+	config.MetricTypeCount:        metrics.CountType,
+	config.MetricTypeGauge:        metrics.GaugeType,
+	config.MetricTypeDistribution: metrics.DistributionType,
+}
+
+// generateMetric emits the metric of a generate_metric rule if content matches its pattern.
+func (p *Processor) generateMetric(rule *config.ProcessingRule, content []byte, msg *message.Message) {
+	if p.metricSender == nil {
+		return
+	}
+	match := rule.Regex.FindSubmatch(content)
+	if match == nil {
+		return
+	}
+
+	value := 1.0
+	var tags []string
+	for i, name := range rule.Regex.SubexpNames() {
+		if name == "" || match[i] == nil {
+			continue
+		}
+		if name == rule.ValueGroup {
+			var err error
+			value, err = strconv.ParseFloat(string(match[i]), 64)
+			if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+				tlmMetricsErrors.Inc(rule.Name)
+				return
+			}
+			continue
+		}
+		tags = append(tags, name+":"+string(match[i]))
+	}
+	if rule.ValueGroup != "" && match[rule.Regex.SubexpIndex(rule.ValueGroup)] == nil {
+		// the value group is optional in the pattern and did not participate in the match
+		tlmMetricsErrors.Inc(rule.Name)
+		return
+	}
+
+	p.metricSender.AddTimeSample(metrics.MetricSample{
+		Name:       rule.MetricName,
+		Value:      value,
+		Mtype:      metricTypes[rule.MetricType],
+		Tags:       tags,
+		Host:       msg.GetHostname(),
+		SampleRate: 1,
+		Timestamp:  float64(time.Now().UnixNano()) / float64(time.Second),
+	})
+	tlmMetricsGenerated.Inc(rule.Name)
+}
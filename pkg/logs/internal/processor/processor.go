@@ -26,11 +26,13 @@ type Processor struct {
 	encoder                   Encoder
 	done                      chan struct{}
 	diagnosticMessageReceiver diagnostic.MessageReceiver
+	metricSender              MetricSender
 	mu                        sync.Mutex
 }
 
 // New returns an initialized Processor.
-func New(inputChan, outputChan chan *message.Message, processingRules []*config.ProcessingRule, encoder Encoder, diagnosticMessageReceiver diagnostic.MessageReceiver) *Processor {
+// metricSender receives the metrics of generate_metric rules, these rules are ignored when it is nil.
+func New(inputChan, outputChan chan *message.Message, processingRules []*config.ProcessingRule, encoder Encoder, diagnosticMessageReceiver diagnostic.MessageReceiver, metricSender MetricSender) *Processor {
 	return &Processor{
 		inputChan:                 inputChan,
 		outputChan:                outputChan,
@@ -38,6 +40,7 @@ func New(inputChan, outputChan chan *message.Message, processingRules []*config.
 		encoder:                   encoder,
 		done:                      make(chan struct{}),
 		diagnosticMessageReceiver: diagnosticMessageReceiver,
+		metricSender:              metricSender,
 	}
 }
 
@@ -87,6 +90,9 @@ func (p *Processor) processMessage(msg *message.Message) {
 	metrics.LogsDecoded.Add(1)
 	metrics.TlmLogsDecoded.Inc()
 	if shouldProcess, redactedMsg := p.applyRedactingRules(msg); shouldProcess {
+		if sampler := msg.Origin.LogSource.GetSampler(); sampler != nil && !sampler.Keep(msg.GetStatus(), len(redactedMsg)) {
+			return
+		}
 		metrics.LogsProcessed.Add(1)
 		metrics.TlmLogsProcessed.Inc()
 
@@ -103,24 +109,75 @@ func (p *Processor) processMessage(msg *message.Message) {
 	}
 }
 
+// RuleObserver is called with each processing rule applied on a message, the content the rule
+// was applied on, and whether the rule matched the message. For the json and grok_parser rules,
+// content is the raw content before the rules sharing the decoded fields were applied.
+type RuleObserver func(rule *config.ProcessingRule, content []byte, matched bool)
+
+// ApplyRules applies the processing rules on msg without sending it, it returns false if
+// msg is dropped by a rule and its redacted content otherwise. observe is called after each
+// rule applied.
+// It is used to test processing rules offline.
+func (p *Processor) ApplyRules(msg *message.Message, observe RuleObserver) (bool, []byte) {
+	return p.applyRules(msg, observe)
+}
+
 // applyRedactingRules returns given a message if we should process it or not,
 // and a copy of the message with some fields redacted, depending on config
 func (p *Processor) applyRedactingRules(msg *message.Message) (bool, []byte) {
+	return p.applyRules(msg, nil)
+}
+
+// applyRules applies the processing rules on msg, calling observe after each rule if it is not nil.
+func (p *Processor) applyRules(msg *message.Message, observe RuleObserver) (bool, []byte) {
 	content := msg.Content
+	// json rules share the decoded content until a regex rule needs the raw bytes again
+	var jsonContent jsonContent
 	rules := append(p.processingRules, msg.Origin.LogSource.Config.ProcessingRules...)
 	for _, rule := range rules {
+		keep, matched := true, false
+		input := content
 		switch rule.Type {
 		case config.ExcludeAtMatch:
-			if rule.Regex.Match(content) {
-				return false, nil
-			}
+			content = jsonContent.encode(content)
+			input = content
+			matched = rule.Regex.Match(content)
+			keep = !matched
 		case config.IncludeAtMatch:
-			if !rule.Regex.Match(content) {
-				return false, nil
-			}
+			content = jsonContent.encode(content)
+			input = content
+			matched = rule.Regex.Match(content)
+			keep = matched
 		case config.MaskSequences:
+			content = jsonContent.encode(content)
+			input = content
+			if observe != nil {
+				matched = rule.Regex.Match(content)
+			}
 			content = rule.Regex.ReplaceAll(content, rule.Placeholder)
+		case config.GenerateMetric:
+			content = jsonContent.encode(content)
+			input = content
+			if observe != nil {
+				matched = rule.Regex.Match(content)
+			}
+			p.generateMetric(rule, content, msg)
+		case config.GrokParser:
+			content = jsonContent.encode(content)
+			input = content
+			parseGrok(rule, content, msg, &jsonContent)
+			matched = jsonContent.dirty
+		case config.JSONDropKeys, config.JSONRenameKey, config.JSONMaskKeys, config.JSONRemapKey:
+			if jsonContent.decode(content) {
+				matched = jsonContent.apply(rule, msg)
+			}
+		}
+		if observe != nil {
+			observe(rule, input, matched)
+		}
+		if !keep {
+			return false, nil
 		}
 	}
-	return true, content
+	return true, jsonContent.encode(content)
 }
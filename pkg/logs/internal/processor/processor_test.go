@@ -8,10 +8,14 @@ package processor
 import (
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestExclusion(t *testing.T) {
@@ -156,3 +160,228 @@ func newSource(ruleType, replacePlaceholder, pattern string) config.LogSource {
 func newMessage(content []byte, source *config.LogSource, status string) *message.Message {
 	return message.NewMessageWithSource(content, status, source, 0)
 }
+
+func TestJSONRules(t *testing.T) {
+	p := &Processor{}
+
+	source := config.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{
+		{Type: config.JSONDropKeys, Name: "drop", Keys: []string{"debug", "http.headers", "missing.key"}},
+		{Type: config.JSONMaskKeys, Name: "mask", Keys: []string{"user.password"}, Placeholder: []byte("****")},
+		{Type: config.JSONRenameKey, Name: "rename", Keys: []string{"msg"}, Target: "message"},
+	}}}
+
+	shouldProcess, redactedMessage := p.applyRedactingRules(newMessage([]byte(`{"msg":"a <b>","debug":true,"http":{"headers":{"a":"b"},"code":200},"user":{"password":"secret"},"id":12345678901234567890}`), &source, ""))
+	assert.True(t, shouldProcess)
+	assert.JSONEq(t, `{"message":"a <b>","http":{"code":200},"user":{"password":"****"},"id":12345678901234567890}`, string(redactedMessage))
+	assert.Contains(t, string(redactedMessage), "12345678901234567890")
+	assert.Contains(t, string(redactedMessage), "<b>")
+
+	// content which is not a JSON object is left untouched
+	shouldProcess, redactedMessage = p.applyRedactingRules(newMessage([]byte(`debug=true msg=hello`), &source, ""))
+	assert.True(t, shouldProcess)
+	assert.Equal(t, []byte(`debug=true msg=hello`), redactedMessage)
+
+	shouldProcess, redactedMessage = p.applyRedactingRules(newMessage([]byte(`{"debug":true} trailing`), &source, ""))
+	assert.True(t, shouldProcess)
+	assert.Equal(t, []byte(`{"debug":true} trailing`), redactedMessage)
+
+	// content without matching keys is not re-encoded
+	shouldProcess, redactedMessage = p.applyRedactingRules(newMessage([]byte(`{"b": 1, "a": 2}`), &source, ""))
+	assert.True(t, shouldProcess)
+	assert.Equal(t, []byte(`{"b": 1, "a": 2}`), redactedMessage)
+}
+
+func TestJSONRemapRules(t *testing.T) {
+	p := &Processor{}
+
+	source := config.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{
+		{Type: config.JSONRemapKey, Name: "status", Keys: []string{"level"}, Target: config.RemapStatus},
+		{Type: config.JSONRemapKey, Name: "service", Keys: []string{"app.name"}, Target: config.RemapService},
+		{Type: config.JSONRemapKey, Name: "tags", Keys: []string{"env"}, Target: config.RemapTags},
+	}}}
+
+	msg := newMessage([]byte(`{"level":"WARNING","app":{"name":"billing"},"env":"prod","message":"hello"}`), &source, "")
+	msg.Origin.SetTags([]string{"foo:bar"})
+	shouldProcess, redactedMessage := p.applyRedactingRules(msg)
+	assert.True(t, shouldProcess)
+	assert.JSONEq(t, `{"app":{},"message":"hello"}`, string(redactedMessage))
+	assert.Equal(t, message.StatusWarning, msg.GetStatus())
+	assert.Equal(t, "billing", msg.Origin.Service())
+	assert.Equal(t, []string{"foo:bar", "env:prod"}, msg.Origin.Tags())
+
+	// unknown statuses are kept in the payload
+	msg = newMessage([]byte(`{"level":"verbose"}`), &source, "")
+	shouldProcess, redactedMessage = p.applyRedactingRules(msg)
+	assert.True(t, shouldProcess)
+	assert.Equal(t, []byte(`{"level":"verbose"}`), redactedMessage)
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+}
+
+func TestJSONRulesWithRegexRules(t *testing.T) {
+	p := &Processor{processingRules: []*config.ProcessingRule{
+		{Type: config.JSONDropKeys, Name: "drop", Keys: []string{"health"}},
+		newProcessingRule(config.ExcludeAtMatch, "", `"health"`),
+		newProcessingRule(config.MaskSequences, "****", `\d{4}-\d{4}`),
+		{Type: config.JSONMaskKeys, Name: "mask", Keys: []string{"token"}, Placeholder: []byte("[masked]")},
+	}}
+
+	source := config.LogSource{Config: &config.LogsConfig{}}
+	shouldProcess, redactedMessage := p.applyRedactingRules(newMessage([]byte(`{"health":"ok","card":"1234-5678","token":"abc"}`), &source, ""))
+	assert.True(t, shouldProcess)
+	assert.JSONEq(t, `{"card":"****","token":"[masked]"}`, string(redactedMessage))
+}
+
+func TestJSONRulesAroundMaskSequences(t *testing.T) {
+	p := &Processor{processingRules: []*config.ProcessingRule{
+		{Type: config.JSONDropKeys, Name: "drop", Keys: []string{"missing"}},
+		newProcessingRule(config.MaskSequences, "****", `secret[0-9]+`),
+		{Type: config.JSONRenameKey, Name: "rename", Keys: []string{"a"}, Target: "b"},
+	}}
+
+	// the json rule after the mask applies on the masked content
+	source := config.LogSource{Config: &config.LogsConfig{}}
+	shouldProcess, redactedMessage := p.applyRedactingRules(newMessage([]byte(`{"a":1,"msg":"secret123"}`), &source, ""))
+	assert.True(t, shouldProcess)
+	assert.JSONEq(t, `{"b":1,"msg":"****"}`, string(redactedMessage))
+
+	// the mask may turn content which is not a JSON object into one
+	p.processingRules[1] = newProcessingRule(config.MaskSequences, `"a":1}`, `secret$`)
+	shouldProcess, redactedMessage = p.applyRedactingRules(newMessage([]byte(`{secret`), &source, ""))
+	assert.True(t, shouldProcess)
+	assert.JSONEq(t, `{"b":1}`, string(redactedMessage))
+}
+
+func TestGrokRules(t *testing.T) {
+	rules := []*config.ProcessingRule{
+		{Type: config.GrokParser, Name: "access", Pattern: "%{COMMONAPACHELOG}", TimestampField: "timestamp", StatusField: "level"},
+		{Type: config.GrokParser, Name: "app", Pattern: "%{LOGLEVEL:level} %{GREEDYDATA:message}", StatusField: "level"},
+		{Type: config.JSONDropKeys, Name: "drop", Keys: []string{"ident"}},
+		newProcessingRule(config.MaskSequences, "****", `frank`),
+	}
+	require.NoError(t, config.CompileProcessingRules(rules))
+	p := &Processor{processingRules: rules}
+	source := config.LogSource{Config: &config.LogsConfig{}}
+
+	line := `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /index.html HTTP/1.0" 200 2326`
+	msg := newMessage([]byte(line), &source, "")
+	shouldProcess, redactedMessage := p.applyRedactingRules(msg)
+	assert.True(t, shouldProcess)
+	assert.JSONEq(t, `{
+		"clientip": "127.0.0.1", "auth": "****", "timestamp": "10/Oct/2000:13:55:36 -0700",
+		"verb": "GET", "request": "/index.html", "httpversion": "1.0", "response": 200, "bytes": 2326,
+		"message": "127.0.0.1 - **** [10/Oct/2000:13:55:36 -0700] \"GET /index.html HTTP/1.0\" 200 2326"
+	}`, string(redactedMessage))
+	assert.Equal(t, time.Date(2000, time.October, 10, 20, 55, 36, 0, time.UTC), msg.Timestamp)
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+
+	// the extracted message takes precedence over the original content
+	msg = newMessage([]byte("ERROR something failed"), &source, "")
+	shouldProcess, redactedMessage = p.applyRedactingRules(msg)
+	assert.True(t, shouldProcess)
+	assert.JSONEq(t, `{"level":"ERROR","message":"something failed"}`, string(redactedMessage))
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+	assert.True(t, msg.Timestamp.IsZero())
+
+	// lines which do not match are left untouched
+	msg = newMessage([]byte("not an access log"), &source, "")
+	shouldProcess, redactedMessage = p.applyRedactingRules(msg)
+	assert.True(t, shouldProcess)
+	assert.Equal(t, []byte("not an access log"), redactedMessage)
+}
+
+func TestGrokRulesPostgreSQLStatus(t *testing.T) {
+	rules := []*config.ProcessingRule{
+		{Type: config.GrokParser, Name: "postgres", Pattern: "%{POSTGRESQL}", StatusField: "level"},
+	}
+	require.NoError(t, config.CompileProcessingRules(rules))
+	p := &Processor{processingRules: rules}
+	source := config.LogSource{Config: &config.LogsConfig{}}
+
+	for level, status := range map[string]string{
+		"LOG":       message.StatusInfo,
+		"STATEMENT": message.StatusInfo,
+		"DETAIL":    message.StatusInfo,
+		"HINT":      message.StatusInfo,
+		"DEBUG2":    message.StatusDebug,
+		"WARNING":   message.StatusWarning,
+		"ERROR":     message.StatusError,
+		"FATAL":     message.StatusCritical,
+		"PANIC":     message.StatusCritical,
+	} {
+		msg := newMessage([]byte("2021-11-03 01:02:03.456 UTC [42] "+level+":  something happened"), &source, "")
+		msg.SetStatus(message.StatusNotice)
+		shouldProcess, _ := p.applyRedactingRules(msg)
+		assert.True(t, shouldProcess)
+		assert.Equal(t, status, msg.GetStatus(), level)
+	}
+}
+
+type mockMetricSender struct {
+	samples []metrics.MetricSample
+}
+
+func (s *mockMetricSender) AddTimeSample(sample metrics.MetricSample) {
+	s.samples = append(s.samples, sample)
+}
+
+func TestGenerateMetricRules(t *testing.T) {
+	metricSender := &mockMetricSender{}
+	p := &Processor{metricSender: metricSender}
+
+	source := config.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{
+		{Type: config.GenerateMetric, Name: "errors", Regex: regexp.MustCompile(`level=error service=(?P<service>\w+)`), MetricName: "app.errors"},
+		{Type: config.GenerateMetric, Name: "duration", Regex: regexp.MustCompile(`endpoint=(?P<endpoint>\S+) took=(?P<duration>\S+)ms`), MetricName: "app.duration", MetricType: config.MetricTypeDistribution, ValueGroup: "duration"},
+		{Type: config.ExcludeAtMatch, Name: "exclude", Regex: regexp.MustCompile("healthcheck")},
+	}}}
+
+	shouldProcess, redactedMessage := p.applyRedactingRules(newMessage([]byte("level=error service=web endpoint=/users took=12.5ms"), &source, ""))
+	assert.True(t, shouldProcess)
+	assert.Equal(t, []byte("level=error service=web endpoint=/users took=12.5ms"), redactedMessage)
+
+	// metrics are generated before the line is excluded
+	shouldProcess, _ = p.applyRedactingRules(newMessage([]byte("level=info endpoint=/healthcheck took=1ms"), &source, ""))
+	assert.False(t, shouldProcess)
+
+	// the value can't be parsed, no metric is generated
+	shouldProcess, _ = p.applyRedactingRules(newMessage([]byte("level=info endpoint=/users took=NaNms"), &source, ""))
+	assert.True(t, shouldProcess)
+
+	require.Len(t, metricSender.samples, 3)
+	assert.Equal(t, "app.errors", metricSender.samples[0].Name)
+	assert.Equal(t, metrics.CountType, metricSender.samples[0].Mtype)
+	assert.Equal(t, 1.0, metricSender.samples[0].Value)
+	assert.Equal(t, []string{"service:web"}, metricSender.samples[0].Tags)
+
+	assert.Equal(t, "app.duration", metricSender.samples[1].Name)
+	assert.Equal(t, metrics.DistributionType, metricSender.samples[1].Mtype)
+	assert.Equal(t, 12.5, metricSender.samples[1].Value)
+	assert.Equal(t, []string{"endpoint:/users"}, metricSender.samples[1].Tags)
+
+	assert.Equal(t, 1.0, metricSender.samples[2].Value)
+	assert.Equal(t, []string{"endpoint:/healthcheck"}, metricSender.samples[2].Tags)
+}
+
+func TestGenerateMetricRulesWithoutMetricSender(t *testing.T) {
+	p := &Processor{}
+	source := config.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{
+		{Type: config.GenerateMetric, Name: "errors", Regex: regexp.MustCompile("error"), MetricName: "app.errors"},
+	}}}
+
+	shouldProcess, redactedMessage := p.applyRedactingRules(newMessage([]byte("an error"), &source, ""))
+	assert.True(t, shouldProcess)
+	assert.Equal(t, []byte("an error"), redactedMessage)
+}
+
+func TestSampling(t *testing.T) {
+	outputChan := make(chan *message.Message, 10)
+	p := New(nil, outputChan, nil, RawEncoder, &diagnostic.NoopMessageReceiver{}, nil)
+
+	source := config.NewLogSource("", &config.LogsConfig{Sampling: &config.SamplingConfig{Rate: 0.5, KeepStatus: message.StatusError}})
+	for i := 0; i < 4; i++ {
+		p.processMessage(newMessage([]byte("hello"), source, message.StatusInfo))
+	}
+	p.processMessage(newMessage([]byte("oops"), source, message.StatusError))
+
+	assert.Len(t, outputChan, 3)
+	assert.Equal(t, map[string][]string{"Sampled Out": {"2"}, "Rate Limited": {"0"}}, source.GetInfoStatus())
+}
@@ -8,12 +8,15 @@ package socket
 import (
 	"io"
 	"net"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/framer"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/noop"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/syslog"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 )
 
@@ -24,24 +27,44 @@ type Tailer struct {
 	Conn       net.Conn
 	outputChan chan *message.Message
 	read       func(*Tailer) ([]byte, error)
+	tags       []string
 	decoder    *decoder.Decoder
 	stop       chan struct{}
 	done       chan struct{}
 }
 
-// NewTailer returns a new Tailer
-func NewTailer(source *config.LogSource, conn net.Conn, outputChan chan *message.Message, read func(*Tailer) ([]byte, error)) *Tailer {
+// NewTailer returns a new Tailer reading a stream, tags are added to all the messages read from conn.
+func NewTailer(source *config.LogSource, conn net.Conn, outputChan chan *message.Message, read func(*Tailer) ([]byte, error), tags []string) *Tailer {
+	return newTailer(source, conn, outputChan, read, tags, framer.Syslog)
+}
+
+// NewDatagramTailer returns a new Tailer reading datagrams, read must return one datagram at a time.
+// Each syslog datagram is one message, even if it contains newlines.
+func NewDatagramTailer(source *config.LogSource, conn net.Conn, outputChan chan *message.Message, read func(*Tailer) ([]byte, error)) *Tailer {
+	return newTailer(source, conn, outputChan, read, nil, framer.SyslogDatagram)
+}
+
+func newTailer(source *config.LogSource, conn net.Conn, outputChan chan *message.Message, read func(*Tailer) ([]byte, error), tags []string, syslogFraming framer.Framing) *Tailer {
 	return &Tailer{
 		source:     source,
 		Conn:       conn,
 		outputChan: outputChan,
 		read:       read,
-		decoder:    decoder.InitializeDecoder(source, noop.New()),
+		tags:       tags,
+		decoder:    buildDecoder(source, syslogFraming),
 		stop:       make(chan struct{}, 1),
 		done:       make(chan struct{}, 1),
 	}
 }
 
+// buildDecoder returns a decoder for the format of the source, syslog messages are framed with syslogFraming
+func buildDecoder(source *config.LogSource, syslogFraming framer.Framing) *decoder.Decoder {
+	if source.Config.Format == config.SyslogFormat {
+		return decoder.NewDecoderWithFraming(source, syslog.New(), syslogFraming, nil)
+	}
+	return decoder.InitializeDecoder(source, noop.New())
+}
+
 // Start prepares the tailer to read and decode data from the connection
 func (t *Tailer) Start() {
 	go t.forwardMessages()
@@ -64,9 +87,28 @@ func (t *Tailer) forwardMessages() {
 	}()
 	for output := range t.decoder.OutputChan {
 		if len(output.Content) > 0 {
-			t.outputChan <- message.NewMessageWithSource(output.Content, message.StatusInfo, t.source, output.IngestionTimestamp)
+			t.outputChan <- t.newMessage(output)
+		}
+	}
+}
+
+// newMessage builds a message from the decoder output, keeping the metadata
+// extracted from the structured formats.
+func (t *Tailer) newMessage(output *decoder.Message) *message.Message {
+	origin := message.NewOrigin(t.source)
+	origin.SetTags(t.tags)
+	if t.source.Config.Format != config.SyslogFormat {
+		return message.NewMessage(output.Content, origin, message.StatusInfo, output.IngestionTimestamp)
+	}
+	origin.AddTags(output.Tags...)
+	msg := message.NewMessage(output.Content, origin, output.Status, output.IngestionTimestamp)
+	msg.Hostname = output.Hostname
+	if output.Timestamp != "" {
+		if ts, err := time.Parse(time.RFC3339Nano, output.Timestamp); err == nil {
+			msg.Timestamp = ts
 		}
 	}
+	return msg
 }
 
 // readForever reads the data from conn.
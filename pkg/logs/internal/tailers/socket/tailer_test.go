@@ -9,6 +9,7 @@ import (
 	"errors"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -19,7 +20,7 @@ import (
 func TestReadAndForwardShouldSucceedWithSuccessfulRead(t *testing.T) {
 	msgChan := make(chan *message.Message)
 	r, w := net.Pipe()
-	tailer := NewTailer(config.NewLogSource("", &config.LogsConfig{}), r, msgChan, read)
+	tailer := NewTailer(config.NewLogSource("", &config.LogsConfig{}), r, msgChan, read, nil)
 	tailer.Start()
 
 	var msg *message.Message
@@ -39,11 +40,38 @@ func TestReadAndForwardShouldSucceedWithSuccessfulRead(t *testing.T) {
 	tailer.Stop()
 }
 
+func TestReadAndForwardSyslogMessages(t *testing.T) {
+	msgChan := make(chan *message.Message)
+	r, w := net.Pipe()
+	tailer := NewTailer(config.NewLogSource("", &config.LogsConfig{Format: config.SyslogFormat}), r, msgChan, read, nil)
+	tailer.Start()
+
+	var msg *message.Message
+
+	// octet-counted framing
+	w.Write([]byte("68 <11>1 2021-11-03T01:02:03Z web-1 nginx 42 - [meta env=\"prod\"] failed"))
+	msg = <-msgChan
+	assert.Equal(t, "failed", string(msg.Content))
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+	assert.Equal(t, "web-1", msg.GetHostname())
+	assert.Equal(t, []string{"syslog_facility:user", "syslog_appname:nginx", "syslog_procid:42", "meta.env:prod"}, msg.Origin.Tags())
+	assert.Equal(t, time.Date(2021, time.November, 3, 1, 2, 3, 0, time.UTC), msg.Timestamp)
+
+	// newline framing, invalid messages are forwarded as is
+	w.Write([]byte("not syslog\n"))
+	msg = <-msgChan
+	assert.Equal(t, "not syslog", string(msg.Content))
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+	assert.Empty(t, msg.Origin.Tags())
+
+	tailer.Stop()
+}
+
 func TestReadShouldFailWithError(t *testing.T) {
 	msgChan := make(chan *message.Message)
 	r, w := net.Pipe()
 	read := func(*Tailer) ([]byte, error) { return nil, errors.New("") }
-	tailer := NewTailer(config.NewLogSource("", &config.LogsConfig{}), r, msgChan, read)
+	tailer := NewTailer(config.NewLogSource("", &config.LogsConfig{}), r, msgChan, read, nil)
 	tailer.Start()
 
 	w.Write([]byte("foo\n"))
@@ -22,6 +22,7 @@ import (
 	metaScheduler "github.com/DataDog/datadog-agent/pkg/autodiscovery/scheduler"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
 	adScheduler "github.com/DataDog/datadog-agent/pkg/logs/schedulers/ad"
 	ccaScheduler "github.com/DataDog/datadog-agent/pkg/logs/schedulers/cca"
 	trapsScheduler "github.com/DataDog/datadog-agent/pkg/logs/schedulers/traps"
@@ -53,15 +54,15 @@ var (
 // getAC is a func returning the prepared AutoConfig. It is nil until
 // the AutoConfig is ready, please consider using BlockUntilAutoConfigRanOnce
 // instead of directly using it.
-// The parameter serverless indicates whether or not this Logs Agent is running
-// in a serverless environment.
-func Start(getAC func() *autodiscovery.AutoConfig) (*Agent, error) {
-	return start(getAC, false)
+// metricSender receives the metrics generated from logs by the processing
+// rules, it can be nil.
+func Start(getAC func() *autodiscovery.AutoConfig, metricSender pipeline.MetricSender) (*Agent, error) {
+	return start(getAC, metricSender, false)
 }
 
 // StartServerless starts a Serverless instance of the Logs Agent.
 func StartServerless(getAC func() *autodiscovery.AutoConfig) (*Agent, error) {
-	return start(getAC, true)
+	return start(getAC, nil, true)
 }
 
 // buildEndpoints builds endpoints for the logs agent
@@ -73,10 +74,17 @@ func buildEndpoints(serverless bool) (*config.Endpoints, error) {
 	if endpoints, err := config.BuildHTTPEndpointsWithVectorOverride(intakeTrackType, AgentJSONIntakeProtocol, config.DefaultIntakeOrigin); err == nil {
 		httpConnectivity = http.CheckConnectivity(endpoints.Main)
 	}
-	return config.BuildEndpointsWithVectorOverride(httpConnectivity, intakeTrackType, AgentJSONIntakeProtocol, config.DefaultIntakeOrigin)
+	endpoints, err := config.BuildEndpointsWithVectorOverride(httpConnectivity, intakeTrackType, AgentJSONIntakeProtocol, config.DefaultIntakeOrigin)
+	if err != nil {
+		return nil, err
+	}
+	endpoints.Kafka, err = config.BuildKafkaConfig()
+	return endpoints, err
 }
 
-func start(getAC func() *autodiscovery.AutoConfig, serverless bool) (*Agent, error) {
+// The parameter serverless indicates whether or not this Logs Agent is running
+// in a serverless environment.
+func start(getAC func() *autodiscovery.AutoConfig, metricSender pipeline.MetricSender, serverless bool) (*Agent, error) {
 	if IsAgentRunning() {
 		return agent, nil
 	}
@@ -119,7 +127,7 @@ func start(getAC func() *autodiscovery.AutoConfig, serverless bool) (*Agent, err
 	if !serverless {
 		// regular logs agent
 		log.Info("Starting logs-agent...")
-		agent = NewAgent(sources, services, processingRules, endpoints)
+		agent = NewAgent(sources, services, processingRules, endpoints, metricSender)
 	} else {
 		// serverless logs agent
 		log.Info("Starting a serverless logs-agent...")
@@ -37,6 +37,12 @@ type Message struct {
 	// Optional.
 	// Used in the Serverless Agent
 	Lambda *Lambda
+	// Optional. Hostname of the emitter of the message, when it differs from the agent host.
+	// Used by the network sources receiving syslog messages
+	Hostname string
+	// Optional. JSON encoding of the processed message, whatever the encoding of Content.
+	// Used by the Kafka destination
+	JSONContent []byte
 }
 
 // Lambda is a struct storing information about the Lambda function and function execution.
@@ -84,6 +90,11 @@ func (m *Message) GetStatus() string {
 	return m.status
 }
 
+// SetStatus sets the status of the message.
+func (m *Message) SetStatus(status string) {
+	m.status = status
+}
+
 // GetLatency returns the latency delta from ingestion time until now
 func (m *Message) GetLatency() int64 {
 	return time.Now().UnixNano() - m.IngestionTimestamp
@@ -94,6 +105,9 @@ func (m *Message) GetHostname() string {
 	if m.Lambda != nil {
 		return m.Lambda.ARN
 	}
+	if m.Hostname != "" {
+		return m.Hostname
+	}
 	hostname, err := util.GetHostname(context.TODO())
 	if err != nil {
 		// this scenario is not likely to happen since
@@ -90,6 +90,14 @@ func (o *Origin) SetTags(tags []string) {
 	o.tags = tags
 }
 
+// AddTags appends tags to the tags of the origin.
+// The tags previously set are copied as they may be shared with other origins.
+func (o *Origin) AddTags(tags ...string) {
+	newTags := make([]string, 0, len(o.tags)+len(tags))
+	newTags = append(newTags, o.tags...)
+	o.tags = append(newTags, tags...)
+}
+
 // SetSource sets the source of the origin.
 func (o *Origin) SetSource(source string) {
 	o.source = source
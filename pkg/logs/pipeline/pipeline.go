@@ -11,6 +11,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/logs/client/http"
+	"github.com/DataDog/datadog-agent/pkg/logs/client/kafka"
 	"github.com/DataDog/datadog-agent/pkg/logs/client/tcp"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
@@ -19,6 +20,10 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/sender"
 )
 
+// MetricSender submits the metrics generated from logs by the processing rules,
+// it is implemented by aggregator.Demultiplexer.
+type MetricSender = processor.MetricSender
+
 // Pipeline processes and sends messages to the backend
 type Pipeline struct {
 	InputChan chan *message.Message
@@ -33,6 +38,8 @@ func NewPipeline(outputChan chan *message.Payload,
 	endpoints *config.Endpoints,
 	destinationsContext *client.DestinationsContext,
 	diagnosticMessageReceiver diagnostic.MessageReceiver,
+	diskBuffer *sender.DiskBuffer,
+	metricSender MetricSender,
 	serverless bool,
 	pipelineID int) *Pipeline {
 
@@ -44,7 +51,7 @@ func NewPipeline(outputChan chan *message.Payload,
 	var logsSender *sender.Sender
 
 	strategy := getStrategy(strategyInput, senderInput, endpoints, serverless, pipelineID)
-	logsSender = sender.NewSender(senderInput, outputChan, mainDestinations, config.DestinationPayloadChanSize)
+	logsSender = sender.NewSenderWithDiskBuffer(senderInput, outputChan, mainDestinations, config.DestinationPayloadChanSize, diskBuffer)
 
 	var encoder processor.Encoder
 	if serverless {
@@ -56,9 +63,13 @@ func NewPipeline(outputChan chan *message.Payload,
 	} else {
 		encoder = processor.RawEncoder
 	}
+	if endpoints.Kafka != nil {
+		// the Kafka records hold the JSON encoding of the messages, whatever the main encoding
+		encoder = processor.WithJSONContent(encoder)
+	}
 
 	inputChan := make(chan *message.Message, config.ChanSize)
-	processor := processor.New(inputChan, strategyInput, processingRules, encoder, diagnosticMessageReceiver)
+	processor := processor.New(inputChan, strategyInput, processingRules, encoder, diagnosticMessageReceiver, metricSender)
 
 	return &Pipeline{
 		InputChan: inputChan,
@@ -100,6 +111,7 @@ func getDestinations(endpoints *config.Endpoints, destinationsContext *client.De
 			telemetryName := fmt.Sprintf("logs_%d_unreliable_%d", pipelineID, i)
 			additionals = append(additionals, http.NewDestination(endpoint, http.JSONContentType, destinationsContext, endpoints.BatchMaxConcurrentSend, false, telemetryName))
 		}
+		reliable, additionals = addKafkaDestination(endpoints, destinationsContext, reliable, additionals)
 		return client.NewDestinations(reliable, additionals)
 	}
 	for _, endpoint := range endpoints.GetReliableEndpoints() {
@@ -108,15 +120,25 @@ func getDestinations(endpoints *config.Endpoints, destinationsContext *client.De
 	for _, endpoint := range endpoints.GetUnReliableEndpoints() {
 		additionals = append(additionals, tcp.NewDestination(endpoint, endpoints.UseProto, destinationsContext, false))
 	}
+	reliable, additionals = addKafkaDestination(endpoints, destinationsContext, reliable, additionals)
 	return client.NewDestinations(reliable, additionals)
 }
 
+// addKafkaDestination adds the Kafka destination, if enabled, to the reliable or the additional destinations.
+func addKafkaDestination(endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, reliable, additionals []client.Destination) ([]client.Destination, []client.Destination) {
+	if endpoints.Kafka == nil {
+		return reliable, additionals
+	}
+	destination := kafka.NewDestination(endpoints.Kafka, destinationsContext)
+	if endpoints.Kafka.IsReliable {
+		return append(reliable, destination), additionals
+	}
+	return reliable, append(additionals, destination)
+}
+
 func getStrategy(inputChan chan *message.Message, outputChan chan *message.Payload, endpoints *config.Endpoints, serverless bool, pipelineID int) sender.Strategy {
 	if endpoints.UseHTTP || serverless {
-		encoder := sender.IdentityContentType
-		if endpoints.Main.UseCompression {
-			encoder = sender.NewGzipContentEncoding(endpoints.Main.CompressionLevel)
-		}
+		encoder := sender.NewEndpointContentEncoding(endpoints.Main)
 		return sender.NewBatchStrategy(inputChan, outputChan, sender.ArraySerializer, endpoints.BatchWait, endpoints.BatchMaxSize, endpoints.BatchMaxContentSize, "logs", encoder)
 	}
 	return sender.NewStreamStrategy(inputChan, outputChan)
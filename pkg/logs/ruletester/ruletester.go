@@ -0,0 +1,172 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package ruletester applies processing rules on sample logs offline, without running the logs agent.
+package ruletester
+
+import (
+	"sort"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/processor"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// RuleResult is the outcome of a processing rule on a message.
+type RuleResult struct {
+	Name    string
+	Type    string
+	Matched bool
+	// Masked holds the sequences replaced by a mask_sequences rule.
+	Masked []string
+}
+
+// Result is the outcome of the processing rules on a message.
+type Result struct {
+	// Original is the content of the message before processing, the lines of
+	// multi-line messages are separated by an escaped line feed.
+	Original string
+	// FirstLine and LastLine are the numbers of the sample lines the message is made of, starting at 1.
+	FirstLine int
+	LastLine  int
+	// Rules holds the outcome of each rule applied, in order, rules following the one
+	// dropping the message are not applied.
+	Rules []RuleResult
+	// DroppedBy is the name of the rule which dropped the message, the fields below are then unset.
+	DroppedBy string
+	Content   string
+	Status    string
+	// Timestamp is zero unless a rule extracted the timestamp of the message.
+	Timestamp time.Time
+}
+
+// SourceResult is the outcome of the decoding and the processing of a sample by a source.
+type SourceResult struct {
+	Messages []Result
+	// DetectedMultiLinePattern is the pattern found by the automatic multi-line detection, if any.
+	DetectedMultiLinePattern string
+}
+
+// Run validates and compiles rules, then applies them on each line the way the pipelines do.
+func Run(rules []*config.ProcessingRule, lines []string) ([]Result, error) {
+	if err := config.ValidateProcessingRules(rules); err != nil {
+		return nil, err
+	}
+	if err := config.CompileProcessingRules(rules); err != nil {
+		return nil, err
+	}
+
+	source := config.NewLogSource("ruletester", &config.LogsConfig{})
+	results := make([]Result, 0, len(lines))
+	for i, line := range lines {
+		msg := message.NewMessageWithSource([]byte(line), message.StatusInfo, source, time.Now().UnixNano())
+		result := process(rules, msg)
+		result.FirstLine, result.LastLine = i+1, i+1
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// RunSource validates the source configuration, decodes sample with the decoder of the source,
+// applying its multi-line rules, and applies the global rules then the source rules on each
+// message the way the pipelines do.
+// globalRules must be validated and compiled, see config.GlobalProcessingRules.
+func RunSource(sourceConfig *config.LogsConfig, globalRules []*config.ProcessingRule, sample []byte) (*SourceResult, error) {
+	if err := sourceConfig.Validate(); err != nil {
+		return nil, err
+	}
+	source := config.NewLogSource("ruletester", sourceConfig)
+
+	// the framer only outputs complete lines
+	if len(sample) > 0 && sample[len(sample)-1] != '\n' {
+		sample = append(sample, '\n')
+	}
+	lineOffsets := []int{0}
+	for i, b := range sample {
+		if b == '\n' && i+1 < len(sample) {
+			lineOffsets = append(lineOffsets, i+1)
+		}
+	}
+
+	d := decoder.NewDecoderFromSource(source)
+	d.Start()
+	go func() {
+		d.InputChan <- decoder.NewInput(sample)
+		d.Stop()
+	}()
+
+	// the messages are processed with a source without rules, the global and source rules are
+	// applied together in the order of the pipelines
+	processingSource := config.NewLogSource(source.Name, &config.LogsConfig{
+		Type:           sourceConfig.Type,
+		Service:        sourceConfig.Service,
+		Source:         sourceConfig.Source,
+		SourceCategory: sourceConfig.SourceCategory,
+		Tags:           sourceConfig.Tags,
+	})
+	rules := append(append([]*config.ProcessingRule{}, globalRules...), nonMultiLineRules(sourceConfig.ProcessingRules)...)
+
+	result := &SourceResult{}
+	offset := 0
+	for output := range d.OutputChan {
+		msg := message.NewMessageWithSource(output.Content, output.Status, processingSource, output.IngestionTimestamp)
+		r := process(rules, msg)
+		r.FirstLine = lineAt(lineOffsets, offset)
+		r.LastLine = r.FirstLine
+		if output.RawDataLen > 0 {
+			r.LastLine = lineAt(lineOffsets, offset+output.RawDataLen-1)
+		}
+		offset += output.RawDataLen
+		result.Messages = append(result.Messages, r)
+	}
+	if pattern := d.GetDetectedPattern(); pattern != nil {
+		result.DetectedMultiLinePattern = pattern.String()
+	}
+	return result, nil
+}
+
+// process applies rules on msg the way the pipelines do, and reports the outcome of each of them.
+func process(rules []*config.ProcessingRule, msg *message.Message) Result {
+	result := Result{Original: string(msg.Content)}
+	p := processor.New(nil, nil, rules, processor.JSONEncoder, &diagnostic.NoopMessageReceiver{}, nil)
+	keep, content := p.ApplyRules(msg, func(rule *config.ProcessingRule, input []byte, matched bool) {
+		ruleResult := RuleResult{Name: rule.Name, Type: rule.Type, Matched: matched}
+		if rule.Type == config.MaskSequences {
+			for _, masked := range rule.Regex.FindAll(input, -1) {
+				ruleResult.Masked = append(ruleResult.Masked, string(masked))
+			}
+		}
+		result.Rules = append(result.Rules, ruleResult)
+	})
+	if !keep {
+		result.DroppedBy = result.Rules[len(result.Rules)-1].Name
+		return result
+	}
+	result.Content = string(content)
+	result.Status = msg.GetStatus()
+	result.Timestamp = msg.Timestamp
+	return result
+}
+
+// nonMultiLineRules returns rules without the multi_line rules, which are applied by the decoder.
+func nonMultiLineRules(rules []*config.ProcessingRule) []*config.ProcessingRule {
+	var filtered []*config.ProcessingRule
+	for _, rule := range rules {
+		if rule.Type != config.MultiLine {
+			filtered = append(filtered, rule)
+		}
+	}
+	return filtered
+}
+
+// lineAt returns the number, starting at 1, of the line holding the byte at offset.
+func lineAt(lineOffsets []int, offset int) int {
+	return sort.Search(len(lineOffsets), func(i int) bool {
+		return lineOffsets[i] > offset
+	})
+}
@@ -0,0 +1,113 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package ruletester
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestRun(t *testing.T) {
+	rules := []*config.ProcessingRule{
+		{Type: config.ExcludeAtMatch, Name: "healthcheck", Pattern: "GET /health"},
+		{Type: config.GrokParser, Name: "app", Pattern: "%{TIMESTAMP_ISO8601:ts} %{LOGLEVEL:level} %{GREEDYDATA:message}", TimestampField: "ts", StatusField: "level"},
+	}
+	results, err := Run(rules, []string{
+		"2021-10-12T08:30:01Z WARN disk almost full",
+		"2021-10-12T08:30:02Z INFO GET /health",
+		"unstructured",
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	assert.JSONEq(t, `{"ts":"2021-10-12T08:30:01Z","level":"WARN","message":"disk almost full"}`, results[0].Content)
+	assert.Equal(t, []RuleResult{{Name: "healthcheck", Type: config.ExcludeAtMatch}, {Name: "app", Type: config.GrokParser, Matched: true}}, results[0].Rules)
+	assert.Equal(t, message.StatusWarning, results[0].Status)
+	assert.Equal(t, time.Date(2021, time.October, 12, 8, 30, 1, 0, time.UTC), results[0].Timestamp)
+
+	assert.Equal(t, "healthcheck", results[1].DroppedBy)
+	assert.Equal(t, []RuleResult{{Name: "healthcheck", Type: config.ExcludeAtMatch, Matched: true}}, results[1].Rules)
+	assert.Empty(t, results[1].Content)
+
+	assert.Equal(t, Result{
+		Original:  "unstructured",
+		FirstLine: 3,
+		LastLine:  3,
+		Rules:     []RuleResult{{Name: "healthcheck", Type: config.ExcludeAtMatch}, {Name: "app", Type: config.GrokParser}},
+		Content:   "unstructured",
+		Status:    message.StatusInfo,
+	}, results[2])
+}
+
+func TestRunSharesJSONContent(t *testing.T) {
+	rules := []*config.ProcessingRule{
+		{Type: config.JSONDropKeys, Name: "drop", Keys: []string{"missing"}},
+		{Type: config.MaskSequences, Name: "secrets", Pattern: `secret[0-9]+`, ReplacePlaceholder: "****"},
+		{Type: config.JSONRenameKey, Name: "rename", Keys: []string{"a"}, Target: "b"},
+	}
+	results, err := Run(rules, []string{`{"a":1,"msg":"secret123"}`})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+
+	// the output is the one of the pipelines, the rules being applied in a single run
+	assert.JSONEq(t, `{"b":1,"msg":"****"}`, results[0].Content)
+	assert.Equal(t, []RuleResult{
+		{Name: "drop", Type: config.JSONDropKeys},
+		{Name: "secrets", Type: config.MaskSequences, Matched: true, Masked: []string{"secret123"}},
+		{Name: "rename", Type: config.JSONRenameKey, Matched: true},
+	}, results[0].Rules)
+}
+
+func TestRunSource(t *testing.T) {
+	sourceConfig := &config.LogsConfig{
+		Type: config.FileType,
+		Path: "/var/log/app.log",
+		ProcessingRules: []*config.ProcessingRule{
+			{Type: config.MultiLine, Name: "new_entry", Pattern: `\d{4}-\d{2}-\d{2}`},
+			{Type: config.MaskSequences, Name: "cards", Pattern: `\d{4}-\d{4}-\d{4}`, ReplacePlaceholder: "[card]"},
+		},
+	}
+	globalRules := []*config.ProcessingRule{{Type: config.ExcludeAtMatch, Name: "debug", Pattern: "DEBUG"}}
+	require.NoError(t, config.CompileProcessingRules(globalRules))
+
+	sample := "2021-10-12 ERROR payment failed\n  card 1234-5678-9012\n  at charge()\n2021-10-12 DEBUG retrying\n2021-10-12 INFO done"
+	result, err := RunSource(sourceConfig, globalRules, []byte(sample))
+	require.NoError(t, err)
+	require.Len(t, result.Messages, 3)
+
+	first := result.Messages[0]
+	assert.Equal(t, 1, first.FirstLine)
+	assert.Equal(t, 3, first.LastLine)
+	assert.Equal(t, `2021-10-12 ERROR payment failed\n  card [card]\n  at charge()`, first.Content)
+	assert.Equal(t, []RuleResult{
+		{Name: "debug", Type: config.ExcludeAtMatch},
+		{Name: "cards", Type: config.MaskSequences, Matched: true, Masked: []string{"1234-5678-9012"}},
+	}, first.Rules)
+
+	assert.Equal(t, 4, result.Messages[1].FirstLine)
+	assert.Equal(t, 4, result.Messages[1].LastLine)
+	assert.Equal(t, "debug", result.Messages[1].DroppedBy)
+
+	assert.Equal(t, 5, result.Messages[2].FirstLine)
+	assert.Equal(t, "2021-10-12 INFO done", result.Messages[2].Content)
+	assert.Empty(t, result.DetectedMultiLinePattern)
+}
+
+func TestRunSourceWithInvalidConfig(t *testing.T) {
+	_, err := RunSource(&config.LogsConfig{Type: config.FileType}, nil, []byte("hello"))
+	assert.Error(t, err)
+}
+
+func TestRunWithInvalidRules(t *testing.T) {
+	_, err := Run([]*config.ProcessingRule{{Type: config.GrokParser, Name: "app", Pattern: "%{UNKNOWN}"}}, []string{"hello"})
+	assert.Error(t, err)
+}
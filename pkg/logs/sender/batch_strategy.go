@@ -17,6 +17,9 @@ import (
 
 var (
 	tlmDroppedTooLarge = telemetry.NewCounter("logs_sender_batch_strategy", "dropped_too_large", []string{"pipeline"}, "Number of payloads dropped due to being too large")
+	tlmUnencodedBytes  = telemetry.NewCounter("logs_sender_batch_strategy", "unencoded_bytes", []string{"pipeline", "encoding"}, "Number of bytes of the payloads before encoding")
+	tlmEncodedBytes    = telemetry.NewCounter("logs_sender_batch_strategy", "encoded_bytes", []string{"pipeline", "encoding"}, "Number of bytes of the payloads after encoding")
+	tlmEncodingRatio   = telemetry.NewGauge("logs_sender_batch_strategy", "compression_ratio", []string{"pipeline", "encoding"}, "Ratio between the size of the payloads before and after encoding")
 )
 
 // batchStrategy contains all the logic to send logs in batch.
@@ -31,6 +34,10 @@ type batchStrategy struct {
 	contentEncoding ContentEncoding
 	stopChan        chan struct{} // closed when the goroutine has finished
 	clock           clock.Clock
+
+	// unencodedBytes and encodedBytes are the total sizes of the payloads sent, used to compute the compression ratio
+	unencodedBytes int64
+	encodedBytes   int64
 }
 
 // NewBatchStrategy returns a new batch concurrent strategy with the specified batch & content size limits
@@ -139,6 +146,7 @@ func (s *batchStrategy) sendMessages(messages []*message.Message, outputChan cha
 		log.Warn("Encoding failed - dropping payload", err)
 		return
 	}
+	s.updateEncodingTelemetry(len(serializedMessage), len(encodedPayload))
 
 	outputChan <- &message.Payload{
 		Messages:      messages,
@@ -147,3 +155,14 @@ func (s *batchStrategy) sendMessages(messages []*message.Message, outputChan cha
 		UnencodedSize: len(serializedMessage),
 	}
 }
+
+func (s *batchStrategy) updateEncodingTelemetry(unencodedSize int, encodedSize int) {
+	encoding := s.contentEncoding.name()
+	tlmUnencodedBytes.Add(float64(unencodedSize), s.pipelineName, encoding)
+	tlmEncodedBytes.Add(float64(encodedSize), s.pipelineName, encoding)
+	s.unencodedBytes += int64(unencodedSize)
+	s.encodedBytes += int64(encodedSize)
+	if s.encodedBytes > 0 {
+		tlmEncodingRatio.Set(float64(s.unencodedBytes)/float64(s.encodedBytes), s.pipelineName, encoding)
+	}
+}
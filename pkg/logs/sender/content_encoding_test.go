@@ -10,7 +10,10 @@ import (
 	"compress/gzip"
 	"testing"
 
+	"github.com/DataDog/zstd"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
 )
 
 func TestIdentityContentType(t *testing.T) {
@@ -42,6 +45,35 @@ func TestGzipContentEncodingName(t *testing.T) {
 	assert.Equal(t, NewGzipContentEncoding(gzip.BestCompression).name(), "gzip")
 }
 
+func TestZstdContentEncoding(t *testing.T) {
+	payload := []byte("my payload")
+
+	encodedPayload, err := NewZstdContentEncoding(zstd.BestCompression).encode(payload)
+	assert.Nil(t, err)
+
+	decompressedPayload, err := zstd.Decompress(nil, encodedPayload)
+	assert.Nil(t, err)
+
+	assert.Equal(t, payload, decompressedPayload)
+}
+
+func TestZstdContentEncodingName(t *testing.T) {
+	assert.Equal(t, NewZstdContentEncoding(zstd.BestCompression).name(), "zstd")
+}
+
+func TestZstdContentEncodingLevel(t *testing.T) {
+	assert.Equal(t, zstd.BestSpeed, NewZstdContentEncoding(-1).level)
+	assert.Equal(t, 3, NewZstdContentEncoding(3).level)
+	assert.Equal(t, zstd.BestCompression, NewZstdContentEncoding(100).level)
+}
+
+func TestNewEndpointContentEncoding(t *testing.T) {
+	assert.Equal(t, IdentityContentType, NewEndpointContentEncoding(config.Endpoint{UseCompression: false, CompressionKind: config.ZstdCompressionKind}))
+	assert.Equal(t, NewGzipContentEncoding(6), NewEndpointContentEncoding(config.Endpoint{UseCompression: true, CompressionKind: config.GzipCompressionKind, CompressionLevel: 6}))
+	assert.Equal(t, NewGzipContentEncoding(6), NewEndpointContentEncoding(config.Endpoint{UseCompression: true, CompressionLevel: 6}))
+	assert.Equal(t, NewZstdContentEncoding(2), NewEndpointContentEncoding(config.Endpoint{UseCompression: true, CompressionKind: config.ZstdCompressionKind, CompressionLevel: 2}))
+}
+
 func decompress(payload []byte) ([]byte, error) {
 	reader, err := gzip.NewReader(bytes.NewReader(payload))
 	if err != nil {
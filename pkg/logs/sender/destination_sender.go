@@ -96,6 +96,13 @@ func (d *DestinationSender) Send(payload *message.Payload) bool {
 	return false
 }
 
+// IsRetrying returns true if the destination is retrying payloads.
+func (d *DestinationSender) IsRetrying() bool {
+	d.retryLock.Lock()
+	defer d.retryLock.Unlock()
+	return d.lastRetryState
+}
+
 // NonBlockingSend tries to send the payload and fails silently if the input is full.
 // returns false if the buffer is full - true if successful.
 func (d *DestinationSender) NonBlockingSend(payload *message.Payload) bool {
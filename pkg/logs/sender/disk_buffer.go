@@ -0,0 +1,247 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	diskBufferExtension     = ".logs"
+	diskBufferTempExtension = ".tmp"
+	diskBufferFileFormat    = "2006_01_02__15_04_05.000000000_"
+)
+
+var (
+	tlmDiskBufferStored   = telemetry.NewCounter("logs_sender_disk_buffer", "payloads_stored", []string{}, "Payloads stored on disk while the destinations were unavailable")
+	tlmDiskBufferReplayed = telemetry.NewCounter("logs_sender_disk_buffer", "payloads_replayed", []string{}, "Payloads read back from disk and sent to the destinations")
+	tlmDiskBufferDropped  = telemetry.NewCounter("logs_sender_disk_buffer", "payloads_dropped", []string{"reason"}, "Payloads dropped by the disk buffer")
+	tlmDiskBufferSize     = telemetry.NewGauge("logs_sender_disk_buffer", "size_bytes", []string{}, "Size in bytes of the payloads stored on disk")
+	tlmDiskBufferFiles    = telemetry.NewGauge("logs_sender_disk_buffer", "files", []string{}, "Number of payloads stored on disk")
+)
+
+// diskBufferHeader is written on the first line of each file, before the encoded payload.
+type diskBufferHeader struct {
+	Encoding      string `json:"encoding"`
+	UnencodedSize int    `json:"unencoded_size"`
+}
+
+type diskBufferFile struct {
+	path    string
+	size    int64
+	created time.Time
+}
+
+// DiskBuffer stores encoded payloads on disk while the reliable destinations are
+// unavailable, to replay them once they recover. It is bounded in size and age: the
+// oldest payloads are dropped to make room for new ones, and outdated payloads are
+// dropped instead of being replayed.
+// A DiskBuffer is safe for concurrent use, it can be shared by several senders.
+type DiskBuffer struct {
+	path           string
+	maxSizeInBytes int64
+	maxAge         time.Duration
+
+	mu                 sync.Mutex
+	files              []diskBufferFile
+	currentSizeInBytes int64
+}
+
+// NewDiskBuffer returns a disk buffer storing payloads in path. The payloads stored
+// by a previous run of the agent are reloaded to be replayed.
+func NewDiskBuffer(path string, maxSizeInBytes int64, maxAge time.Duration) (*DiskBuffer, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, err
+	}
+	b := &DiskBuffer{
+		path:           path,
+		maxSizeInBytes: maxSizeInBytes,
+		maxAge:         maxAge,
+	}
+	if err := b.reloadExistingFiles(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Store writes a payload on disk, returns an error if the payload could not be stored.
+func (b *DiskBuffer) Store(payload *message.Payload) error {
+	header, err := json.Marshal(diskBufferHeader{Encoding: payload.Encoding, UnencodedSize: payload.UnencodedSize})
+	if err != nil {
+		return err
+	}
+	size := int64(len(header) + 1 + len(payload.Encoded))
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if size > b.maxSizeInBytes {
+		tlmDiskBufferDropped.Inc("too_large")
+		return fmt.Errorf("payload too large for the disk buffer. Current:%v Maximum:%v", size, b.maxSizeInBytes)
+	}
+	b.removeOutdatedFiles()
+	for len(b.files) > 0 && b.currentSizeInBytes+size > b.maxSizeInBytes {
+		log.Warnf("Maximum disk space for logs payloads is reached. Removing %s", b.files[0].path)
+		b.removeFileAt(0)
+		tlmDiskBufferDropped.Inc("max_size")
+	}
+
+	// write to a temporary file first so that a partially written file is never replayed
+	file, err := ioutil.TempFile(b.path, time.Now().UTC().Format(diskBufferFileFormat)+"*"+diskBufferTempExtension)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(append(header, '\n'), payload.Encoded...))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
+	path := strings.TrimSuffix(file.Name(), diskBufferTempExtension) + diskBufferExtension
+	if err := os.Rename(file.Name(), path); err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
+
+	b.files = append(b.files, diskBufferFile{path: path, size: size, created: time.Now()})
+	b.currentSizeInBytes += size
+	b.updateTelemetry()
+	tlmDiskBufferStored.Inc()
+	return nil
+}
+
+// Replay reads the stored payloads, oldest first, and passes them to send until
+// send returns false or the buffer is empty. Payloads are removed from disk once
+// send accepted them. Returns the number of payloads replayed.
+func (b *DiskBuffer) Replay(send func(*message.Payload) bool) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.removeOutdatedFiles()
+	replayed := 0
+	for len(b.files) > 0 {
+		payload, err := readDiskBufferFile(b.files[0].path)
+		if err != nil {
+			log.Warnf("Could not read logs payload %s, dropping it: %v", b.files[0].path, err)
+			b.removeFileAt(0)
+			tlmDiskBufferDropped.Inc("corrupted")
+			continue
+		}
+		if !send(payload) {
+			break
+		}
+		b.removeFileAt(0)
+		tlmDiskBufferReplayed.Inc()
+		replayed++
+	}
+	b.updateTelemetry()
+	return replayed
+}
+
+// IsEmpty returns true if there is no payload stored on disk.
+func (b *DiskBuffer) IsEmpty() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.files) == 0
+}
+
+// SizeInBytes returns the size of the payloads stored on disk.
+func (b *DiskBuffer) SizeInBytes() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.currentSizeInBytes
+}
+
+func (b *DiskBuffer) removeOutdatedFiles() {
+	if b.maxAge <= 0 {
+		return
+	}
+	deadline := time.Now().Add(-b.maxAge)
+	for len(b.files) > 0 && b.files[0].created.Before(deadline) {
+		log.Warnf("Logs payload %s is older than %v. Removing it", b.files[0].path, b.maxAge)
+		b.removeFileAt(0)
+		tlmDiskBufferDropped.Inc("max_age")
+	}
+}
+
+func (b *DiskBuffer) removeFileAt(index int) {
+	file := b.files[index]
+	b.files = append(b.files[:index], b.files[index+1:]...)
+	b.currentSizeInBytes -= file.size
+	if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Could not remove logs payload %s: %v", file.path, err)
+	}
+}
+
+func (b *DiskBuffer) reloadExistingFiles() error {
+	entries, err := ioutil.ReadDir(b.path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(b.path, entry.Name())
+		switch filepath.Ext(entry.Name()) {
+		case diskBufferTempExtension:
+			// left over by an interrupted write
+			_ = os.Remove(path)
+		case diskBufferExtension:
+			b.files = append(b.files, diskBufferFile{path: path, size: entry.Size(), created: entry.ModTime()})
+			b.currentSizeInBytes += entry.Size()
+		}
+	}
+	// the file names start with their creation time, their order is more precise than the modification times
+	sort.SliceStable(b.files, func(i, j int) bool {
+		return b.files[i].path < b.files[j].path
+	})
+	if len(b.files) > 0 {
+		log.Infof("Found %d logs payloads to replay in %s", len(b.files), b.path)
+	}
+	b.updateTelemetry()
+	return nil
+}
+
+func (b *DiskBuffer) updateTelemetry() {
+	tlmDiskBufferSize.Set(float64(b.currentSizeInBytes))
+	tlmDiskBufferFiles.Set(float64(len(b.files)))
+}
+
+func readDiskBufferFile(path string) (*message.Payload, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	eol := bytes.IndexByte(content, '\n')
+	if eol == -1 {
+		return nil, fmt.Errorf("missing header")
+	}
+	var header diskBufferHeader
+	if err := json.Unmarshal(content[:eol], &header); err != nil {
+		return nil, fmt.Errorf("invalid header: %v", err)
+	}
+	return &message.Payload{
+		Encoded:       content[eol+1:],
+		Encoding:      header.Encoding,
+		UnencodedSize: header.UnencodedSize,
+	}, nil
+}
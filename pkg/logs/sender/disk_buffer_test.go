@@ -0,0 +1,135 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func newEncodedPayload(content string) *message.Payload {
+	return &message.Payload{
+		Encoded:       []byte(content),
+		Encoding:      "gzip",
+		UnencodedSize: len(content) * 2,
+	}
+}
+
+func replayAll(b *DiskBuffer) []*message.Payload {
+	var payloads []*message.Payload
+	b.Replay(func(payload *message.Payload) bool {
+		payloads = append(payloads, payload)
+		return true
+	})
+	return payloads
+}
+
+func TestDiskBufferStoreAndReplay(t *testing.T) {
+	b, err := NewDiskBuffer(t.TempDir(), 1024, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, b.IsEmpty())
+
+	require.NoError(t, b.Store(newEncodedPayload("first")))
+	require.NoError(t, b.Store(newEncodedPayload("second")))
+	assert.False(t, b.IsEmpty())
+
+	payloads := replayAll(b)
+	require.Len(t, payloads, 2)
+	assert.Equal(t, "first", string(payloads[0].Encoded))
+	assert.Equal(t, "gzip", payloads[0].Encoding)
+	assert.Equal(t, 10, payloads[0].UnencodedSize)
+	assert.Equal(t, "second", string(payloads[1].Encoded))
+	assert.True(t, b.IsEmpty())
+	assert.Equal(t, int64(0), b.SizeInBytes())
+}
+
+func TestDiskBufferReplayStopsWhenSendFails(t *testing.T) {
+	b, err := NewDiskBuffer(t.TempDir(), 1024, time.Hour)
+	require.NoError(t, err)
+
+	require.NoError(t, b.Store(newEncodedPayload("first")))
+	require.NoError(t, b.Store(newEncodedPayload("second")))
+
+	replayed := b.Replay(func(payload *message.Payload) bool {
+		return string(payload.Encoded) == "first"
+	})
+	assert.Equal(t, 1, replayed)
+
+	payloads := replayAll(b)
+	require.Len(t, payloads, 1)
+	assert.Equal(t, "second", string(payloads[0].Encoded))
+}
+
+func TestDiskBufferEvictsOldestPayloads(t *testing.T) {
+	b, err := NewDiskBuffer(t.TempDir(), 150, time.Hour)
+	require.NoError(t, err)
+
+	// each file holds a header line and a 50 bytes payload, only one fits in the buffer
+	for _, content := range []string{"a", "b", "c"} {
+		require.NoError(t, b.Store(newEncodedPayload(string(make([]byte, 49))+content)))
+	}
+	assert.True(t, b.SizeInBytes() <= 150)
+
+	payloads := replayAll(b)
+	require.Len(t, payloads, 1)
+	assert.Equal(t, byte('c'), payloads[0].Encoded[49])
+}
+
+func TestDiskBufferRejectsTooLargePayloads(t *testing.T) {
+	b, err := NewDiskBuffer(t.TempDir(), 10, time.Hour)
+	require.NoError(t, err)
+
+	assert.Error(t, b.Store(newEncodedPayload("too large for the buffer")))
+	assert.True(t, b.IsEmpty())
+}
+
+func TestDiskBufferDropsOutdatedPayloads(t *testing.T) {
+	b, err := NewDiskBuffer(t.TempDir(), 1024, time.Hour)
+	require.NoError(t, err)
+
+	require.NoError(t, b.Store(newEncodedPayload("outdated")))
+	b.files[0].created = time.Now().Add(-2 * time.Hour)
+	require.NoError(t, b.Store(newEncodedPayload("recent")))
+
+	payloads := replayAll(b)
+	require.Len(t, payloads, 1)
+	assert.Equal(t, "recent", string(payloads[0].Encoded))
+}
+
+func TestDiskBufferReloadsExistingPayloads(t *testing.T) {
+	path := t.TempDir()
+	b, err := NewDiskBuffer(path, 1024, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, b.Store(newEncodedPayload("first")))
+	require.NoError(t, b.Store(newEncodedPayload("second")))
+
+	// a file left over by an interrupted write and a corrupted file
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "partial"+diskBufferTempExtension), []byte("partial"), 0600))
+	corrupted := filepath.Join(path, "corrupted"+diskBufferExtension)
+	require.NoError(t, ioutil.WriteFile(corrupted, []byte("no header"), 0600))
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(corrupted, future, future))
+
+	b, err = NewDiskBuffer(path, 1024, time.Hour)
+	require.NoError(t, err)
+
+	payloads := replayAll(b)
+	require.Len(t, payloads, 2)
+	assert.Equal(t, "first", string(payloads[0].Encoded))
+	assert.Equal(t, "second", string(payloads[1].Encoded))
+
+	entries, err := ioutil.ReadDir(path)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -12,8 +12,13 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
+// diskBufferReplayInterval is the interval at which payloads stored on disk are replayed
+// to the reliable destinations.
+const diskBufferReplayInterval = 100 * time.Millisecond
+
 var (
 	tlmPayloadsDropped = telemetry.NewCounter("logs_sender", "payloads_dropped", []string{"reliable", "destination"}, "Payloads dropped")
 	tlmMessagesDropped = telemetry.NewCounter("logs_sender", "messages_dropped", []string{"reliable", "destination"}, "Messages dropped")
@@ -27,20 +32,31 @@ var (
 // one reliable destination is also sending logs. However they do not update
 // the auditor or block the pipeline if they fail. There will always be at
 // least 1 reliable destination (the main destination).
+// When a disk buffer is set, payloads are written to disk instead of blocking
+// the pipeline while all reliable destinations are retrying, and are replayed
+// once one of them recovers.
 type Sender struct {
 	inputChan    chan *message.Payload
 	outputChan   chan *message.Payload
 	destinations *client.Destinations
+	diskBuffer   *DiskBuffer
 	done         chan struct{}
 	bufferSize   int
 }
 
 // NewSender returns a new sender.
 func NewSender(inputChan chan *message.Payload, outputChan chan *message.Payload, destinations *client.Destinations, bufferSize int) *Sender {
+	return NewSenderWithDiskBuffer(inputChan, outputChan, destinations, bufferSize, nil)
+}
+
+// NewSenderWithDiskBuffer returns a new sender buffering payloads in diskBuffer during outages,
+// diskBuffer can be nil.
+func NewSenderWithDiskBuffer(inputChan chan *message.Payload, outputChan chan *message.Payload, destinations *client.Destinations, bufferSize int, diskBuffer *DiskBuffer) *Sender {
 	return &Sender{
 		inputChan:    inputChan,
 		outputChan:   outputChan,
 		destinations: destinations,
+		diskBuffer:   diskBuffer,
 		done:         make(chan struct{}),
 		bufferSize:   bufferSize,
 	}
@@ -64,57 +80,116 @@ func (s *Sender) run() {
 	sink := additionalDestinationsSink(s.bufferSize)
 	unreliableDestinations := buildDestinationSenders(s.destinations.Unreliable, sink, s.bufferSize)
 
-	for payload := range s.inputChan {
-		var startInUse = time.Now()
+	var replayTicker <-chan time.Time
+	if s.diskBuffer != nil {
+		ticker := time.NewTicker(diskBufferReplayInterval)
+		defer ticker.Stop()
+		replayTicker = ticker.C
+	}
 
-		sent := false
-		for !sent {
-			for _, destSender := range reliableDestinations {
-				if destSender.Send(payload) {
-					sent = true
-				}
+loop:
+	for {
+		select {
+		case payload, isOpen := <-s.inputChan:
+			if !isOpen {
+				break loop
 			}
+			s.send(payload, reliableDestinations, unreliableDestinations)
+		case <-replayTicker:
+			s.replay(reliableDestinations)
+		}
+	}
 
-			if !sent {
-				// Throttle the poll loop while waiting for a send to succeed
-				// This will only happen when all reliable destinations
-				// are blocked so logs have no where to go.
-				time.Sleep(100 * time.Millisecond)
-			}
+	// Cleanup the destinations
+	for _, destSender := range reliableDestinations {
+		destSender.Stop()
+	}
+	for _, destSender := range unreliableDestinations {
+		destSender.Stop()
+	}
+	close(sink)
+	s.done <- struct{}{}
+}
+
+// send sends a payload to the destinations, blocking until one of the reliable destinations
+// accepts it, or until it is stored in the disk buffer.
+func (s *Sender) send(payload *message.Payload, reliableDestinations []*DestinationSender, unreliableDestinations []*DestinationSender) {
+	if s.diskBuffer != nil && allRetrying(reliableDestinations) {
+		err := s.diskBuffer.Store(payload)
+		if err == nil {
+			// the payload is safely stored, commit it to the auditor
+			s.outputChan <- payload
+			return
 		}
+		log.Warnf("Could not store logs payload on disk: %v", err)
+	}
+
+	var startInUse = time.Now()
 
-		for i, destSender := range reliableDestinations {
-			// If an endpoint is stuck in the previous step, try to buffer the payloads if we have room to mitigate
-			// loss on intermittent failures.
-			if !destSender.lastSendSucceeded {
-				if !destSender.NonBlockingSend(payload) {
-					tlmPayloadsDropped.Inc("true", strconv.Itoa(i))
-					tlmMessagesDropped.Add(float64(len(payload.Messages)), "true", strconv.Itoa(i))
-				}
+	sent := false
+	for !sent {
+		for _, destSender := range reliableDestinations {
+			if destSender.Send(payload) {
+				sent = true
 			}
 		}
 
-		// Attempt to send to unreliable destinations
-		for i, destSender := range unreliableDestinations {
+		if !sent {
+			// Throttle the poll loop while waiting for a send to succeed
+			// This will only happen when all reliable destinations
+			// are blocked so logs have no where to go.
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	for i, destSender := range reliableDestinations {
+		// If an endpoint is stuck in the previous step, try to buffer the payloads if we have room to mitigate
+		// loss on intermittent failures.
+		if !destSender.lastSendSucceeded {
 			if !destSender.NonBlockingSend(payload) {
-				tlmPayloadsDropped.Inc("false", strconv.Itoa(i))
-				tlmMessagesDropped.Add(float64(len(payload.Messages)), "false", strconv.Itoa(i))
+				tlmPayloadsDropped.Inc("true", strconv.Itoa(i))
+				tlmMessagesDropped.Add(float64(len(payload.Messages)), "true", strconv.Itoa(i))
 			}
 		}
+	}
 
-		inUse := float64(time.Since(startInUse) / time.Millisecond)
-		tlmSendWaitTime.Add(inUse)
+	// Attempt to send to unreliable destinations
+	for i, destSender := range unreliableDestinations {
+		if !destSender.NonBlockingSend(payload) {
+			tlmPayloadsDropped.Inc("false", strconv.Itoa(i))
+			tlmMessagesDropped.Add(float64(len(payload.Messages)), "false", strconv.Itoa(i))
+		}
 	}
 
-	// Cleanup the destinations
+	inUse := float64(time.Since(startInUse) / time.Millisecond)
+	tlmSendWaitTime.Add(inUse)
+}
+
+// replay sends the payloads stored on disk once none of the reliable destinations is retrying.
+func (s *Sender) replay(reliableDestinations []*DestinationSender) {
 	for _, destSender := range reliableDestinations {
-		destSender.Stop()
+		if destSender.IsRetrying() {
+			return
+		}
 	}
-	for _, destSender := range unreliableDestinations {
-		destSender.Stop()
+	s.diskBuffer.Replay(func(payload *message.Payload) bool {
+		sent := false
+		for _, destSender := range reliableDestinations {
+			if destSender.NonBlockingSend(payload) {
+				sent = true
+			}
+		}
+		return sent
+	})
+}
+
+func allRetrying(destinations []*DestinationSender) bool {
+	for _, destSender := range destinations {
+		if !destSender.IsRetrying() {
+			return false
+		}
 	}
-	close(sink)
-	s.done <- struct{}{}
+	return len(destinations) > 0
 }
 
 // Drains the output channel from destinations that don't update the auditor.
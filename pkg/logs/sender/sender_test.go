@@ -7,6 +7,7 @@ package sender
 
 import (
 	"testing"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/logs/client/http"
@@ -299,3 +300,37 @@ func TestSenderReliableWhenOneFailsAndRecovers(t *testing.T) {
 	reliableServer2.Stop()
 	sender.Stop()
 }
+
+func TestSenderStoresPayloadsOnDiskWhileDestinationsAreRetrying(t *testing.T) {
+	diskBuffer, err := NewDiskBuffer(t.TempDir(), 1024, time.Hour)
+	assert.NoError(t, err)
+
+	output := make(chan *message.Payload, 1)
+	sender := NewSenderWithDiskBuffer(nil, output, nil, 0, diskBuffer)
+
+	dest := &mockDestination{}
+	destSender := NewDestinationSender(dest, make(chan *message.Payload, 1), 1)
+	reliableDestinations := []*DestinationSender{destSender}
+
+	dest.isRetrying <- true
+	assert.Eventually(t, destSender.IsRetrying, time.Second, 10*time.Millisecond)
+
+	// the payload is stored on disk and committed to the auditor
+	payload := &message.Payload{Encoded: []byte("payload"), Encoding: "identity"}
+	sender.send(payload, reliableDestinations, nil)
+	assert.Equal(t, payload, <-output)
+	assert.False(t, diskBuffer.IsEmpty())
+
+	// nothing is replayed while the destination is retrying
+	sender.replay(reliableDestinations)
+	assert.False(t, diskBuffer.IsEmpty())
+
+	dest.isRetrying <- false
+	assert.Eventually(t, func() bool { return !destSender.IsRetrying() }, time.Second, 10*time.Millisecond)
+
+	sender.replay(reliableDestinations)
+	assert.True(t, diskBuffer.IsEmpty())
+	replayed := <-dest.input
+	assert.Equal(t, "payload", string(replayed.Encoded))
+	assert.Empty(t, replayed.Messages)
+}
@@ -43,6 +43,12 @@ func (a *AddSampleTelemetry) Inc(isStateful bool) {
 
 // AddSample add a sample to the current ContextMetrics and initialize a new metrics if needed.
 func (m ContextMetrics) AddSample(contextKey ckey.ContextKey, sample *MetricSample, timestamp float64, interval int64, t *AddSampleTelemetry) error {
+	return m.AddSampleWithHistogramConfig(contextKey, sample, timestamp, interval, t, nil)
+}
+
+// AddSampleWithHistogramConfig is AddSample, a new histogram computing the aggregates and percentiles
+// of hc, or the default ones when hc is nil.
+func (m ContextMetrics) AddSampleWithHistogramConfig(contextKey ckey.ContextKey, sample *MetricSample, timestamp float64, interval int64, t *AddSampleTelemetry, hc *HistogramConfig) error {
 	if math.IsInf(sample.Value, 0) || math.IsNaN(sample.Value) {
 		return fmt.Errorf("sample with value '%v'", sample.Value)
 	}
@@ -57,7 +63,7 @@ func (m ContextMetrics) AddSample(contextKey ckey.ContextKey, sample *MetricSamp
 		case MonotonicCountType:
 			m[contextKey] = &MonotonicCount{}
 		case HistogramType:
-			m[contextKey] = NewHistogram(interval) // default histogram configuration (no call to `configure`) for now
+			m[contextKey] = NewHistogramWithConfig(interval, hc)
 		case HistorateType:
 			m[contextKey] = NewHistorate(interval) // internal histogram has the configuration for now
 		case SetType:
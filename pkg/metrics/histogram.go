@@ -57,26 +57,33 @@ type histogramPercentilesConfig struct {
 func (h *histogramPercentilesConfig) percentiles() []int {
 	res := []int{}
 	for _, p := range h.Percentiles {
-		i, err := strconv.ParseFloat(p, 64)
+		i, err := parsePercentile(p)
 		if err != nil {
 			log.Errorf("Could not parse '%s' from 'histogram_percentiles' (skipping): %s", p, err)
 			continue
 		}
-		if i < 0 || i > 1 {
-			log.Errorf("histogram_percentiles must be between 0 and 1: skipping %f", i)
-			continue
-		}
-		// in some cases the '*100' will lower the number resulting in
-		// an int lower by 1 from what is expected (ex: 0.29 would
-		// become 28). As a workaround we add 0.5 before casting.
-		res = append(res, int(i*100+0.5))
+		res = append(res, i)
 	}
 	return res
 }
 
-// NewHistogram returns a newly initialized histogram
-func NewHistogram(interval int64) *Histogram {
-	// we initialize default value on the first histogram creation
+// parsePercentile parses a percentile between 0 and 1 to its value in the 0-100 range.
+func parsePercentile(p string) (int, error) {
+	i, err := strconv.ParseFloat(p, 64)
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 || i > 1 {
+		return 0, fmt.Errorf("percentiles must be between 0 and 1: %f", i)
+	}
+	// in some cases the '*100' will lower the number resulting in
+	// an int lower by 1 from what is expected (ex: 0.29 would
+	// become 28). As a workaround we add 0.5 before casting.
+	return int(i*100 + 0.5), nil
+}
+
+// loadDefaults loads the default aggregates and percentiles from the configuration, once.
+func loadDefaults() {
 	if defaultAggregates == nil {
 		defaultAggregates = config.Datadog.GetStringSlice("histogram_aggregates")
 	}
@@ -90,6 +97,60 @@ func NewHistogram(interval int64) *Histogram {
 			sort.Ints(defaultPercentiles)
 		}
 	}
+}
+
+// HistogramConfig holds the aggregates and percentiles computed by histograms.
+type HistogramConfig struct {
+	aggregates  []string
+	percentiles []int
+}
+
+// NewHistogramConfig validates aggregates, among min, max, median, avg, sum and count, and
+// percentiles, between 0 and 1 like in `histogram_percentiles`. nil aggregates or percentiles
+// are the ones of the `histogram_aggregates` and `histogram_percentiles` settings.
+func NewHistogramConfig(aggregates []string, percentiles []string) (*HistogramConfig, error) {
+	loadDefaults()
+	hc := &HistogramConfig{aggregates: defaultAggregates, percentiles: defaultPercentiles}
+
+	if aggregates != nil {
+		hc.aggregates = make([]string, 0, len(aggregates))
+		for _, aggregate := range aggregates {
+			switch aggregate {
+			case maxAgg, minAgg, medianAgg, avgAgg, sumAgg, countAgg:
+				hc.aggregates = append(hc.aggregates, aggregate)
+			default:
+				return nil, fmt.Errorf("unknown aggregate '%s', must be one of min, max, median, avg, sum or count", aggregate)
+			}
+		}
+	}
+	if percentiles != nil {
+		hc.percentiles = make([]int, 0, len(percentiles))
+		for _, p := range percentiles {
+			i, err := parsePercentile(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid percentile '%s': %s", p, err)
+			}
+			hc.percentiles = append(hc.percentiles, i)
+		}
+		sort.Ints(hc.percentiles)
+	}
+	return hc, nil
+}
+
+// Aggregates returns the aggregates computed.
+func (hc *HistogramConfig) Aggregates() []string {
+	return hc.aggregates
+}
+
+// Percentiles returns the percentiles computed, in the 1-100 range.
+func (hc *HistogramConfig) Percentiles() []int {
+	return hc.percentiles
+}
+
+// NewHistogram returns a newly initialized histogram
+func NewHistogram(interval int64) *Histogram {
+	// we initialize default value on the first histogram creation
+	loadDefaults()
 
 	return &Histogram{
 		interval:    interval,
@@ -98,6 +159,19 @@ func NewHistogram(interval int64) *Histogram {
 	}
 }
 
+// NewHistogramWithConfig returns a newly initialized histogram computing the aggregates and
+// percentiles of hc, or the default ones when hc is nil.
+func NewHistogramWithConfig(interval int64, hc *HistogramConfig) *Histogram {
+	if hc == nil {
+		return NewHistogram(interval)
+	}
+	return &Histogram{
+		interval:    interval,
+		aggregates:  hc.aggregates,
+		percentiles: hc.percentiles,
+	}
+}
+
 func (h *Histogram) configure(aggregates []string, percentiles []int) {
 	h.aggregates = aggregates
 	sort.Ints(percentiles)
@@ -62,6 +62,45 @@ func TestConfigure(t *testing.T) {
 	assert.Equal(t, []int{30, 50, 98}, hist.percentiles)
 }
 
+func TestNewHistogramConfig(t *testing.T) {
+	hc, err := NewHistogramConfig([]string{"min", "sum"}, []string{"0.99", "0.5"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"min", "sum"}, hc.Aggregates())
+	assert.Equal(t, []int{50, 99}, hc.Percentiles())
+
+	// nil aggregates or percentiles are the default ones
+	hc, err = NewHistogramConfig(nil, []string{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"max", "median", "avg", "count"}, hc.Aggregates())
+	assert.Empty(t, hc.Percentiles())
+
+	_, err = NewHistogramConfig([]string{"max", "p99"}, nil)
+	assert.Error(t, err)
+	_, err = NewHistogramConfig(nil, []string{"95"})
+	assert.Error(t, err)
+	_, err = NewHistogramConfig(nil, []string{"test"})
+	assert.Error(t, err)
+}
+
+func TestHistogramWithConfig(t *testing.T) {
+	hc, err := NewHistogramConfig([]string{"sum"}, []string{"0.5"})
+	require.NoError(t, err)
+	hist := NewHistogramWithConfig(10, hc)
+	hist.addSample(&MetricSample{Value: 1}, 50)
+	hist.addSample(&MetricSample{Value: 2}, 55)
+
+	series, err := hist.flush(60)
+	require.NoError(t, err)
+	require.Len(t, series, 2)
+	assert.Equal(t, ".sum", series[0].NameSuffix)
+	assert.Equal(t, 3.0, series[0].Points[0].Value)
+	assert.Equal(t, ".50percentile", series[1].NameSuffix)
+	assert.Equal(t, 1.0, series[1].Points[0].Value)
+
+	// the default configuration
+	assert.Equal(t, NewHistogram(10), NewHistogramWithConfig(10, nil))
+}
+
 func TestDefaultHistogramSampling(t *testing.T) {
 	// Initialize default histogram
 	mHistogram := NewHistogram(10)
@@ -0,0 +1,290 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package openmetrics exposes the series and sketches of the last flush of the aggregator in the
+// OpenMetrics text format, so that a local Prometheus can scrape the data sent to Datadog.
+package openmetrics
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/quantile"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// ContentType is the content type of the OpenMetrics text format
+	ContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+	typeGauge   = "gauge"
+	typeSummary = "summary"
+)
+
+// quantiles are the quantiles of the sketches exposed in the summaries
+var quantiles = []float64{0.5, 0.75, 0.9, 0.95, 0.99}
+
+// Exporter keeps the series and sketches of the last flush of the aggregator, and serves
+// them in the OpenMetrics text format.
+//
+// The series and sketches of a flush are added with AddSerie and AddSketches, and exposed
+// once Commit is called. The count and rate series are exposed as gauges of their value
+// over the flush interval, and the sketches as summaries of the flush interval.
+type Exporter struct {
+	mu      sync.Mutex
+	pending map[string]*family
+
+	lastMu sync.RWMutex
+	last   []byte
+}
+
+// family is a metric family, its samples are indexed by their labels.
+type family struct {
+	name    string
+	mtype   string
+	samples map[string]sample
+}
+
+// sample is the last point of a serie or sketch, with its rendered labels.
+type sample struct {
+	labels string
+	ts     float64
+	value  float64
+	sketch *quantile.Sketch
+}
+
+// NewExporter returns an exporter which exposes no metrics until the first commit.
+func NewExporter() *Exporter {
+	return &Exporter{
+		pending: make(map[string]*family),
+		last:    []byte("# EOF\n"),
+	}
+}
+
+// AddSerie adds the last point of a serie to the pending flush.
+func (e *Exporter) AddSerie(serie *metrics.Serie) {
+	if len(serie.Points) == 0 {
+		return
+	}
+	last := serie.Points[0]
+	for _, p := range serie.Points[1:] {
+		if p.Ts >= last.Ts {
+			last = p
+		}
+	}
+	labels := renderLabels(serie.Tags.UnsafeToReadOnlySliceString(), serie.Host, serie.Device)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.add(serie.Name, typeGauge, sample{labels: labels, ts: last.Ts, value: last.Value})
+}
+
+// AddSketches adds the last point of the sketches to the pending flush.
+func (e *Exporter) AddSketches(sketches metrics.SketchSeriesList) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, ss := range sketches {
+		if len(ss.Points) == 0 {
+			continue
+		}
+		last := ss.Points[0]
+		for _, p := range ss.Points[1:] {
+			if p.Ts >= last.Ts {
+				last = p
+			}
+		}
+		if last.Sketch == nil {
+			continue
+		}
+		labels := renderLabels(ss.Tags.UnsafeToReadOnlySliceString(), ss.Host, "")
+		e.add(ss.Name, typeSummary, sample{labels: labels, ts: float64(last.Ts), sketch: last.Sketch.Copy()})
+	}
+}
+
+// add adds a sample to its family, replacing an older sample with the same labels.
+// e.mu must be held.
+func (e *Exporter) add(name, mtype string, s sample) {
+	name = SanitizeName(name)
+	f, found := e.pending[name]
+	if !found {
+		f = &family{name: name, mtype: mtype, samples: make(map[string]sample)}
+		e.pending[name] = f
+	} else if f.mtype != mtype {
+		log.Debugf("OpenMetrics export: %s is already exposed as a %s, dropping its %s", name, f.mtype, mtype)
+		return
+	}
+	if previous, found := f.samples[s.labels]; found && previous.ts > s.ts {
+		return
+	}
+	f.samples[s.labels] = s
+}
+
+// Commit renders the pending flush, which replaces the metrics exposed.
+func (e *Exporter) Commit() {
+	e.mu.Lock()
+	pending := e.pending
+	e.pending = make(map[string]*family)
+	e.mu.Unlock()
+
+	rendered := render(pending)
+
+	e.lastMu.Lock()
+	e.last = rendered
+	e.lastMu.Unlock()
+}
+
+// Bytes returns the metrics exposed, in the OpenMetrics text format.
+func (e *Exporter) Bytes() []byte {
+	e.lastMu.RLock()
+	defer e.lastMu.RUnlock()
+	return e.last
+}
+
+// ServeHTTP serves the metrics exposed.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	w.Write(e.Bytes()) //nolint:errcheck
+}
+
+func render(families map[string]*family) []byte {
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	config := quantile.Default()
+	for _, name := range names {
+		f := families[name]
+		labels := make([]string, 0, len(f.samples))
+		for l := range f.samples {
+			labels = append(labels, l)
+		}
+		sort.Strings(labels)
+
+		buf.WriteString("# TYPE " + name + " " + f.mtype + "\n")
+		for _, l := range labels {
+			s := f.samples[l]
+			if f.mtype == typeGauge {
+				writeSample(&buf, name, l, s.value, s.ts)
+				continue
+			}
+			for _, q := range quantiles {
+				ql := `quantile="` + formatFloat(q) + `"`
+				if l != "" {
+					ql = l + "," + ql
+				}
+				writeSample(&buf, name, ql, s.sketch.Quantile(config, q), s.ts)
+			}
+			writeSample(&buf, name+"_sum", l, s.sketch.Basic.Sum, s.ts)
+			writeSample(&buf, name+"_count", l, float64(s.sketch.Basic.Cnt), s.ts)
+		}
+	}
+	buf.WriteString("# EOF\n")
+	return buf.Bytes()
+}
+
+func writeSample(buf *bytes.Buffer, name, labels string, value, ts float64) {
+	buf.WriteString(name)
+	if labels != "" {
+		buf.WriteString("{" + labels + "}")
+	}
+	buf.WriteString(" " + formatFloat(value) + " " + strconv.FormatFloat(ts, 'f', -1, 64) + "\n")
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// renderLabels translates the tags to labels, sorted by name: the "key:value" tags become
+// key="value" labels, the tags without value become tag="true" labels and the values of the
+// tags with the same key are joined with commas. The host and device are set as labels too.
+func renderLabels(tags []string, host, device string) string {
+	values := make(map[string][]string, len(tags)+2)
+	for _, tag := range tags {
+		name, value := tag, "true"
+		if i := strings.IndexByte(tag, ':'); i >= 0 {
+			name, value = tag[:i], tag[i+1:]
+		}
+		if name = SanitizeLabelName(name); name == "" || value == "" {
+			continue
+		}
+		values[name] = append(values[name], value)
+	}
+	if host != "" {
+		values["host"] = []string{host}
+	}
+	if device != "" {
+		values["device"] = []string{device}
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		vs := values[name]
+		sort.Strings(vs)
+		b.WriteString(name + `="` + escapeLabelValue(strings.Join(vs, ",")) + `"`)
+	}
+	return b.String()
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
+
+// SanitizeName returns a valid OpenMetrics metric name, where the invalid characters, such as
+// the dots of the Datadog metric names, are replaced with underscores.
+func SanitizeName(name string) string {
+	return sanitize(name, true)
+}
+
+// SanitizeLabelName returns a valid OpenMetrics label name, where the invalid characters are
+// replaced with underscores. The names reserved to Prometheus, starting with "__", are
+// prefixed with "tag".
+func SanitizeLabelName(name string) string {
+	name = sanitize(name, false)
+	if strings.HasPrefix(name, "__") {
+		name = "tag" + name
+	}
+	return name
+}
+
+func sanitize(name string, colons bool) string {
+	b := []byte(name)
+	for i, c := range b {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == ':' && colons) {
+			b[i] = '_'
+		}
+	}
+	if len(b) > 0 && b[0] >= '0' && b[0] <= '9' {
+		return "_" + string(b)
+	}
+	return string(b)
+}
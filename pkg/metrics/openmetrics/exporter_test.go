@@ -0,0 +1,125 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package openmetrics
+
+import (
+	"io/ioutil"
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/quantile"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+func TestSanitize(t *testing.T) {
+	assert.Equal(t, "system_cpu_user", SanitizeName("system.cpu.user"))
+	assert.Equal(t, "ns:my_metric_", SanitizeName("ns:my-metric!"))
+	assert.Equal(t, "_2xx_count", SanitizeName("2xx.count"))
+	assert.Equal(t, "kube_namespace", SanitizeLabelName("kube.namespace"))
+	assert.Equal(t, "a_b", SanitizeLabelName("a:b"))
+	assert.Equal(t, "tag__name__", SanitizeLabelName("__name__"))
+}
+
+func TestRenderLabels(t *testing.T) {
+	assert.Equal(t, "", renderLabels(nil, "", ""))
+	assert.Equal(t,
+		`device="sda",env="prod,staging",host="myhost",path="/a\"b\\c",urgent="true",url="http://x"`,
+		renderLabels([]string{"urgent", "env:staging", "path:/a\"b\\c", "url:http://x", "env:prod", "empty:", "host:other"}, "myhost", "sda"))
+}
+
+func TestExporterSeries(t *testing.T) {
+	e := NewExporter()
+	assert.Equal(t, "# EOF\n", string(e.Bytes()))
+
+	e.AddSerie(&metrics.Serie{
+		Name:   "my.gauge",
+		Points: []metrics.Point{{Ts: 20, Value: 2}, {Ts: 10, Value: 1}},
+		Tags:   tagset.CompositeTagsFromSlice([]string{"env:prod"}),
+		Host:   "myhost",
+		MType:  metrics.APIGaugeType,
+	})
+	e.AddSerie(&metrics.Serie{
+		Name:   "my.count",
+		Points: []metrics.Point{{Ts: 10, Value: math.Inf(1)}},
+		MType:  metrics.APICountType,
+	})
+	e.AddSerie(&metrics.Serie{Name: "no.points"})
+	// nothing is exposed before the commit
+	assert.Equal(t, "# EOF\n", string(e.Bytes()))
+
+	e.Commit()
+	assert.Equal(t, `# TYPE my_count gauge
+my_count +Inf 10
+# TYPE my_gauge gauge
+my_gauge{env="prod",host="myhost"} 2 20
+# EOF
+`, string(e.Bytes()))
+
+	// the next commit replaces the metrics exposed
+	e.Commit()
+	assert.Equal(t, "# EOF\n", string(e.Bytes()))
+}
+
+func TestExporterSketches(t *testing.T) {
+	e := NewExporter()
+	sketch := &quantile.Sketch{}
+	sketch.Insert(quantile.Default(), 1, 2, 3, 4)
+
+	e.AddSketches(metrics.SketchSeriesList{{
+		Name:   "my.distribution",
+		Tags:   tagset.CompositeTagsFromSlice([]string{"env:prod"}),
+		Points: []metrics.SketchPoint{{Sketch: sketch, Ts: 10}},
+	}})
+	// a serie with the name of a sketch is dropped
+	e.AddSerie(&metrics.Serie{Name: "my.distribution", Points: []metrics.Point{{Ts: 10, Value: 1}}})
+	e.Commit()
+
+	c := quantile.Default()
+	assert.Equal(t, `# TYPE my_distribution summary
+my_distribution{env="prod",quantile="0.5"} `+formatFloat(sketch.Quantile(c, 0.5))+` 10
+my_distribution{env="prod",quantile="0.75"} `+formatFloat(sketch.Quantile(c, 0.75))+` 10
+my_distribution{env="prod",quantile="0.9"} `+formatFloat(sketch.Quantile(c, 0.9))+` 10
+my_distribution{env="prod",quantile="0.95"} `+formatFloat(sketch.Quantile(c, 0.95))+` 10
+my_distribution{env="prod",quantile="0.99"} `+formatFloat(sketch.Quantile(c, 0.99))+` 10
+my_distribution_sum{env="prod"} 10 10
+my_distribution_count{env="prod"} 4 10
+# EOF
+`, string(e.Bytes()))
+}
+
+func TestExporterDuplicateLabels(t *testing.T) {
+	e := NewExporter()
+	// both tags translate to the same label, the latest point is kept
+	e.AddSerie(&metrics.Serie{Name: "m", Tags: tagset.CompositeTagsFromSlice([]string{"a.b:1"}), Points: []metrics.Point{{Ts: 20, Value: 2}}})
+	e.AddSerie(&metrics.Serie{Name: "m", Tags: tagset.CompositeTagsFromSlice([]string{"a_b:1"}), Points: []metrics.Point{{Ts: 10, Value: 1}}})
+	e.Commit()
+	assert.Equal(t, "# TYPE m gauge\nm{a_b=\"1\"} 2 20\n# EOF\n", string(e.Bytes()))
+}
+
+func TestServer(t *testing.T) {
+	e := NewExporter()
+	e.AddSerie(&metrics.Serie{Name: "my.gauge", Points: []metrics.Point{{Ts: 10, Value: 1}}})
+	e.Commit()
+
+	s, err := newServer("127.0.0.1:0", e)
+	require.NoError(t, err)
+	s.Start()
+	defer s.Stop()
+
+	resp, err := http.Get("http://" + s.Addr().String() + MetricsPath)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, ContentType, resp.Header.Get("Content-Type"))
+	assert.Equal(t, "# TYPE my_gauge gauge\nmy_gauge 1 10\n# EOF\n", string(body))
+}
@@ -0,0 +1,80 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package openmetrics
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// MetricsPath is the path of the scrape endpoint
+const MetricsPath = "/metrics"
+
+// Server serves the metrics of an exporter on the scrape endpoint.
+type Server struct {
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewServer returns a server listening on the address set in the configuration, it is
+// started with Start.
+func NewServer(exporter *Exporter) (*Server, error) {
+	var addr string
+	port := config.Datadog.GetInt("openmetrics_export.port")
+	if config.Datadog.GetBool("openmetrics_export.non_local_traffic") {
+		// Listen to all network interfaces
+		addr = fmt.Sprintf(":%d", port)
+	} else {
+		addr = net.JoinHostPort(config.GetBindHost(), fmt.Sprintf("%d", port))
+	}
+	return newServer(addr, exporter)
+}
+
+func newServer(addr string, exporter *Exporter) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %s", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(MetricsPath, exporter)
+	return &Server{
+		listener: listener,
+		server: &http.Server{
+			Handler:     mux,
+			ReadTimeout: 30 * time.Second,
+		},
+	}, nil
+}
+
+// Start starts serving the scrapes.
+func (s *Server) Start() {
+	log.Infof("OpenMetrics export: listening on %s%s", s.listener.Addr(), MetricsPath)
+	go func() {
+		if err := s.server.Serve(s.listener); err != nil && err != http.ErrServerClosed {
+			log.Errorf("OpenMetrics export: error serving the scrapes: %v", err)
+		}
+	}()
+}
+
+// Stop stops the server.
+func (s *Server) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		log.Warnf("OpenMetrics export: error stopping the server: %v", err)
+	}
+}
+
+// Addr returns the address the server listens to.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
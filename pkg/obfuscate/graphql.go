@@ -0,0 +1,185 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import "strings"
+
+// ObfuscateGraphQLString obfuscates the given GraphQL query. The literal values of the arguments,
+// of the directives and of the variable defaults are replaced by "?" and the comments are removed,
+// while the operation name, the selected fields, the types and the variable references are kept.
+// Whitespaces are compacted. The query is returned unchanged when GraphQL obfuscation is disabled.
+func (o *Obfuscator) ObfuscateGraphQLString(query string) string {
+	if !o.opts.GraphQL.Enabled || query == "" {
+		return query
+	}
+	return obfuscateGraphQL(query)
+}
+
+func obfuscateGraphQL(query string) string {
+	var (
+		out   strings.Builder
+		space bool // a whitespace is pending before the next token
+		// parens is the depth of the arguments and variable definitions, the only places where literals are
+		// allowed, other than default values.
+		parens int
+		// inType is true while scanning the type of a variable definition, e.g. `[ID!]` in `($ids: [ID!])`.
+		inType bool
+		// prev is the last significant token written.
+		prev string
+		// variable is true when the last token written is the name of a variable.
+		variable bool
+	)
+	out.Grow(len(query))
+	write := func(tok string) {
+		if space && out.Len() > 0 {
+			out.WriteByte(' ')
+		}
+		space = false
+		out.WriteString(tok)
+		prev = tok
+		variable = false
+	}
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case isGraphQLIgnored(c):
+			if c == ',' {
+				out.WriteByte(',')
+			} else {
+				space = true
+			}
+			i++
+		case c == '#':
+			i = skipGraphQLComment(query, i)
+			space = true
+		case strings.HasPrefix(query[i:], `"""`):
+			i = skipGraphQLBlockString(query, i)
+			write("?")
+		case c == '"':
+			i = skipGraphQLString(query, i)
+			write("?")
+		case c == '-' || isDigit(rune(c)):
+			i++
+			for i < len(query) && isGraphQLNumberPart(query[i]) {
+				i++
+			}
+			write("?")
+		case isGraphQLNameStart(c):
+			j := i + 1
+			for j < len(query) && isGraphQLNamePart(query[j]) {
+				j++
+			}
+			name := query[i:j]
+			isVariable := prev == "$"
+			i = j
+			switch {
+			case isVariable || prev == "@" || parens == 0 || inType:
+				// variable, directive, selection or type
+				write(name)
+			case nextGraphQLToken(query, i) == ':':
+				// argument or input object field name
+				write(name)
+			default:
+				// boolean, null or enum value
+				write("?")
+			}
+			variable = isVariable
+		default:
+			switch c {
+			case '(':
+				parens++
+			case ')':
+				if parens > 0 {
+					parens--
+				}
+				inType = false
+			case ':':
+				// the type of a variable definition follows its name
+				inType = parens > 0 && variable
+			case '=', '$', '@':
+				inType = false
+			}
+			if c == '.' && strings.HasPrefix(query[i:], "...") {
+				write("...")
+				i += 3
+				continue
+			}
+			write(query[i : i+1])
+			i++
+		}
+	}
+	return out.String()
+}
+
+// nextGraphQLToken returns the first byte of the next significant token starting at i, 0 if there is none.
+func nextGraphQLToken(query string, i int) byte {
+	for i < len(query) {
+		switch c := query[i]; {
+		case isGraphQLIgnored(c):
+			i++
+		case c == '#':
+			i = skipGraphQLComment(query, i)
+		default:
+			return c
+		}
+	}
+	return 0
+}
+
+// skipGraphQLComment returns the position following the comment starting at i.
+func skipGraphQLComment(query string, i int) int {
+	for i < len(query) && query[i] != '\n' && query[i] != '\r' {
+		i++
+	}
+	return i
+}
+
+// skipGraphQLString returns the position following the string starting at i.
+func skipGraphQLString(query string, i int) int {
+	for i++; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		case '\n', '\r':
+			// unterminated string
+			return i
+		}
+	}
+	return i
+}
+
+// skipGraphQLBlockString returns the position following the block string starting at i.
+func skipGraphQLBlockString(query string, i int) int {
+	for i += 3; i < len(query); i++ {
+		if strings.HasPrefix(query[i:], `\"""`) {
+			i += 3
+			continue
+		}
+		if strings.HasPrefix(query[i:], `"""`) {
+			return i + 3
+		}
+	}
+	return i
+}
+
+// isGraphQLIgnored reports whether c is a whitespace or a comma, which are insignificant in GraphQL.
+func isGraphQLIgnored(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ','
+}
+
+func isGraphQLNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isGraphQLNamePart(c byte) bool {
+	return isGraphQLNameStart(c) || (c >= '0' && c <= '9')
+}
+
+func isGraphQLNumberPart(c byte) bool {
+	return (c >= '0' && c <= '9') || c == '.' || c == 'e' || c == 'E' || c == '+' || c == '-'
+}
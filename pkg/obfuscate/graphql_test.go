@@ -0,0 +1,61 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateGraphQL(t *testing.T) {
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{
+			"{ hero { name friends { name } } }",
+			"{ hero { name friends { name } } }",
+		},
+		{
+			"query GetUser($id: ID!) {\n  user(id: $id) {\n    name\n  }\n}",
+			"query GetUser($id: ID!) { user(id: $id) { name } }",
+		},
+		{
+			`query { user(id: 42, name: "john", active: true, role: ADMIN, score: -1.5e3) { name } }`,
+			"query { user(id: ?, name: ?, active: ?, role: ?, score: ?) { name } }",
+		},
+		{
+			`mutation CreateUser { createUser(input: {email: "john@example.com", tags: ["a", "b"], address: {zip: 75001}}) { id } }`,
+			"mutation CreateUser { createUser(input: {email: ?, tags: [?, ?], address: {zip: ?}}) { id } }",
+		},
+		{
+			`query Search($first: Int = 10, $order: Order = ASC, $ids: [ID!]) { search(first: $first, order: $order, ids: $ids) { id } }`,
+			"query Search($first: Int = ?, $order: Order = ?, $ids: [ID!]) { search(first: $first, order: $order, ids: $ids) { id } }",
+		},
+		{
+			"query Q($show: Boolean!) {\n  # the secret is 1234\n  me { email @include(if: $show) avatar(size: 64) @skip(if: false) }\n}",
+			"query Q($show: Boolean!) { me { email @include(if: $show) avatar(size: ?) @skip(if: ?) } }",
+		},
+		{
+			`query { user(bio: """multi "line" \""" bio""") { ...UserFields ... on Admin { level } } }`,
+			"query { user(bio: ?) { ...UserFields ... on Admin { level } } }",
+		},
+		{
+			`{ user(name: "unterminated`,
+			"{ user(name: ?",
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			o := NewObfuscator(Config{GraphQL: GraphQLConfig{Enabled: true}})
+			assert.Equal(t, tt.out, o.ObfuscateGraphQLString(tt.in))
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		q := `query { user(id: 42) { name } }`
+		assert.Equal(t, q, NewObfuscator(Config{}).ObfuscateGraphQLString(q))
+	})
+}
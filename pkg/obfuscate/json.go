@@ -20,6 +20,12 @@ func (o *Obfuscator) ObfuscateElasticSearchString(cmd string) string {
 	return obfuscateJSONString(cmd, o.es)
 }
 
+// ObfuscateDynamoDBString obfuscates the given DynamoDB JSON attribute values, such as the expression
+// attribute values, the keys or the items of a request.
+func (o *Obfuscator) ObfuscateDynamoDBString(cmd string) string {
+	return obfuscateJSONString(cmd, o.dynamodb)
+}
+
 // obfuscateJSONString obfuscates the given span's tag using the given obfuscator. If the obfuscator is
 // nil it is considered disabled.
 func obfuscateJSONString(cmd string, obfuscator *jsonObfuscator) string {
@@ -121,6 +121,17 @@ func TestObfuscateJSON(t *testing.T) {
 	}
 }
 
+func TestObfuscateDynamoDB(t *testing.T) {
+	in := `{":id": {"S": "user-1234"}, ":age": {"N": "42"}, ":tags": {"SS": ["a", "b"]}}`
+	o := NewObfuscator(Config{DynamoDB: JSONConfig{Enabled: true}})
+	assert.Equal(t, `{":id":{"S":"?"},":age":{"N":"?"},":tags":{"SS":["?","?"]}}`, o.ObfuscateDynamoDBString(in))
+
+	o = NewObfuscator(Config{DynamoDB: JSONConfig{Enabled: true, KeepValues: []string{":age"}}})
+	assert.Equal(t, `{":id":{"S":"?"},":age":{"N":"42"},":tags":{"SS":["?","?"]}}`, o.ObfuscateDynamoDBString(in))
+
+	assert.Equal(t, in, NewObfuscator(Config{}).ObfuscateDynamoDBString(in))
+}
+
 func BenchmarkObfuscateJSON(b *testing.B) {
 	cfg := &JSONConfig{KeepValues: []string{"highlight"}}
 	if len(jsonSuite) == 0 {
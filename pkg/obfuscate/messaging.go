@@ -0,0 +1,66 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import "strings"
+
+// defaultMessagingKeys are the meta keys obfuscated in the spans of messaging systems
+// when MessagingConfig.Keys is empty.
+var defaultMessagingKeys = []string{
+	"kafka.message.key",
+	"messaging.kafka.message.key",
+	"messaging.message.payload",
+	"messaging.message.body",
+}
+
+// messagingObfuscator obfuscates the values of a set of meta keys.
+type messagingObfuscator struct {
+	keys     map[string]bool // exact keys
+	prefixes []string        // prefixes of the keys ending with "*"
+}
+
+func newMessagingObfuscator(cfg *MessagingConfig) *messagingObfuscator {
+	keys := cfg.Keys
+	if len(keys) == 0 {
+		keys = defaultMessagingKeys
+	}
+	mo := &messagingObfuscator{keys: make(map[string]bool, len(keys))}
+	for _, k := range keys {
+		if strings.HasSuffix(k, "*") {
+			mo.prefixes = append(mo.prefixes, strings.TrimSuffix(k, "*"))
+			continue
+		}
+		mo.keys[k] = true
+	}
+	return mo
+}
+
+// matches reports whether the value of the given meta key should be obfuscated.
+func (mo *messagingObfuscator) matches(k string) bool {
+	if mo.keys[k] {
+		return true
+	}
+	for _, p := range mo.prefixes {
+		if strings.HasPrefix(k, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// ObfuscateMessagingMeta replaces with "?" the values of the given messaging span (e.g. Kafka) meta
+// whose keys are set in the messaging configuration, such as message keys and payloads. The meta
+// is left unchanged when messaging obfuscation is disabled.
+func (o *Obfuscator) ObfuscateMessagingMeta(meta map[string]string) {
+	if o.messaging == nil {
+		return
+	}
+	for k, v := range meta {
+		if v != "" && o.messaging.matches(k) {
+			meta[k] = "?"
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateMessagingMeta(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		meta := map[string]string{
+			"kafka.message.key":           "user-1234",
+			"messaging.message.body":      `{"card": "4111"}`,
+			"kafka.topic":                 "payments",
+			"messaging.kafka.message.key": "",
+		}
+		NewObfuscator(Config{Messaging: MessagingConfig{Enabled: true}}).ObfuscateMessagingMeta(meta)
+		assert.Equal(t, map[string]string{
+			"kafka.message.key":           "?",
+			"messaging.message.body":      "?",
+			"kafka.topic":                 "payments",
+			"messaging.kafka.message.key": "",
+		}, meta)
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		meta := map[string]string{
+			"kafka.message.key":     "user-1234",
+			"kafka.header.token":    "abc",
+			"kafka.header.trace_id": "def",
+			"kafka.topic":           "payments",
+		}
+		o := NewObfuscator(Config{Messaging: MessagingConfig{Enabled: true, Keys: []string{"kafka.topic", "kafka.header.*"}}})
+		o.ObfuscateMessagingMeta(meta)
+		assert.Equal(t, map[string]string{
+			"kafka.message.key":     "user-1234",
+			"kafka.header.token":    "?",
+			"kafka.header.trace_id": "?",
+			"kafka.topic":           "?",
+		}, meta)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		meta := map[string]string{"kafka.message.key": "user-1234"}
+		NewObfuscator(Config{}).ObfuscateMessagingMeta(meta)
+		assert.Equal(t, "user-1234", meta["kafka.message.key"])
+	})
+}
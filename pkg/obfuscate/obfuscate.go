@@ -23,10 +23,12 @@ import (
 // concurrent use.
 type Obfuscator struct {
 	opts                 *Config
-	es                   *jsonObfuscator // nil if disabled
-	mongo                *jsonObfuscator // nil if disabled
-	sqlExecPlan          *jsonObfuscator // nil if disabled
-	sqlExecPlanNormalize *jsonObfuscator // nil if disabled
+	es                   *jsonObfuscator      // nil if disabled
+	mongo                *jsonObfuscator      // nil if disabled
+	sqlExecPlan          *jsonObfuscator      // nil if disabled
+	sqlExecPlanNormalize *jsonObfuscator      // nil if disabled
+	dynamodb             *jsonObfuscator      // nil if disabled
+	messaging            *messagingObfuscator // nil if disabled
 	// sqlLiteralEscapes reports whether we should treat escape characters literally or as escape characters.
 	// Different SQL engines behave in different ways and the tokenizer needs to be generic.
 	sqlLiteralEscapes *atomic.Bool
@@ -82,6 +84,15 @@ type Config struct {
 	// HTTP holds the obfuscation settings for HTTP URLs.
 	HTTP HTTPConfig
 
+	// GraphQL holds the obfuscation configuration for GraphQL queries.
+	GraphQL GraphQLConfig
+
+	// DynamoDB holds the obfuscation configuration for DynamoDB JSON attribute values.
+	DynamoDB JSONConfig
+
+	// Messaging holds the obfuscation configuration for the meta of messaging spans, such as Kafka ones.
+	Messaging MessagingConfig
+
 	// Statsd specifies the statsd client to use for reporting metrics.
 	Statsd StatsClient
 
@@ -152,6 +163,22 @@ type HTTPConfig struct {
 	RemovePathDigits bool
 }
 
+// GraphQLConfig holds the configuration settings for GraphQL obfuscation.
+type GraphQLConfig struct {
+	// Enabled specifies whether the literal values of GraphQL queries should be obfuscated.
+	Enabled bool
+}
+
+// MessagingConfig holds the configuration settings for the obfuscation of messaging span meta.
+type MessagingConfig struct {
+	// Enabled specifies whether messaging obfuscation should be enabled.
+	Enabled bool
+
+	// Keys specifies the meta keys whose values are obfuscated. A key ending with "*" matches all
+	// the keys starting with it. When empty, the message keys and payloads are obfuscated.
+	Keys []string
+}
+
 // JSONConfig holds the obfuscation configuration for sensitive
 // data found in JSON objects.
 type JSONConfig struct {
@@ -189,6 +216,12 @@ func NewObfuscator(cfg Config) *Obfuscator {
 	if cfg.SQLExecPlanNormalize.Enabled {
 		o.sqlExecPlanNormalize = newJSONObfuscator(&cfg.SQLExecPlanNormalize, &o)
 	}
+	if cfg.DynamoDB.Enabled {
+		o.dynamodb = newJSONObfuscator(&cfg.DynamoDB, &o)
+	}
+	if cfg.Messaging.Enabled {
+		o.messaging = newMessagingObfuscator(&cfg.Messaging)
+	}
 	if cfg.Statsd == nil {
 		cfg.Statsd = &statsd.NoOpClient{}
 	}
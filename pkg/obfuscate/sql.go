@@ -296,29 +296,51 @@ func (o *Obfuscator) ObfuscateSQLString(in string) (*ObfuscatedQuery, error) {
 // to quantize and obfuscate the given input SQL query string. Quantization removes some elements such as comments
 // and aliases and obfuscation attempts to hide sensitive information in strings and numbers by redacting them.
 func (o *Obfuscator) ObfuscateSQLStringWithOptions(in string, opts *SQLConfig) (*ObfuscatedQuery, error) {
-	if v, ok := o.queryCache.Get(in); ok {
+	key := in
+	if opts.DBMS != "" {
+		// the same query may be tokenized differently by each dialect
+		key = opts.DBMS + "|" + in
+	}
+	if v, ok := o.queryCache.Get(key); ok {
 		return v.(*ObfuscatedQuery), nil
 	}
 	oq, err := o.obfuscateSQLString(in, opts)
 	if err != nil {
 		return oq, err
 	}
-	o.queryCache.Set(in, oq, oq.Cost())
+	o.queryCache.Set(key, oq, oq.Cost())
 	return oq, nil
 }
 
+// ObfuscateSQLStringForDBMS is like ObfuscateSQLString, but tokenizes the query following the rules of the
+// given database management system, such as DBMSPostgres, rather than the configured one.
+func (o *Obfuscator) ObfuscateSQLStringForDBMS(in string, dbms string) (*ObfuscatedQuery, error) {
+	if dbms == "" || dbms == o.opts.SQL.DBMS {
+		return o.ObfuscateSQLString(in)
+	}
+	opts := o.opts.SQL
+	opts.DBMS = dbms
+	return o.ObfuscateSQLStringWithOptions(in, &opts)
+}
+
 func (o *Obfuscator) obfuscateSQLString(in string, opts *SQLConfig) (*ObfuscatedQuery, error) {
-	lesc := o.useSQLLiteralEscapes()
+	lesc, known := dbmsLiteralEscapes(opts.DBMS)
+	if !known {
+		lesc = o.useSQLLiteralEscapes()
+	}
 	tok := NewSQLTokenizer(in, lesc, opts)
 	out, err := attemptObfuscation(tok)
 	if err != nil && tok.SeenEscape() {
 		// If the tokenizer failed, but saw an escape character in the process,
-		// try again treating escapes differently
+		// try again treating escapes differently. Servers of a known DBMS may be
+		// configured to treat them differently too (e.g. standard_conforming_strings=off).
 		tok = NewSQLTokenizer(in, !lesc, opts)
 		if out, err2 := attemptObfuscation(tok); err2 == nil {
-			// If the second attempt succeeded, change the default behavior so that
-			// on the next run we get it right in the first run.
-			o.setSQLLiteralEscapes(!lesc)
+			if !known {
+				// If the second attempt succeeded, change the default behavior so that
+				// on the next run we get it right in the first run.
+				o.setSQLLiteralEscapes(!lesc)
+			}
 			return out, nil
 		}
 	}
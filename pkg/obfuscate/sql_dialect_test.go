@@ -0,0 +1,75 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sqlDialectTestDir holds a corpus of test queries for each DBMS, in a file named after the DBMS.
+const sqlDialectTestDir = "./testdata/sql_dialects"
+
+type xmlSQLDialectTests struct {
+	XMLName xml.Name `xml:"SQLDialectTests"`
+	Tests   []struct {
+		In  string
+		Out string
+	} `xml:"Test"`
+}
+
+func TestSQLDialects(t *testing.T) {
+	for _, dbms := range []string{DBMSPostgres, DBMSMySQL, DBMSOracle, DBMSSQLServer} {
+		t.Run(dbms, func(t *testing.T) {
+			f, err := os.Open(filepath.Join(sqlDialectTestDir, dbms+".xml"))
+			require.NoError(t, err)
+			defer f.Close()
+			var suite xmlSQLDialectTests
+			require.NoError(t, xml.NewDecoder(f).Decode(&suite))
+			require.NotEmpty(t, suite.Tests)
+
+			o := NewObfuscator(Config{})
+			for i, tt := range suite.Tests {
+				t.Run(strconv.Itoa(i+1), func(t *testing.T) {
+					oq, err := o.ObfuscateSQLStringForDBMS(tt.In, dbms)
+					require.NoError(t, err, tt.In)
+					assert.Equal(t, tt.Out, oq.Query, tt.In)
+				})
+			}
+		})
+	}
+}
+
+func TestSQLDialectCache(t *testing.T) {
+	o := NewObfuscator(Config{SQL: SQLConfig{Cache: true}})
+	defer o.Stop()
+	query := `SELECT * FROM "Users" WHERE id = 1`
+	for i := 0; i < 2; i++ {
+		oq, err := o.ObfuscateSQLStringForDBMS(query, DBMSPostgres)
+		require.NoError(t, err)
+		assert.Equal(t, `SELECT * FROM "Users" WHERE id = ?`, oq.Query)
+		o.queryCache.Wait()
+
+		oq, err = o.ObfuscateSQLString(query)
+		require.NoError(t, err)
+		assert.Equal(t, `SELECT * FROM Users WHERE id = ?`, oq.Query)
+		o.queryCache.Wait()
+	}
+}
+
+func TestSQLDialectLiteralEscapes(t *testing.T) {
+	o := NewObfuscator(Config{})
+	// the escapes of a dialect do not change the behavior detected for the other queries
+	_, err := o.ObfuscateSQLStringForDBMS(`SELECT 'C:\path' FROM t`, DBMSPostgres)
+	require.NoError(t, err)
+	assert.False(t, o.useSQLLiteralEscapes())
+}
@@ -153,8 +153,29 @@ func (k TokenKind) String() string {
 const (
 	// DBMSSQLServer is a MS SQL Server
 	DBMSSQLServer = "mssql"
+	// DBMSPostgres is a PostgreSQL Server
+	DBMSPostgres = "postgresql"
+	// DBMSMySQL is a MySQL Server
+	DBMSMySQL = "mysql"
+	// DBMSOracle is an Oracle Server
+	DBMSOracle = "oracle"
 )
 
+// dbmsLiteralEscapes reports whether the given DBMS treats backslashes in strings literally by default,
+// and ok is false when the DBMS is unknown, in which case the behavior is detected while obfuscating.
+// The other behavior is still tried for the queries which can't be tokenized with the default one.
+func dbmsLiteralEscapes(dbms string) (literal bool, ok bool) {
+	switch dbms {
+	case DBMSPostgres, DBMSOracle:
+		// with standard_conforming_strings, the default since PostgreSQL 9.1, backslashes are
+		// only escape characters within E'...' strings.
+		return true, true
+	case DBMSMySQL:
+		return false, true
+	}
+	return false, false
+}
+
 const escapeCharacter = '\\'
 
 // SQLTokenizer is the struct used to generate SQL
@@ -311,8 +332,21 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 				return TokenKind(ch), tkn.bytes()
 			}
 		case '#':
-			if tkn.cfg.DBMS == DBMSSQLServer {
+			switch tkn.cfg.DBMS {
+			case DBMSSQLServer:
 				return tkn.scanIdentifier()
+			case DBMSPostgres:
+				// '#' is the bitwise XOR operator and starts the #>, #>> and #- JSON operators
+				switch tkn.lastChar {
+				case '>':
+					tkn.advance()
+					if tkn.lastChar == '>' {
+						tkn.advance()
+					}
+				case '-':
+					tkn.advance()
+				}
+				return TokenKind(ch), tkn.bytes()
 			}
 			tkn.advance()
 			return tkn.scanCommentType1("#")
@@ -363,8 +397,17 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 		case '\'':
 			return tkn.scanString(ch, String)
 		case '"':
+			switch tkn.cfg.DBMS {
+			case DBMSPostgres, DBMSOracle:
+				return tkn.scanQuotedIdentifier(ch)
+			case DBMSMySQL:
+				return tkn.scanString(ch, String)
+			}
 			return tkn.scanString(ch, DoubleQuotedString)
 		case '`':
+			if tkn.cfg.DBMS == DBMSMySQL {
+				return tkn.scanQuotedIdentifier(ch)
+			}
 			return tkn.scanString(ch, ID)
 		case '%':
 			if tkn.lastChar == '(' {
@@ -471,8 +514,15 @@ func toUpper(src, dst []byte) []byte {
 
 func (tkn *SQLTokenizer) scanIdentifier() (TokenKind, []byte) {
 	tkn.advance()
-	for isLetter(tkn.lastChar) || isDigit(tkn.lastChar) || tkn.lastChar == '.' || tkn.lastChar == '*' {
-		tkn.advance()
+	if !tkn.scanNameParts() {
+		tkn.setErr("unexpected EOF in quoted identifier")
+		return LexError, tkn.bytes()
+	}
+	if tkn.lastChar == '\'' {
+		// the identifier may be the prefix of a string, e.g. E'\n' or N'text'
+		if kind, tok, ok := tkn.scanPrefixedString(tkn.buf[:tkn.off-1]); ok {
+			return kind, tok
+		}
 	}
 
 	t := tkn.bytes()
@@ -487,6 +537,168 @@ func (tkn *SQLTokenizer) scanIdentifier() (TokenKind, []byte) {
 	return ID, t
 }
 
+// identifierQuote returns the delimiter of the quoted identifiers which are kept as such by the
+// dialect of the tokenizer, 0 if there is none.
+func (tkn *SQLTokenizer) identifierQuote() rune {
+	switch tkn.cfg.DBMS {
+	case DBMSPostgres, DBMSOracle:
+		return '"'
+	case DBMSMySQL:
+		return '`'
+	}
+	return 0
+}
+
+// dollarIdentifiers reports whether the dialect of the tokenizer allows dollar signs within
+// identifiers, e.g. Oracle's v$session.
+func (tkn *SQLTokenizer) dollarIdentifiers() bool {
+	switch tkn.cfg.DBMS {
+	case DBMSPostgres, DBMSMySQL, DBMSOracle:
+		return true
+	}
+	return false
+}
+
+// scanNameParts scans the remaining parts of an identifier, including the quoted parts following
+// a dot in a qualified name, e.g. public."Users". It returns false if a quoted part is not terminated.
+func (tkn *SQLTokenizer) scanNameParts() bool {
+	quote := tkn.identifierQuote()
+	for {
+		switch ch := tkn.lastChar; {
+		case isLetter(ch) || isDigit(ch) || ch == '.' || ch == '*':
+			tkn.advance()
+		case ch == '$' && tkn.dollarIdentifiers():
+			tkn.advance()
+		case quote != 0 && ch == quote && tkn.off > 1 && tkn.buf[tkn.off-2] == '.':
+			tkn.advance()
+			if !tkn.skipQuotedPart(quote) {
+				return false
+			}
+		default:
+			return true
+		}
+	}
+}
+
+// skipQuotedPart advances past the closing delimiter of a quoted identifier, whose opening delimiter has been read.
+// It returns false if the closing delimiter is missing.
+func (tkn *SQLTokenizer) skipQuotedPart(delim rune) bool {
+	for {
+		ch := tkn.lastChar
+		if ch == EndChar {
+			return false
+		}
+		tkn.advance()
+		if ch == delim {
+			if tkn.lastChar != delim {
+				return true
+			}
+			// doubling a delimiter embeds it within the identifier
+			tkn.advance()
+		}
+	}
+}
+
+// scanQuotedIdentifier scans an identifier quoted with delim, whose opening delimiter has been read,
+// along with the following parts of a qualified name. Unlike scanString, the delimiters are kept so
+// that the resource stays a valid query, e.g. `db`.`table` for MySQL.
+func (tkn *SQLTokenizer) scanQuotedIdentifier(delim rune) (TokenKind, []byte) {
+	if !tkn.skipQuotedPart(delim) || !tkn.scanNameParts() {
+		tkn.setErr("unexpected EOF in quoted identifier")
+		return LexError, tkn.bytes()
+	}
+	return ID, tkn.bytes()
+}
+
+// scanPrefixedString scans a string literal with the given prefix when the dialect of the tokenizer
+// supports it, such as the PostgreSQL escape strings (E'...'), the national character strings (N'...'),
+// the hexadecimal and bit strings (X'...', B'...'), the MySQL character set introducers (_utf8mb4'...')
+// or the Oracle alternative quoting (q'[...]'). The opening quote is the last read character. It returns
+// false if the prefix is not the one of a string.
+func (tkn *SQLTokenizer) scanPrefixedString(prefix []byte) (TokenKind, []byte, bool) {
+	var space [8]byte
+	upper := string(toUpper(prefix, space[:0]))
+	var escapes, alternative bool
+	switch tkn.cfg.DBMS {
+	case DBMSPostgres:
+		switch upper {
+		case "E":
+			escapes = true
+		case "B", "X":
+		default:
+			return 0, nil, false
+		}
+	case DBMSMySQL:
+		switch {
+		case upper == "B", upper == "X", upper == "N":
+		case len(upper) > 1 && upper[0] == '_':
+			// character set introducer
+		default:
+			return 0, nil, false
+		}
+	case DBMSOracle:
+		switch upper {
+		case "N":
+		case "Q", "NQ":
+			alternative = true
+		default:
+			return 0, nil, false
+		}
+	case DBMSSQLServer:
+		if upper != "N" {
+			return 0, nil, false
+		}
+	default:
+		return 0, nil, false
+	}
+	tkn.bytes() // discard the prefix
+	tkn.advance()
+	if alternative {
+		kind, tok := tkn.scanAlternativeQuotedString()
+		return kind, tok, true
+	}
+	if escapes {
+		literalEscapes := tkn.literalEscapes
+		tkn.literalEscapes = false
+		defer func() { tkn.literalEscapes = literalEscapes }()
+	}
+	kind, tok := tkn.scanString('\'', String)
+	return kind, tok, true
+}
+
+// scanAlternativeQuotedString scans an Oracle alternative quoted string, e.g. q'[it's]', whose
+// q' prefix has been read. The delimiter closing the string is the opening one, or its closing
+// pair for brackets, followed by a quote.
+func (tkn *SQLTokenizer) scanAlternativeQuotedString() (TokenKind, []byte) {
+	closing := tkn.lastChar
+	switch closing {
+	case '[':
+		closing = ']'
+	case '{':
+		closing = '}'
+	case '(':
+		closing = ')'
+	case '<':
+		closing = '>'
+	case EndChar, ' ', '\t', '\n', '\r':
+		tkn.setErr(`invalid delimiter in quoted string: "%c" (%d)`, tkn.lastChar, tkn.lastChar)
+		return LexError, tkn.bytes()
+	}
+	tkn.advance()
+	for {
+		ch := tkn.lastChar
+		if ch == EndChar {
+			tkn.setErr("unexpected EOF in quoted string")
+			return LexError, tkn.bytes()
+		}
+		tkn.advance()
+		if ch == closing && tkn.lastChar == '\'' {
+			tkn.advance()
+			return String, tkn.bytes()
+		}
+	}
+}
+
 func (tkn *SQLTokenizer) scanVariableIdentifier(prefix rune) (TokenKind, []byte) {
 	for tkn.advance(); tkn.lastChar != ')' && tkn.lastChar != EndChar; tkn.advance() {
 	}
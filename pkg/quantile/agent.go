@@ -17,6 +17,16 @@ type Agent struct {
 	Sketch   Sketch
 	Buf      []Key
 	CountBuf []KeyCount
+
+	// Config is the config of the sketch, created with NewAgentConfig, nil for the default one
+	Config *Config
+}
+
+func (a *Agent) config() *Config {
+	if a.Config != nil {
+		return a.Config
+	}
+	return agentConfig
 }
 
 // IsEmpty returns true if the sketch is empty
@@ -38,12 +48,12 @@ func (a *Agent) Finish() *Sketch {
 // flush buffered values into the sketch.
 func (a *Agent) flush() {
 	if len(a.Buf) != 0 {
-		a.Sketch.insert(agentConfig, a.Buf)
+		a.Sketch.insert(a.config(), a.Buf)
 		a.Buf = nil
 	}
 
 	if len(a.CountBuf) != 0 {
-		a.Sketch.insertCounts(agentConfig, a.CountBuf)
+		a.Sketch.insertCounts(a.config(), a.CountBuf)
 		a.CountBuf = nil
 	}
 }
@@ -56,7 +66,7 @@ func (a *Agent) Reset() {
 
 // Insert v into the sketch.
 func (a *Agent) Insert(v float64, sampleRate float64) {
-	k := agentConfig.key(v)
+	k := a.config().key(v)
 	// bounds enforcement
 	if sampleRate <= 0 || sampleRate > 1 {
 		sampleRate = 1
@@ -83,19 +93,22 @@ func (a *Agent) Insert(v float64, sampleRate float64) {
 }
 
 // InsertInterpolate linearly interpolates a count from the given lower to upper bounds
+// The counts are interpolated over the keys of the default gamma, and grouped afterwards
+// when the config has a coarser accuracy.
 func (a *Agent) InsertInterpolate(lower float64, upper float64, count uint) {
+	c := a.config()
 	keys := make([]Key, 0)
-	for k := agentConfig.key(lower); k <= agentConfig.key(upper); k++ {
+	for k := c.rawKey(lower); k <= c.rawKey(upper); k++ {
 		keys = append(keys, k)
 	}
 	whatsLeft := int(count)
 	distance := upper - lower
 	startIdx := 0
-	lowerB := agentConfig.binLow(keys[startIdx])
+	lowerB := c.binLow(keys[startIdx])
 	endIdx := 1
 	var remainder float64
 	for endIdx < len(keys) && whatsLeft > 0 {
-		upperB := agentConfig.binLow(keys[endIdx])
+		upperB := c.binLow(keys[endIdx])
 		// ((upperB - lowerB) / distance) is the ratio of the distance between the current buckets to the total distance
 		// which tells us how much of the remaining value to put in this bucket
 		fkn := ((upperB - lowerB) / distance) * float64(count)
@@ -114,7 +127,7 @@ func (a *Agent) InsertInterpolate(lower float64, upper float64, count uint) {
 				kn = whatsLeft
 			}
 			a.Sketch.Basic.InsertN(lowerB, float64(kn))
-			a.CountBuf = append(a.CountBuf, KeyCount{k: keys[startIdx], n: uint(kn)})
+			a.CountBuf = append(a.CountBuf, KeyCount{k: c.groupKey(keys[startIdx]), n: uint(kn)})
 			whatsLeft -= kn
 			startIdx = endIdx
 			lowerB = upperB
@@ -122,8 +135,8 @@ func (a *Agent) InsertInterpolate(lower float64, upper float64, count uint) {
 		endIdx++
 	}
 	if whatsLeft > 0 {
-		a.Sketch.Basic.InsertN(agentConfig.binLow(keys[startIdx]), float64(whatsLeft))
-		a.CountBuf = append(a.CountBuf, KeyCount{k: keys[startIdx], n: uint(whatsLeft)})
+		a.Sketch.Basic.InsertN(c.binLow(keys[startIdx]), float64(whatsLeft))
+		a.CountBuf = append(a.CountBuf, KeyCount{k: c.groupKey(keys[startIdx]), n: uint(whatsLeft)})
 	}
 	a.flush()
 }
@@ -191,3 +191,62 @@ func TestAgentInterpolation(t *testing.T) {
 		check(t, tt)
 	}
 }
+
+func TestNewAgentConfig(t *testing.T) {
+	c, err := NewAgentConfig(0, 0)
+	require.NoError(t, err)
+	require.Equal(t, defaultEps, c.RelativeAccuracy())
+	require.Equal(t, defaultBinLimit, c.BinLimit())
+
+	for _, relativeAccuracy := range []float64{defaultEps / 2, 1, -0.1} {
+		_, err = NewAgentConfig(relativeAccuracy, 0)
+		require.Error(t, err, relativeAccuracy)
+	}
+	_, err = NewAgentConfig(0.01, -1)
+	require.Error(t, err)
+
+	c, err = NewAgentConfig(0.05, 100)
+	require.NoError(t, err)
+	require.Equal(t, 6, c.keyGroup)
+	require.InDelta(t, 6*defaultEps, c.RelativeAccuracy(), 1e-12)
+	require.Equal(t, 100, c.BinLimit())
+
+	// keys are grouped around the middle of their group, symmetrically for the negative keys
+	require.Equal(t, Key(4), c.groupKey(1))
+	require.Equal(t, Key(4), c.groupKey(6))
+	require.Equal(t, Key(10), c.groupKey(7))
+	require.Equal(t, Key(-10), c.groupKey(-7))
+	require.Equal(t, Key(0), c.groupKey(0))
+	require.Equal(t, InfKey(1), c.groupKey(InfKey(1)))
+}
+
+func TestAgentCoarseConfig(t *testing.T) {
+	c, err := NewAgentConfig(0.05, 0)
+	require.NoError(t, err)
+
+	def, coarse := &Agent{}, &Agent{Config: c}
+	for v := 1; v <= 10000; v++ {
+		def.Insert(float64(v), 1)
+		coarse.Insert(float64(v), 1)
+	}
+	ds, cs := def.Finish(), coarse.Finish()
+
+	// the keys are grouped, in the default key space
+	require.Less(t, cs.BinCount(), ds.BinCount())
+	for _, b := range cs.bins {
+		require.Equal(t, b.k, c.groupKey(b.k))
+	}
+	for _, q := range []float64{0.75, 0.9, 0.99} {
+		exp := Default()
+		require.InEpsilon(t, ds.Quantile(exp, q), cs.Quantile(exp, q), 0.06, q)
+	}
+
+	// interpolated counts are grouped too
+	coarse = &Agent{Config: c}
+	coarse.InsertInterpolate(10, 1000, 1000)
+	s := coarse.Finish()
+	require.Equal(t, 1000, s.count)
+	for _, b := range s.bins {
+		require.Equal(t, b.k, c.groupKey(b.k))
+	}
+}
@@ -20,6 +20,10 @@ const (
 type Config struct {
 	binLimit int
 
+	// keyGroup is the number of consecutive keys grouped into one, for a coarser accuracy
+	// with keys compatible with the default gamma. 0 or 1 for no grouping.
+	keyGroup int
+
 	// TODO: interpolation type enum (e.g. https://github.com/gee-go/util/blob/ec29b7754/vec/quantile.go#L13-L29)
 
 	gamma struct {
@@ -79,12 +83,18 @@ func (c *Config) binLow(k Key) float64 {
 	return c.powGamma(exp)
 }
 
-// key returns a value k such that:
-//   γ^k <= v < γ^(k+1)
+// key returns the key of v, grouped with the keys of its neighbours when the config
+// has a coarser accuracy than its gamma.
 func (c *Config) key(v float64) Key {
+	return c.groupKey(c.rawKey(v))
+}
+
+// rawKey returns a value k such that:
+//   γ^k <= v < γ^(k+1)
+func (c *Config) rawKey(v float64) Key {
 	switch {
 	case v < 0:
-		return -c.key(-v)
+		return -c.rawKey(-v)
 	case v == 0, v > 0 && v < c.norm.min, v < 0 && v > -c.norm.min:
 		return 0
 	}
@@ -103,6 +113,22 @@ func (c *Config) key(v float64) Key {
 	return Key(i)
 }
 
+// groupKey returns the key at the middle of the group of c.keyGroup consecutive keys k belongs to.
+func (c *Config) groupKey(k Key) Key {
+	switch {
+	case c.keyGroup <= 1, k == 0, k.IsInf():
+		return k
+	case k < 0:
+		return -c.groupKey(-k)
+	}
+
+	g := (int(k)-1)/c.keyGroup*c.keyGroup + 1 + c.keyGroup/2
+	if g > maxKey {
+		g = maxKey
+	}
+	return Key(g)
+}
+
 func (c *Config) logGamma(v float64) float64 {
 	return math.Log(v) / c.gamma.ln
 }
@@ -165,6 +191,43 @@ func (c *Config) refresh(eps, minValue float64) error {
 	return nil
 }
 
+// RelativeAccuracy returns the relative accuracy of the keys of the config.
+func (c *Config) RelativeAccuracy() float64 {
+	eps := (c.gamma.v - 1) / 2
+	if c.keyGroup > 1 {
+		eps *= float64(c.keyGroup)
+	}
+	return eps
+}
+
+// BinLimit returns the maximum number of bins of the sketches, the lowest bins are collapsed
+// above it.
+func (c *Config) BinLimit() int {
+	return c.binLimit
+}
+
+// NewAgentConfig returns a config for the agent sketches with the given relative accuracy
+// and bin limit, 0 for the defaults.
+//
+// The keys of the agent sketches are decoded with the default gamma, so the relative accuracy
+// can't be finer than the default one: a coarser accuracy groups consecutive default keys into
+// one, which lowers the number of bins of the sketches.
+func NewAgentConfig(relativeAccuracy float64, binLimit int) (*Config, error) {
+	switch {
+	case relativeAccuracy == 0:
+		relativeAccuracy = defaultEps
+	case relativeAccuracy < defaultEps || relativeAccuracy >= 1:
+		return nil, fmt.Errorf("%g: relative accuracy must be between %g and 1", relativeAccuracy, defaultEps)
+	}
+
+	c, err := NewConfig(0, 0, binLimit)
+	if err != nil {
+		return nil, err
+	}
+	c.keyGroup = int(relativeAccuracy / defaultEps)
+	return c, nil
+}
+
 // NewConfig creates a config object with.
 // TODO|DOC: describe params
 func NewConfig(eps, min float64, binLimit int) (*Config, error) {
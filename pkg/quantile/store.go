@@ -35,6 +35,11 @@ func (s *sparseStore) Cols() (k []int32, n []uint32) {
 	return
 }
 
+// BinCount returns the number of bins of the store.
+func (s *sparseStore) BinCount() int {
+	return s.bins.Len()
+}
+
 // MemSize returns memory use in bytes:
 //   used: uses len(bins)
 //   allocated: uses cap(bins)
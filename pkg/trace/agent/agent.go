@@ -42,6 +42,7 @@ type Agent struct {
 	ClientStatsAggregator *stats.ClientStatsAggregator
 	Blacklister           *filters.Blacklister
 	Replacer              *filters.Replacer
+	SpanRules             *filters.SpanRules
 	PrioritySampler       *sampler.PrioritySampler
 	ErrorsSampler         *sampler.ErrorsSampler
 	RareSampler           *sampler.RareSampler
@@ -50,6 +51,10 @@ type Agent struct {
 	TraceWriter           *writer.TraceWriter
 	StatsWriter           *writer.StatsWriter
 
+	// TailSampler buffers the chunks dropped by the samplers to keep the traces matching
+	// its policies. It is nil when the tail-based sampling is disabled.
+	TailSampler *TailSampler
+
 	// obfuscator is used to obfuscate sensitive data from various span
 	// tags based on their type.
 	obfuscator     *obfuscate.Obfuscator
@@ -82,11 +87,17 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig) *Agent {
 	if oconf.Statsd == nil {
 		oconf.Statsd = metrics.Client
 	}
+	spanRules, err := filters.NewSpanRules(conf.SpanRules)
+	if err != nil {
+		log.Errorf("Invalid span rules, spans will not be filtered: %v", err)
+		spanRules, _ = filters.NewSpanRules(nil)
+	}
 	agnt := &Agent{
 		Concentrator:          stats.NewConcentrator(conf, statsChan, time.Now()),
 		ClientStatsAggregator: stats.NewClientStatsAggregator(conf, statsChan),
 		Blacklister:           filters.NewBlacklister(conf.Ignore["resource"]),
 		Replacer:              filters.NewReplacer(conf.ReplaceTags),
+		SpanRules:             spanRules,
 		PrioritySampler:       sampler.NewPrioritySampler(conf, dynConf),
 		ErrorsSampler:         sampler.NewErrorsSampler(conf),
 		RareSampler:           sampler.NewRareSampler(),
@@ -100,6 +111,9 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig) *Agent {
 		conf:                  conf,
 		ctx:                   ctx,
 	}
+	if conf.TailSampling != nil && conf.TailSampling.Enabled {
+		agnt.TailSampler = NewTailSampler(conf.TailSampling, agnt.TraceWriter.In)
+	}
 	agnt.Receiver = api.NewHTTPReceiver(conf, dynConf, in, agnt)
 	agnt.OTLPReceiver = api.NewOTLPReceiver(in, conf)
 	return agnt
@@ -111,6 +125,7 @@ func (a *Agent) Run() {
 		a.Receiver,
 		a.Concentrator,
 		a.ClientStatsAggregator,
+		a.SpanRules,
 		a.PrioritySampler,
 		a.ErrorsSampler,
 		a.NoPrioritySampler,
@@ -119,6 +134,9 @@ func (a *Agent) Run() {
 	} {
 		starter.Start()
 	}
+	if a.TailSampler != nil {
+		a.TailSampler.Start()
+	}
 
 	go a.TraceWriter.Run()
 	go a.StatsWriter.Run()
@@ -169,9 +187,14 @@ func (a *Agent) loop() {
 			if err := a.Receiver.Stop(); err != nil {
 				log.Error(err)
 			}
+			if a.TailSampler != nil {
+				// forward the buffered traces kept before stopping the TraceWriter
+				a.TailSampler.Stop()
+			}
 			for _, stopper := range []interface{ Stop() }{
 				a.Concentrator,
 				a.ClientStatsAggregator,
+				a.SpanRules,
 				a.TraceWriter,
 				a.StatsWriter,
 				a.PrioritySampler,
@@ -202,6 +225,8 @@ func (a *Agent) Process(p *api.Payload) {
 	defer timing.Since("datadog.trace_agent.internal.process_payload_ms", now)
 	ts := p.Source
 	ss := new(writer.SampledChunks)
+	// tailHeader holds the metadata of the payload for the chunks buffered by the TailSampler
+	var tailHeader *pb.TracerPayload
 	statsInput := stats.NewStatsInput(len(p.TracerPayload.Chunks), p.TracerPayload.ContainerID, p.ClientComputedStats, a.conf)
 
 	p.TracerPayload.Env = traceutil.NormalizeTag(p.TracerPayload.Env)
@@ -226,6 +251,18 @@ func (a *Agent) Process(p *api.Payload) {
 			continue
 		}
 
+		chunk.Spans = a.SpanRules.Apply(chunk.Spans)
+		if dropped := tracen - int64(len(chunk.Spans)); dropped > 0 {
+			atomic.AddInt64(&ts.SpansFiltered, dropped)
+			if len(chunk.Spans) == 0 {
+				log.Debugf("Trace rejected by span rules, all of its spans were dropped.")
+				atomic.AddInt64(&ts.TracesFiltered, 1)
+				p.RemoveChunk(i)
+				continue
+			}
+			tracen = int64(len(chunk.Spans))
+		}
+
 		// Root span is used to carry some trace-level metadata, such as sampling rate and priority.
 		root := traceutil.GetRoot(chunk.Spans)
 		normalizeChunk(chunk, root)
@@ -308,6 +345,12 @@ func (a *Agent) Process(p *api.Payload) {
 		if !keep {
 			if numEvents == 0 {
 				// the trace was dropped and no analyzed span were kept
+				if a.TailSampler != nil {
+					if tailHeader == nil {
+						tailHeader = newTailHeader(p.TracerPayload)
+					}
+					a.TailSampler.Add(now, tailHeader, chunk)
+				}
 				p.RemoveChunk(i)
 				continue
 			}
@@ -315,6 +358,8 @@ func (a *Agent) Process(p *api.Payload) {
 			// is added to the TracerPayload to be sent to TraceWriter.
 			// The complete chunk is still sent to the stats concentrator.
 			p.ReplaceChunk(i, filteredChunk)
+		} else if a.TailSampler != nil {
+			a.TailSampler.MarkKept(chunk.Spans[0].TraceID)
 		}
 
 		if !chunk.DroppedTrace {
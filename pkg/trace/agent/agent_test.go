@@ -103,6 +103,52 @@ func TestProcess(t *testing.T) {
 		assert.Equal("SELECT name FROM people WHERE age = ? AND extra = ?", span.Meta["sql.query"])
 	})
 
+	t.Run("SpanRules", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.SpanRules = []*config.SpanRule{
+			{Name: "health", Match: []string{"resource == GET /health"}, Action: "drop_span"},
+			{Match: []string{"service == web"}, Action: "rename_service", Value: "frontend"},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewAgent(ctx, cfg)
+		defer cancel()
+
+		now := time.Now()
+		newSpan := func(spanID, parentID uint64, resource string) *pb.Span {
+			return &pb.Span{
+				TraceID:  1,
+				SpanID:   spanID,
+				ParentID: parentID,
+				Service:  "web",
+				Name:     "http.request",
+				Resource: resource,
+				Start:    now.Add(-time.Second).UnixNano(),
+				Duration: (500 * time.Millisecond).Nanoseconds(),
+			}
+		}
+		root, child := newSpan(1, 0, "GET /users"), newSpan(2, 1, "GET /health")
+		want := agnt.Receiver.Stats.GetTagStats(info.Tags{})
+		payload := &api.Payload{
+			TracerPayload: testutil.TracerPayloadWithChunk(testutil.TraceChunkWithSpans([]*pb.Span{root, child})),
+			Source:        want,
+		}
+		agnt.Process(payload)
+
+		assert := assert.New(t)
+		assert.EqualValues(0, want.TracesFiltered)
+		assert.EqualValues(1, want.SpansFiltered)
+		assert.Equal("frontend", root.Service)
+		assert.Len(payload.TracerPayload.Chunks[0].Spans, 1)
+
+		agnt.Process(&api.Payload{
+			TracerPayload: testutil.TracerPayloadWithChunk(testutil.TraceChunkWithSpan(newSpan(3, 0, "GET /health"))),
+			Source:        want,
+		})
+		assert.EqualValues(1, want.TracesFiltered)
+		assert.EqualValues(2, want.SpansFiltered)
+	})
+
 	t.Run("Blacklister", func(t *testing.T) {
 		cfg := config.New()
 		cfg.Endpoints[0].APIKey = "test"
@@ -929,10 +975,13 @@ func TestPartialSamplingFree(t *testing.T) {
 	writerChan := make(chan *writer.SampledChunks, 100)
 	dynConf := sampler.NewDynamicConfig()
 	in := make(chan *api.Payload, 1000)
+	spanRules, err := filters.NewSpanRules(nil)
+	assert.NoError(t, err)
 	agnt := &Agent{
 		Concentrator:      stats.NewConcentrator(cfg, statsChan, time.Now()),
 		Blacklister:       filters.NewBlacklister(cfg.Ignore["resource"]),
 		Replacer:          filters.NewReplacer(cfg.ReplaceTags),
+		SpanRules:         spanRules,
 		NoPrioritySampler: sampler.NewNoPrioritySampler(cfg),
 		ErrorsSampler:     sampler.NewErrorsSampler(cfg),
 		PrioritySampler:   sampler.NewPrioritySampler(cfg, &sampler.DynamicConfig{}),
@@ -22,8 +22,32 @@ const (
 	tagElasticBody      = "elasticsearch.body"
 	tagSQLQuery         = "sql.query"
 	tagHTTPURL          = "http.url"
+	tagGraphQLSource    = "graphql.source"
+	tagDBType           = "db.type"
+
+	// tagGraphQLVariablesPrefix prefixes the tags holding the values of the variables of a GraphQL query.
+	tagGraphQLVariablesPrefix = "graphql.variables."
 )
 
+// dbmsByDBType maps the "db.type" tag values set by the tracers to the DBMS whose dialect is used to
+// obfuscate the SQL queries. Other databases use the default tokenizer.
+var dbmsByDBType = map[string]string{
+	"postgres":   obfuscate.DBMSPostgres,
+	"postgresql": obfuscate.DBMSPostgres,
+	"mysql":      obfuscate.DBMSMySQL,
+	"mariadb":    obfuscate.DBMSMySQL,
+	"oracle":     obfuscate.DBMSOracle,
+	"mssql":      obfuscate.DBMSSQLServer,
+	"sqlserver":  obfuscate.DBMSSQLServer,
+}
+
+// dynamoDBTags are the tags of "dynamodb" spans holding JSON attribute values.
+var dynamoDBTags = []string{
+	"aws.dynamodb.expression_attribute_values",
+	"aws.dynamodb.key",
+	"aws.dynamodb.item",
+}
+
 const (
 	textNonParsable = "Non-parsable SQL query"
 )
@@ -35,7 +59,7 @@ func (a *Agent) obfuscateSpan(span *pb.Span) {
 		if span.Resource == "" {
 			return
 		}
-		oq, err := o.ObfuscateSQLString(span.Resource)
+		oq, err := o.ObfuscateSQLStringForDBMS(span.Resource, dbmsByDBType[strings.ToLower(span.Meta[tagDBType])])
 		if err != nil {
 			// we have an error, discard the SQL to avoid polluting user resources.
 			log.Debugf("Error parsing SQL query: %v. Resource: %q", err, span.Resource)
@@ -97,6 +121,27 @@ func (a *Agent) obfuscateSpan(span *pb.Span) {
 			return
 		}
 		span.Meta[tagElasticBody] = o.ObfuscateElasticSearchString(v)
+	case "graphql":
+		if !a.conf.Obfuscation.GraphQL.Enabled {
+			return
+		}
+		span.Resource = o.ObfuscateGraphQLString(span.Resource)
+		for k, v := range span.Meta {
+			switch {
+			case k == tagGraphQLSource:
+				span.Meta[k] = o.ObfuscateGraphQLString(v)
+			case strings.HasPrefix(k, tagGraphQLVariablesPrefix):
+				span.Meta[k] = "?"
+			}
+		}
+	case "dynamodb":
+		for _, k := range dynamoDBTags {
+			if v, ok := span.Meta[k]; ok {
+				span.Meta[k] = o.ObfuscateDynamoDBString(v)
+			}
+		}
+	case "queue", "kafka":
+		o.ObfuscateMessagingMeta(span.Meta)
 	}
 }
 
@@ -104,7 +149,7 @@ func (a *Agent) obfuscateStatsGroup(b *pb.ClientGroupedStats) {
 	o := a.obfuscator
 	switch b.Type {
 	case "sql", "cassandra":
-		oq, err := o.ObfuscateSQLString(b.Resource)
+		oq, err := o.ObfuscateSQLStringForDBMS(b.Resource, dbmsByDBType[strings.ToLower(b.DBType)])
 		if err != nil {
 			log.Errorf("Error obfuscating stats group resource %q: %v", b.Resource, err)
 			b.Resource = textNonParsable
@@ -113,6 +158,8 @@ func (a *Agent) obfuscateStatsGroup(b *pb.ClientGroupedStats) {
 		}
 	case "redis":
 		b.Resource = o.QuantizeRedisString(b.Resource)
+	case "graphql":
+		b.Resource = o.ObfuscateGraphQLString(b.Resource)
 	}
 }
 
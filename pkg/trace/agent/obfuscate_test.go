@@ -64,7 +64,10 @@ func TestObfuscateStatsGroup(t *testing.T) {
 		{statsGroup("sql", "SELECT 1 FROM db"), "SELECT ? FROM db"},
 		{statsGroup("sql", "SELECT 1\nFROM Blogs AS [b\nORDER BY [b]"), textNonParsable},
 		{statsGroup("redis", "ADD 1, 2"), "ADD"},
+		{statsGroup("graphql", "query { user(id: 42) { name } }"), "query { user(id: 42) { name } }"},
 		{statsGroup("other", "ADD 1, 2"), "ADD 1, 2"},
+		// the dialect of the database gives the same resource as for the spans
+		{&pb.ClientGroupedStats{Type: "sql", DBType: "PostgreSQL", Resource: `SELECT "Users".id FROM "Users" WHERE id = 1`}, `SELECT "Users".id FROM "Users" WHERE id = ?`},
 	} {
 		agnt, stop := agentWithDefaults()
 		defer stop()
@@ -220,6 +223,70 @@ func TestObfuscateConfig(t *testing.T) {
 		"set key 0 0 0 noreply\r\nvalue",
 		&config.ObfuscationConfig{},
 	))
+
+	t.Run("graphql/enabled", testConfig(
+		"graphql",
+		"graphql.source",
+		`query GetUser { user(id: 42) { name } }`,
+		`query GetUser { user(id: ?) { name } }`,
+		&config.ObfuscationConfig{GraphQL: config.Enablable{Enabled: true}},
+	))
+
+	t.Run("graphql/variables", testConfig(
+		"graphql",
+		"graphql.variables.id",
+		"42",
+		"?",
+		&config.ObfuscationConfig{GraphQL: config.Enablable{Enabled: true}},
+	))
+
+	t.Run("graphql/disabled", testConfig(
+		"graphql",
+		"graphql.variables.id",
+		"42",
+		"42",
+		&config.ObfuscationConfig{},
+	))
+
+	t.Run("dynamodb/enabled", testConfig(
+		"dynamodb",
+		"aws.dynamodb.expression_attribute_values",
+		`{":id": {"S": "user-1234"}}`,
+		`{":id":{"S":"?"}}`,
+		&config.ObfuscationConfig{DynamoDB: config.JSONObfuscationConfig{Enabled: true}},
+	))
+
+	t.Run("dynamodb/disabled", testConfig(
+		"dynamodb",
+		"aws.dynamodb.expression_attribute_values",
+		`{":id": {"S": "user-1234"}}`,
+		`{":id": {"S": "user-1234"}}`,
+		&config.ObfuscationConfig{},
+	))
+
+	t.Run("messaging/enabled", testConfig(
+		"queue",
+		"kafka.message.key",
+		"user-1234",
+		"?",
+		&config.ObfuscationConfig{Messaging: config.MessagingObfuscationConfig{Enabled: true}},
+	))
+
+	t.Run("messaging/keys", testConfig(
+		"kafka",
+		"kafka.header.token",
+		"secret",
+		"?",
+		&config.ObfuscationConfig{Messaging: config.MessagingObfuscationConfig{Enabled: true, Keys: []string{"kafka.header.*"}}},
+	))
+
+	t.Run("messaging/disabled", testConfig(
+		"queue",
+		"kafka.message.key",
+		"user-1234",
+		"user-1234",
+		&config.ObfuscationConfig{},
+	))
 }
 
 func SQLSpan(query string) *pb.Span {
@@ -249,6 +316,30 @@ func TestSQLResourceQuery(t *testing.T) {
 	assert.Equal("SELECT * FROM users WHERE id = 42", span.Meta["sql.query"])
 }
 
+func TestSQLResourceDBType(t *testing.T) {
+	for _, tt := range []struct {
+		dbType, in, out string
+	}{
+		{"postgresql", `SELECT data #>> '{a}' FROM "Users"`, `SELECT data #>> ? FROM "Users"`},
+		{"postgres", `SELECT * FROM t WHERE a = E'it\'s'`, `SELECT * FROM t WHERE a = ?`},
+		{"mysql", "SELECT * FROM `my table` WHERE name = \"john\"", "SELECT * FROM `my table` WHERE name = ?"},
+		{"Oracle", `SELECT q'[it's]' FROM v$session`, `SELECT ? FROM v$session`},
+		{"", `SELECT * FROM "Users" WHERE id = 1`, `SELECT * FROM Users WHERE id = ?`},
+	} {
+		t.Run(tt.dbType, func(t *testing.T) {
+			span := &pb.Span{
+				Resource: tt.in,
+				Type:     "sql",
+				Meta:     map[string]string{"db.type": tt.dbType},
+			}
+			agnt, stop := agentWithDefaults()
+			defer stop()
+			agnt.obfuscateSpan(span)
+			assert.Equal(t, tt.out, span.Resource)
+		})
+	}
+}
+
 func TestSQLResourceWithoutQuery(t *testing.T) {
 	assert := assert.New(t)
 	span := &pb.Span{
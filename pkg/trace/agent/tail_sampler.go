@@ -0,0 +1,379 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
+	"github.com/DataDog/datadog-agent/pkg/trace/writer"
+)
+
+const (
+	tailPolicyError     = "error"
+	tailPolicyLatency   = "latency"
+	tailPolicyService   = "service"
+	tailPolicyAttribute = "attribute"
+
+	// tailPolicyHead is the telemetry tag of the traces kept because another of their chunks
+	// was kept by the other samplers.
+	tailPolicyHead = "head"
+
+	// tailTickInterval is the frequency at which the expired traces are evaluated.
+	tailTickInterval = time.Second
+	// tailDecisionCacheSize is the number of decisions remembered to apply them to the
+	// chunks received after the evaluation of their trace.
+	tailDecisionCacheSize = 10000
+)
+
+// tailPolicy is a condition on the spans of a reassembled trace.
+type tailPolicy struct {
+	name  string
+	match func(spans []*pb.Span) bool
+}
+
+// newTailPolicy validates a policy and returns its condition.
+func newTailPolicy(p config.TailSamplingPolicy) (tailPolicy, error) {
+	tp := tailPolicy{name: p.Name}
+	if tp.name == "" {
+		tp.name = p.Type
+	}
+	switch p.Type {
+	case tailPolicyError:
+		tp.match = func(spans []*pb.Span) bool {
+			return traceContainsError(spans)
+		}
+	case tailPolicyLatency:
+		if p.ThresholdMs <= 0 {
+			return tp, fmt.Errorf("policy %q: threshold_ms must be positive", tp.name)
+		}
+		threshold := int64(p.ThresholdMs * float64(time.Millisecond))
+		tp.match = func(spans []*pb.Span) bool {
+			return traceutil.GetRoot(spans).Duration >= threshold
+		}
+	case tailPolicyService:
+		if len(p.Services) == 0 {
+			return tp, fmt.Errorf("policy %q: services are required", tp.name)
+		}
+		services := toSet(p.Services)
+		tp.match = func(spans []*pb.Span) bool {
+			for _, s := range spans {
+				if _, ok := services[s.Service]; ok {
+					return true
+				}
+			}
+			return false
+		}
+	case tailPolicyAttribute:
+		if p.Key == "" {
+			return tp, fmt.Errorf("policy %q: key is required", tp.name)
+		}
+		key, values := p.Key, toSet(p.Values)
+		tp.match = func(spans []*pb.Span) bool {
+			for _, s := range spans {
+				v, ok := s.Meta[key]
+				if !ok {
+					continue
+				}
+				if _, match := values[v]; match || len(values) == 0 {
+					return true
+				}
+			}
+			return false
+		}
+	default:
+		return tp, fmt.Errorf("policy %q: unknown type %q, must be one of %s, %s, %s or %s",
+			tp.name, p.Type, tailPolicyError, tailPolicyLatency, tailPolicyService, tailPolicyAttribute)
+	}
+	return tp, nil
+}
+
+func toSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return set
+}
+
+// tailChunk is a buffered chunk along with the metadata of the payload it was received in.
+type tailChunk struct {
+	header *pb.TracerPayload
+	chunk  *pb.TraceChunk
+}
+
+// newTailHeader returns a copy of the metadata of a payload, without its chunks.
+func newTailHeader(tp *pb.TracerPayload) *pb.TracerPayload {
+	header := *tp
+	header.Chunks = nil
+	return &header
+}
+
+// tailTrace holds the buffered chunks of a trace.
+type tailTrace struct {
+	id       uint64
+	deadline time.Time
+	chunks   []tailChunk
+	spans    int
+	// headKept is set when another chunk of the trace was kept by the other samplers.
+	headKept bool
+}
+
+// TailSampler buffers the chunks dropped by the other samplers by trace ID for a decision
+// wait, and evaluates policies on the reassembled traces. The traces matching a policy are
+// forwarded to the TraceWriter, the others are dropped.
+type TailSampler struct {
+	decisionWait time.Duration
+	maxSpans     int
+	policies     []tailPolicy
+	out          chan<- *writer.SampledChunks
+
+	mu     sync.Mutex
+	traces map[uint64]*tailTrace
+	// queue holds the buffered traces by arrival, hence by deadline.
+	queue []*tailTrace
+	spans int
+	// decisions remembers the latest decisions, decisionIDs being a ring buffer of their trace IDs.
+	decisions   map[uint64]bool
+	decisionIDs []uint64
+	decisionPos int
+
+	exit   chan struct{}
+	exitWG sync.WaitGroup
+}
+
+// NewTailSampler returns a TailSampler forwarding the traces it keeps to out.
+// Invalid policies are logged and ignored.
+func NewTailSampler(conf *config.TailSamplingConfig, out chan<- *writer.SampledChunks) *TailSampler {
+	policies := make([]tailPolicy, 0, len(conf.Policies))
+	for _, p := range conf.Policies {
+		tp, err := newTailPolicy(p)
+		if err != nil {
+			log.Errorf("Ignoring tail sampling policy: %v", err)
+			continue
+		}
+		policies = append(policies, tp)
+	}
+	return &TailSampler{
+		decisionWait: conf.DecisionWait,
+		maxSpans:     conf.MaxBufferedSpans,
+		policies:     policies,
+		out:          out,
+		traces:       make(map[uint64]*tailTrace),
+		decisions:    make(map[uint64]bool, tailDecisionCacheSize),
+		decisionIDs:  make([]uint64, 0, tailDecisionCacheSize),
+		exit:         make(chan struct{}),
+	}
+}
+
+// Start starts evaluating the expired traces.
+func (s *TailSampler) Start() {
+	s.exitWG.Add(1)
+	go func() {
+		defer watchdog.LogOnPanic()
+		defer s.exitWG.Done()
+		ticker := time.NewTicker(tailTickInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				s.flush(now)
+				s.report()
+			case <-s.exit:
+				// evaluate all the buffered traces
+				s.flush(time.Now().Add(s.decisionWait))
+				return
+			}
+		}
+	}()
+}
+
+// Stop evaluates the buffered traces and stops the sampler.
+func (s *TailSampler) Stop() {
+	close(s.exit)
+	s.exitWG.Wait()
+}
+
+// Add buffers a chunk dropped by the other samplers, header holding the metadata of the
+// payload it was received in. Chunks dropped by the user are ignored, and the chunks of the
+// traces already evaluated follow the decision taken.
+func (s *TailSampler) Add(now time.Time, header *pb.TracerPayload, chunk *pb.TraceChunk) {
+	if len(chunk.Spans) == 0 {
+		return
+	}
+	if priority, ok := sampler.GetSamplingPriority(chunk); ok && priority < 0 {
+		return
+	}
+	id := chunk.Spans[0].TraceID
+	var evicted []*tailTrace
+	s.mu.Lock()
+	if keep, ok := s.decisions[id]; ok {
+		s.mu.Unlock()
+		metrics.Count("datadog.trace_agent.tail_sampler.late_chunks", 1, nil, 1)
+		if keep {
+			s.send([]tailChunk{{header: header, chunk: chunk}})
+		}
+		return
+	}
+	t, ok := s.traces[id]
+	if !ok {
+		t = &tailTrace{id: id, deadline: now.Add(s.decisionWait)}
+		s.traces[id] = t
+		s.queue = append(s.queue, t)
+	}
+	t.chunks = append(t.chunks, tailChunk{header: header, chunk: chunk})
+	t.spans += len(chunk.Spans)
+	s.spans += len(chunk.Spans)
+	for s.maxSpans > 0 && s.spans > s.maxSpans && len(s.queue) > 0 {
+		evicted = append(evicted, s.pop())
+	}
+	s.mu.Unlock()
+
+	if len(evicted) > 0 {
+		n := 0
+		for _, t := range evicted {
+			n += t.spans
+		}
+		metrics.Count("datadog.trace_agent.tail_sampler.evicted_traces", int64(len(evicted)), nil, 1)
+		metrics.Count("datadog.trace_agent.tail_sampler.evicted_spans", int64(n), nil, 1)
+		s.evaluate(evicted)
+	}
+}
+
+// MarkKept reports that a chunk of the given trace was kept by the other samplers, so that
+// its buffered chunks, and the ones received later, are kept too.
+func (s *TailSampler) MarkKept(traceID uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.traces[traceID]; ok {
+		t.headKept = true
+		return
+	}
+	if _, ok := s.decisions[traceID]; ok {
+		s.decisions[traceID] = true
+		return
+	}
+	s.remember(traceID, true)
+}
+
+// pop removes the oldest trace from the buffer.
+// Callers must guard!
+func (s *TailSampler) pop() *tailTrace {
+	t := s.queue[0]
+	s.queue[0] = nil
+	s.queue = s.queue[1:]
+	delete(s.traces, t.id)
+	s.spans -= t.spans
+	return t
+}
+
+// flush evaluates the traces whose decision wait has expired at now.
+func (s *TailSampler) flush(now time.Time) {
+	var expired []*tailTrace
+	s.mu.Lock()
+	for len(s.queue) > 0 && !s.queue[0].deadline.After(now) {
+		expired = append(expired, s.pop())
+	}
+	s.mu.Unlock()
+	s.evaluate(expired)
+}
+
+// evaluate takes the decision on traces removed from the buffer and forwards the ones kept.
+func (s *TailSampler) evaluate(traces []*tailTrace) {
+	var kept []tailChunk
+	decisions := make(map[uint64]bool, len(traces))
+	for _, t := range traces {
+		policy, keep := s.match(t)
+		decisions[t.id] = keep
+		if !keep {
+			metrics.Count("datadog.trace_agent.tail_sampler.dropped", 1, nil, 1)
+			continue
+		}
+		metrics.Count("datadog.trace_agent.tail_sampler.kept", 1, []string{"policy:" + policy}, 1)
+		kept = append(kept, t.chunks...)
+	}
+	s.mu.Lock()
+	for id, keep := range decisions {
+		s.remember(id, keep)
+	}
+	s.mu.Unlock()
+	if len(kept) > 0 {
+		s.send(kept)
+	}
+}
+
+// match returns the name of the first policy matching a trace, and whether there is one.
+func (s *TailSampler) match(t *tailTrace) (string, bool) {
+	if t.headKept {
+		return tailPolicyHead, true
+	}
+	if len(s.policies) == 0 {
+		return "", false
+	}
+	spans := t.chunks[0].chunk.Spans
+	if len(t.chunks) > 1 {
+		spans = make([]*pb.Span, 0, t.spans)
+		for _, c := range t.chunks {
+			spans = append(spans, c.chunk.Spans...)
+		}
+	}
+	for _, p := range s.policies {
+		if p.match(spans) {
+			return p.name, true
+		}
+	}
+	return "", false
+}
+
+// remember records the decision taken on a trace, forgetting the oldest one when the cache is full.
+// Callers must guard!
+func (s *TailSampler) remember(id uint64, keep bool) {
+	if len(s.decisionIDs) < tailDecisionCacheSize {
+		s.decisionIDs = append(s.decisionIDs, id)
+	} else {
+		delete(s.decisions, s.decisionIDs[s.decisionPos])
+		s.decisionIDs[s.decisionPos] = id
+		s.decisionPos = (s.decisionPos + 1) % tailDecisionCacheSize
+	}
+	s.decisions[id] = keep
+}
+
+// send forwards chunks to the TraceWriter, grouped by the payload they were received in.
+func (s *TailSampler) send(chunks []tailChunk) {
+	payloads := make(map[*pb.TracerPayload]*writer.SampledChunks)
+	var order []*writer.SampledChunks
+	for _, c := range chunks {
+		ss, ok := payloads[c.header]
+		if !ok {
+			ss = &writer.SampledChunks{TracerPayload: newTailHeader(c.header)}
+			payloads[c.header] = ss
+			order = append(order, ss)
+		}
+		ss.TracerPayload.Chunks = append(ss.TracerPayload.Chunks, c.chunk)
+		ss.SpanCount += int64(len(c.chunk.Spans))
+		ss.Size += c.chunk.Msgsize()
+	}
+	for _, ss := range order {
+		s.out <- ss
+	}
+}
+
+// report sends the buffer telemetry.
+func (s *TailSampler) report() {
+	s.mu.Lock()
+	traces, spans := len(s.traces), s.spans
+	s.mu.Unlock()
+	metrics.Gauge("datadog.trace_agent.tail_sampler.buffered_traces", float64(traces), nil, 1)
+	metrics.Gauge("datadog.trace_agent.tail_sampler.buffered_spans", float64(spans), nil, 1)
+}
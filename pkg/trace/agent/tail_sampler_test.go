@@ -0,0 +1,198 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/writer"
+)
+
+func TestNewTailPolicy(t *testing.T) {
+	spans := []*pb.Span{
+		{SpanID: 1, Service: "web", Duration: int64(200 * time.Millisecond)},
+		{SpanID: 2, ParentID: 1, Service: "db", Meta: map[string]string{"customer_tier": "gold"}},
+	}
+	for _, tt := range []struct {
+		policy config.TailSamplingPolicy
+		match  bool
+	}{
+		{config.TailSamplingPolicy{Type: "error"}, false},
+		{config.TailSamplingPolicy{Type: "latency", ThresholdMs: 100}, true},
+		{config.TailSamplingPolicy{Type: "latency", ThresholdMs: 300}, false},
+		{config.TailSamplingPolicy{Type: "service", Services: []string{"db", "cache"}}, true},
+		{config.TailSamplingPolicy{Type: "service", Services: []string{"cache"}}, false},
+		{config.TailSamplingPolicy{Type: "attribute", Key: "customer_tier"}, true},
+		{config.TailSamplingPolicy{Type: "attribute", Key: "customer_tier", Values: []string{"gold"}}, true},
+		{config.TailSamplingPolicy{Type: "attribute", Key: "customer_tier", Values: []string{"silver"}}, false},
+	} {
+		p, err := newTailPolicy(tt.policy)
+		require.NoError(t, err)
+		assert.Equal(t, tt.policy.Type, p.name)
+		assert.Equal(t, tt.match, p.match(spans), "%+v", tt.policy)
+	}
+
+	for _, invalid := range []config.TailSamplingPolicy{
+		{Type: "unknown"},
+		{Type: "latency"},
+		{Type: "service"},
+		{Type: "attribute", Values: []string{"gold"}},
+	} {
+		_, err := newTailPolicy(invalid)
+		assert.Error(t, err, "%+v", invalid)
+	}
+}
+
+func newTestTailSampler(maxSpans int, policies ...config.TailSamplingPolicy) (*TailSampler, chan *writer.SampledChunks) {
+	out := make(chan *writer.SampledChunks, 10)
+	return NewTailSampler(&config.TailSamplingConfig{
+		DecisionWait:     10 * time.Second,
+		MaxBufferedSpans: maxSpans,
+		Policies:         policies,
+	}, out), out
+}
+
+func tailChunkWithSpans(spans ...*pb.Span) *pb.TraceChunk {
+	return &pb.TraceChunk{Priority: int32(sampler.PriorityAutoDrop), Spans: spans}
+}
+
+func TestTailSampler(t *testing.T) {
+	s, out := newTestTailSampler(0, config.TailSamplingPolicy{Name: "errors", Type: "error"})
+	now := time.Now()
+	header1 := &pb.TracerPayload{Env: "prod", Hostname: "host1"}
+	header2 := &pb.TracerPayload{Env: "prod", Hostname: "host2"}
+
+	s.Add(now, header1, tailChunkWithSpans(&pb.Span{TraceID: 1, SpanID: 1}))
+	s.Add(now, header1, tailChunkWithSpans(&pb.Span{TraceID: 2, SpanID: 3}))
+	s.Add(now.Add(time.Second), header2, tailChunkWithSpans(&pb.Span{TraceID: 1, SpanID: 2, ParentID: 1, Error: 1}))
+	// chunks dropped by the user are ignored
+	s.Add(now, header1, &pb.TraceChunk{Priority: int32(sampler.PriorityUserDrop), Spans: []*pb.Span{{TraceID: 3, Error: 1}}})
+	assert.Len(t, s.traces, 2)
+	assert.Equal(t, 3, s.spans)
+
+	// the decision wait has not expired yet
+	s.flush(now.Add(5 * time.Second))
+	assert.Len(t, out, 0)
+
+	s.flush(now.Add(10 * time.Second))
+	assert.Len(t, s.traces, 0)
+	assert.Equal(t, 0, s.spans)
+	// the chunks of trace 1 are forwarded with the metadata of the payload they were received in
+	require.Len(t, out, 2)
+	ss := <-out
+	assert.Equal(t, "host1", ss.TracerPayload.Hostname)
+	require.Len(t, ss.TracerPayload.Chunks, 1)
+	assert.Equal(t, uint64(1), ss.TracerPayload.Chunks[0].Spans[0].SpanID)
+	assert.Equal(t, int64(1), ss.SpanCount)
+	ss = <-out
+	assert.Equal(t, "host2", ss.TracerPayload.Hostname)
+	assert.Equal(t, uint64(2), ss.TracerPayload.Chunks[0].Spans[0].SpanID)
+
+	// late chunks follow the decision taken on their trace
+	s.Add(now, header1, tailChunkWithSpans(&pb.Span{TraceID: 1, SpanID: 4}))
+	s.Add(now, header1, tailChunkWithSpans(&pb.Span{TraceID: 2, SpanID: 5}))
+	assert.Len(t, s.traces, 0)
+	require.Len(t, out, 1)
+	assert.Equal(t, uint64(4), (<-out).TracerPayload.Chunks[0].Spans[0].SpanID)
+}
+
+func TestTailSamplerMarkKept(t *testing.T) {
+	s, out := newTestTailSampler(0)
+	now := time.Now()
+	s.Add(now, &pb.TracerPayload{}, tailChunkWithSpans(&pb.Span{TraceID: 1, SpanID: 2, ParentID: 1}))
+	s.Add(now, &pb.TracerPayload{}, tailChunkWithSpans(&pb.Span{TraceID: 2, SpanID: 3}))
+	s.MarkKept(1)
+
+	s.flush(now.Add(time.Minute))
+	require.Len(t, out, 1)
+	assert.Equal(t, uint64(1), (<-out).TracerPayload.Chunks[0].Spans[0].TraceID)
+
+	// the chunks received after the kept one are kept too, without being buffered
+	s.MarkKept(3)
+	s.Add(now, &pb.TracerPayload{}, tailChunkWithSpans(&pb.Span{TraceID: 3, SpanID: 5, ParentID: 4}))
+	assert.Len(t, s.traces, 0)
+	require.Len(t, out, 1)
+	assert.Equal(t, uint64(5), (<-out).TracerPayload.Chunks[0].Spans[0].SpanID)
+
+	// a trace dropped before one of its chunks was kept is kept from then on
+	s.MarkKept(2)
+	s.Add(now, &pb.TracerPayload{}, tailChunkWithSpans(&pb.Span{TraceID: 2, SpanID: 6, ParentID: 3}))
+	require.Len(t, out, 1)
+	assert.Equal(t, uint64(6), (<-out).TracerPayload.Chunks[0].Spans[0].SpanID)
+	assert.Len(t, s.decisionIDs, 3)
+}
+
+func TestTailSamplerEviction(t *testing.T) {
+	s, out := newTestTailSampler(3, config.TailSamplingPolicy{Type: "service", Services: []string{"web"}})
+	now := time.Now()
+	s.Add(now, &pb.TracerPayload{}, tailChunkWithSpans(&pb.Span{TraceID: 1, Service: "web"}, &pb.Span{TraceID: 1, Service: "db"}))
+	s.Add(now, &pb.TracerPayload{}, tailChunkWithSpans(&pb.Span{TraceID: 2, Service: "db"}))
+	assert.Len(t, out, 0)
+
+	// the oldest trace is evaluated to stay under the buffer limit
+	s.Add(now, &pb.TracerPayload{}, tailChunkWithSpans(&pb.Span{TraceID: 3, Service: "web"}))
+	assert.Equal(t, 2, s.spans)
+	require.Len(t, out, 1)
+	assert.Equal(t, uint64(1), (<-out).TracerPayload.Chunks[0].Spans[0].TraceID)
+
+	// stopping the sampler evaluates the buffered traces
+	s.Start()
+	s.Stop()
+	require.Len(t, out, 1)
+	assert.Equal(t, uint64(3), (<-out).TracerPayload.Chunks[0].Spans[0].TraceID)
+}
+
+func TestTailSamplerDecisionCache(t *testing.T) {
+	s, _ := newTestTailSampler(0)
+	for id := uint64(0); id < tailDecisionCacheSize+10; id++ {
+		s.remember(id, true)
+	}
+	assert.Len(t, s.decisions, tailDecisionCacheSize)
+	assert.NotContains(t, s.decisions, uint64(9))
+	assert.Contains(t, s.decisions, uint64(10))
+}
+
+func TestProcessTailSampling(t *testing.T) {
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.DisableRareSampler = true
+	cfg.TailSampling.Enabled = true
+	cfg.TailSampling.Policies = []config.TailSamplingPolicy{{Type: "latency", ThresholdMs: 100}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agnt := NewAgent(ctx, cfg)
+	require.NotNil(t, agnt.TailSampler)
+	agnt.TraceWriter.In = make(chan *writer.SampledChunks, 10)
+	agnt.TailSampler.out = agnt.TraceWriter.In
+
+	now := time.Now()
+	slow := &pb.Span{TraceID: 1, SpanID: 1, Service: "web", Name: "http.request", Resource: "GET /", Start: now.Add(-time.Second).UnixNano(), Duration: int64(500 * time.Millisecond)}
+	fast := &pb.Span{TraceID: 2, SpanID: 2, Service: "web", Name: "http.request", Resource: "GET /", Start: now.Add(-time.Second).UnixNano(), Duration: int64(time.Millisecond)}
+	agnt.Process(&api.Payload{
+		TracerPayload: &pb.TracerPayload{Env: "prod", Chunks: []*pb.TraceChunk{tailChunkWithSpans(slow), tailChunkWithSpans(fast)}},
+		Source:        info.NewReceiverStats().GetTagStats(info.Tags{}),
+	})
+	// both chunks are dropped by the priority sampler
+	assert.Len(t, agnt.TraceWriter.In, 0)
+	assert.Len(t, agnt.TailSampler.traces, 2)
+
+	agnt.TailSampler.flush(now.Add(time.Minute))
+	require.Len(t, agnt.TraceWriter.In, 1)
+	ss := <-agnt.TraceWriter.In
+	assert.Equal(t, "prod", ss.TracerPayload.Env)
+	require.Len(t, ss.TracerPayload.Chunks, 1)
+	assert.Equal(t, uint64(1), ss.TracerPayload.Chunks[0].Spans[0].TraceID)
+}
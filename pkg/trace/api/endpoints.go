@@ -116,4 +116,12 @@ var endpoints = []Endpoint{
 		Pattern: "/debugger/v1/input",
 		Handler: func(r *HTTPReceiver) http.Handler { return r.debuggerProxyHandler() },
 	},
+	{
+		Pattern: "/api/v2/spans",
+		Handler: func(r *HTTPReceiver) http.Handler { return r.handleWithVersion(vZipkinV2, r.handleZipkin) },
+	},
+	{
+		Pattern: "/api/traces",
+		Handler: func(r *HTTPReceiver) http.Handler { return r.handleWithVersion(vJaegerThrift, r.handleJaeger) },
+	},
 }
@@ -0,0 +1,213 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/api/apiutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
+)
+
+const (
+	// vZipkinV2 is the version of the endpoint receiving Zipkin v2 JSON spans.
+	vZipkinV2 Version = "zipkin_v2"
+	// vJaegerThrift is the version of the endpoint receiving Jaeger Thrift batches.
+	vJaegerThrift Version = "jaeger_thrift"
+)
+
+// foreignPayload holds the spans decoded from a payload of a non-Datadog format.
+type foreignPayload struct {
+	// spans are the converted spans, in any order.
+	spans []*pb.Span
+	// keep holds the IDs of the traces which were forced to be kept by the client (debug).
+	keep map[uint64]bool
+	// hostname is the hostname reported by the client, if any.
+	hostname string
+	// tracerVersion describes the client library, if known.
+	tracerVersion string
+}
+
+// handleForeignTraces handles a request on an endpoint receiving traces in a non-Datadog format,
+// using decode to convert its body. The traces go through the same pipeline as the Datadog ones.
+func (r *HTTPReceiver) handleForeignTraces(v Version, w http.ResponseWriter, req *http.Request, decode func([]byte) (*foreignPayload, error)) {
+	ts := r.tagStats(v, req.Header)
+	start := time.Now()
+	fp, err := r.readForeignPayload(req, decode)
+	defer func(err error) {
+		tags := append(ts.AsTags(), fmt.Sprintf("success:%v", err == nil))
+		metrics.Histogram("datadog.trace_agent.receiver.serve_traces_ms", float64(time.Since(start))/float64(time.Millisecond), tags, 1)
+	}(err)
+	if err != nil {
+		httpDecodingError(err, []string{"handler:traces", fmt.Sprintf("v:%s", v)}, w)
+		log.Errorf("Cannot decode %s traces payload: %v", v, err)
+		return
+	}
+	chunks := groupForeignSpans(fp)
+	if r.rateLimited(int64(len(chunks))) {
+		// this payload can not be accepted
+		w.WriteHeader(r.rateLimiterResponse)
+		atomic.AddInt64(&ts.PayloadRefused, 1)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+
+	atomic.AddInt64(&ts.TracesReceived, int64(len(chunks)))
+	atomic.AddInt64(&ts.TracesBytes, req.Body.(*apiutil.LimitedReader).Count)
+	atomic.AddInt64(&ts.PayloadAccepted, 1)
+
+	tp := &pb.TracerPayload{
+		Hostname:      fp.hostname,
+		Chunks:        chunks,
+		ContainerID:   req.Header.Get(headerContainerID),
+		LanguageName:  ts.Lang,
+		TracerVersion: fp.tracerVersion,
+	}
+	if ctags := getContainerTags(r.conf.ContainerTags, tp.ContainerID); ctags != "" {
+		tp.Tags = map[string]string{tagContainersTags: ctags}
+	}
+	payload := &Payload{
+		Source:        ts,
+		TracerPayload: tp,
+	}
+	select {
+	case r.out <- payload:
+		// ok
+	default:
+		// channel blocked, add a goroutine to ensure we never drop
+		r.wg.Add(1)
+		go func() {
+			metrics.Count("datadog.trace_agent.receiver.queued_send", 1, nil, 1)
+			defer func() {
+				r.wg.Done()
+				watchdog.LogOnPanic()
+			}()
+			r.out <- payload
+		}()
+	}
+}
+
+// readForeignPayload reads the body of req, decompressing it if needed, and decodes it.
+// The decompressed body is limited to the maximum request size too.
+func (r *HTTPReceiver) readForeignPayload(req *http.Request, decode func([]byte) (*foreignPayload, error)) (*foreignPayload, error) {
+	var body io.Reader = req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gzipr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer gzipr.Close()
+		body = apiutil.NewLimitedReader(gzipr, r.conf.MaxRequestBytes)
+	}
+	slurp, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	return decode(slurp)
+}
+
+// groupForeignSpans groups the spans of fp by trace. The spans reported by Zipkin and Jaeger
+// clients were sampled by them, so their traces are kept.
+func groupForeignSpans(fp *foreignPayload) []*pb.TraceChunk {
+	var (
+		chunks  []*pb.TraceChunk
+		byTrace = make(map[uint64]*pb.TraceChunk)
+	)
+	for _, span := range fp.spans {
+		chunk, ok := byTrace[span.TraceID]
+		if !ok {
+			chunk = &pb.TraceChunk{Priority: int32(sampler.PriorityAutoKeep)}
+			if fp.keep[span.TraceID] {
+				chunk.Priority = int32(sampler.PriorityUserKeep)
+			}
+			byTrace[span.TraceID] = chunk
+			chunks = append(chunks, chunk)
+		}
+		chunk.Spans = append(chunk.Spans, span)
+	}
+	return chunks
+}
+
+// finishForeignSpan sets the fields of span which are derived from its tags, once they are all
+// known: the environment, the type from the span kind, the resource and the name. format is the
+// name of the format the span was received in.
+func finishForeignSpan(format, kind, name string, span *pb.Span) {
+	if _, ok := span.Meta["env"]; !ok {
+		if env := span.Meta["deployment.environment"]; env != "" {
+			span.Meta["env"] = traceutil.NormalizeTag(env)
+		}
+	}
+	kind = strings.ToLower(kind)
+	if kind == "" {
+		kind = "internal"
+	}
+	span.Meta["span.kind"] = kind
+	if span.Type == "" {
+		span.Type = foreignSpanType(kind, span.Meta)
+	}
+	if r := resourceFromTags(span.Meta); r != "" {
+		span.Resource = r
+	} else {
+		span.Resource = name
+	}
+	span.Name = format + "." + kind
+}
+
+// foreignSpanType returns the type of a span based on its kind and meta.
+func foreignSpanType(kind string, meta map[string]string) string {
+	switch kind {
+	case "server":
+		return "web"
+	case "client":
+		db := meta["db.system"]
+		if db == "" {
+			db = meta["db.type"]
+		}
+		switch db {
+		case "":
+			return "http"
+		case "redis", "memcached":
+			return "cache"
+		default:
+			return "db"
+		}
+	case "producer", "consumer":
+		return "queue"
+	default:
+		return "custom"
+	}
+}
+
+// foreignEvent is a timestamped annotation or log of a span, serialized in the "events" meta.
+type foreignEvent struct {
+	TimeUnixNano int64             `json:"time_unix_nano"`
+	Name         string            `json:"name,omitempty"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+}
+
+// marshalForeignEvents marshals the events of a span, in chronological order.
+func marshalForeignEvents(events []foreignEvent) string {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].TimeUnixNano < events[j].TimeUnixNano })
+	b, err := json.Marshal(events)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
@@ -129,7 +129,9 @@ func TestInfoHandler(t *testing.T) {
 		"/v0.6/stats",
 		"/v0.1/pipeline_stats",
 		"/appsec/proxy/",
-		"/debugger/v1/input"
+		"/debugger/v1/input",
+		"/api/v2/spans",
+		"/api/traces"
 	],
 	"feature_flags": [
 		"feature_flag"
@@ -188,7 +190,9 @@ func TestInfoHandler(t *testing.T) {
 		"/v0.6/stats",
 		"/v0.1/pipeline_stats",
 		"/appsec/proxy/",
-		"/debugger/v1/input"
+		"/debugger/v1/input",
+		"/api/v2/spans",
+		"/api/traces"
 	],
 	"feature_flags": [
 		"feature_flag"
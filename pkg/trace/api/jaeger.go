@@ -0,0 +1,354 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+// Jaeger tag value types.
+const (
+	jaegerTagString int32 = 0
+	jaegerTagDouble int32 = 1
+	jaegerTagBool   int32 = 2
+	jaegerTagLong   int32 = 3
+	jaegerTagBinary int32 = 4
+)
+
+// jaegerRefChildOf is the type of the references to the parent of a span.
+const jaegerRefChildOf int32 = 0
+
+// jaegerFlagDebug is set on the spans of the traces which the client forced to be kept.
+const jaegerFlagDebug int32 = 2
+
+// The types below hold the parts of the Jaeger Thrift model which are converted.
+// See https://github.com/jaegertracing/jaeger-idl/blob/main/thrift/jaeger.thrift
+
+type jaegerTag struct {
+	key     string
+	vType   int32
+	vStr    string
+	vDouble float64
+	vBool   bool
+	vLong   int64
+	vBinary []byte
+}
+
+type jaegerLog struct {
+	timestamp int64 // in microseconds
+	fields    []jaegerTag
+}
+
+type jaegerSpanRef struct {
+	refType int32
+	spanID  int64
+}
+
+type jaegerSpan struct {
+	traceIDLow    int64
+	traceIDHigh   int64
+	spanID        int64
+	parentSpanID  int64
+	operationName string
+	references    []jaegerSpanRef
+	flags         int32
+	startTime     int64 // in microseconds
+	duration      int64 // in microseconds
+	tags          []jaegerTag
+	logs          []jaegerLog
+}
+
+type jaegerBatch struct {
+	serviceName string
+	processTags []jaegerTag
+	spans       []jaegerSpan
+}
+
+// handleJaeger handles a request containing a Jaeger batch, encoded with the Thrift binary protocol.
+func (r *HTTPReceiver) handleJaeger(v Version, w http.ResponseWriter, req *http.Request) {
+	switch mediaType := getMediaType(req); mediaType {
+	case "application/x-thrift", "application/vnd.apache.thrift.binary":
+	default:
+		httpFormatError(w, v, fmt.Errorf("unsupported media type: %q", mediaType))
+		return
+	}
+	r.handleForeignTraces(v, w, req, decodeJaegerBatch)
+}
+
+// decodeJaegerBatch decodes a Jaeger batch encoded with the Thrift binary protocol.
+func decodeJaegerBatch(body []byte) (*foreignPayload, error) {
+	r := &thriftReader{buf: body}
+	batch, err := readJaegerBatch(r)
+	if err != nil {
+		return nil, err
+	}
+	if r.off != len(body) {
+		return nil, fmt.Errorf("unexpected %d bytes after the batch", len(body)-r.off)
+	}
+	fp := &foreignPayload{
+		spans:         make([]*pb.Span, 0, len(batch.spans)),
+		keep:          make(map[uint64]bool),
+		tracerVersion: "jaeger",
+	}
+	processMeta := make(map[string]string, len(batch.processTags))
+	for _, tag := range batch.processTags {
+		switch tag.key {
+		case "hostname":
+			fp.hostname = tag.value()
+		case "jaeger.version":
+			fp.tracerVersion = "jaeger-" + tag.value()
+		default:
+			processMeta[tag.key] = tag.value()
+		}
+	}
+	for i := range batch.spans {
+		in := &batch.spans[i]
+		span := convertJaegerSpan(batch.serviceName, processMeta, in)
+		if in.flags&jaegerFlagDebug != 0 {
+			fp.keep[span.TraceID] = true
+		}
+		fp.spans = append(fp.spans, span)
+	}
+	return fp, nil
+}
+
+// convertJaegerSpan converts a Jaeger span to a Datadog span, adding the processMeta tags of its process.
+func convertJaegerSpan(service string, processMeta map[string]string, in *jaegerSpan) *pb.Span {
+	span := &pb.Span{
+		Service:  service,
+		TraceID:  uint64(in.traceIDLow),
+		SpanID:   uint64(in.spanID),
+		ParentID: uint64(in.parentSpanID),
+		Start:    in.startTime * 1000,
+		Duration: in.duration * 1000,
+		Meta:     make(map[string]string, len(processMeta)+len(in.tags)),
+		Metrics:  map[string]float64{},
+	}
+	if span.ParentID == 0 {
+		for _, ref := range in.references {
+			if ref.refType == jaegerRefChildOf {
+				span.ParentID = uint64(ref.spanID)
+				break
+			}
+		}
+	}
+	if in.traceIDHigh != 0 {
+		span.Meta["jaeger.trace_id"] = fmt.Sprintf("%016x%016x", uint64(in.traceIDHigh), uint64(in.traceIDLow))
+	}
+	for k, v := range processMeta {
+		span.Meta[k] = v
+	}
+	var kind string
+	for _, tag := range in.tags {
+		switch {
+		case tag.key == "span.kind":
+			kind = tag.value()
+		case tag.key == "error":
+			if tag.value() == "true" {
+				span.Error = 1
+			}
+		case tag.vType == jaegerTagDouble:
+			span.Metrics[tag.key] = tag.vDouble
+		case tag.vType == jaegerTagLong:
+			span.Metrics[tag.key] = float64(tag.vLong)
+		default:
+			span.Meta[tag.key] = tag.value()
+		}
+	}
+	if len(in.logs) > 0 {
+		events := make([]foreignEvent, 0, len(in.logs))
+		for _, l := range in.logs {
+			e := foreignEvent{TimeUnixNano: l.timestamp * 1000, Attributes: make(map[string]string, len(l.fields))}
+			for _, f := range l.fields {
+				e.Attributes[f.key] = f.value()
+			}
+			e.Name = e.Attributes["event"]
+			delete(e.Attributes, "event")
+			if e.Name == "error" && span.Error == 1 {
+				setJaegerErrorDetails(span, e.Attributes)
+			}
+			events = append(events, e)
+		}
+		span.Meta["events"] = marshalForeignEvents(events)
+	}
+	finishForeignSpan("jaeger", kind, in.operationName, span)
+	return span
+}
+
+// setJaegerErrorDetails sets the error meta of span from the fields of an OpenTracing error log.
+func setJaegerErrorDetails(span *pb.Span, fields map[string]string) {
+	if v := fields["message"]; v != "" {
+		span.Meta["error.msg"] = v
+	} else if v := fields["error.object"]; v != "" {
+		span.Meta["error.msg"] = v
+	}
+	if v := fields["error.kind"]; v != "" {
+		span.Meta["error.type"] = v
+	}
+	if v := fields["stack"]; v != "" {
+		span.Meta["error.stack"] = v
+	}
+}
+
+// value returns the value of the tag as a string.
+func (t *jaegerTag) value() string {
+	switch t.vType {
+	case jaegerTagDouble:
+		return strconv.FormatFloat(t.vDouble, 'f', -1, 64)
+	case jaegerTagBool:
+		return strconv.FormatBool(t.vBool)
+	case jaegerTagLong:
+		return strconv.FormatInt(t.vLong, 10)
+	case jaegerTagBinary:
+		return base64.StdEncoding.EncodeToString(t.vBinary)
+	default:
+		return t.vStr
+	}
+}
+
+func readJaegerBatch(r *thriftReader) (*jaegerBatch, error) {
+	var b jaegerBatch
+	err := r.readStruct(func(id int16, typ byte) (bool, error) {
+		switch {
+		case id == 1 && typ == thriftStruct:
+			return true, r.readStruct(func(id int16, typ byte) (bool, error) {
+				var err error
+				switch {
+				case id == 1 && typ == thriftString:
+					b.serviceName, err = r.readString()
+				case id == 2 && typ == thriftList:
+					b.processTags, err = readJaegerTags(r)
+				default:
+					return false, nil
+				}
+				return true, err
+			})
+		case id == 2 && typ == thriftList:
+			return true, r.readList(thriftStruct, func() error {
+				s, err := readJaegerSpan(r)
+				b.spans = append(b.spans, s)
+				return err
+			})
+		default:
+			return false, nil
+		}
+	})
+	return &b, err
+}
+
+func readJaegerSpan(r *thriftReader) (jaegerSpan, error) {
+	var s jaegerSpan
+	err := r.readStruct(func(id int16, typ byte) (bool, error) {
+		var err error
+		switch {
+		case id == 1 && typ == thriftI64:
+			s.traceIDLow, err = r.readI64()
+		case id == 2 && typ == thriftI64:
+			s.traceIDHigh, err = r.readI64()
+		case id == 3 && typ == thriftI64:
+			s.spanID, err = r.readI64()
+		case id == 4 && typ == thriftI64:
+			s.parentSpanID, err = r.readI64()
+		case id == 5 && typ == thriftString:
+			s.operationName, err = r.readString()
+		case id == 6 && typ == thriftList:
+			err = r.readList(thriftStruct, func() error {
+				ref, err := readJaegerSpanRef(r)
+				s.references = append(s.references, ref)
+				return err
+			})
+		case id == 7 && typ == thriftI32:
+			s.flags, err = r.readI32()
+		case id == 8 && typ == thriftI64:
+			s.startTime, err = r.readI64()
+		case id == 9 && typ == thriftI64:
+			s.duration, err = r.readI64()
+		case id == 10 && typ == thriftList:
+			s.tags, err = readJaegerTags(r)
+		case id == 11 && typ == thriftList:
+			err = r.readList(thriftStruct, func() error {
+				l, err := readJaegerLog(r)
+				s.logs = append(s.logs, l)
+				return err
+			})
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+	return s, err
+}
+
+func readJaegerSpanRef(r *thriftReader) (jaegerSpanRef, error) {
+	var ref jaegerSpanRef
+	err := r.readStruct(func(id int16, typ byte) (bool, error) {
+		var err error
+		switch {
+		case id == 1 && typ == thriftI32:
+			ref.refType, err = r.readI32()
+		case id == 4 && typ == thriftI64:
+			ref.spanID, err = r.readI64()
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+	return ref, err
+}
+
+func readJaegerLog(r *thriftReader) (jaegerLog, error) {
+	var l jaegerLog
+	err := r.readStruct(func(id int16, typ byte) (bool, error) {
+		var err error
+		switch {
+		case id == 1 && typ == thriftI64:
+			l.timestamp, err = r.readI64()
+		case id == 2 && typ == thriftList:
+			l.fields, err = readJaegerTags(r)
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+	return l, err
+}
+
+func readJaegerTags(r *thriftReader) ([]jaegerTag, error) {
+	var tags []jaegerTag
+	err := r.readList(thriftStruct, func() error {
+		var t jaegerTag
+		err := r.readStruct(func(id int16, typ byte) (bool, error) {
+			var err error
+			switch {
+			case id == 1 && typ == thriftString:
+				t.key, err = r.readString()
+			case id == 2 && typ == thriftI32:
+				t.vType, err = r.readI32()
+			case id == 3 && typ == thriftString:
+				t.vStr, err = r.readString()
+			case id == 4 && typ == thriftDouble:
+				t.vDouble, err = r.readDouble()
+			case id == 5 && typ == thriftBool:
+				t.vBool, err = r.readBool()
+			case id == 6 && typ == thriftI64:
+				t.vLong, err = r.readI64()
+			case id == 7 && typ == thriftString:
+				t.vBinary, err = r.readBinary()
+			default:
+				return false, nil
+			}
+			return true, err
+		})
+		tags = append(tags, t)
+		return err
+	})
+	return tags, err
+}
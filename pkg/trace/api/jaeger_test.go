@@ -0,0 +1,281 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// thriftWriter encodes values with the Thrift binary protocol, to build test payloads.
+type thriftWriter struct{ bytes.Buffer }
+
+func (w *thriftWriter) field(id int16, typ byte) {
+	w.WriteByte(typ)
+	binary.Write(w, binary.BigEndian, id) //nolint:errcheck
+}
+
+func (w *thriftWriter) stop() { w.WriteByte(thriftStop) }
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(id, thriftI32)
+	binary.Write(w, binary.BigEndian, v) //nolint:errcheck
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(id, thriftI64)
+	binary.Write(w, binary.BigEndian, v) //nolint:errcheck
+}
+
+func (w *thriftWriter) double(id int16, v float64) {
+	w.field(id, thriftDouble)
+	binary.Write(w, binary.BigEndian, math.Float64bits(v)) //nolint:errcheck
+}
+
+func (w *thriftWriter) bool(id int16, v bool) {
+	w.field(id, thriftBool)
+	if v {
+		w.WriteByte(1)
+	} else {
+		w.WriteByte(0)
+	}
+}
+
+func (w *thriftWriter) string(id int16, v string) {
+	w.field(id, thriftString)
+	binary.Write(w, binary.BigEndian, int32(len(v))) //nolint:errcheck
+	w.WriteString(v)
+}
+
+// list writes a list field of n structs, each written by elem.
+func (w *thriftWriter) list(id int16, n int, elem func(i int)) {
+	w.field(id, thriftList)
+	w.WriteByte(thriftStruct)
+	binary.Write(w, binary.BigEndian, int32(n)) //nolint:errcheck
+	for i := 0; i < n; i++ {
+		elem(i)
+		w.stop()
+	}
+}
+
+func (w *thriftWriter) tags(id int16, tags []jaegerTag) {
+	w.list(id, len(tags), func(i int) {
+		t := tags[i]
+		w.string(1, t.key)
+		w.i32(2, t.vType)
+		switch t.vType {
+		case jaegerTagString:
+			w.string(3, t.vStr)
+		case jaegerTagDouble:
+			w.double(4, t.vDouble)
+		case jaegerTagBool:
+			w.bool(5, t.vBool)
+		case jaegerTagLong:
+			w.i64(6, t.vLong)
+		case jaegerTagBinary:
+			w.string(7, string(t.vBinary))
+		}
+	})
+}
+
+func strTag(k, v string) jaegerTag { return jaegerTag{key: k, vType: jaegerTagString, vStr: v} }
+
+// encodeJaegerBatch encodes a batch as sent by the Jaeger clients to the collector.
+func encodeJaegerBatch(b *jaegerBatch) []byte {
+	var w thriftWriter
+	w.field(1, thriftStruct)
+	w.string(1, b.serviceName)
+	w.tags(2, b.processTags)
+	w.stop()
+	w.list(2, len(b.spans), func(i int) {
+		s := b.spans[i]
+		w.i64(1, s.traceIDLow)
+		w.i64(2, s.traceIDHigh)
+		w.i64(3, s.spanID)
+		w.i64(4, s.parentSpanID)
+		w.string(5, s.operationName)
+		w.list(6, len(s.references), func(i int) {
+			w.i32(1, s.references[i].refType)
+			w.i64(2, s.traceIDLow)
+			w.i64(3, s.traceIDHigh)
+			w.i64(4, s.references[i].spanID)
+		})
+		w.i32(7, s.flags)
+		w.i64(8, s.startTime)
+		w.i64(9, s.duration)
+		w.tags(10, s.tags)
+		w.list(11, len(s.logs), func(i int) {
+			w.i64(1, s.logs[i].timestamp)
+			w.tags(2, s.logs[i].fields)
+		})
+		// unknown field, to be skipped
+		w.field(99, thriftMap)
+		w.WriteByte(thriftString)
+		w.WriteByte(thriftI32)
+		binary.Write(&w, binary.BigEndian, int32(1)) //nolint:errcheck
+		binary.Write(&w, binary.BigEndian, int32(1)) //nolint:errcheck
+		w.WriteString("k")
+		binary.Write(&w, binary.BigEndian, int32(1)) //nolint:errcheck
+	})
+	// seqNo
+	w.i64(3, 1)
+	w.stop()
+	return w.Bytes()
+}
+
+var jaegerTestBatch = &jaegerBatch{
+	serviceName: "frontend",
+	processTags: []jaegerTag{
+		strTag("hostname", "host-1"),
+		strTag("jaeger.version", "Go-2.30.0"),
+		strTag("ip", "10.0.0.1"),
+	},
+	spans: []jaegerSpan{
+		{
+			traceIDLow:    0x48485a3953bb6124,
+			traceIDHigh:   0x463ac35c9f6413ad,
+			spanID:        1,
+			operationName: "HTTP GET /users",
+			startTime:     1600000000000000,
+			duration:      1500,
+			tags: []jaegerTag{
+				strTag("span.kind", "server"),
+				strTag("http.method", "GET"),
+				{key: "http.status_code", vType: jaegerTagLong, vLong: 500},
+				{key: "error", vType: jaegerTagBool, vBool: true},
+				{key: "retried", vType: jaegerTagBool, vBool: false},
+				{key: "ratio", vType: jaegerTagDouble, vDouble: 0.5},
+				{key: "blob", vType: jaegerTagBinary, vBinary: []byte{1, 2}},
+			},
+			logs: []jaegerLog{{
+				timestamp: 1600000000001000,
+				fields: []jaegerTag{
+					strTag("event", "error"),
+					strTag("message", "boom"),
+					strTag("error.kind", "io.EOF"),
+				},
+			}},
+		},
+		{
+			traceIDLow:    0x48485a3953bb6124,
+			traceIDHigh:   0x463ac35c9f6413ad,
+			spanID:        2,
+			operationName: "GET",
+			references:    []jaegerSpanRef{{refType: jaegerRefChildOf, spanID: 1}},
+			startTime:     1600000000000100,
+			duration:      800,
+			tags:          []jaegerTag{strTag("span.kind", "client"), strTag("db.type", "redis")},
+		},
+		{
+			traceIDLow:    3,
+			spanID:        3,
+			operationName: "consume",
+			flags:         jaegerFlagDebug | 1,
+			tags:          []jaegerTag{strTag("span.kind", "consumer")},
+		},
+	},
+}
+
+func TestDecodeJaegerBatch(t *testing.T) {
+	assert := assert.New(t)
+	fp, err := decodeJaegerBatch(encodeJaegerBatch(jaegerTestBatch))
+	require.NoError(t, err)
+	require.Len(t, fp.spans, 3)
+	assert.Equal("host-1", fp.hostname)
+	assert.Equal("jaeger-Go-2.30.0", fp.tracerVersion)
+	assert.Equal(map[uint64]bool{3: true}, fp.keep)
+
+	assert.Equal(&pb.Span{
+		Service:  "frontend",
+		Name:     "jaeger.server",
+		Resource: "GET",
+		TraceID:  0x48485a3953bb6124,
+		SpanID:   1,
+		Start:    1600000000000000000,
+		Duration: 1500000,
+		Error:    1,
+		Type:     "web",
+		Meta: map[string]string{
+			"jaeger.trace_id": "463ac35c9f6413ad48485a3953bb6124",
+			"ip":              "10.0.0.1",
+			"http.method":     "GET",
+			"retried":         "false",
+			"blob":            "AQI=",
+			"span.kind":       "server",
+			"error.msg":       "boom",
+			"error.type":      "io.EOF",
+			"events":          `[{"time_unix_nano":1600000000001000000,"name":"error","attributes":{"error.kind":"io.EOF","message":"boom"}}]`,
+		},
+		Metrics: map[string]float64{
+			"http.status_code": 500,
+			"ratio":            0.5,
+		},
+	}, fp.spans[0])
+
+	client := fp.spans[1]
+	assert.Equal(uint64(1), client.ParentID)
+	assert.Equal("jaeger.client", client.Name)
+	assert.Equal("GET", client.Resource)
+	assert.Equal("cache", client.Type)
+
+	consumer := fp.spans[2]
+	assert.Equal("jaeger.consumer", consumer.Name)
+	assert.Equal("queue", consumer.Type)
+
+	chunks := groupForeignSpans(fp)
+	require.Len(t, chunks, 2)
+	assert.Equal(int32(sampler.PriorityAutoKeep), chunks[0].Priority)
+	assert.Equal(int32(sampler.PriorityUserKeep), chunks[1].Priority)
+}
+
+func TestDecodeJaegerBatchErrors(t *testing.T) {
+	body := encodeJaegerBatch(jaegerTestBatch)
+	for name, in := range map[string][]byte{
+		"empty":     nil,
+		"truncated": body[:len(body)/2],
+		"trailing":  append(append([]byte{}, body...), 0),
+		"type":      {thriftI32, 0, 1},
+		"size":      {thriftList, 0, 2, thriftStruct, 0x7f, 0xff, 0xff, 0xff},
+	} {
+		_, err := decodeJaegerBatch(in)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestHandleJaeger(t *testing.T) {
+	r := newTestReceiverFromConfig(newTestReceiverConfig())
+	server := httptest.NewServer(r.handleWithVersion(vJaegerThrift, r.handleJaeger))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL, "application/x-thrift", bytes.NewReader(encodeJaegerBatch(jaegerTestBatch)))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	select {
+	case p := <-r.out:
+		assert.Len(t, p.Chunks(), 2)
+		assert.Equal(t, "host-1", p.TracerPayload.Hostname)
+		assert.Equal(t, string(vJaegerThrift), p.Source.EndpointVersion)
+	case <-time.After(time.Second):
+		t.Fatal("no payload received")
+	}
+
+	resp, err = http.Post(server.URL, "application/json", bytes.NewReader(encodeJaegerBatch(jaegerTestBatch)))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+	assert.Empty(t, r.out)
+}
@@ -0,0 +1,223 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Thrift field types, as encoded by the binary protocol.
+const (
+	thriftStop   byte = 0
+	thriftBool   byte = 2
+	thriftByte   byte = 3
+	thriftDouble byte = 4
+	thriftI16    byte = 6
+	thriftI32    byte = 8
+	thriftI64    byte = 10
+	thriftString byte = 11
+	thriftStruct byte = 12
+	thriftMap    byte = 13
+	thriftSet    byte = 14
+	thriftList   byte = 15
+)
+
+// thriftMaxDepth is the maximum nesting of structs and containers accepted when skipping fields.
+const thriftMaxDepth = 64
+
+var errThriftTooLarge = errors.New("thrift: container or string larger than the payload")
+
+// thriftReader decodes values encoded with the Thrift binary protocol. It only implements
+// what is needed to decode the Jaeger model, without depending on the Thrift library.
+type thriftReader struct {
+	buf []byte
+	off int
+}
+
+func (r *thriftReader) next(n int) ([]byte, error) {
+	if n < 0 || n > len(r.buf)-r.off {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := r.buf[r.off : r.off+n]
+	r.off += n
+	return b, nil
+}
+
+func (r *thriftReader) readByte() (byte, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *thriftReader) readBool() (bool, error) {
+	b, err := r.readByte()
+	return b != 0, err
+}
+
+func (r *thriftReader) readI16() (int16, error) {
+	b, err := r.next(2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(binary.BigEndian.Uint16(b)), nil
+}
+
+func (r *thriftReader) readI32() (int32, error) {
+	b, err := r.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+func (r *thriftReader) readI64() (int64, error) {
+	b, err := r.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+func (r *thriftReader) readDouble() (float64, error) {
+	b, err := r.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+}
+
+func (r *thriftReader) readBinary() ([]byte, error) {
+	n, err := r.readI32()
+	if err != nil {
+		return nil, err
+	}
+	if int(n) > len(r.buf)-r.off {
+		return nil, errThriftTooLarge
+	}
+	return r.next(int(n))
+}
+
+func (r *thriftReader) readString() (string, error) {
+	b, err := r.readBinary()
+	return string(b), err
+}
+
+// readListBegin returns the type and the number of the elements of a list or a set.
+func (r *thriftReader) readListBegin() (byte, int, error) {
+	typ, err := r.readByte()
+	if err != nil {
+		return 0, 0, err
+	}
+	n, err := r.readI32()
+	if err != nil {
+		return 0, 0, err
+	}
+	// each element takes at least one byte
+	if n < 0 || int(n) > len(r.buf)-r.off {
+		return 0, 0, errThriftTooLarge
+	}
+	return typ, int(n), nil
+}
+
+// readStruct reads the fields of a struct, calling field for each of them. field must consume
+// the value of the fields it knows, and return false for the other ones, which are skipped.
+func (r *thriftReader) readStruct(field func(id int16, typ byte) (bool, error)) error {
+	for {
+		typ, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		if typ == thriftStop {
+			return nil
+		}
+		id, err := r.readI16()
+		if err != nil {
+			return err
+		}
+		ok, err := field(id, typ)
+		if err != nil {
+			return fmt.Errorf("field %d: %v", id, err)
+		}
+		if !ok {
+			if err := r.skip(typ, 0); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readList reads a list of elements of type typ, calling elem for each of them.
+func (r *thriftReader) readList(typ byte, elem func() error) error {
+	etyp, n, err := r.readListBegin()
+	if err != nil {
+		return err
+	}
+	if etyp != typ {
+		return fmt.Errorf("expected a list of type %d, got %d", typ, etyp)
+	}
+	for i := 0; i < n; i++ {
+		if err := elem(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skip skips a value of the given type.
+func (r *thriftReader) skip(typ byte, depth int) error {
+	if depth > thriftMaxDepth {
+		return errors.New("thrift: maximum depth exceeded")
+	}
+	var err error
+	switch typ {
+	case thriftBool, thriftByte:
+		_, err = r.next(1)
+	case thriftI16:
+		_, err = r.next(2)
+	case thriftI32:
+		_, err = r.next(4)
+	case thriftI64, thriftDouble:
+		_, err = r.next(8)
+	case thriftString:
+		_, err = r.readBinary()
+	case thriftStruct:
+		err = r.readStruct(func(_ int16, typ byte) (bool, error) {
+			return true, r.skip(typ, depth+1)
+		})
+	case thriftMap:
+		var ktyp, vtyp byte
+		if ktyp, err = r.readByte(); err != nil {
+			return err
+		}
+		var n int
+		if vtyp, n, err = r.readListBegin(); err != nil {
+			return err
+		}
+		for i := 0; i < n && err == nil; i++ {
+			if err = r.skip(ktyp, depth+1); err == nil {
+				err = r.skip(vtyp, depth+1)
+			}
+		}
+	case thriftSet, thriftList:
+		var etyp byte
+		var n int
+		if etyp, n, err = r.readListBegin(); err != nil {
+			return err
+		}
+		for i := 0; i < n && err == nil; i++ {
+			err = r.skip(etyp, depth+1)
+		}
+	default:
+		err = fmt.Errorf("thrift: unknown type %d", typ)
+	}
+	return err
+}
@@ -0,0 +1,158 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+// zipkinSpan is a span of the Zipkin v2 JSON model.
+// See https://zipkin.io/zipkin-api/#/default/post_spans
+type zipkinSpan struct {
+	TraceID        string             `json:"traceId"`
+	ID             string             `json:"id"`
+	ParentID       string             `json:"parentId"`
+	Name           string             `json:"name"`
+	Kind           string             `json:"kind"`
+	Timestamp      int64              `json:"timestamp"` // in microseconds
+	Duration       int64              `json:"duration"`  // in microseconds
+	Debug          bool               `json:"debug"`
+	LocalEndpoint  *zipkinEndpoint    `json:"localEndpoint"`
+	RemoteEndpoint *zipkinEndpoint    `json:"remoteEndpoint"`
+	Annotations    []zipkinAnnotation `json:"annotations"`
+	Tags           map[string]string  `json:"tags"`
+}
+
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+	IPv4        string `json:"ipv4"`
+	IPv6        string `json:"ipv6"`
+	Port        int    `json:"port"`
+}
+
+type zipkinAnnotation struct {
+	Timestamp int64  `json:"timestamp"` // in microseconds
+	Value     string `json:"value"`
+}
+
+// handleZipkin handles a request containing a list of Zipkin v2 JSON spans.
+func (r *HTTPReceiver) handleZipkin(v Version, w http.ResponseWriter, req *http.Request) {
+	if mediaType := getMediaType(req); mediaType != "application/json" {
+		httpFormatError(w, v, fmt.Errorf("unsupported media type: %q", mediaType))
+		return
+	}
+	r.handleForeignTraces(v, w, req, decodeZipkinSpans)
+}
+
+// decodeZipkinSpans decodes a list of Zipkin v2 JSON spans.
+func decodeZipkinSpans(body []byte) (*foreignPayload, error) {
+	var in []zipkinSpan
+	if err := json.Unmarshal(body, &in); err != nil {
+		return nil, err
+	}
+	fp := &foreignPayload{
+		spans:         make([]*pb.Span, 0, len(in)),
+		keep:          make(map[uint64]bool),
+		tracerVersion: "zipkin",
+	}
+	for i := range in {
+		span, err := convertZipkinSpan(&in[i])
+		if err != nil {
+			return nil, fmt.Errorf("span %d: %v", i, err)
+		}
+		if in[i].Debug {
+			fp.keep[span.TraceID] = true
+		}
+		fp.spans = append(fp.spans, span)
+	}
+	return fp, nil
+}
+
+// convertZipkinSpan converts a Zipkin span to a Datadog span.
+func convertZipkinSpan(in *zipkinSpan) (*pb.Span, error) {
+	traceID, err := parseHexID(in.TraceID, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid traceId: %v", err)
+	}
+	spanID, err := parseHexID(in.ID, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %v", err)
+	}
+	var parentID uint64
+	if in.ParentID != "" {
+		if parentID, err = parseHexID(in.ParentID, 16); err != nil {
+			return nil, fmt.Errorf("invalid parentId: %v", err)
+		}
+	}
+	span := &pb.Span{
+		TraceID:  traceID,
+		SpanID:   spanID,
+		ParentID: parentID,
+		Start:    in.Timestamp * 1000,
+		Duration: in.Duration * 1000,
+		Meta:     make(map[string]string, len(in.Tags)+2),
+		Metrics:  map[string]float64{},
+	}
+	if len(in.TraceID) > 16 {
+		span.Meta["zipkin.trace_id"] = in.TraceID
+	}
+	if in.LocalEndpoint != nil {
+		span.Service = in.LocalEndpoint.ServiceName
+	}
+	if ep := in.RemoteEndpoint; ep != nil {
+		if ep.ServiceName != "" {
+			span.Meta["peer.service"] = ep.ServiceName
+		}
+		if ep.IPv4 != "" {
+			span.Meta["peer.ipv4"] = ep.IPv4
+		}
+		if ep.IPv6 != "" {
+			span.Meta["peer.ipv6"] = ep.IPv6
+		}
+		if ep.Port != 0 {
+			span.Meta["peer.port"] = strconv.Itoa(ep.Port)
+		}
+	}
+	for k, v := range in.Tags {
+		span.Meta[k] = v
+	}
+	if msg, ok := in.Tags["error"]; ok {
+		// Zipkin instrumentations set the error tag to the error message, or to an empty string
+		span.Error = 1
+		delete(span.Meta, "error")
+		if msg != "" && msg != "true" {
+			span.Meta["error.msg"] = msg
+		}
+	}
+	if len(in.Annotations) > 0 {
+		events := make([]foreignEvent, 0, len(in.Annotations))
+		for _, a := range in.Annotations {
+			events = append(events, foreignEvent{TimeUnixNano: a.Timestamp * 1000, Name: a.Value})
+		}
+		span.Meta["events"] = marshalForeignEvents(events)
+	}
+	finishForeignSpan("zipkin", in.Kind, in.Name, span)
+	return span, nil
+}
+
+// parseHexID parses a lower-hex encoded ID of up to maxLen characters, and returns its lower 64 bits.
+func parseHexID(s string, maxLen int) (uint64, error) {
+	if s == "" || len(s) > maxLen {
+		return 0, fmt.Errorf("%q is not a hex ID of up to %d characters", s, maxLen)
+	}
+	if len(s) > 16 {
+		if _, err := strconv.ParseUint(s[:len(s)-16], 16, 64); err != nil {
+			return 0, err
+		}
+		s = s[len(s)-16:]
+	}
+	return strconv.ParseUint(s, 16, 64)
+}
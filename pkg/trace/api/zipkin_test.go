@@ -0,0 +1,200 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const zipkinTestPayload = `[
+	{
+		"traceId": "463ac35c9f6413ad48485a3953bb6124",
+		"id": "a2fb4a1d1a96d312",
+		"name": "get /users",
+		"kind": "SERVER",
+		"timestamp": 1600000000000000,
+		"duration": 1500,
+		"localEndpoint": {"serviceName": "frontend", "ipv4": "10.0.0.1"},
+		"remoteEndpoint": {"ipv4": "10.0.0.2", "port": 55000},
+		"tags": {"http.method": "GET", "http.route": "/users", "http.status_code": "500", "error": "Internal Server Error", "deployment.environment": "Prod"},
+		"annotations": [{"timestamp": 1600000000001000, "value": "ws"}, {"timestamp": 1600000000000500, "value": "wr"}]
+	},
+	{
+		"traceId": "48485a3953bb6124",
+		"parentId": "a2fb4a1d1a96d312",
+		"id": "b2fb4a1d1a96d313",
+		"name": "select",
+		"kind": "CLIENT",
+		"timestamp": 1600000000000100,
+		"duration": 800,
+		"localEndpoint": {"serviceName": "frontend"},
+		"remoteEndpoint": {"serviceName": "postgres"},
+		"tags": {"db.type": "postgresql", "sql.query": "SELECT * FROM users"}
+	},
+	{
+		"traceId": "0000000000000001",
+		"id": "0000000000000002",
+		"name": "send",
+		"kind": "PRODUCER",
+		"debug": true,
+		"localEndpoint": {"serviceName": "worker"}
+	}
+]`
+
+func TestDecodeZipkinSpans(t *testing.T) {
+	assert := assert.New(t)
+	fp, err := decodeZipkinSpans([]byte(zipkinTestPayload))
+	require.NoError(t, err)
+	require.Len(t, fp.spans, 3)
+	assert.Equal("zipkin", fp.tracerVersion)
+	assert.Equal(map[uint64]bool{1: true}, fp.keep)
+
+	assert.Equal(&pb.Span{
+		Service:  "frontend",
+		Name:     "zipkin.server",
+		Resource: "GET /users",
+		TraceID:  0x48485a3953bb6124,
+		SpanID:   0xa2fb4a1d1a96d312,
+		Start:    1600000000000000000,
+		Duration: 1500000,
+		Error:    1,
+		Type:     "web",
+		Meta: map[string]string{
+			"zipkin.trace_id":        "463ac35c9f6413ad48485a3953bb6124",
+			"peer.ipv4":              "10.0.0.2",
+			"peer.port":              "55000",
+			"http.method":            "GET",
+			"http.route":             "/users",
+			"http.status_code":       "500",
+			"error.msg":              "Internal Server Error",
+			"deployment.environment": "Prod",
+			"env":                    "prod",
+			"span.kind":              "server",
+			"events":                 `[{"time_unix_nano":1600000000000500000,"name":"wr"},{"time_unix_nano":1600000000001000000,"name":"ws"}]`,
+		},
+		Metrics: map[string]float64{},
+	}, fp.spans[0])
+
+	client := fp.spans[1]
+	assert.Equal(uint64(0x48485a3953bb6124), client.TraceID)
+	assert.Equal(uint64(0xa2fb4a1d1a96d312), client.ParentID)
+	assert.Equal("zipkin.client", client.Name)
+	assert.Equal("select", client.Resource)
+	assert.Equal("db", client.Type)
+	assert.Equal("postgres", client.Meta["peer.service"])
+	assert.Zero(client.Error)
+
+	producer := fp.spans[2]
+	assert.Equal("zipkin.producer", producer.Name)
+	assert.Equal("queue", producer.Type)
+
+	chunks := groupForeignSpans(fp)
+	require.Len(t, chunks, 2)
+	assert.Equal(int32(sampler.PriorityAutoKeep), chunks[0].Priority)
+	assert.Len(chunks[0].Spans, 2)
+	assert.Equal(int32(sampler.PriorityUserKeep), chunks[1].Priority)
+	assert.Len(chunks[1].Spans, 1)
+}
+
+func TestDecodeZipkinSpansErrors(t *testing.T) {
+	for _, in := range []string{
+		`{"traceId": "1", "id": "2"}`,
+		`[{"id": "2"}]`,
+		`[{"traceId": "1"}]`,
+		`[{"traceId": "xyz", "id": "2"}]`,
+		`[{"traceId": "1", "id": "12345678901234567"}]`,
+		`[{"traceId": "z0000000000000000000000000000001", "id": "2"}]`,
+		`[{"traceId": "1", "id": "2", "parentId": "-1"}]`,
+	} {
+		_, err := decodeZipkinSpans([]byte(in))
+		assert.Error(t, err, in)
+	}
+}
+
+func TestHandleZipkin(t *testing.T) {
+	r := newTestReceiverFromConfig(newTestReceiverConfig())
+	server := httptest.NewServer(r.handleWithVersion(vZipkinV2, r.handleZipkin))
+	defer server.Close()
+
+	t.Run("accepted", func(t *testing.T) {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		_, err := gz.Write([]byte(zipkinTestPayload))
+		require.NoError(t, err)
+		require.NoError(t, gz.Close())
+		req, err := http.NewRequest("POST", server.URL, &buf)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "gzip")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+		select {
+		case p := <-r.out:
+			assert.Len(t, p.Chunks(), 2)
+			assert.Equal(t, "zipkin", p.TracerPayload.TracerVersion)
+			assert.Equal(t, string(vZipkinV2), p.Source.EndpointVersion)
+		case <-time.After(time.Second):
+			t.Fatal("no payload received")
+		}
+	})
+
+	for name, tt := range map[string]struct {
+		contentType string
+		body        string
+		status      int
+	}{
+		"media-type": {"application/x-protobuf", zipkinTestPayload, http.StatusUnsupportedMediaType},
+		"invalid":    {"application/json", `[{"traceId": "xyz"}]`, http.StatusBadRequest},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp, err := http.Post(server.URL, tt.contentType, bytes.NewBufferString(tt.body))
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, tt.status, resp.StatusCode)
+			assert.Empty(t, r.out)
+		})
+	}
+}
+
+func TestHandleZipkinGzipTooLarge(t *testing.T) {
+	conf := newTestReceiverConfig()
+	conf.MaxRequestBytes = 1024
+	r := newTestReceiverFromConfig(conf)
+	server := httptest.NewServer(r.handleWithVersion(vZipkinV2, r.handleZipkin))
+	defer server.Close()
+
+	// small once compressed, but larger than the limit once decompressed
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte("[" + strings.Repeat(" ", 100*1024) + "]"))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	require.Less(t, buf.Len(), 1024)
+
+	req, err := http.NewRequest("POST", server.URL, &buf)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	assert.Empty(t, r.out)
+}
@@ -94,6 +94,18 @@ type ObfuscationConfig struct {
 
 	// CreditCards holds the configuration for obfuscating credit cards.
 	CreditCards CreditCardsConfig `mapstructure:"credit_cards"`
+
+	// GraphQL holds the configuration for obfuscating the "graphql.source" tag, the resource
+	// and the variables of spans of type "graphql".
+	GraphQL Enablable `mapstructure:"graphql"`
+
+	// DynamoDB holds the obfuscation configuration for the attribute values of spans
+	// of type "dynamodb".
+	DynamoDB JSONObfuscationConfig `mapstructure:"dynamodb"`
+
+	// Messaging holds the obfuscation configuration for the meta of spans of type "queue"
+	// and "kafka".
+	Messaging MessagingObfuscationConfig `mapstructure:"messaging"`
 }
 
 // AppSecConfig ...
@@ -142,6 +154,18 @@ func (o *ObfuscationConfig) Export() obfuscate.Config {
 			RemoveQueryString: o.HTTP.RemoveQueryString,
 			RemovePathDigits:  o.HTTP.RemovePathDigits,
 		},
+		GraphQL: obfuscate.GraphQLConfig{
+			Enabled: o.GraphQL.Enabled,
+		},
+		DynamoDB: obfuscate.JSONConfig{
+			Enabled:            o.DynamoDB.Enabled,
+			KeepValues:         o.DynamoDB.KeepValues,
+			ObfuscateSQLValues: o.DynamoDB.ObfuscateSQLValues,
+		},
+		Messaging: obfuscate.MessagingConfig{
+			Enabled: o.Messaging.Enabled,
+			Keys:    o.Messaging.Keys,
+		},
 		Logger: new(debugLogger),
 	}
 }
@@ -173,6 +197,16 @@ type HTTPObfuscationConfig struct {
 	RemovePathDigits bool `mapstructure:"remove_paths_with_digits" json:"remove_path_digits"`
 }
 
+// MessagingObfuscationConfig holds the configuration settings for the obfuscation of messaging span meta.
+type MessagingObfuscationConfig struct {
+	// Enabled specifies whether messaging obfuscation should be enabled.
+	Enabled bool `mapstructure:"enabled"`
+
+	// Keys specifies the meta keys whose values are obfuscated, a key ending with "*" matches
+	// all the keys starting with it. When empty, the message keys and payloads are obfuscated.
+	Keys []string `mapstructure:"keys"`
+}
+
 // Enablable can represent any option that has an "enabled" boolean sub-field.
 type Enablable struct {
 	Enabled bool `mapstructure:"enabled"`
@@ -217,6 +251,66 @@ type ReplaceRule struct {
 	Repl string `mapstructure:"repl"`
 }
 
+// SpanRule specifies a rule dropping or rewriting the spans which match all of its expressions.
+type SpanRule struct {
+	// Name identifies the rule in the hit counters.
+	Name string `mapstructure:"name"`
+
+	// Match lists the expressions a span must match, such as `service == web`,
+	// `meta.http.url =~ ^/admin` or `metrics._sampling_priority_v1 < 0`.
+	Match []string `mapstructure:"match"`
+
+	// Action is one of drop_span, drop_tag, hash_tag, add_tag or rename_service.
+	Action string `mapstructure:"action"`
+
+	// Tag is the tag addressed by the drop_tag, hash_tag and add_tag actions.
+	Tag string `mapstructure:"tag"`
+
+	// Value is the value set by the add_tag and rename_service actions.
+	Value string `mapstructure:"value"`
+}
+
+// TailSamplingConfig holds the configuration of the tail-based sampling of the traces
+// dropped by the other samplers.
+type TailSamplingConfig struct {
+	// Enabled reports whether the tail-based sampling is enabled.
+	Enabled bool `mapstructure:"enabled"`
+
+	// DecisionWait is the time during which the chunks of a trace are buffered before
+	// the policies are evaluated on the reassembled trace.
+	DecisionWait time.Duration `mapstructure:"-"`
+
+	// MaxBufferedSpans is the maximum number of spans buffered. Above it, the oldest
+	// traces are evaluated before the end of their decision wait.
+	MaxBufferedSpans int `mapstructure:"max_buffered_spans"`
+
+	// Policies are evaluated on the reassembled traces, a trace matching any of them is kept.
+	Policies []TailSamplingPolicy `mapstructure:"policies"`
+}
+
+// TailSamplingPolicy is a condition on a reassembled trace for it to be kept by the
+// tail-based sampling.
+type TailSamplingPolicy struct {
+	// Name identifies the policy in the telemetry.
+	Name string `mapstructure:"name"`
+
+	// Type is one of "error", "latency", "service" or "attribute".
+	Type string `mapstructure:"type"`
+
+	// ThresholdMs is the root span duration above which a trace is kept, for "latency" policies.
+	ThresholdMs float64 `mapstructure:"threshold_ms"`
+
+	// Services are the services of which any span keeps a trace, for "service" policies.
+	Services []string `mapstructure:"services"`
+
+	// Key is the span meta key of "attribute" policies.
+	Key string `mapstructure:"key"`
+
+	// Values are the values of Key of which any span keeps a trace, for "attribute" policies.
+	// When empty, any span having the Key keeps the trace.
+	Values []string `mapstructure:"values"`
+}
+
 // WriterConfig specifies configuration for an API writer.
 type WriterConfig struct {
 	// ConnectionLimit specifies the maximum number of concurrent outgoing
@@ -232,6 +326,20 @@ type WriterConfig struct {
 	FlushPeriodSeconds float64 `mapstructure:"flush_period_seconds"`
 }
 
+// DiskBufferConfig holds the configuration of the disk buffer in which the writers store the
+// payloads they can not keep in memory during intake outages or when stopping, to send them later.
+type DiskBufferConfig struct {
+	// MaxSizeInBytes is the maximum size of the payloads stored on disk by each writer.
+	// The disk buffer is disabled when it is 0.
+	MaxSizeInBytes int64
+
+	// Path is the directory in which the payloads are stored.
+	Path string
+
+	// MaxAge is the age above which the stored payloads are dropped instead of being sent.
+	MaxAge time.Duration
+}
+
 // FargateOrchestratorName is a Fargate orchestrator name.
 type FargateOrchestratorName string
 
@@ -288,6 +396,12 @@ type AgentConfig struct {
 	BucketInterval   time.Duration // the size of our pre-aggregation per bucket
 	ExtraAggregators []string
 
+	// StatsDimensions are span meta keys added to the aggregation key of the stats computed by the agent.
+	StatsDimensions []string
+	// StatsDimensionsMaxCardinality is the maximum number of values of each stats dimension in a stats
+	// bucket, the spans with other values are aggregated together.
+	StatsDimensionsMaxCardinality int
+
 	// Sampler configuration
 	ExtraSampleRate    float64
 	TargetTPS          float64
@@ -295,6 +409,7 @@ type AgentConfig struct {
 	DisableRareSampler bool
 	MaxEPS             float64
 	MaxRemoteTPS       float64
+	TailSampling       *TailSamplingConfig
 
 	// Receiver
 	ReceiverHost    string
@@ -314,6 +429,7 @@ type AgentConfig struct {
 	SynchronousFlushing     bool // Mode where traces are only submitted when FlushAsync is called, used for Serverless Extension
 	StatsWriter             *WriterConfig
 	TraceWriter             *WriterConfig
+	DiskBuffer              *DiskBufferConfig
 	ConnectionResetInterval time.Duration // frequency at which outgoing connections are reset. 0 means no reset is performed
 
 	// internal telemetry
@@ -343,6 +459,9 @@ type AgentConfig struct {
 	// It maps tag keys to a set of replacements. Only supported in A6.
 	ReplaceTags []*ReplaceRule
 
+	// SpanRules drop or rewrite the spans matching them, in order.
+	SpanRules []*SpanRule
+
 	// GlobalTags list metadata that will be added to all spans
 	GlobalTags map[string]string
 
@@ -421,11 +540,17 @@ func New() *AgentConfig {
 
 		BucketInterval: time.Duration(10) * time.Second,
 
+		StatsDimensionsMaxCardinality: 100,
+
 		ExtraSampleRate: 1.0,
 		TargetTPS:       10,
 		ErrorTPS:        10,
 		MaxEPS:          200,
 		MaxRemoteTPS:    100,
+		TailSampling: &TailSamplingConfig{
+			DecisionWait:     10 * time.Second,
+			MaxBufferedSpans: 100000,
+		},
 
 		ReceiverHost:           "localhost",
 		ReceiverPort:           8126,
@@ -436,6 +561,7 @@ func New() *AgentConfig {
 
 		StatsWriter:             new(WriterConfig),
 		TraceWriter:             new(WriterConfig),
+		DiskBuffer:              &DiskBufferConfig{MaxAge: 24 * time.Hour},
 		ConnectionResetInterval: 0, // disabled
 
 		StatsdHost: "localhost",
@@ -0,0 +1,374 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
+)
+
+const (
+	// ActionDropSpan removes the span from its trace.
+	ActionDropSpan = "drop_span"
+	// ActionDropTag removes a tag from the span meta and metrics.
+	ActionDropTag = "drop_tag"
+	// ActionHashTag replaces the value of a span meta by its hash.
+	ActionHashTag = "hash_tag"
+	// ActionAddTag sets a span meta.
+	ActionAddTag = "add_tag"
+	// ActionRenameService sets the span service.
+	ActionRenameService = "rename_service"
+
+	// hashedTagLength is the number of hexadecimal characters kept from the hash of hashed tags.
+	hashedTagLength = 32
+
+	// spanRulesReportInterval is the frequency at which the rule hits are reported.
+	spanRulesReportInterval = 10 * time.Second
+)
+
+// spanCondition reports whether a span matches one of the expressions of a rule.
+type spanCondition func(s *pb.Span) bool
+
+// spanRule is a compiled config.SpanRule.
+type spanRule struct {
+	name       string
+	action     string
+	conditions []spanCondition
+	tag        string
+	value      string
+
+	// hits is the number of spans matched, accessed atomically.
+	hits int64
+	// reported is the number of hits already sent to the telemetry.
+	reported int64
+}
+
+// matches reports whether a span meets all the conditions of the rule.
+func (r *spanRule) matches(s *pb.Span) bool {
+	for _, c := range r.conditions {
+		if !c(s) {
+			return false
+		}
+	}
+	return true
+}
+
+// SpanRules is a filter which drops or rewrites the spans matching its rules. The rules
+// are applied in order, each rule seeing the changes of the previous ones.
+type SpanRules struct {
+	rules []*spanRule
+	exit  chan struct{}
+}
+
+// NewSpanRules validates the given rules and returns a SpanRules applying them.
+func NewSpanRules(rules []*config.SpanRule) (*SpanRules, error) {
+	sr := &SpanRules{
+		rules: make([]*spanRule, 0, len(rules)),
+		exit:  make(chan struct{}),
+	}
+	for i, rule := range rules {
+		r, err := compileSpanRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule num %d: %v", i, err)
+		}
+		if r.name == "" {
+			r.name = fmt.Sprintf("rule_%d", i)
+		}
+		sr.rules = append(sr.rules, r)
+	}
+	return sr, nil
+}
+
+func compileSpanRule(rule *config.SpanRule) (*spanRule, error) {
+	r := &spanRule{name: rule.Name, action: rule.Action, tag: rule.Tag, value: rule.Value}
+	switch rule.Action {
+	case ActionDropSpan:
+	case ActionDropTag, ActionHashTag:
+		if rule.Tag == "" {
+			return nil, fmt.Errorf("action %s requires a tag", rule.Action)
+		}
+	case ActionAddTag:
+		if rule.Tag == "" || rule.Value == "" {
+			return nil, fmt.Errorf("action %s requires a tag and a value", rule.Action)
+		}
+	case ActionRenameService:
+		svc, err := traceutil.NormalizeService(rule.Value, "")
+		if err != nil {
+			return nil, fmt.Errorf("action %s: invalid service %q: %v", rule.Action, rule.Value, err)
+		}
+		r.value = svc
+	case "":
+		return nil, errors.New("action is required")
+	default:
+		return nil, fmt.Errorf("unknown action %q, must be one of %s, %s, %s, %s or %s", rule.Action,
+			ActionDropSpan, ActionDropTag, ActionHashTag, ActionAddTag, ActionRenameService)
+	}
+	for _, expr := range rule.Match {
+		c, err := parseSpanCondition(expr)
+		if err != nil {
+			return nil, fmt.Errorf("match %q: %v", expr, err)
+		}
+		r.conditions = append(r.conditions, c)
+	}
+	return r, nil
+}
+
+// parseSpanCondition parses an expression of the form `<field> <operator> <value>` or `<field> exists`.
+// The fields are service, name, resource, type, meta.<key> and metrics.<key>. The string fields
+// support the ==, !=, =~ and !~ operators, the metrics the ==, !=, <, <=, > and >= ones. Values
+// may be double-quoted.
+func parseSpanCondition(expr string) (spanCondition, error) {
+	expr = strings.TrimSpace(expr)
+	i := strings.IndexByte(expr, ' ')
+	if i < 0 {
+		return nil, errors.New("expected `<field> <operator> <value>` or `<field> exists`")
+	}
+	field, rest := expr[:i], strings.TrimSpace(expr[i:])
+	op, value := rest, ""
+	if j := strings.IndexByte(rest, ' '); j >= 0 {
+		op, value = rest[:j], strings.TrimSpace(rest[j:])
+	}
+	if strings.HasPrefix(value, `"`) {
+		v, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quoted value %s", value)
+		}
+		value = v
+	}
+
+	if key := strings.TrimPrefix(field, "metrics."); key != field && key != "" {
+		return metricCondition(key, op, value)
+	}
+	var get func(s *pb.Span) (string, bool)
+	switch field {
+	case "service":
+		get = func(s *pb.Span) (string, bool) { return s.Service, true }
+	case "name":
+		get = func(s *pb.Span) (string, bool) { return s.Name, true }
+	case "resource":
+		get = func(s *pb.Span) (string, bool) { return s.Resource, true }
+	case "type":
+		get = func(s *pb.Span) (string, bool) { return s.Type, true }
+	default:
+		key := strings.TrimPrefix(field, "meta.")
+		if key == field || key == "" {
+			return nil, fmt.Errorf("unknown field %q, must be service, name, resource, type, meta.<key> or metrics.<key>", field)
+		}
+		get = func(s *pb.Span) (string, bool) {
+			v, ok := s.Meta[key]
+			return v, ok
+		}
+	}
+	return stringCondition(get, op, value)
+}
+
+func stringCondition(get func(s *pb.Span) (string, bool), op, value string) (spanCondition, error) {
+	switch op {
+	case "exists":
+		if value != "" {
+			return nil, errors.New("exists takes no value")
+		}
+		return func(s *pb.Span) bool {
+			_, ok := get(s)
+			return ok
+		}, nil
+	case "==":
+		return func(s *pb.Span) bool {
+			v, ok := get(s)
+			return ok && v == value
+		}, nil
+	case "!=":
+		return func(s *pb.Span) bool {
+			v, ok := get(s)
+			return !ok || v != value
+		}, nil
+	case "=~", "!~":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, err
+		}
+		negate := op == "!~"
+		return func(s *pb.Span) bool {
+			v, ok := get(s)
+			return (ok && re.MatchString(v)) != negate
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown operator %q, must be ==, !=, =~, !~ or exists", op)
+	}
+}
+
+func metricCondition(key, op, value string) (spanCondition, error) {
+	if op == "exists" {
+		if value != "" {
+			return nil, errors.New("exists takes no value")
+		}
+		return func(s *pb.Span) bool {
+			_, ok := s.Metrics[key]
+			return ok
+		}, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q", value)
+	}
+	var cmp func(v float64) bool
+	switch op {
+	case "==":
+		cmp = func(v float64) bool { return v == f }
+	case "!=":
+		cmp = func(v float64) bool { return v != f }
+	case "<":
+		cmp = func(v float64) bool { return v < f }
+	case "<=":
+		cmp = func(v float64) bool { return v <= f }
+	case ">":
+		cmp = func(v float64) bool { return v > f }
+	case ">=":
+		cmp = func(v float64) bool { return v >= f }
+	default:
+		return nil, fmt.Errorf("unknown operator %q, must be ==, !=, <, <=, >, >= or exists", op)
+	}
+	return func(s *pb.Span) bool {
+		v, ok := s.Metrics[key]
+		return ok && cmp(v)
+	}, nil
+}
+
+// Apply applies the rules to the spans of a trace, and returns the spans which were not dropped.
+// The children of the dropped spans are re-parented onto the closest ancestor which was kept.
+func (sr *SpanRules) Apply(trace pb.Trace) pb.Trace {
+	if len(sr.rules) == 0 {
+		return trace
+	}
+	var dropped map[uint64]uint64 // parent IDs of the dropped spans, by span ID
+	n := 0
+	for _, s := range trace {
+		if sr.apply(s) {
+			trace[n] = s
+			n++
+			continue
+		}
+		if dropped == nil {
+			dropped = make(map[uint64]uint64)
+		}
+		dropped[s.SpanID] = s.ParentID
+	}
+	// set everything at the back of the array to nil to avoid memory leaking
+	for i := n; i < len(trace); i++ {
+		trace[i] = nil
+	}
+	trace = trace[:n]
+	if dropped != nil {
+		reparent(trace, dropped)
+	}
+	return trace
+}
+
+// reparent sets the parent of the spans whose parent was dropped to their closest kept ancestor.
+func reparent(trace pb.Trace, dropped map[uint64]uint64) {
+	for _, s := range trace {
+		// bounded by the number of dropped spans in case their parent IDs form a cycle
+		for i := 0; i < len(dropped); i++ {
+			parentID, ok := dropped[s.ParentID]
+			if !ok {
+				break
+			}
+			s.ParentID = parentID
+		}
+	}
+}
+
+// apply applies the rules to a span, and reports whether it should be kept.
+func (sr *SpanRules) apply(s *pb.Span) bool {
+	for _, r := range sr.rules {
+		if !r.matches(s) {
+			continue
+		}
+		atomic.AddInt64(&r.hits, 1)
+		switch r.action {
+		case ActionDropSpan:
+			return false
+		case ActionDropTag:
+			delete(s.Meta, r.tag)
+			delete(s.Metrics, r.tag)
+		case ActionHashTag:
+			if v, ok := s.Meta[r.tag]; ok {
+				s.Meta[r.tag] = hashTag(v)
+			}
+		case ActionAddTag:
+			traceutil.SetMeta(s, r.tag, r.value)
+		case ActionRenameService:
+			s.Service = r.value
+		}
+	}
+	return true
+}
+
+// hashTag returns the hexadecimal SHA-256 hash of a tag value, truncated to hashedTagLength.
+func hashTag(v string) string {
+	h := sha256.Sum256([]byte(v))
+	return hex.EncodeToString(h[:])[:hashedTagLength]
+}
+
+// Stats returns the number of spans matched by each rule.
+func (sr *SpanRules) Stats() []info.SpanRuleStats {
+	stats := make([]info.SpanRuleStats, 0, len(sr.rules))
+	for _, r := range sr.rules {
+		stats = append(stats, info.SpanRuleStats{Name: r.name, Action: r.action, Hits: atomic.LoadInt64(&r.hits)})
+	}
+	return stats
+}
+
+// Start starts reporting the rule hits.
+func (sr *SpanRules) Start() {
+	if len(sr.rules) == 0 {
+		return
+	}
+	go func() {
+		defer watchdog.LogOnPanic()
+		ticker := time.NewTicker(spanRulesReportInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				sr.report()
+			case <-sr.exit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops reporting the rule hits.
+func (sr *SpanRules) Stop() {
+	close(sr.exit)
+}
+
+// report updates the rule hits in the info and sends the new hits to the telemetry.
+func (sr *SpanRules) report() {
+	info.UpdateSpanRuleStats(sr.Stats())
+	for _, r := range sr.rules {
+		hits := atomic.LoadInt64(&r.hits)
+		if hits > r.reported {
+			metrics.Count("datadog.trace_agent.span_rules.hits", hits-r.reported, []string{"rule:" + r.name, "action:" + r.action}, 1)
+			r.reported = hits
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpanCondition(t *testing.T) {
+	span := &pb.Span{
+		Service:  "web",
+		Name:     "http.request",
+		Resource: "GET /admin/users",
+		Type:     "web",
+		Meta:     map[string]string{"http.url": "http://host/admin/users", "user": "john doe"},
+		Metrics:  map[string]float64{"_sampling_priority_v1": 1, "http.status_code": 503},
+	}
+	for _, tt := range []struct {
+		expr string
+		want bool
+	}{
+		{"service == web", true},
+		{"service != web", false},
+		{"name == http.request", true},
+		{"resource =~ ^GET /admin", true},
+		{"resource !~ ^GET /admin", false},
+		{`resource == "GET /admin/users"`, true},
+		{"type == db", false},
+		{"meta.http.url =~ /admin/", true},
+		{"meta.user == john doe", true},
+		{"meta.missing == a", false},
+		{"meta.missing != a", true},
+		{"meta.missing !~ a", true},
+		{"meta.user exists", true},
+		{"meta.missing exists", false},
+		{"metrics.http.status_code >= 500", true},
+		{"metrics.http.status_code < 500", false},
+		{"metrics._sampling_priority_v1 == 1", true},
+		{"metrics._sampling_priority_v1 != 1", false},
+		{"metrics.missing <= 1", false},
+		{"metrics.http.status_code exists", true},
+	} {
+		t.Run(tt.expr, func(t *testing.T) {
+			c, err := parseSpanCondition(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, c(span))
+		})
+	}
+}
+
+func TestNewSpanRulesErrors(t *testing.T) {
+	for _, rule := range []*config.SpanRule{
+		{Match: []string{"service == web"}},
+		{Match: []string{"service == web"}, Action: "unknown"},
+		{Action: ActionDropTag},
+		{Action: ActionAddTag, Tag: "a"},
+		{Action: ActionRenameService, Value: ""},
+		{Action: ActionDropSpan, Match: []string{"service"}},
+		{Action: ActionDropSpan, Match: []string{"host == a"}},
+		{Action: ActionDropSpan, Match: []string{"meta. == a"}},
+		{Action: ActionDropSpan, Match: []string{"service < a"}},
+		{Action: ActionDropSpan, Match: []string{"resource =~ ("}},
+		{Action: ActionDropSpan, Match: []string{"metrics.a =~ a"}},
+		{Action: ActionDropSpan, Match: []string{"metrics.a > a"}},
+		{Action: ActionDropSpan, Match: []string{"service exists web"}},
+		{Action: ActionDropSpan, Match: []string{`service == "web`}},
+	} {
+		_, err := NewSpanRules([]*config.SpanRule{rule})
+		assert.Error(t, err, "%+v", rule)
+	}
+}
+
+func TestSpanRulesApply(t *testing.T) {
+	sr, err := NewSpanRules([]*config.SpanRule{
+		{Name: "health", Match: []string{"resource == GET /health"}, Action: ActionDropSpan},
+		{Match: []string{"service == web", "meta.env == prod"}, Action: ActionRenameService, Value: "Front End"},
+		{Match: []string{"service == front_end"}, Action: ActionAddTag, Tag: "team", Value: "frontend"},
+		{Action: ActionHashTag, Tag: "user"},
+		{Action: ActionDropTag, Tag: "secret"},
+	})
+	require.NoError(t, err)
+
+	newSpan := func(resource, env string) *pb.Span {
+		return &pb.Span{
+			Service:  "web",
+			Resource: resource,
+			Meta:     map[string]string{"env": env, "user": "john", "secret": "s3cr3t"},
+			Metrics:  map[string]float64{"secret": 1},
+		}
+	}
+	trace := pb.Trace{newSpan("GET /health", "prod"), newSpan("GET /users", "prod"), newSpan("GET /users", "staging")}
+	trace = sr.Apply(trace)
+	require.Len(t, trace, 2)
+
+	assert.Equal(t, "front_end", trace[0].Service)
+	assert.Equal(t, map[string]string{"env": "prod", "team": "frontend", "user": hashTag("john")}, trace[0].Meta)
+	assert.Empty(t, trace[0].Metrics)
+	assert.Len(t, trace[0].Meta["user"], hashedTagLength)
+
+	assert.Equal(t, "web", trace[1].Service)
+	assert.Equal(t, map[string]string{"env": "staging", "user": hashTag("john")}, trace[1].Meta)
+
+	assert.Equal(t, []info.SpanRuleStats{
+		{Name: "health", Action: ActionDropSpan, Hits: 1},
+		{Name: "rule_1", Action: ActionRenameService, Hits: 1},
+		{Name: "rule_2", Action: ActionAddTag, Hits: 1},
+		{Name: "rule_3", Action: ActionHashTag, Hits: 2},
+		{Name: "rule_4", Action: ActionDropTag, Hits: 2},
+	}, sr.Stats())
+}
+
+func TestSpanRulesDropSpanReparents(t *testing.T) {
+	sr, err := NewSpanRules([]*config.SpanRule{
+		{Match: []string{"service == middleware"}, Action: ActionDropSpan},
+	})
+	require.NoError(t, err)
+
+	// root -> middleware -> middleware -> handler -> db
+	trace := pb.Trace{
+		{SpanID: 1, Service: "web"},
+		{SpanID: 2, ParentID: 1, Service: "middleware"},
+		{SpanID: 3, ParentID: 2, Service: "middleware"},
+		{SpanID: 4, ParentID: 3, Service: "handler"},
+		{SpanID: 5, ParentID: 4, Service: "db"},
+	}
+	trace = sr.Apply(trace)
+	require.Len(t, trace, 3)
+	parents := make(map[uint64]uint64)
+	for _, s := range trace {
+		parents[s.SpanID] = s.ParentID
+	}
+	assert.Equal(t, map[uint64]uint64{1: 0, 4: 1, 5: 4}, parents)
+	assert.Equal(t, uint64(1), traceutil.GetRoot(trace).SpanID)
+}
+
+func TestSpanRulesNoRules(t *testing.T) {
+	sr, err := NewSpanRules(nil)
+	require.NoError(t, err)
+	trace := pb.Trace{{Service: "web"}}
+	assert.Equal(t, trace, sr.Apply(trace))
+	sr.Start()
+	sr.Stop()
+}
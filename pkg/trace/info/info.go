@@ -37,6 +37,7 @@ var (
 	watchdogInfo     watchdog.Info
 	rateByService    map[string]float64
 	rateLimiterStats RateLimiterStats
+	spanRuleStats    []SpanRuleStats
 	start            = time.Now()
 	once             sync.Once
 	infoTmpl         *template.Template
@@ -77,6 +78,13 @@ const (
   {{if lt .Status.RateLimiter.TargetRate 1.0}}
   WARNING: Rate-limiter keep percentage: {{percent .Status.RateLimiter.TargetRate}} %
   {{end}}
+  {{if .Status.SpanRules}}
+  --- Span rules ---
+
+  {{ range $i, $r := .Status.SpanRules }}
+  Rule '{{ $r.Name }}' ({{ $r.Action }}): {{ $r.Hits }} spans matched
+  {{ end }}
+  {{end}}
 
   --- Writer stats (1 min) ---
 
@@ -188,6 +196,29 @@ func publishRateLimiterStats() interface{} {
 	return rateLimiterStats
 }
 
+// SpanRuleStats contains the number of spans matched by a span rule since the start.
+type SpanRuleStats struct {
+	// Name is the name of the rule.
+	Name string `json:"name"`
+	// Action is the action of the rule.
+	Action string `json:"action"`
+	// Hits is the number of spans matched by the rule.
+	Hits int64 `json:"hits"`
+}
+
+// UpdateSpanRuleStats updates internal stats about the span rules.
+func UpdateSpanRuleStats(ss []SpanRuleStats) {
+	infoMu.Lock()
+	defer infoMu.Unlock()
+	spanRuleStats = ss
+}
+
+func publishSpanRuleStats() interface{} {
+	infoMu.RLock()
+	defer infoMu.RUnlock()
+	return spanRuleStats
+}
+
 func publishUptime() interface{} {
 	return int(time.Since(start) / time.Second)
 }
@@ -219,6 +250,7 @@ func InitInfo(conf *config.AgentConfig) error {
 		expvar.Publish("ratebyservice", expvar.Func(publishRateByService))
 		expvar.Publish("watchdog", expvar.Func(publishWatchdogInfo))
 		expvar.Publish("ratelimiter", expvar.Func(publishRateLimiterStats))
+		expvar.Publish("span_rules", expvar.Func(publishSpanRuleStats))
 
 		// copy the config to ensure we don't expose sensitive data such as API keys
 		c := *conf
@@ -276,6 +308,7 @@ type StatusInfo struct {
 	StatsWriter   StatsWriterInfo    `json:"stats_writer"`
 	Watchdog      watchdog.Info      `json:"watchdog"`
 	RateLimiter   RateLimiterStats   `json:"ratelimiter"`
+	SpanRules     []SpanRuleStats    `json:"span_rules"`
 	Config        config.AgentConfig `json:"config"`
 }
 
@@ -33,6 +33,8 @@ type BucketsAggregationKey struct {
 	Type       string
 	StatusCode uint32
 	Synthetics bool
+	// Dimensions holds the custom aggregation dimensions, encoded with encodeDimensions.
+	Dimensions string
 }
 
 // PayloadAggregationKey specifies the key by which a payload is aggregated.
@@ -81,6 +83,7 @@ func NewAggregationFromGroup(g pb.ClientGroupedStats) Aggregation {
 			Name:       g.Name,
 			StatusCode: g.HTTPStatusCode,
 			Synthetics: g.Synthetics,
+			Dimensions: encodeDimensions(g.Tags),
 		},
 	}
 }
@@ -13,6 +13,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/trace/config/features"
 	"github.com/DataDog/datadog-agent/pkg/trace/info"
 	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
 	"github.com/DataDog/datadog-agent/pkg/trace/pb"
 	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
 	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
@@ -46,6 +47,11 @@ type Concentrator struct {
 	mu            sync.Mutex
 	agentEnv      string
 	agentHostname string
+
+	// dimensions are the span meta keys added to the aggregation key of the stats
+	dimensions []string
+	// dimensionsMaxCardinality is the maximum number of values of each dimension in a bucket
+	dimensionsMaxCardinality int
 }
 
 // NewConcentrator initializes a new concentrator ready to be started
@@ -64,6 +70,9 @@ func NewConcentrator(conf *config.AgentConfig, out chan pb.StatsPayload, now tim
 		exit:          make(chan struct{}),
 		agentEnv:      conf.DefaultEnv,
 		agentHostname: conf.Hostname,
+
+		dimensions:               sanitizeDimensions(conf.StatsDimensions),
+		dimensionsMaxCardinality: conf.StatsDimensionsMaxCardinality,
 	}
 	return &c
 }
@@ -177,6 +186,9 @@ func (c *Concentrator) addNow(pt *traceutil.ProcessedTrace, containerID string)
 		b, ok := c.buckets[btime]
 		if !ok {
 			b = NewRawBucket(uint64(btime), uint64(c.bsize))
+			if len(c.dimensions) > 0 {
+				b.dimensions = newSpanDimensions(c.dimensions, c.dimensionsMaxCardinality)
+			}
 			c.buckets[btime] = b
 		}
 		b.HandleSpan(s, weight, isTop, pt.TraceChunk.Origin, aggKey)
@@ -200,6 +212,11 @@ func (c *Concentrator) flushNow(now int64) pb.StatsPayload {
 			continue
 		}
 		log.Debugf("flushing bucket %d", ts)
+		if srb.dimensions != nil {
+			for k, n := range srb.dimensions.overflows {
+				metrics.Count("datadog.trace_agent.stats.dimension_overflow", n, []string{"dimension:" + k}, 1)
+			}
+		}
 		for k, b := range srb.Export() {
 			m[k] = append(m[k], b)
 		}
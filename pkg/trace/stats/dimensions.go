@@ -0,0 +1,111 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package stats
+
+import (
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+const (
+	// maxDimensions is the maximum number of custom aggregation dimensions.
+	maxDimensions = 10
+	// dimensionOverflowValue replaces the values of a dimension once its cardinality limit is reached.
+	dimensionOverflowValue = "_overflow"
+	// dimensionsSeparator precedes each "key:value" tag of the encoded dimensions of an aggregation.
+	dimensionsSeparator = "\x00"
+)
+
+// sanitizeDimensions returns the custom aggregation dimensions to use from the configured span meta keys,
+// without duplicates and bounded to maxDimensions.
+func sanitizeDimensions(keys []string) []string {
+	var dims []string
+	seen := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		if len(dims) == maxDimensions {
+			log.Warnf("Too many stats dimensions, only the first %d are used: %v", maxDimensions, dims)
+			break
+		}
+		seen[k] = struct{}{}
+		dims = append(dims, k)
+	}
+	return dims
+}
+
+// spanDimensions extracts the custom aggregation dimensions of the spans of a bucket from their meta.
+// Each dimension is limited to maxCardinality values, the spans with other values are aggregated
+// with the dimensionOverflowValue value.
+type spanDimensions struct {
+	keys           []string
+	maxCardinality int
+	// values holds the values seen for each key
+	values []map[string]struct{}
+	// overflows counts the spans whose value has been replaced, by key
+	overflows map[string]int64
+}
+
+func newSpanDimensions(keys []string, maxCardinality int) *spanDimensions {
+	values := make([]map[string]struct{}, len(keys))
+	for i := range values {
+		values[i] = make(map[string]struct{})
+	}
+	return &spanDimensions{
+		keys:           keys,
+		maxCardinality: maxCardinality,
+		values:         values,
+		overflows:      make(map[string]int64),
+	}
+}
+
+// encode returns the encoded dimensions of the given span. A span without a key in its meta
+// has no value for this dimension.
+func (d *spanDimensions) encode(s *pb.Span) string {
+	var b strings.Builder
+	for i, k := range d.keys {
+		v, ok := s.Meta[k]
+		if !ok || v == "" {
+			continue
+		}
+		if _, ok := d.values[i][v]; !ok {
+			if d.maxCardinality > 0 && len(d.values[i]) >= d.maxCardinality {
+				d.overflows[k]++
+				v = dimensionOverflowValue
+			} else {
+				d.values[i][v] = struct{}{}
+			}
+		}
+		b.WriteString(dimensionsSeparator)
+		b.WriteString(k)
+		b.WriteByte(':')
+		b.WriteString(v)
+	}
+	return b.String()
+}
+
+// encodeDimensions returns the encoded dimensions of a list of "key:value" tags.
+func encodeDimensions(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return dimensionsSeparator + strings.Join(tags, dimensionsSeparator)
+}
+
+// decodeDimensions returns the "key:value" tags of encoded dimensions.
+func decodeDimensions(dims string) []string {
+	if dims == "" {
+		return nil
+	}
+	return strings.Split(dims[len(dimensionsSeparator):], dimensionsSeparator)
+}
@@ -0,0 +1,86 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package stats
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+)
+
+func TestSanitizeDimensions(t *testing.T) {
+	assert.Nil(t, sanitizeDimensions(nil))
+	assert.Equal(t, []string{"region", "customer_tier"}, sanitizeDimensions([]string{" region", "", "customer_tier", "region"}))
+
+	var keys []string
+	for i := 0; i < 2*maxDimensions; i++ {
+		keys = append(keys, fmt.Sprintf("key%d", i))
+	}
+	assert.Equal(t, keys[:maxDimensions], sanitizeDimensions(keys))
+}
+
+func TestSpanDimensions(t *testing.T) {
+	d := newSpanDimensions([]string{"region", "customer_tier"}, 2)
+	span := func(meta map[string]string) *pb.Span { return &pb.Span{Meta: meta} }
+
+	assert.Equal(t, "", d.encode(span(nil)))
+	assert.Equal(t, "\x00customer_tier:gold", d.encode(span(map[string]string{"customer_tier": "gold"})))
+	assert.Equal(t, "\x00region:eu\x00customer_tier:gold", d.encode(span(map[string]string{"region": "eu", "customer_tier": "gold"})))
+	assert.Equal(t, "\x00region:us", d.encode(span(map[string]string{"region": "us"})))
+	// the cardinality limit of region is reached
+	assert.Equal(t, "\x00region:_overflow", d.encode(span(map[string]string{"region": "ap"})))
+	assert.Equal(t, "\x00region:eu\x00customer_tier:silver", d.encode(span(map[string]string{"region": "eu", "customer_tier": "silver"})))
+	assert.Equal(t, map[string]int64{"region": 1}, d.overflows)
+
+	assert.Equal(t, []string{"region:eu", "customer_tier:gold"}, decodeDimensions(encodeDimensions([]string{"region:eu", "customer_tier:gold"})))
+	assert.Equal(t, []string{""}, decodeDimensions(encodeDimensions([]string{""})))
+	assert.Nil(t, decodeDimensions(encodeDimensions(nil)))
+}
+
+func TestConcentratorDimensions(t *testing.T) {
+	now := time.Now()
+	c := NewConcentrator(&config.AgentConfig{
+		BucketInterval:                time.Duration(testBucketInterval),
+		DefaultEnv:                    "env",
+		Hostname:                      "hostname",
+		StatsDimensions:               []string{"customer_tier"},
+		StatsDimensionsMaxCardinality: 2,
+	}, make(chan pb.StatsPayload), now)
+
+	var spans []*pb.Span
+	for i, tier := range []string{"gold", "gold", "silver", "bronze", "platinum", ""} {
+		s := testSpan(uint64(i+1), 0, 50, 5, "A1", "resource1", 0)
+		if tier != "" {
+			s.Meta = map[string]string{"customer_tier": tier}
+		}
+		spans = append(spans, s)
+	}
+	traceutil.ComputeTopLevel(spans)
+	c.addNow(toProcessedTrace(spans, "none", ""), "")
+
+	stats := c.flushNow(now.UnixNano() + int64(c.bufferLen)*testBucketInterval)
+	require.Len(t, stats.Stats, 1)
+	require.Len(t, stats.Stats[0].Stats, 1)
+	hits := make(map[string]uint64)
+	for _, gs := range stats.Stats[0].Stats[0].Stats {
+		assert.Equal(t, "A1", gs.Service)
+		assert.LessOrEqual(t, len(gs.Tags), 1)
+		hits[fmt.Sprint(gs.Tags)] = gs.Hits
+	}
+	assert.Equal(t, map[string]uint64{
+		"[customer_tier:gold]":      2,
+		"[customer_tier:silver]":    1,
+		"[customer_tier:_overflow]": 2,
+		"[]":                        1,
+	}, hits)
+}
@@ -0,0 +1,281 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package writer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+)
+
+const (
+	diskBufferExtension     = ".payload"
+	diskBufferTempExtension = ".tmp"
+	diskBufferFileFormat    = "2006_01_02__15_04_05.000000000_"
+)
+
+// diskBufferHeader is written on the first line of each file, before the payload body.
+type diskBufferHeader struct {
+	Headers map[string]string `json:"headers"`
+}
+
+type diskBufferFile struct {
+	queue   string
+	path    string
+	size    int64
+	created time.Time
+}
+
+// diskBuffer stores on disk the payloads which could not be kept in the senders' memory,
+// because their queue was full or because the agent is stopping, to replay them once the
+// endpoint recovers, possibly after a restart. Each sender has its own queue in the buffer,
+// identified by its endpoint. The buffer is bounded in size and age: the oldest payloads are
+// dropped to make room for new ones, and outdated payloads are dropped instead of being replayed.
+// A diskBuffer is safe for concurrent use, it is shared by the senders of a writer.
+type diskBuffer struct {
+	path           string
+	maxSizeInBytes int64
+	maxAge         time.Duration
+
+	mu                 sync.Mutex
+	files              []diskBufferFile // all files, oldest first
+	queues             map[string]int   // number of files by queue
+	currentSizeInBytes int64
+}
+
+// newDiskBuffer returns a disk buffer storing payloads in path. The payloads stored by a
+// previous run of the agent are reloaded to be replayed.
+func newDiskBuffer(path string, maxSizeInBytes int64, maxAge time.Duration) (*diskBuffer, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, err
+	}
+	b := &diskBuffer{
+		path:           path,
+		maxSizeInBytes: maxSizeInBytes,
+		maxAge:         maxAge,
+		queues:         make(map[string]int),
+	}
+	if err := b.reloadExistingFiles(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// newWriterDiskBuffer returns the disk buffer of the writer with the given name, storing its
+// payloads in a sub-directory of the configured path. It returns nil when the disk buffer is
+// disabled or can not be created.
+func newWriterDiskBuffer(cfg *config.AgentConfig, name string) *diskBuffer {
+	dcfg := cfg.DiskBuffer
+	if dcfg == nil || dcfg.MaxSizeInBytes <= 0 || dcfg.Path == "" {
+		return nil
+	}
+	path := filepath.Join(dcfg.Path, name)
+	b, err := newDiskBuffer(path, dcfg.MaxSizeInBytes, dcfg.MaxAge)
+	if err != nil {
+		log.Errorf("Could not create the disk buffer in %s, payloads will be dropped during outages: %v", path, err)
+		return nil
+	}
+	return b
+}
+
+// diskBufferQueue returns the name of the queue of the payloads sent to url with apiKey. The
+// API key is part of it since several endpoints may share the same host, it is hashed to not be
+// written on disk.
+func diskBufferQueue(url, apiKey string) string {
+	h := sha256.Sum256([]byte(url + "\n" + apiKey))
+	return hex.EncodeToString(h[:8])
+}
+
+// store writes the payload p on disk in the given queue, returns an error if it could not be stored.
+func (b *diskBuffer) store(queue string, p *payload) error {
+	header, err := json.Marshal(diskBufferHeader{Headers: p.headers})
+	if err != nil {
+		return err
+	}
+	size := int64(len(header) + 1 + p.body.Len())
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if size > b.maxSizeInBytes {
+		return fmt.Errorf("payload too large for the disk buffer. Current:%v Maximum:%v", size, b.maxSizeInBytes)
+	}
+	b.removeOutdatedFiles()
+	for len(b.files) > 0 && b.currentSizeInBytes+size > b.maxSizeInBytes {
+		log.Warnf("Maximum disk space for APM payloads is reached. Removing %s", b.files[0].path)
+		b.removeFileAt(0)
+	}
+
+	dir := filepath.Join(b.path, queue)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	// write to a temporary file first so that a partially written file is never replayed
+	file, err := ioutil.TempFile(dir, time.Now().UTC().Format(diskBufferFileFormat)+"*"+diskBufferTempExtension)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(header, '\n'))
+	if err == nil {
+		_, err = file.Write(p.body.Bytes())
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
+	path := strings.TrimSuffix(file.Name(), diskBufferTempExtension) + diskBufferExtension
+	if err := os.Rename(file.Name(), path); err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
+
+	b.addFile(diskBufferFile{queue: queue, path: path, size: size, created: time.Now()})
+	return nil
+}
+
+// replay reads the payloads stored in the given queue, oldest first, and passes them to send
+// until send returns false or the queue is empty. Payloads are removed from disk once send
+// accepted them. Returns the number of payloads replayed.
+func (b *diskBuffer) replay(queue string, send func(*payload) bool) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.removeOutdatedFiles()
+	replayed := 0
+	for i := 0; i < len(b.files); {
+		if b.files[i].queue != queue {
+			i++
+			continue
+		}
+		p, err := readDiskBufferFile(b.files[i].path)
+		if err != nil {
+			log.Warnf("Could not read APM payload %s, dropping it: %v", b.files[i].path, err)
+			b.removeFileAt(i)
+			continue
+		}
+		if !send(p) {
+			ppool.Put(p)
+			break
+		}
+		b.removeFileAt(i)
+		replayed++
+	}
+	return replayed
+}
+
+// has reports whether there are payloads stored in the given queue.
+func (b *diskBuffer) has(queue string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.queues[queue] > 0
+}
+
+// sizeInBytes returns the size of the payloads stored on disk.
+func (b *diskBuffer) sizeInBytes() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.currentSizeInBytes
+}
+
+func (b *diskBuffer) removeOutdatedFiles() {
+	if b.maxAge <= 0 {
+		return
+	}
+	deadline := time.Now().Add(-b.maxAge)
+	for len(b.files) > 0 && b.files[0].created.Before(deadline) {
+		log.Warnf("APM payload %s is older than %v. Removing it", b.files[0].path, b.maxAge)
+		b.removeFileAt(0)
+	}
+}
+
+func (b *diskBuffer) addFile(file diskBufferFile) {
+	b.files = append(b.files, file)
+	b.queues[file.queue]++
+	b.currentSizeInBytes += file.size
+}
+
+func (b *diskBuffer) removeFileAt(index int) {
+	file := b.files[index]
+	b.files = append(b.files[:index], b.files[index+1:]...)
+	if b.queues[file.queue]--; b.queues[file.queue] <= 0 {
+		delete(b.queues, file.queue)
+	}
+	b.currentSizeInBytes -= file.size
+	if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Could not remove APM payload %s: %v", file.path, err)
+	}
+}
+
+func (b *diskBuffer) reloadExistingFiles() error {
+	queues, err := ioutil.ReadDir(b.path)
+	if err != nil {
+		return err
+	}
+	for _, queue := range queues {
+		if !queue.IsDir() {
+			continue
+		}
+		dir := filepath.Join(b.path, queue.Name())
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			switch filepath.Ext(entry.Name()) {
+			case diskBufferTempExtension:
+				// left over by an interrupted write
+				_ = os.Remove(path)
+			case diskBufferExtension:
+				b.addFile(diskBufferFile{queue: queue.Name(), path: path, size: entry.Size(), created: entry.ModTime()})
+			}
+		}
+	}
+	// the file names start with their creation time, their order is more precise than the modification times
+	sort.SliceStable(b.files, func(i, j int) bool {
+		return filepath.Base(b.files[i].path) < filepath.Base(b.files[j].path)
+	})
+	if len(b.files) > 0 {
+		log.Infof("Found %d APM payloads to replay in %s", len(b.files), b.path)
+	}
+	return nil
+}
+
+func readDiskBufferFile(path string) (*payload, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	eol := bytes.IndexByte(content, '\n')
+	if eol == -1 {
+		return nil, fmt.Errorf("missing header")
+	}
+	var header diskBufferHeader
+	if err := json.Unmarshal(content[:eol], &header); err != nil {
+		return nil, fmt.Errorf("invalid header: %v", err)
+	}
+	p := newPayload(header.Headers)
+	p.body.Write(content[eol+1:])
+	return p, nil
+}
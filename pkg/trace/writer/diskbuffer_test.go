@@ -0,0 +1,119 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package writer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+)
+
+func newDiskBufferPayload(body string) *payload {
+	p := newPayload(map[string]string{"Content-Type": "application/msgpack"})
+	p.body.WriteString(body)
+	return p
+}
+
+// replayAll returns the bodies of all the payloads of the given queue.
+func replayAll(b *diskBuffer, queue string) []string {
+	var bodies []string
+	b.replay(queue, func(p *payload) bool {
+		bodies = append(bodies, p.body.String())
+		return true
+	})
+	return bodies
+}
+
+func TestDiskBuffer(t *testing.T) {
+	t.Run("store", func(t *testing.T) {
+		b, err := newDiskBuffer(t.TempDir(), 1024, time.Hour)
+		require.NoError(t, err)
+		for _, body := range []string{"a1", "a2", "a3"} {
+			require.NoError(t, b.store("a", newDiskBufferPayload(body)))
+		}
+		require.NoError(t, b.store("b", newDiskBufferPayload("b1")))
+		assert.True(t, b.has("a"))
+		assert.True(t, b.has("b"))
+		assert.False(t, b.has("c"))
+
+		var headers map[string]string
+		n := b.replay("a", func(p *payload) bool {
+			headers = p.headers
+			return p.body.String() != "a3"
+		})
+		assert.Equal(t, 2, n)
+		assert.Equal(t, map[string]string{"Content-Type": "application/msgpack"}, headers)
+		assert.Equal(t, []string{"a3"}, replayAll(b, "a"))
+		assert.False(t, b.has("a"))
+		assert.Equal(t, []string{"b1"}, replayAll(b, "b"))
+		assert.Zero(t, b.sizeInBytes())
+	})
+
+	t.Run("max-size", func(t *testing.T) {
+		size := int64(len(`{"headers":{"Content-Type":"application/msgpack"}}`) + 1 + 2)
+		b, err := newDiskBuffer(t.TempDir(), 3*size, time.Hour)
+		require.NoError(t, err)
+		for _, body := range []string{"a1", "b1", "a2", "a3"} {
+			require.NoError(t, b.store(body[:1], newDiskBufferPayload(body)))
+		}
+		assert.Equal(t, 3*size, b.sizeInBytes())
+		assert.Error(t, b.store("a", newDiskBufferPayload(string(make([]byte, 3*size)))))
+		assert.Equal(t, []string{"a2", "a3"}, replayAll(b, "a"))
+		assert.Equal(t, []string{"b1"}, replayAll(b, "b"))
+	})
+
+	t.Run("max-age", func(t *testing.T) {
+		b, err := newDiskBuffer(t.TempDir(), 1024, time.Hour)
+		require.NoError(t, err)
+		require.NoError(t, b.store("a", newDiskBufferPayload("a1")))
+		require.NoError(t, b.store("a", newDiskBufferPayload("a2")))
+		b.files[0].created = time.Now().Add(-2 * time.Hour)
+		assert.Equal(t, []string{"a2"}, replayAll(b, "a"))
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		dir := t.TempDir()
+		b, err := newDiskBuffer(dir, 1024, time.Hour)
+		require.NoError(t, err)
+		for _, body := range []string{"a1", "b1", "a2"} {
+			require.NoError(t, b.store(body[:1], newDiskBufferPayload(body)))
+		}
+		// an interrupted write and a corrupted file
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a", "2000_01_01__00_00_00.000000000_1"+diskBufferTempExtension), []byte("{}\nx"), 0600))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a", "2000_01_01__00_00_00.000000000_2"+diskBufferExtension), []byte("x"), 0600))
+
+		b, err = newDiskBuffer(dir, 1024, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a1", "a2"}, replayAll(b, "a"))
+		assert.Equal(t, []string{"b1"}, replayAll(b, "b"))
+		entries, err := ioutil.ReadDir(filepath.Join(dir, "a"))
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+}
+
+func TestNewWriterDiskBuffer(t *testing.T) {
+	cfg := config.New()
+	assert.Nil(t, newWriterDiskBuffer(cfg, "traces"))
+
+	dir := t.TempDir()
+	cfg.DiskBuffer.MaxSizeInBytes = 1024
+	cfg.DiskBuffer.Path = dir
+	b := newWriterDiskBuffer(cfg, "traces")
+	require.NotNil(t, b)
+	assert.Equal(t, filepath.Join(dir, "traces"), b.path)
+	_, err := os.Stat(b.path)
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, diskBufferQueue("https://host", "key1"), diskBufferQueue("https://host", "key2"))
+}
@@ -27,8 +27,9 @@ import (
 )
 
 // newSenders returns a list of senders based on the given agent configuration, using climit
-// as the maximum number of concurrent outgoing connections, writing to path.
-func newSenders(cfg *config.AgentConfig, r eventRecorder, path string, climit, qsize int) []*sender {
+// as the maximum number of concurrent outgoing connections, writing to path. When buf is not
+// nil, the payloads which would be dropped are stored in it instead.
+func newSenders(cfg *config.AgentConfig, r eventRecorder, path string, climit, qsize int, buf *diskBuffer) []*sender {
 	if e := cfg.Endpoints; len(e) == 0 || e[0].Host == "" || e[0].APIKey == "" {
 		panic(errors.New("config was not properly validated"))
 	}
@@ -48,6 +49,8 @@ func newSenders(cfg *config.AgentConfig, r eventRecorder, path string, climit, q
 			url:       url,
 			apiKey:    endpoint.APIKey,
 			recorder:  r,
+			buffer:    buf,
+			queueName: diskBufferQueue(url.String(), endpoint.APIKey),
 		})
 	}
 	return senders
@@ -74,6 +77,12 @@ const (
 	// eventTypeDropped specifies that a payload had to be dropped to make room
 	// in the queue.
 	eventTypeDropped
+	// eventTypeStored specifies that a payload was stored in the disk buffer
+	// instead of being dropped.
+	eventTypeStored
+	// eventTypeReplayed specifies that a payload was read back from the disk
+	// buffer and queued to be sent.
+	eventTypeReplayed
 )
 
 var eventTypeStrings = map[eventType]string{
@@ -81,6 +90,8 @@ var eventTypeStrings = map[eventType]string{
 	eventTypeSent:     "eventTypeSent",
 	eventTypeRejected: "eventTypeRejected",
 	eventTypeDropped:  "eventTypeDropped",
+	eventTypeStored:   "eventTypeStored",
+	eventTypeReplayed: "eventTypeReplayed",
 }
 
 // String implements fmt.Stringer.
@@ -125,6 +136,11 @@ type senderConfig struct {
 	// recorder specifies the eventRecorder to use when reporting events occurring
 	// in the sender.
 	recorder eventRecorder
+	// buffer, when set, stores the payloads which would otherwise be dropped, to
+	// replay them once the destination recovers.
+	buffer *diskBuffer
+	// queueName specifies the queue of the sender in the buffer.
+	queueName string
 }
 
 // sender is responsible for sending payloads to a given URL. It uses a size-limited
@@ -132,10 +148,11 @@ type senderConfig struct {
 type sender struct {
 	cfg *senderConfig
 
-	queue    chan *payload // payload queue
-	climit   chan struct{} // semaphore for limiting concurrent connections
-	inflight int32         // inflight payloads
-	attempt  int32         // active retry attempt
+	queue     chan *payload // payload queue
+	climit    chan struct{} // semaphore for limiting concurrent connections
+	inflight  int32         // inflight payloads
+	attempt   int32         // active retry attempt
+	replaying int32         // 1 while payloads are being replayed from the disk buffer
 
 	mu     sync.RWMutex // guards closed
 	closed bool         // closed reports if the loop is stopped
@@ -149,6 +166,10 @@ func newSender(cfg *senderConfig) *sender {
 		climit: make(chan struct{}, cfg.maxConns),
 	}
 	go s.loop()
+	if cfg.buffer != nil && cfg.buffer.has(cfg.queueName) {
+		// payloads stored by a previous run
+		go s.replay()
+	}
 	return &s
 }
 
@@ -174,16 +195,66 @@ func (s *sender) backoff() {
 	time.Sleep(delay)
 }
 
+// replay queues the payloads stored in the disk buffer, oldest first, as long as the queue
+// is less than half full, to leave room for the new payloads.
+func (s *sender) replay() {
+	if !atomic.CompareAndSwapInt32(&s.replaying, 0, 1) {
+		// already replaying
+		return
+	}
+	defer atomic.StoreInt32(&s.replaying, 0)
+	n := s.cfg.buffer.replay(s.cfg.queueName, func(p *payload) bool {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		if s.closed || len(s.queue) >= (cap(s.queue)+1)/2 {
+			return false
+		}
+		select {
+		case s.queue <- p:
+			atomic.AddInt32(&s.inflight, 1)
+			s.recordEvent(eventTypeReplayed, &eventData{
+				bytes: p.body.Len(),
+				count: 1,
+			})
+			return true
+		default:
+			return false
+		}
+	})
+	if n > 0 {
+		log.Debugf("Replayed %d payloads from the disk buffer to %s", n, s.cfg.url.Hostname())
+	}
+}
+
 // Stop stops the sender. It attempts to wait for all inflight payloads to complete
-// with a timeout of 5 seconds.
+// with a timeout of 5 seconds. The payloads left in the queue are stored in the disk
+// buffer, if any, to be sent by the next run.
 func (s *sender) Stop() {
 	s.WaitForInflight()
 	s.mu.Lock()
 	s.closed = true
 	s.mu.Unlock()
+	if s.cfg.buffer != nil {
+		s.storeQueued()
+	}
 	close(s.queue)
 }
 
+// storeQueued stores the payloads of the queue in the disk buffer.
+func (s *sender) storeQueued() {
+	for {
+		select {
+		case p := <-s.queue:
+			s.dropPayload(p, &eventData{
+				bytes: p.body.Len(),
+				count: 1,
+			})
+		default:
+			return
+		}
+	}
+}
+
 // WaitForInflight blocks until all in progress payloads are sent,
 // or the timeout is reached.
 func (s *sender) WaitForInflight() {
@@ -214,7 +285,7 @@ func (s *sender) Push(p *payload) {
 			// drop the oldest item in the queue to make room
 			select {
 			case p := <-s.queue:
-				s.releasePayload(p, eventTypeDropped, &eventData{
+				s.dropPayload(p, &eventData{
 					bytes: p.body.Len(),
 					count: 1,
 				})
@@ -245,28 +316,16 @@ func (s *sender) sendPayload(p *payload) {
 	switch err.(type) {
 	case *retriableError:
 		// request failed again, but can be retried
-		s.mu.RLock()
-		defer s.mu.RUnlock()
-		if s.closed {
-			// sender is stopped
+		if s.retry(p, err, stats) {
 			return
 		}
-		atomic.AddInt32(&s.attempt, 1)
-
-		if r := atomic.AddInt32(&p.retries, 1); (r&(r-1)) == 0 && r > 3 {
-			// Only log a warning if the retry attempt is a power of 2
-			// and larger than 3, to avoid alerting the user unnecessarily.
-			// e.g. attempts 4, 8, 16, etc.
-			log.Warnf("Retried payload %d times: %s", r, err.Error())
-		}
-		select {
-		case s.queue <- p:
-			s.recordEvent(eventTypeRetry, stats)
+		// queue is full or sender is stopped; since this is the oldest payload, we drop it,
+		// unless it can be kept on disk. The disk buffer must not be used while holding mu,
+		// as replays lock them in the reverse order.
+		if s.cfg.buffer == nil && s.isClosed() {
 			return
-		default:
-			// queue is full; since this is the oldest payload, we drop it
-			s.releasePayload(p, eventTypeDropped, stats)
 		}
+		s.dropPayload(p, stats)
 	case nil:
 		// request was successful; the retry queue may have grown large - we should
 		// reduce the backoff gradually to avoid hitting the edge too hard.
@@ -278,12 +337,62 @@ func (s *sender) sendPayload(p *payload) {
 			}
 		}
 		s.releasePayload(p, eventTypeSent, stats)
+		if s.cfg.buffer != nil && s.cfg.buffer.has(s.cfg.queueName) {
+			// the destination recovered, send the payloads stored during the outage
+			go s.replay()
+		}
 	default:
 		// this is a fatal error, we have to drop this payload
 		s.releasePayload(p, eventTypeRejected, stats)
 	}
 }
 
+// retry puts back the payload p on the queue after a retriable error err. It returns false
+// if the queue is full or if the sender is stopped.
+func (s *sender) retry(p *payload, err error, stats *eventData) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return false
+	}
+	atomic.AddInt32(&s.attempt, 1)
+
+	if r := atomic.AddInt32(&p.retries, 1); (r&(r-1)) == 0 && r > 3 {
+		// Only log a warning if the retry attempt is a power of 2
+		// and larger than 3, to avoid alerting the user unnecessarily.
+		// e.g. attempts 4, 8, 16, etc.
+		log.Warnf("Retried payload %d times: %s", r, err.Error())
+	}
+	select {
+	case s.queue <- p:
+		s.recordEvent(eventTypeRetry, stats)
+		return true
+	default:
+		return false
+	}
+}
+
+// isClosed reports whether the sender is stopped.
+func (s *sender) isClosed() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.closed
+}
+
+// dropPayload releases the payload p which can not be sent, after storing it in the
+// disk buffer if there is one.
+func (s *sender) dropPayload(p *payload, data *eventData) {
+	if s.cfg.buffer != nil {
+		err := s.cfg.buffer.store(s.cfg.queueName, p)
+		if err == nil {
+			s.releasePayload(p, eventTypeStored, data)
+			return
+		}
+		log.Errorf("Could not store payload in the disk buffer: %v", err)
+	}
+	s.releasePayload(p, eventTypeDropped, data)
+}
+
 // waitForSenders blocks until all senders have sent their inflight payloads
 func waitForSenders(senders []*sender) {
 	var wg sync.WaitGroup
@@ -252,6 +252,57 @@ func TestSender(t *testing.T) {
 			assert.True(time.Since(start)-failed[i].duration < time.Second)
 		}
 	})
+
+	t.Run("disk buffer", func(t *testing.T) {
+		assert := assert.New(t)
+		buf, err := newDiskBuffer(t.TempDir(), 1024*1024, time.Hour)
+		assert.NoError(err)
+
+		// the destination is down: the payloads which do not fit in the queue are stored,
+		// and so are the ones left in the queue when stopping
+		var recorder mockRecorder
+		cfg := testSenderConfig("http://localhost")
+		cfg.recorder = &recorder
+		cfg.buffer = buf
+		cfg.queueName = "test"
+		s := &sender{cfg: cfg, queue: make(chan *payload, 2)}
+		var want []string
+		for i := 0; i < 5; i++ {
+			p := expectResponses(200)
+			p.headers = map[string]string{"Content-Type": "application/msgpack"}
+			want = append(want, p.body.String())
+			s.Push(p)
+		}
+		assert.Len(recorder.data(eventTypeStored), 3)
+		s.closed = true
+		s.storeQueued()
+		assert.Len(recorder.data(eventTypeStored), 5)
+		assert.Empty(recorder.data(eventTypeDropped))
+		assert.True(buf.has("test"))
+
+		// the destination is up again: the stored payloads are replayed, a few at
+		// a time to leave room in the queue, on startup and after each successful send
+		server := newTestServer()
+		defer server.Close()
+		cfg = testSenderConfig(server.URL)
+		cfg.maxQueued = 4
+		cfg.recorder = &recorder
+		cfg.buffer = buf
+		cfg.queueName = "test"
+		s = newSender(cfg)
+		assert.Eventually(func() bool { return server.Accepted() == 5 }, 5*time.Second, 10*time.Millisecond)
+		s.Stop()
+
+		var got []string
+		for _, p := range server.Payloads() {
+			got = append(got, p.body.String())
+			assert.Equal("application/msgpack", p.headers["Content-Type"])
+		}
+		assert.ElementsMatch(want, got)
+		assert.Len(recorder.data(eventTypeReplayed), 5)
+		assert.False(buf.has("test"))
+		assert.Zero(buf.sizeInBytes())
+	})
 }
 
 func TestPayload(t *testing.T) {
@@ -306,8 +357,8 @@ func useBackoffDuration(d time.Duration) func() {
 
 // mockRecorder is a mock eventRecorder which records all calls to recordEvent.
 type mockRecorder struct {
-	mu                             sync.RWMutex
-	retry, sent, dropped, rejected []*eventData
+	mu                                               sync.RWMutex
+	retry, sent, dropped, rejected, stored, replayed []*eventData
 }
 
 // data returns all call data for the given eventType.
@@ -323,6 +374,10 @@ func (r *mockRecorder) data(t eventType) []*eventData {
 		return r.dropped
 	case eventTypeRejected:
 		return r.rejected
+	case eventTypeStored:
+		return r.stored
+	case eventTypeReplayed:
+		return r.replayed
 	default:
 		panic("unknown event")
 	}
@@ -341,5 +396,9 @@ func (r *mockRecorder) recordEvent(t eventType, data *eventData) {
 		r.dropped = append(r.dropped, data)
 	case eventTypeRejected:
 		r.rejected = append(r.rejected, data)
+	case eventTypeStored:
+		r.stored = append(r.stored, data)
+	case eventTypeReplayed:
+		r.replayed = append(r.replayed, data)
 	}
 }
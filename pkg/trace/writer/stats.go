@@ -83,7 +83,7 @@ func NewStatsWriter(cfg *config.AgentConfig, in <-chan pb.StatsPayload) *StatsWr
 		qsize = int(math.Max(1, maxmem/payloadSize))
 	}
 	log.Debugf("Stats writer initialized (climit=%d qsize=%d)", climit, qsize)
-	sw.senders = newSenders(cfg, sw, pathStats, climit, qsize)
+	sw.senders = newSenders(cfg, sw, pathStats, climit, qsize, newWriterDiskBuffer(cfg, "stats"))
 	return sw
 }
 
@@ -352,5 +352,14 @@ func (w *StatsWriter) recordEvent(t eventType, data *eventData) {
 		w.easylog.Warn("Stats writer queue full. Payload dropped (%.2fKB).", float64(data.bytes)/1024)
 		metrics.Count("datadog.trace_agent.stats_writer.dropped", 1, nil, 1)
 		metrics.Count("datadog.trace_agent.stats_writer.dropped_bytes", int64(data.bytes), nil, 1)
+
+	case eventTypeStored:
+		w.easylog.Warn("Stats writer queue full. Payload stored on disk (%.2fKB).", float64(data.bytes)/1024)
+		metrics.Count("datadog.trace_agent.stats_writer.disk_buffer.stored", 1, nil, 1)
+		metrics.Count("datadog.trace_agent.stats_writer.disk_buffer.stored_bytes", int64(data.bytes), nil, 1)
+
+	case eventTypeReplayed:
+		metrics.Count("datadog.trace_agent.stats_writer.disk_buffer.replayed", 1, nil, 1)
+		metrics.Count("datadog.trace_agent.stats_writer.disk_buffer.replayed_bytes", int64(data.bytes), nil, 1)
 	}
 }
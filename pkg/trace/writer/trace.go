@@ -106,7 +106,7 @@ func NewTraceWriter(cfg *config.AgentConfig) *TraceWriter {
 		tw.tick = time.Duration(s*1000) * time.Millisecond
 	}
 	log.Debugf("Trace writer initialized (climit=%d qsize=%d)", climit, qsize)
-	tw.senders = newSenders(cfg, tw, pathTraces, climit, qsize)
+	tw.senders = newSenders(cfg, tw, pathTraces, climit, qsize, newWriterDiskBuffer(cfg, "traces"))
 	return tw
 }
 
@@ -308,5 +308,14 @@ func (w *TraceWriter) recordEvent(t eventType, data *eventData) {
 		w.easylog.Warn("Trace writer queue full. Payload dropped (%.2fKB).", float64(data.bytes)/1024)
 		metrics.Count("datadog.trace_agent.trace_writer.dropped", 1, nil, 1)
 		metrics.Count("datadog.trace_agent.trace_writer.dropped_bytes", int64(data.bytes), nil, 1)
+
+	case eventTypeStored:
+		w.easylog.Warn("Trace writer queue full. Payload stored on disk (%.2fKB).", float64(data.bytes)/1024)
+		metrics.Count("datadog.trace_agent.trace_writer.disk_buffer.stored", 1, nil, 1)
+		metrics.Count("datadog.trace_agent.trace_writer.disk_buffer.stored_bytes", int64(data.bytes), nil, 1)
+
+	case eventTypeReplayed:
+		metrics.Count("datadog.trace_agent.trace_writer.disk_buffer.replayed", 1, nil, 1)
+		metrics.Count("datadog.trace_agent.trace_writer.disk_buffer.replayed_bytes", int64(data.bytes), nil, 1)
 	}
 }